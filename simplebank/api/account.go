@@ -1,14 +1,12 @@
 package api
 
 import (
-	"database/sql"
 	"errors"
 	"net/http"
 
 	db "github.com/ebaudet/simplebank/db/sqlc"
 	"github.com/ebaudet/simplebank/token"
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
 )
 
 type createAccountRequest struct {
@@ -29,19 +27,18 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		Balance:  0,
 		Currency: req.Currency,
 	}
-	account, err := server.store.CreateAccount(ctx, account_params)
+	account, err := server.store.CreateAccountTx(ctx, account_params)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation", "foreign_key_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
-				return
-			}
+		switch db.ErrorCode(err) {
+		case db.UniqueViolation, db.ForeignKeyViolation:
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	ctx.Header(etagHeaderKey, accountETag(account))
 	ctx.JSON(http.StatusCreated, account)
 }
 
@@ -58,7 +55,7 @@ func (server *Server) getAccount(ctx *gin.Context) {
 
 	account, err := server.store.GetAccount(ctx, req.ID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, db.ErrRecordNotFound) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
@@ -75,6 +72,13 @@ func (server *Server) getAccount(ctx *gin.Context) {
 		return
 	}
 
+	etag := accountETag(account)
+	ctx.Header(etagHeaderKey, etag)
+	if ctx.GetHeader(ifNoneMatchHeaderKey) == etag {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
 	ctx.JSON(http.StatusOK, account)
 }
 
@@ -117,18 +121,29 @@ func (server *Server) deleteAccount(ctx *gin.Context) {
 		return
 	}
 
+	ifVersion, err := ifMatchVersion(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	args := db.DeleteAccountOwnerParams{
-		ID:    req.ID,
-		Owner: authPayload.Username,
+	args := db.DeleteAccountTxParams{
+		ID:        req.ID,
+		Owner:     authPayload.Username,
+		IfVersion: ifVersion,
 	}
-	err := server.store.DeleteAccountOwner(ctx, args)
+	err = server.store.DeleteAccountTx(ctx, args)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, db.ErrRecordNotFound) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
+		if errors.Is(err, db.ErrVersionMismatch) {
+			ctx.JSON(http.StatusPreconditionFailed, errorResponse(err))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -156,11 +171,16 @@ func (server *Server) debitAccount(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	ifVersion, err := ifMatchVersion(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
 
 	// Checking if the account belongs to the user.
 	account, err := server.store.GetAccount(ctx, uri.ID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, db.ErrRecordNotFound) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
@@ -175,16 +195,22 @@ func (server *Server) debitAccount(ctx *gin.Context) {
 	}
 
 	// Debiting the amount to the account.
-	arg := db.AddAccountBalanceParams{
-		ID:     uri.ID,
-		Amount: -form.Amount,
+	arg := db.AddAccountBalanceTxParams{
+		ID:        uri.ID,
+		Amount:    -form.Amount,
+		IfVersion: ifVersion,
 	}
-	account, err = server.store.AddAccountBalance(ctx, arg)
+	account, err = server.store.AddAccountBalanceTx(ctx, arg)
 	if err != nil {
+		if errors.Is(err, db.ErrVersionMismatch) {
+			ctx.JSON(http.StatusPreconditionFailed, errorResponse(err))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	ctx.Header(etagHeaderKey, accountETag(account))
 	ctx.JSON(http.StatusOK, account)
 }
 
@@ -208,11 +234,16 @@ func (server *Server) creditAccount(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	ifVersion, err := ifMatchVersion(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
 
 	// Checking if the account belongs to the user.
 	account, err := server.store.GetAccount(ctx, uri.ID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, db.ErrRecordNotFound) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
@@ -227,15 +258,21 @@ func (server *Server) creditAccount(ctx *gin.Context) {
 	}
 
 	// Crediting the amount to the account.
-	arg := db.AddAccountBalanceParams{
-		ID:     uri.ID,
-		Amount: form.Amount,
+	arg := db.AddAccountBalanceTxParams{
+		ID:        uri.ID,
+		Amount:    form.Amount,
+		IfVersion: ifVersion,
 	}
-	account, err = server.store.AddAccountBalance(ctx, arg)
+	account, err = server.store.AddAccountBalanceTx(ctx, arg)
 	if err != nil {
+		if errors.Is(err, db.ErrVersionMismatch) {
+			ctx.JSON(http.StatusPreconditionFailed, errorResponse(err))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	ctx.Header(etagHeaderKey, accountETag(account))
 	ctx.JSON(http.StatusOK, account)
 }
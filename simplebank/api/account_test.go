@@ -35,7 +35,7 @@ func TestGetAccountAPI(t *testing.T) {
 			name:      "OK",
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -67,13 +67,13 @@ func TestGetAccountAPI(t *testing.T) {
 			name:      "NotFound",
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
-					Return(db.Account{}, sql.ErrNoRows)
+					Return(db.Account{}, db.ErrRecordNotFound)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusNotFound, recorder.Code)
@@ -83,7 +83,7 @@ func TestGetAccountAPI(t *testing.T) {
 			name:      "InternalError",
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -99,7 +99,7 @@ func TestGetAccountAPI(t *testing.T) {
 			name:      "InvalidID",
 			accountID: 0,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -122,6 +122,7 @@ func TestGetAccountAPI(t *testing.T) {
 			store := mockdb.NewMockStore(ctrl)
 			// build stubs
 			tc.buildStubs(store)
+			allowValidTokens(store)
 			// start test server and send request
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
@@ -155,7 +156,7 @@ func TestCreateAccountAPI(t *testing.T) {
 				"currency": account.Currency,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				arg := db.CreateAccountParams{
@@ -163,7 +164,7 @@ func TestCreateAccountAPI(t *testing.T) {
 					Currency: account.Currency,
 					Balance:  0,
 				}
-				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusCreated, recorder.Code)
@@ -185,7 +186,7 @@ func TestCreateAccountAPI(t *testing.T) {
 				}
 
 				store.EXPECT().
-					CreateAccount(gomock.Any(), gomock.Eq(arg)).
+					CreateAccountTx(gomock.Any(), gomock.Eq(arg)).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -200,7 +201,7 @@ func TestCreateAccountAPI(t *testing.T) {
 				"currency": account.Currency,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				arg := db.CreateAccountParams{
@@ -210,7 +211,7 @@ func TestCreateAccountAPI(t *testing.T) {
 				}
 
 				store.EXPECT().
-					CreateAccount(gomock.Any(), gomock.Eq(arg)).
+					CreateAccountTx(gomock.Any(), gomock.Eq(arg)).
 					Times(1).
 					Return(db.Account{}, sql.ErrConnDone)
 			},
@@ -225,11 +226,11 @@ func TestCreateAccountAPI(t *testing.T) {
 				"currency": "invalid",
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateAccount(gomock.Any(), gomock.Any()).
+					CreateAccountTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -248,6 +249,8 @@ func TestCreateAccountAPI(t *testing.T) {
 			store := mockdb.NewMockStore(ctrl)
 			// build stubs
 			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
 			// start test server and send request
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
@@ -303,7 +306,7 @@ func TestGetAccountsAPI(t *testing.T) {
 				pageSize: n,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				arg := db.ListAccountsByOwnerParams{
@@ -349,7 +352,7 @@ func TestGetAccountsAPI(t *testing.T) {
 				pageSize: n,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -367,7 +370,7 @@ func TestGetAccountsAPI(t *testing.T) {
 				pageSize: n,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				arg := db.ListAccountsByOwnerParams{
@@ -396,6 +399,7 @@ func TestGetAccountsAPI(t *testing.T) {
 			store := mockdb.NewMockStore(ctrl)
 			// build stubs
 			tc.buildStubs(store)
+			allowValidTokens(store)
 			// start test server and send request
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
@@ -434,15 +438,15 @@ func TestDeleteAccountAPI(t *testing.T) {
 			name:      "OK",
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				args := db.DeleteAccountOwnerParams{
+				args := db.DeleteAccountTxParams{
 					ID:    account.ID,
 					Owner: user.Username,
 				}
 				store.EXPECT().
-					DeleteAccountOwner(gomock.Any(), gomock.Eq(args)).
+					DeleteAccountTx(gomock.Any(), gomock.Eq(args)).
 					Times(1).
 					Return(nil)
 			},
@@ -456,7 +460,7 @@ func TestDeleteAccountAPI(t *testing.T) {
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					DeleteAccountOwner(gomock.Any(), gomock.Any()).
+					DeleteAccountTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -467,17 +471,17 @@ func TestDeleteAccountAPI(t *testing.T) {
 			name:      "NotFound",
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				args := db.DeleteAccountOwnerParams{
+				args := db.DeleteAccountTxParams{
 					ID:    account.ID,
 					Owner: user.Username,
 				}
 				store.EXPECT().
-					DeleteAccountOwner(gomock.Any(), gomock.Eq(args)).
+					DeleteAccountTx(gomock.Any(), gomock.Eq(args)).
 					Times(1).
-					Return(sql.ErrNoRows)
+					Return(db.ErrRecordNotFound)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusNotFound, recorder.Code)
@@ -487,15 +491,15 @@ func TestDeleteAccountAPI(t *testing.T) {
 			name:      "InternalServerError",
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				args := db.DeleteAccountOwnerParams{
+				args := db.DeleteAccountTxParams{
 					ID:    account.ID,
 					Owner: user.Username,
 				}
 				store.EXPECT().
-					DeleteAccountOwner(gomock.Any(), gomock.Eq(args)).
+					DeleteAccountTx(gomock.Any(), gomock.Eq(args)).
 					Times(1).
 					Return(sql.ErrConnDone)
 			},
@@ -507,15 +511,15 @@ func TestDeleteAccountAPI(t *testing.T) {
 			name:      "BadRequest",
 			accountID: 0,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				args := db.DeleteAccountOwnerParams{
+				args := db.DeleteAccountTxParams{
 					ID:    account.ID,
 					Owner: user.Username,
 				}
 				store.EXPECT().
-					DeleteAccountOwner(gomock.Any(), gomock.Eq(args)).
+					DeleteAccountTx(gomock.Any(), gomock.Eq(args)).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -534,6 +538,8 @@ func TestDeleteAccountAPI(t *testing.T) {
 			store := mockdb.NewMockStore(ctrl)
 			// build stubs
 			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
 			// start test server and send request
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
@@ -576,15 +582,15 @@ func TestDebitAccountAPI(t *testing.T) {
 				amount: amount,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
-				arg := db.AddAccountBalanceParams{
+				arg := db.AddAccountBalanceTxParams{
 					ID:     account.ID,
 					Amount: -amount,
 				}
-				store.EXPECT().AddAccountBalance(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -600,7 +606,7 @@ func TestDebitAccountAPI(t *testing.T) {
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
-				store.EXPECT().AddAccountBalance(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -614,11 +620,11 @@ func TestDebitAccountAPI(t *testing.T) {
 				amount: amount,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
-				store.EXPECT().AddAccountBalance(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
@@ -631,11 +637,11 @@ func TestDebitAccountAPI(t *testing.T) {
 				amount: -10,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
-				store.EXPECT().AddAccountBalance(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
@@ -648,15 +654,15 @@ func TestDebitAccountAPI(t *testing.T) {
 				amount: amount,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
-				arg := db.AddAccountBalanceParams{
+				arg := db.AddAccountBalanceTxParams{
 					ID:     account.ID,
 					Amount: -amount,
 				}
-				store.EXPECT().AddAccountBalance(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.Account{}, sql.ErrConnDone)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.Account{}, sql.ErrConnDone)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
@@ -673,6 +679,8 @@ func TestDebitAccountAPI(t *testing.T) {
 
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
 
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
@@ -717,15 +725,15 @@ func TestCreditAccountAPI(t *testing.T) {
 				amount: amount,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
-				arg := db.AddAccountBalanceParams{
+				arg := db.AddAccountBalanceTxParams{
 					ID:     account.ID,
 					Amount: amount,
 				}
-				store.EXPECT().AddAccountBalance(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -741,7 +749,7 @@ func TestCreditAccountAPI(t *testing.T) {
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
-				store.EXPECT().AddAccountBalance(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -755,11 +763,11 @@ func TestCreditAccountAPI(t *testing.T) {
 				amount: amount,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					AddAccountBalance(gomock.Any(), gomock.Any()).
+					AddAccountBalanceTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -773,11 +781,11 @@ func TestCreditAccountAPI(t *testing.T) {
 				amount: -10,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					AddAccountBalance(gomock.Any(), gomock.Any()).
+					AddAccountBalanceTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -791,15 +799,15 @@ func TestCreditAccountAPI(t *testing.T) {
 				amount: amount,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
-				arg := db.AddAccountBalanceParams{
+				arg := db.AddAccountBalanceTxParams{
 					ID:     account.ID,
 					Amount: amount,
 				}
-				store.EXPECT().AddAccountBalance(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.Account{}, sql.ErrConnDone)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.Account{}, sql.ErrConnDone)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
@@ -815,6 +823,8 @@ func TestCreditAccountAPI(t *testing.T) {
 			defer ctrl.Finish()
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
 
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
@@ -835,12 +845,165 @@ func TestCreditAccountAPI(t *testing.T) {
 	}
 }
 
+func TestAccountETagAPI(t *testing.T) {
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+	etag := fmt.Sprintf(`"%d"`, account.Version)
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		header        http.Header
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "GetAccountETag",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, etag, recorder.Header().Get(etagHeaderKey))
+				requireBodyMatchAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name:   "NotModified",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			header: http.Header{ifNoneMatchHeaderKey: []string{etag}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotModified, recorder.Code)
+				require.Empty(t, recorder.Body.String())
+			},
+		},
+		{
+			name:   "DebitIfMatch",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/accounts/%d/debit?amount=10", account.ID),
+			header: http.Header{ifMatchHeaderKey: []string{etag}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.AddAccountBalanceTxParams{
+					ID:        account.ID,
+					Amount:    -10,
+					IfVersion: account.Version,
+				}
+				updated := account
+				updated.Version++
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(updated, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, fmt.Sprintf(`"%d"`, account.Version+1), recorder.Header().Get(etagHeaderKey))
+			},
+		},
+		{
+			name:   "CreditVersionMismatch",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/accounts/%d/credit?amount=10", account.ID),
+			header: http.Header{ifMatchHeaderKey: []string{etag}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					AddAccountBalanceTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, db.ErrVersionMismatch)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidIfMatch",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/accounts/%d/credit?amount=10", account.ID),
+			header: http.Header{ifMatchHeaderKey: []string{"W/" + etag}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "DeleteVersionMismatch",
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			header: http.Header{ifMatchHeaderKey: []string{etag}},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.DeleteAccountTxParams{
+					ID:        account.ID,
+					Owner:     user.Username,
+					IfVersion: account.Version,
+				}
+				store.EXPECT().DeleteAccountTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.ErrVersionMismatch)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+			},
+		},
+		{
+			name:   "DeleteAnyVersion",
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			header: http.Header{ifMatchHeaderKey: []string{"*"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.DeleteAccountTxParams{
+					ID:    account.ID,
+					Owner: user.Username,
+				}
+				store.EXPECT().DeleteAccountTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, nil)
+			require.NoError(t, err)
+			for key, values := range tc.header {
+				request.Header[key] = values
+			}
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
 func randomAccount(owner string) db.Account {
 	return db.Account{
 		ID:       utils.RandomInt(1, 1000),
 		Owner:    owner,
 		Balance:  utils.RandomMoney(),
 		Currency: utils.RandomCurrency(),
+		Version:  utils.RandomInt(1, 100),
 	}
 }
 
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+var errSelfAdministration = errors.New("cannot change the role or freeze the authenticated user")
+
+type adminUserResponse struct {
+	userResponse
+	IsFrozen bool `json:"is_frozen"`
+}
+
+func newAdminUserResponse(user db.User) adminUserResponse {
+	return adminUserResponse{
+		userResponse: newUserResponse(user),
+		IsFrozen:     user.IsFrozen,
+	}
+}
+
+type listUsersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listUsers returns all the users, by username.
+func (server *Server) listUsers(ctx *gin.Context) {
+	var req listUsersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	users, err := server.store.ListUsers(ctx, db.ListUsersParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]adminUserResponse, len(users))
+	for i, user := range users {
+		rsp[i] = newAdminUserResponse(user)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type listAllAccountsRequest struct {
+	Owner    string `form:"owner" binding:"omitempty,alphanum"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listAllAccounts returns the accounts of every user, or of the owner when given.
+func (server *Server) listAllAccounts(ctx *gin.Context) {
+	var req listAllAccountsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var accounts []db.Account
+	var err error
+	if req.Owner != "" {
+		accounts, err = server.store.ListAccountsByOwner(ctx, db.ListAccountsByOwnerParams{
+			Owner:  req.Owner,
+			Limit:  req.PageSize,
+			Offset: (req.PageID - 1) * req.PageSize,
+		})
+	} else {
+		accounts, err = server.store.ListAccounts(ctx, db.ListAccountsParams{
+			Limit:  req.PageSize,
+			Offset: (req.PageID - 1) * req.PageSize,
+		})
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accounts)
+}
+
+type adjustAccountBalanceUriRequest struct {
+	ID int64 `uri:"id" binding:"min=1,required"`
+}
+
+type adjustAccountBalanceRequest struct {
+	// Amount is added to the balance, it is negative to take money out
+	Amount int64  `json:"amount" binding:"required"`
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// adjustAccountBalance corrects the balance of any account. The adjustment is
+// recorded in the audit log along with its reason.
+func (server *Server) adjustAccountBalance(ctx *gin.Context) {
+	var uri adjustAccountBalanceUriRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req adjustAccountBalanceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	ifVersion, err := ifMatchVersion(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.store.AddAccountBalanceTx(ctx, db.AddAccountBalanceTxParams{
+		ID:        uri.ID,
+		Amount:    req.Amount,
+		IfVersion: ifVersion,
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrVersionMismatch) {
+			ctx.JSON(http.StatusPreconditionFailed, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Header(etagHeaderKey, accountETag(account))
+	ctx.JSON(http.StatusOK, account)
+}
+
+type adminUserUriRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type updateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,role"`
+}
+
+// updateUserRole changes the role of a user, who has to log in again to get it.
+func (server *Server) updateUserRole(ctx *gin.Context) {
+	var uri adminUserUriRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// an admin demoting itself could leave the bank without any admin
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if uri.Username == authPayload.Username {
+		ctx.JSON(http.StatusForbidden, errorResponse(errSelfAdministration))
+		return
+	}
+
+	user, err := server.store.UpdateUserRoleTx(ctx, db.UpdateUserRoleTxParams{
+		Username:     uri.Username,
+		Role:         req.Role,
+		IssuedBefore: time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAdminUserResponse(user))
+}
+
+// updateUser updates the profile of a user, e.g. to fix a typo in its name.
+// A changed email has to be verified by the user, as on updateMe.
+func (server *Server) updateUser(ctx *gin.Context) {
+	var uri adminUserUriRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, ok := server.updateUserProfile(ctx, uri.Username)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAdminUserResponse(user))
+}
+
+// freezeUser shuts a user out: the login is refused and the tokens are revoked.
+func (server *Server) freezeUser(ctx *gin.Context) {
+	server.setUserFrozen(ctx, true)
+}
+
+// unfreezeUser lets a frozen user log in again.
+func (server *Server) unfreezeUser(ctx *gin.Context) {
+	server.setUserFrozen(ctx, false)
+}
+
+func (server *Server) setUserFrozen(ctx *gin.Context, frozen bool) {
+	var uri adminUserUriRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if uri.Username == authPayload.Username {
+		ctx.JSON(http.StatusForbidden, errorResponse(errSelfAdministration))
+		return
+	}
+
+	user, err := server.store.FreezeUserTx(ctx, db.FreezeUserTxParams{
+		Username:     uri.Username,
+		Frozen:       frozen,
+		IssuedBefore: time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAdminUserResponse(user))
+}
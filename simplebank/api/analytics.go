@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+// spendingDefaultRange is used when the `from` parameter is omitted.
+const spendingDefaultRange = 365 * 24 * time.Hour
+
+type getSpendingRequest struct {
+	GroupBy string    `form:"group_by" binding:"required,oneof=category month"`
+	From    time.Time `form:"from"`
+	To      time.Time `form:"to"`
+}
+
+// getSpending aggregates the outgoing entries of all the accounts of the
+// authenticated user, per currency.
+func (server *Server) getSpending(ctx *gin.Context) {
+	var req getSpendingRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.To.IsZero() {
+		req.To = time.Now()
+	}
+	if req.From.IsZero() {
+		req.From = req.To.Add(-spendingDefaultRange)
+	}
+	if !req.From.Before(req.To) {
+		err := errors.New("from must be before to")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	switch req.GroupBy {
+	case "category":
+		arg := db.GetSpendingByCategoryParams{
+			Owner:    authPayload.Username,
+			FromTime: req.From,
+			ToTime:   req.To,
+		}
+		rows, err := server.store.GetSpendingByCategory(ctx, arg)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusOK, rows)
+	case "month":
+		arg := db.GetSpendingByMonthParams{
+			Owner:    authPayload.Username,
+			FromTime: req.From,
+			ToTime:   req.To,
+		}
+		rows, err := server.store.GetSpendingByMonth(ctx, arg)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusOK, rows)
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSpendingAPI(t *testing.T) {
+	user, _ := randomUser()
+
+	to := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	from := to.Add(-90 * 24 * time.Hour)
+
+	byCategory := []db.GetSpendingByCategoryRow{
+		{Category: "groceries", Currency: utils.EUR, Total: 420, Count: 12},
+		{Category: "uncategorized", Currency: utils.EUR, Total: 35, Count: 2},
+	}
+	byMonth := []db.GetSpendingByMonthRow{
+		{Month: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC), Currency: utils.USD, Total: 100, Count: 3},
+	}
+
+	testCases := []struct {
+		name          string
+		groupBy       string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "ByCategory",
+			groupBy: "category",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.GetSpendingByCategoryParams{Owner: user.Username, FromTime: from, ToTime: to}
+				store.EXPECT().GetSpendingByCategory(gomock.Any(), gomock.Eq(arg)).Times(1).Return(byCategory, nil)
+				store.EXPECT().GetSpendingByMonth(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []db.GetSpendingByCategoryRow
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, byCategory, got)
+			},
+		},
+		{
+			name:    "ByMonth",
+			groupBy: "month",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.GetSpendingByMonthParams{Owner: user.Username, FromTime: from, ToTime: to}
+				store.EXPECT().GetSpendingByMonth(gomock.Any(), gomock.Eq(arg)).Times(1).Return(byMonth, nil)
+				store.EXPECT().GetSpendingByCategory(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []db.GetSpendingByMonthRow
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, byMonth, got)
+			},
+		},
+		{
+			name:    "InvalidGroupBy",
+			groupBy: "tag",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetSpendingByCategory(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetSpendingByMonth(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "Unauthorized",
+			groupBy:   "category",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetSpendingByCategory(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:    "InternalError",
+			groupBy: "category",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSpendingByCategory(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.GetSpendingByCategoryRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/analytics/spending", nil)
+			require.NoError(t, err)
+
+			q := request.URL.Query()
+			q.Add("group_by", tc.groupBy)
+			q.Add("from", from.Format(time.RFC3339))
+			q.Add("to", to.Format(time.RFC3339))
+			request.URL.RawQuery = q.Encode()
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+// scopeResources are the route resources an API key, or an OAuth2 client, can
+// be given a scope on. They can't reach the others, e.g. /users, /api-keys and
+// /oauth, so that a scoped credential can't be used to get more access.
+var scopeResources = []string{
+	"accounts",
+	"transfers",
+	"goals",
+	"entries",
+	"categories",
+	"category-rules",
+	"analytics",
+	"webhooks",
+	"admin",
+}
+
+// Accesses of a scope, the GET requests only need the read access.
+const (
+	scopeRead  = "read"
+	scopeWrite = "write"
+)
+
+// routeScope returns the scope needed for the route of the request, named after
+// its first path segment and its access, e.g. accounts:read for GET /accounts/:id.
+func routeScope(ctx *gin.Context) string {
+	resource := strings.SplitN(strings.TrimPrefix(ctx.FullPath(), "/"), "/", 2)[0]
+	access := scopeWrite
+	switch ctx.Request.Method {
+	case http.MethodGet, http.MethodHead:
+		access = scopeRead
+	}
+	return resource + ":" + access
+}
+
+// isSupportedScope returns true if an API key can be given the scope.
+func isSupportedScope(scope string) bool {
+	resource, access, ok := strings.Cut(scope, ":")
+	if !ok || (access != scopeRead && access != scopeWrite) {
+		return false
+	}
+	for _, r := range scopeResources {
+		if resource == r {
+			return true
+		}
+	}
+	return false
+}
+
+type apiKeyResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPIKeyResponse(key db.ApiKey) apiKeyResponse {
+	rsp := apiKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+	}
+	if key.LastUsedAt.Valid {
+		rsp.LastUsedAt = &key.LastUsedAt.Time
+	}
+	if key.RevokedAt.Valid {
+		rsp.RevokedAt = &key.RevokedAt.Time
+	}
+	return rsp
+}
+
+type createAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,scope"`
+}
+
+type createAPIKeyResponse struct {
+	// APIKey is only shown once, only its hash is stored
+	APIKey string `json:"api_key"`
+	apiKeyResponse
+}
+
+// createAPIKey creates an API key acting for the authenticated user on the
+// routes of its scopes.
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	apiKey, err := token.NewAPIKey()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	key, err := server.store.CreateAPIKey(ctx, db.CreateAPIKeyParams{
+		Owner:   authPayload.Username,
+		Name:    req.Name,
+		Prefix:  token.APIKeyDisplayPrefix(apiKey),
+		KeyHash: token.HashAPIKey(apiKey),
+		Scopes:  req.Scopes,
+	})
+	if err != nil {
+		switch db.ErrorCode(err) {
+		case db.UniqueViolation, db.ForeignKeyViolation:
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := createAPIKeyResponse{
+		APIKey:         apiKey,
+		apiKeyResponse: newAPIKeyResponse(key),
+	}
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// listAPIKeys returns the API keys of the authenticated user, revoked ones included.
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	keys, err := server.store.ListAPIKeys(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]apiKeyResponse, len(keys))
+	for i, key := range keys {
+		rsp[i] = newAPIKeyResponse(key)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type revokeAPIKeyRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// revokeAPIKey revokes an API key of the authenticated user, it is refused
+// right away.
+func (server *Server) revokeAPIKey(ctx *gin.Context) {
+	var req revokeAPIKeyRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	key, err := server.store.RevokeAPIKey(ctx, db.RevokeAPIKeyParams{
+		ID:    req.ID,
+		Owner: authPayload.Username,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAPIKeyResponse(key))
+}
@@ -0,0 +1,426 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func randomAPIKey(owner string, scopes ...string) (db.ApiKey, string) {
+	apiKey, _ := token.NewAPIKey()
+
+	key := db.ApiKey{
+		ID:        utils.RandomInt(1, 1000),
+		Owner:     owner,
+		Name:      utils.RandomString(8),
+		Prefix:    token.APIKeyDisplayPrefix(apiKey),
+		KeyHash:   token.HashAPIKey(apiKey),
+		Scopes:    scopes,
+		CreatedAt: time.Now().Add(-time.Hour),
+	}
+	return key, apiKey
+}
+
+func TestCreateAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser()
+	key, _ := randomAPIKey(user.Username, "accounts:read", "transfers:write")
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"name": key.Name, "scopes": key.Scopes},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+						require.Equal(t, user.Username, arg.Owner)
+						require.Equal(t, key.Name, arg.Name)
+						require.Equal(t, key.Scopes, arg.Scopes)
+						require.Len(t, arg.KeyHash, 64)
+						created := key
+						created.Prefix = arg.Prefix
+						created.KeyHash = arg.KeyHash
+						return created, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var rsp createAPIKeyResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, key.ID, rsp.ID)
+				require.Equal(t, token.APIKeyDisplayPrefix(rsp.APIKey), rsp.Prefix)
+				require.NotContains(t, recorder.Body.String(), token.HashAPIKey(rsp.APIKey))
+			},
+		},
+		{
+			name: "UnsupportedScope",
+			body: gin.H{"name": key.Name, "scopes": []string{"api-keys:write"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoScopes",
+			body: gin.H{"name": key.Name, "scopes": []string{}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "DuplicateName",
+			body: gin.H{"name": key.Name, "scopes": key.Scopes},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, &pgconn.PgError{Code: db.UniqueViolation})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"name": key.Name, "scopes": key.Scopes},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/api-keys", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRevokeAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser()
+	key, _ := randomAPIKey(user.Username, "accounts:read")
+
+	revokedKey := key
+	revokedKey.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.RevokeAPIKeyParams{
+					ID:    key.ID,
+					Owner: user.Username,
+				}
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(revokedKey, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp apiKeyResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotNil(t, rsp.RevokedAt)
+			},
+		},
+		{
+			name: "NotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api-keys/%d", key.ID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAPIKeyAuthorization(t *testing.T) {
+	user, _ := randomUser()
+	key, apiKey := randomAPIKey(user.Username, "accounts:read")
+
+	keyRow := func(key db.ApiKey) db.GetAPIKeyByHashRow {
+		return db.GetAPIKeyByHashRow{
+			ID:        key.ID,
+			Owner:     key.Owner,
+			Scopes:    key.Scopes,
+			RevokedAt: key.RevokedAt,
+			CreatedAt: key.CreatedAt,
+			Role:      utils.DepositorRole,
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		apiKey        string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			method: http.MethodGet,
+			url:    "/accounts?page_id=1&page_size=5",
+			apiKey: apiKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByHash(gomock.Any(), gomock.Eq(key.KeyHash)).
+					Times(1).
+					Return(keyRow(key), nil)
+				store.EXPECT().
+					TouchAPIKey(gomock.Any(), gomock.Eq(key.ID)).
+					Times(1).
+					Return(nil)
+				arg := db.ListAccountsByOwnerParams{
+					Owner:  user.Username,
+					Limit:  5,
+					Offset: 0,
+				}
+				store.EXPECT().
+					ListAccountsByOwner(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.Account{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "TouchError",
+			method: http.MethodGet,
+			url:    "/accounts?page_id=1&page_size=5",
+			apiKey: apiKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByHash(gomock.Any(), gomock.Any()).Times(1).Return(keyRow(key), nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(sql.ErrConnDone)
+				store.EXPECT().ListAccountsByOwner(gomock.Any(), gomock.Any()).Times(1).Return([]db.Account{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "MissingWriteScope",
+			method: http.MethodPost,
+			url:    "/accounts",
+			apiKey: apiKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByHash(gomock.Any(), gomock.Any()).Times(1).Return(keyRow(key), nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "APIKeysRoute",
+			method: http.MethodGet,
+			url:    "/api-keys",
+			apiKey: apiKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKeyByHash(gomock.Any(), gomock.Any()).Times(1).Return(keyRow(key), nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().ListAPIKeys(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "RevokedKey",
+			method: http.MethodGet,
+			url:    "/accounts?page_id=1&page_size=5",
+			apiKey: apiKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				revoked := keyRow(key)
+				revoked.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				store.EXPECT().GetAPIKeyByHash(gomock.Any(), gomock.Any()).Times(1).Return(revoked, nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "FrozenOwner",
+			method: http.MethodGet,
+			url:    "/accounts?page_id=1&page_size=5",
+			apiKey: apiKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				frozen := keyRow(key)
+				frozen.IsFrozen = true
+				store.EXPECT().GetAPIKeyByHash(gomock.Any(), gomock.Any()).Times(1).Return(frozen, nil)
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "UnknownKey",
+			method: http.MethodGet,
+			url:    "/accounts?page_id=1&page_size=5",
+			apiKey: "sbk_unknown",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByHash(gomock.Any(), gomock.Eq(token.HashAPIKey("sbk_unknown"))).
+					Times(1).
+					Return(db.GetAPIKeyByHashRow{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "InternalError",
+			method: http.MethodGet,
+			url:    "/accounts?page_id=1&page_size=5",
+			apiKey: apiKey,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetAPIKeyByHashRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader([]byte(`{"currency": "USD"}`)))
+			require.NoError(t, err)
+
+			request.Header.Set(authorizationHeaderKey, "ApiKey "+tc.apiKey)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestIsSupportedScope(t *testing.T) {
+	require.True(t, isSupportedScope("accounts:read"))
+	require.True(t, isSupportedScope("transfers:write"))
+	require.True(t, isSupportedScope("category-rules:write"))
+	require.False(t, isSupportedScope("accounts"))
+	require.False(t, isSupportedScope("accounts:delete"))
+	require.False(t, isSupportedScope("api-keys:write"))
+	require.False(t, isSupportedScope("users:write"))
+}
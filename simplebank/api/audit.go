@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type listAuditLogRequest struct {
+	Actor      string    `form:"actor"`
+	Action     string    `form:"action"`
+	TargetType string    `form:"target_type"`
+	TargetID   int64     `form:"target_id" binding:"omitempty,min=1"`
+	RequestID  string    `form:"request_id"`
+	From       time.Time `form:"from"`
+	To         time.Time `form:"to"`
+	PageID     int32     `form:"page_id" binding:"required,min=1"`
+	PageSize   int32     `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listAuditLog returns the audit log, most recent first.
+// Every filter is optional, `from` is inclusive and `to` exclusive.
+func (server *Server) listAuditLog(ctx *gin.Context) {
+	var req listAuditLogRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListAuditLogParams{
+		Actor:       pgtype.Text{String: req.Actor, Valid: req.Actor != ""},
+		Action:      pgtype.Text{String: req.Action, Valid: req.Action != ""},
+		TargetType:  pgtype.Text{String: req.TargetType, Valid: req.TargetType != ""},
+		TargetID:    pgtype.Int8{Int64: req.TargetID, Valid: req.TargetID != 0},
+		RequestID:   pgtype.Text{String: req.RequestID, Valid: req.RequestID != ""},
+		FromTime:    pgtype.Timestamptz{Time: req.From, Valid: !req.From.IsZero()},
+		ToTime:      pgtype.Timestamptz{Time: req.To, Valid: !req.To.IsZero()},
+		LimitCount:  req.PageSize,
+		OffsetCount: (req.PageID - 1) * req.PageSize,
+	}
+	logs, err := server.store.ListAuditLog(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, logs)
+}
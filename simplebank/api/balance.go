@@ -0,0 +1,135 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// balanceHistoryDefaultRange is used when the `from` parameter is omitted.
+	balanceHistoryDefaultRange = 30 * 24 * time.Hour
+	// Ranges longer than balanceHistorySnapshotRange are computed from the
+	// daily snapshots instead of the raw entries.
+	balanceHistorySnapshotRange = 90 * 24 * time.Hour
+)
+
+type balanceHistoryUriRequest struct {
+	ID int64 `uri:"id" binding:"min=1,required"`
+}
+
+type balanceHistoryFormRequest struct {
+	Interval string    `form:"interval" binding:"omitempty,oneof=day week month"`
+	From     time.Time `form:"from"`
+	To       time.Time `form:"to"`
+}
+
+type balanceBucketResponse struct {
+	Bucket         time.Time `json:"bucket"`
+	OpeningBalance int64     `json:"opening_balance"`
+	ClosingBalance int64     `json:"closing_balance"`
+	MinBalance     int64     `json:"min_balance"`
+	MaxBalance     int64     `json:"max_balance"`
+}
+
+type balanceHistoryResponse struct {
+	AccountID int64                   `json:"account_id"`
+	Interval  string                  `json:"interval"`
+	From      time.Time               `json:"from"`
+	To        time.Time               `json:"to"`
+	Buckets   []balanceBucketResponse `json:"buckets"`
+}
+
+// getBalanceHistory returns the opening, closing, min and max balance of an
+// account for each interval bucket between `from` and `to`.
+// Buckets without any entry are omitted.
+func (server *Server) getBalanceHistory(ctx *gin.Context) {
+	var uri balanceHistoryUriRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var form balanceHistoryFormRequest
+	if err := ctx.ShouldBindQuery(&form); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if form.Interval == "" {
+		form.Interval = "day"
+	}
+	if form.To.IsZero() {
+		form.To = time.Now()
+	}
+	if form.From.IsZero() {
+		form.From = form.To.Add(-balanceHistoryDefaultRange)
+	}
+	if !form.From.Before(form.To) {
+		err := errors.New("from must be before to")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// Checking if the account belongs to the user.
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		err := errors.New("account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	buckets := []balanceBucketResponse{}
+	if form.To.Sub(form.From) > balanceHistorySnapshotRange {
+		arg := db.GetBalanceHistoryFromSnapshotsParams{
+			AccountID:      account.ID,
+			BucketInterval: form.Interval,
+			FromTime:       form.From,
+			ToTime:         form.To,
+		}
+		rows, err := server.store.GetBalanceHistoryFromSnapshots(ctx, arg)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		for _, row := range rows {
+			buckets = append(buckets, balanceBucketResponse(row))
+		}
+	} else {
+		arg := db.GetBalanceHistoryParams{
+			AccountID:      account.ID,
+			BucketInterval: form.Interval,
+			FromTime:       form.From,
+			ToTime:         form.To,
+		}
+		rows, err := server.store.GetBalanceHistory(ctx, arg)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		for _, row := range rows {
+			buckets = append(buckets, balanceBucketResponse(row))
+		}
+	}
+
+	rsp := balanceHistoryResponse{
+		AccountID: account.ID,
+		Interval:  form.Interval,
+		From:      form.From,
+		To:        form.To,
+		Buckets:   buckets,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
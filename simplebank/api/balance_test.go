@@ -0,0 +1,234 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBalanceHistoryAPI(t *testing.T) {
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+	otherUser, _ := randomUser()
+
+	to := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	from := to.Add(-7 * 24 * time.Hour)
+
+	rows := []db.GetBalanceHistoryRow{
+		{Bucket: from, OpeningBalance: 0, ClosingBalance: 50, MinBalance: 0, MaxBalance: 60},
+		{Bucket: from.Add(24 * time.Hour), OpeningBalance: 50, ClosingBalance: 20, MinBalance: 20, MaxBalance: 50},
+	}
+
+	type Query struct {
+		interval string
+		from     time.Time
+		to       time.Time
+	}
+
+	testCases := []struct {
+		name          string
+		accountID     int64
+		query         Query
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			query:     Query{interval: "day", from: from, to: to},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.GetBalanceHistoryParams{
+					AccountID:      account.ID,
+					BucketInterval: "day",
+					FromTime:       from,
+					ToTime:         to,
+				}
+				store.EXPECT().GetBalanceHistory(gomock.Any(), gomock.Eq(arg)).Times(1).Return(rows, nil)
+				store.EXPECT().GetBalanceHistoryFromSnapshots(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				rsp := requireBodyBalanceHistory(t, recorder)
+				require.Equal(t, account.ID, rsp.AccountID)
+				require.Equal(t, "day", rsp.Interval)
+				require.Len(t, rsp.Buckets, len(rows))
+				for i, row := range rows {
+					require.Equal(t, balanceBucketResponse(row), rsp.Buckets[i])
+				}
+			},
+		},
+		{
+			name:      "LongRangeUsesSnapshots",
+			accountID: account.ID,
+			query:     Query{interval: "month", from: to.Add(-365 * 24 * time.Hour), to: to},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.GetBalanceHistoryFromSnapshotsParams{
+					AccountID:      account.ID,
+					BucketInterval: "month",
+					FromTime:       to.Add(-365 * 24 * time.Hour),
+					ToTime:         to,
+				}
+				store.EXPECT().
+					GetBalanceHistoryFromSnapshots(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.GetBalanceHistoryFromSnapshotsRow{}, nil)
+				store.EXPECT().GetBalanceHistory(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				rsp := requireBodyBalanceHistory(t, recorder)
+				require.Empty(t, rsp.Buckets)
+			},
+		},
+		{
+			name:      "Unauthorized",
+			accountID: account.ID,
+			query:     Query{interval: "day", from: from, to: to},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetBalanceHistory(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "NotOwner",
+			accountID: account.ID,
+			query:     Query{interval: "day", from: from, to: to},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, otherUser.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetBalanceHistory(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "NotFound",
+			accountID: account.ID,
+			query:     Query{interval: "day", from: from, to: to},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().GetBalanceHistory(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidInterval",
+			accountID: account.ID,
+			query:     Query{interval: "year", from: from, to: to},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "FromAfterTo",
+			accountID: account.ID,
+			query:     Query{interval: "day", from: to, to: from},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InternalError",
+			accountID: account.ID,
+			query:     Query{interval: "day", from: from, to: to},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					GetBalanceHistory(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.GetBalanceHistoryRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/balance-history", tc.accountID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			q := request.URL.Query()
+			q.Add("interval", tc.query.interval)
+			q.Add("from", tc.query.from.Format(time.RFC3339))
+			q.Add("to", tc.query.to.Format(time.RFC3339))
+			request.URL.RawQuery = q.Encode()
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func requireBodyBalanceHistory(t *testing.T, recorder *httptest.ResponseRecorder) balanceHistoryResponse {
+	data, err := ioutil.ReadAll(recorder.Body)
+	require.NoError(t, err)
+
+	var rsp balanceHistoryResponse
+	err = json.Unmarshal(data, &rsp)
+	require.NoError(t, err)
+	return rsp
+}
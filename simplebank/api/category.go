@@ -0,0 +1,216 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type categoryResponse struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	BuiltIn   bool      `json:"built_in"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newCategoryResponse(category db.Category) categoryResponse {
+	return categoryResponse{
+		ID:        category.ID,
+		Name:      category.Name,
+		BuiltIn:   !category.Owner.Valid,
+		CreatedAt: category.CreatedAt,
+	}
+}
+
+func (server *Server) listCategories(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	owner := pgtype.Text{String: authPayload.Username, Valid: true}
+	categories, err := server.store.ListCategories(ctx, owner)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]categoryResponse, len(categories))
+	for i, category := range categories {
+		rsp[i] = newCategoryResponse(category)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type createCategoryRequest struct {
+	Name string `json:"name" binding:"required,max=32"`
+}
+
+func (server *Server) createCategory(ctx *gin.Context) {
+	var req createCategoryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.CreateCategoryParams{
+		Owner: pgtype.Text{String: authPayload.Username, Valid: true},
+		Name:  req.Name,
+	}
+	category, err := server.store.CreateCategory(ctx, arg)
+	if err != nil {
+		switch db.ErrorCode(err) {
+		case db.UniqueViolation, db.ForeignKeyViolation:
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newCategoryResponse(category))
+}
+
+type deleteCategoryRequest struct {
+	ID int64 `uri:"id" binding:"min=1,required"`
+}
+
+// deleteCategory deletes a category of the authenticated user. The built-in
+// categories can't be deleted.
+func (server *Server) deleteCategory(ctx *gin.Context) {
+	var req deleteCategoryRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	category, valid := server.validCategory(ctx, req.ID)
+	if !valid {
+		return
+	}
+	if !category.Owner.Valid {
+		err := errors.New("built-in categories can't be deleted")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	arg := db.DeleteCategoryParams{
+		ID:    category.ID,
+		Owner: category.Owner,
+	}
+	err := server.store.DeleteCategory(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
+
+// validCategory checks that the category exists and is either built-in or
+// owned by the authenticated user.
+func (server *Server) validCategory(ctx *gin.Context, categoryID int64) (db.Category, bool) {
+	category, err := server.store.GetCategory(ctx, categoryID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return category, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return category, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if category.Owner.Valid && category.Owner.String != authPayload.Username {
+		err := errors.New("category doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return category, false
+	}
+	return category, true
+}
+
+type createCategoryRuleRequest struct {
+	CategoryID int64  `json:"category_id" binding:"required,min=1"`
+	Field      string `json:"field" binding:"required,oneof=memo counterparty"`
+	Pattern    string `json:"pattern" binding:"required,max=64"`
+	Priority   int32  `json:"priority"`
+}
+
+// createCategoryRule adds a rule and re-categorizes the existing entries of
+// the user which were not manually overridden.
+func (server *Server) createCategoryRule(ctx *gin.Context) {
+	var req createCategoryRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.validCategory(ctx, req.CategoryID); !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.CreateCategoryRuleParams{
+		Owner:      authPayload.Username,
+		CategoryID: req.CategoryID,
+		Field:      req.Field,
+		Pattern:    req.Pattern,
+		Priority:   req.Priority,
+	}
+	rule, err := server.store.CreateCategoryRule(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.ApplyCategoryRulesByOwner(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, rule)
+}
+
+func (server *Server) listCategoryRules(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	rules, err := server.store.ListCategoryRules(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rules)
+}
+
+type deleteCategoryRuleRequest struct {
+	ID int64 `uri:"id" binding:"min=1,required"`
+}
+
+func (server *Server) deleteCategoryRule(ctx *gin.Context) {
+	var req deleteCategoryRuleRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.DeleteCategoryRuleParams{
+		ID:    req.ID,
+		Owner: authPayload.Username,
+	}
+	err := server.store.DeleteCategoryRule(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type entryResponse struct {
+	ID                 int64     `json:"id"`
+	AccountID          int64     `json:"account_id"`
+	Amount             int64     `json:"amount"`
+	Memo               string    `json:"memo"`
+	Counterparty       string    `json:"counterparty"`
+	CategoryID         *int64    `json:"category_id"`
+	CategoryOverridden bool      `json:"category_overridden"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+func newEntryResponse(entry db.Entry) entryResponse {
+	rsp := entryResponse{
+		ID:                 entry.ID,
+		AccountID:          entry.AccountID,
+		Amount:             entry.Amount,
+		Memo:               entry.Memo,
+		Counterparty:       entry.Counterparty,
+		CategoryOverridden: entry.CategoryOverridden,
+		CreatedAt:          entry.CreatedAt,
+	}
+	if entry.CategoryID.Valid {
+		rsp.CategoryID = &entry.CategoryID.Int64
+	}
+	return rsp
+}
+
+type entryUriRequest struct {
+	ID int64 `uri:"id" binding:"min=1,required"`
+}
+
+// ownedEntry fetches the entry and checks that its account belongs to the
+// authenticated user.
+func (server *Server) ownedEntry(ctx *gin.Context, entryID int64) (db.Entry, bool) {
+	entry, err := server.store.GetEntry(ctx, entryID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return entry, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return entry, false
+	}
+
+	account, err := server.store.GetAccount(ctx, entry.AccountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return entry, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		err := errors.New("entry doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return entry, false
+	}
+	return entry, true
+}
+
+type updateEntryCategoryRequest struct {
+	// CategoryID overrides the category, null gives the entry back to the rules.
+	CategoryID *int64 `json:"category_id" binding:"omitempty,min=1"`
+}
+
+func (server *Server) updateEntryCategory(ctx *gin.Context) {
+	var uri entryUriRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req updateEntryCategoryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.ownedEntry(ctx, uri.ID); !ok {
+		return
+	}
+
+	arg := db.SetEntryCategoryParams{ID: uri.ID}
+	if req.CategoryID != nil {
+		if _, ok := server.validCategory(ctx, *req.CategoryID); !ok {
+			return
+		}
+		arg.CategoryID = pgtype.Int8{Int64: *req.CategoryID, Valid: true}
+		arg.CategoryOverridden = true
+	}
+
+	entry, err := server.store.SetEntryCategory(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !entry.CategoryOverridden {
+		entry, err = server.store.ApplyCategoryRules(ctx, entry.ID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, newEntryResponse(entry))
+}
+
+func (server *Server) listEntryTags(ctx *gin.Context) {
+	var uri entryUriRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.ownedEntry(ctx, uri.ID); !ok {
+		return
+	}
+
+	tags, err := server.store.ListEntryTags(ctx, uri.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tags)
+}
+
+type addEntryTagRequest struct {
+	Tag string `json:"tag" binding:"required,max=32"`
+}
+
+func (server *Server) addEntryTag(ctx *gin.Context) {
+	var uri entryUriRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req addEntryTagRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.ownedEntry(ctx, uri.ID); !ok {
+		return
+	}
+
+	arg := db.AddEntryTagParams{
+		EntryID: uri.ID,
+		Tag:     req.Tag,
+	}
+	tag, err := server.store.AddEntryTag(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, tag)
+}
+
+type deleteEntryTagRequest struct {
+	ID  int64  `uri:"id" binding:"min=1,required"`
+	Tag string `uri:"tag" binding:"required,max=32"`
+}
+
+func (server *Server) deleteEntryTag(ctx *gin.Context) {
+	var uri deleteEntryTagRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.ownedEntry(ctx, uri.ID); !ok {
+		return
+	}
+
+	arg := db.DeleteEntryTagParams{
+		EntryID: uri.ID,
+		Tag:     uri.Tag,
+	}
+	err := server.store.DeleteEntryTag(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
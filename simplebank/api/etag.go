@@ -0,0 +1,36 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	etagHeaderKey        = "ETag"
+	ifMatchHeaderKey     = "If-Match"
+	ifNoneMatchHeaderKey = "If-None-Match"
+)
+
+// accountETag returns the strong ETag of the account, built from its version.
+func accountETag(account db.Account) string {
+	return fmt.Sprintf(`"%d"`, account.Version)
+}
+
+// ifMatchVersion returns the account version required by the If-Match header,
+// or 0 when the header is missing or is the `*` wildcard.
+func ifMatchVersion(ctx *gin.Context) (int64, error) {
+	ifMatch := strings.TrimSpace(ctx.GetHeader(ifMatchHeaderKey))
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(ifMatch, `"`), 10, 64)
+	if err != nil || version < 1 || !strings.HasPrefix(ifMatch, `"`) || !strings.HasSuffix(ifMatch, `"`) {
+		return 0, fmt.Errorf("invalid %s header %s, expected an account ETag", ifMatchHeaderKey, ifMatch)
+	}
+	return version, nil
+}
@@ -0,0 +1,307 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type goalResponse struct {
+	ID                   int64      `json:"id"`
+	AccountID            int64      `json:"account_id"`
+	Name                 string     `json:"name"`
+	TargetAmount         int64      `json:"target_amount"`
+	Balance              int64      `json:"balance"`
+	Deadline             *time.Time `json:"deadline"`
+	RoundUpUnit          int64      `json:"round_up_unit"`
+	ContributionAmount   int64      `json:"contribution_amount"`
+	ContributionInterval string     `json:"contribution_interval"`
+	NextContributionAt   *time.Time `json:"next_contribution_at"`
+	CreatedAt            time.Time  `json:"created_at"`
+	// Progress is the percentage of the target already saved.
+	Progress  float64 `json:"progress"`
+	Remaining int64   `json:"remaining"`
+	// OnTrack tells if the balance is at least the linear progression from
+	// the creation of the goal to its deadline.
+	OnTrack bool `json:"on_track"`
+}
+
+func newGoalResponse(goal db.Goal, now time.Time) goalResponse {
+	rsp := goalResponse{
+		ID:                   goal.ID,
+		AccountID:            goal.AccountID,
+		Name:                 goal.Name,
+		TargetAmount:         goal.TargetAmount,
+		Balance:              goal.Balance,
+		RoundUpUnit:          goal.RoundUpUnit,
+		ContributionAmount:   goal.ContributionAmount,
+		ContributionInterval: goal.ContributionInterval,
+		CreatedAt:            goal.CreatedAt,
+		OnTrack:              true,
+	}
+	if goal.NextContributionAt.Valid {
+		rsp.NextContributionAt = &goal.NextContributionAt.Time
+	}
+
+	if goal.TargetAmount > 0 {
+		rsp.Progress = float64(goal.Balance) * 100 / float64(goal.TargetAmount)
+	}
+	if goal.Balance < goal.TargetAmount {
+		rsp.Remaining = goal.TargetAmount - goal.Balance
+	}
+
+	if goal.Deadline.Valid {
+		rsp.Deadline = &goal.Deadline.Time
+		total := goal.Deadline.Time.Sub(goal.CreatedAt)
+		elapsed := now.Sub(goal.CreatedAt)
+		if total > 0 && elapsed < total {
+			expected := float64(goal.TargetAmount) * float64(elapsed) / float64(total)
+			rsp.OnTrack = float64(goal.Balance) >= expected
+		} else {
+			rsp.OnTrack = goal.Balance >= goal.TargetAmount
+		}
+	}
+	return rsp
+}
+
+// nextContribution returns the date of the contribution following t.
+func nextContribution(t time.Time, interval string) time.Time {
+	switch interval {
+	case "day":
+		return t.AddDate(0, 0, 1)
+	case "week":
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.AddDate(0, 1, 0)
+	}
+}
+
+type createGoalRequest struct {
+	AccountID            int64      `json:"account_id" binding:"required,min=1"`
+	Name                 string     `json:"name" binding:"required,max=64"`
+	TargetAmount         int64      `json:"target_amount" binding:"required,gt=0"`
+	Deadline             *time.Time `json:"deadline"`
+	RoundUpUnit          int64      `json:"round_up_unit" binding:"min=0"`
+	ContributionAmount   int64      `json:"contribution_amount" binding:"min=0"`
+	ContributionInterval string     `json:"contribution_interval" binding:"required_with=ContributionAmount,omitempty,oneof=day week month"`
+}
+
+func (server *Server) createGoal(ctx *gin.Context) {
+	var req createGoalRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// Checking if the account belongs to the user.
+	account, err := server.store.GetAccount(ctx, req.AccountID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		err := errors.New("account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	arg := db.CreateGoalParams{
+		AccountID:    req.AccountID,
+		Name:         req.Name,
+		TargetAmount: req.TargetAmount,
+		RoundUpUnit:  req.RoundUpUnit,
+	}
+	if req.Deadline != nil {
+		arg.Deadline = pgtype.Timestamptz{Time: *req.Deadline, Valid: true}
+	}
+	if req.ContributionAmount > 0 {
+		arg.ContributionAmount = req.ContributionAmount
+		arg.ContributionInterval = req.ContributionInterval
+		arg.NextContributionAt = pgtype.Timestamptz{
+			Time:  nextContribution(time.Now(), req.ContributionInterval),
+			Valid: true,
+		}
+	}
+
+	goal, err := server.store.CreateGoal(ctx, arg)
+	if err != nil {
+		switch db.ErrorCode(err) {
+		case db.UniqueViolation:
+			err := errors.New("the account already has a round-up goal")
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newGoalResponse(goal, time.Now()))
+}
+
+type listGoalsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func (server *Server) listGoals(ctx *gin.Context) {
+	var req listGoalsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.ListGoalsByOwnerParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+	goals, err := server.store.ListGoalsByOwner(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	now := time.Now()
+	rsp := make([]goalResponse, len(goals))
+	for i, goal := range goals {
+		rsp[i] = newGoalResponse(goal, now)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type goalUriRequest struct {
+	ID int64 `uri:"id" binding:"min=1,required"`
+}
+
+// ownedGoal fetches the goal and checks that its account belongs to the
+// authenticated user.
+func (server *Server) ownedGoal(ctx *gin.Context, goalID int64) (db.Goal, bool) {
+	goal, err := server.store.GetGoal(ctx, goalID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return goal, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return goal, false
+	}
+
+	account, err := server.store.GetAccount(ctx, goal.AccountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return goal, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		err := errors.New("goal doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return goal, false
+	}
+	return goal, true
+}
+
+func (server *Server) getGoal(ctx *gin.Context) {
+	var uri goalUriRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	goal, ok := server.ownedGoal(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newGoalResponse(goal, time.Now()))
+}
+
+type goalTransferRequest struct {
+	Amount int64 `json:"amount" binding:"required,gt=0"`
+}
+
+type goalTransferResponse struct {
+	Goal    goalResponse  `json:"goal"`
+	Account db.Account    `json:"account"`
+	Entry   entryResponse `json:"entry"`
+}
+
+func (server *Server) depositGoal(ctx *gin.Context) {
+	server.goalTransfer(ctx, 1)
+}
+
+func (server *Server) withdrawGoal(ctx *gin.Context) {
+	server.goalTransfer(ctx, -1)
+}
+
+// goalTransfer moves the requested amount into the goal when sign is positive,
+// or out of it when sign is negative.
+func (server *Server) goalTransfer(ctx *gin.Context, sign int64) {
+	var uri goalUriRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req goalTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.ownedGoal(ctx, uri.ID); !ok {
+		return
+	}
+
+	arg := db.GoalTransferTxParams{
+		GoalID: uri.ID,
+		Amount: sign * req.Amount,
+	}
+	result, err := server.store.GoalTransferTx(ctx, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := goalTransferResponse{
+		Goal:    newGoalResponse(result.Goal, time.Now()),
+		Account: result.Account,
+		Entry:   newEntryResponse(result.Entry),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+func (server *Server) deleteGoal(ctx *gin.Context) {
+	var uri goalUriRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.ownedGoal(ctx, uri.ID); !ok {
+		return
+	}
+
+	account, err := server.store.DeleteGoalTx(ctx, uri.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, account)
+}
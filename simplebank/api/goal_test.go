@@ -0,0 +1,314 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func randomGoal(accountID int64) db.Goal {
+	return db.Goal{
+		ID:           utils.RandomInt(1, 1000),
+		AccountID:    accountID,
+		Name:         utils.RandomString(8),
+		TargetAmount: utils.RandomInt(1000, 5000),
+		Balance:      utils.RandomInt(0, 500),
+	}
+}
+
+func TestNewGoalResponse(t *testing.T) {
+	createdAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	goal := db.Goal{
+		TargetAmount: 1000,
+		Balance:      250,
+		CreatedAt:    createdAt,
+		Deadline:     pgtype.Timestamptz{Time: createdAt.AddDate(0, 0, 100), Valid: true},
+	}
+
+	rsp := newGoalResponse(goal, createdAt.AddDate(0, 0, 20))
+	require.Equal(t, 25.0, rsp.Progress)
+	require.Equal(t, int64(750), rsp.Remaining)
+	require.True(t, rsp.OnTrack)
+
+	rsp = newGoalResponse(goal, createdAt.AddDate(0, 0, 50))
+	require.False(t, rsp.OnTrack)
+
+	rsp = newGoalResponse(goal, createdAt.AddDate(0, 0, 200))
+	require.False(t, rsp.OnTrack)
+
+	goal.Balance = 1200
+	rsp = newGoalResponse(goal, createdAt.AddDate(0, 0, 200))
+	require.True(t, rsp.OnTrack)
+	require.Zero(t, rsp.Remaining)
+}
+
+func TestCreateGoalAPI(t *testing.T) {
+	user, _ := randomUser()
+	otherUser, _ := randomUser()
+	account := randomAccount(user.Username)
+	goal := randomGoal(account.ID)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Created",
+			body: gin.H{
+				"account_id":    account.ID,
+				"name":          goal.Name,
+				"target_amount": goal.TargetAmount,
+				"round_up_unit": 10,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.CreateGoalParams{
+					AccountID:    account.ID,
+					Name:         goal.Name,
+					TargetAmount: goal.TargetAmount,
+					RoundUpUnit:  10,
+				}
+				store.EXPECT().CreateGoal(gomock.Any(), gomock.Eq(arg)).Times(1).Return(goal, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var got goalResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, goal.ID, got.ID)
+				require.Equal(t, goal.Balance, got.Balance)
+			},
+		},
+		{
+			name: "ScheduledContribution",
+			body: gin.H{
+				"account_id":            account.ID,
+				"name":                  goal.Name,
+				"target_amount":         goal.TargetAmount,
+				"contribution_amount":   100,
+				"contribution_interval": "week",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					CreateGoal(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateGoalParams) (db.Goal, error) {
+						require.Equal(t, int64(100), arg.ContributionAmount)
+						require.Equal(t, "week", arg.ContributionInterval)
+						require.True(t, arg.NextContributionAt.Valid)
+						require.WithinDuration(t, time.Now().AddDate(0, 0, 7), arg.NextContributionAt.Time, time.Second)
+						return goal, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "ContributionWithoutInterval",
+			body: gin.H{
+				"account_id":          account.ID,
+				"name":                goal.Name,
+				"target_amount":       goal.TargetAmount,
+				"contribution_amount": 100,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateGoal(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NotOwner",
+			body: gin.H{
+				"account_id":    account.ID,
+				"name":          goal.Name,
+				"target_amount": goal.TargetAmount,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, otherUser.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().CreateGoal(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "SecondRoundUpGoal",
+			body: gin.H{
+				"account_id":    account.ID,
+				"name":          goal.Name,
+				"target_amount": goal.TargetAmount,
+				"round_up_unit": 10,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					CreateGoal(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Goal{}, &pgconn.PgError{Code: db.UniqueViolation})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/goals", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGoalTransferAPI(t *testing.T) {
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+	goal := randomGoal(account.ID)
+	amount := int64(30)
+
+	testCases := []struct {
+		name          string
+		action        string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Deposit",
+			action: "deposit",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetGoal(gomock.Any(), gomock.Eq(goal.ID)).Times(1).Return(goal, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.GoalTransferTxParams{GoalID: goal.ID, Amount: amount}
+				store.EXPECT().GoalTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.GoalTransferTxResult{Goal: goal}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "Withdraw",
+			action: "withdraw",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetGoal(gomock.Any(), gomock.Eq(goal.ID)).Times(1).Return(goal, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.GoalTransferTxParams{GoalID: goal.ID, Amount: -amount}
+				store.EXPECT().GoalTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.GoalTransferTxResult{Goal: goal}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "InsufficientFunds",
+			action: "withdraw",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetGoal(gomock.Any(), gomock.Eq(goal.ID)).Times(1).Return(goal, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					GoalTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GoalTransferTxResult{}, fmt.Errorf("cannot move: %w", db.ErrInsufficientFunds))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "GoalNotFound",
+			action: "deposit",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetGoal(gomock.Any(), gomock.Eq(goal.ID)).Times(1).Return(db.Goal{}, db.ErrRecordNotFound)
+				store.EXPECT().GoalTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"amount": amount})
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/goals/%d/%s", goal.ID, tc.action)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
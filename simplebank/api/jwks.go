@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ebaudet/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+// getJWKS publishes the public keys verifying the access tokens, so that the
+// other services can verify them without calling us. The set is empty when the
+// tokens are made with a symmetric key.
+func (server *Server) getJWKS(ctx *gin.Context) {
+	keys := server.tokenMaker.PublicKeys()
+	if keys == nil {
+		keys = []token.JWK{}
+	}
+
+	ctx.Header(cacheControlHeaderKey, "public, max-age=300")
+	ctx.JSON(http.StatusOK, token.JWKS{Keys: keys})
+}
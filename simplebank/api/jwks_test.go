@@ -0,0 +1,102 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetJWKSAPI(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	privateKeyFile := filepath.Join(t.TempDir(), "token_key.pem")
+	err = os.WriteFile(privateKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		config        utils.Config
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server)
+	}{
+		{
+			name: "Symmetric",
+			config: utils.Config{
+				TokenMaker:        token.MakerPaseto,
+				TokenSymmetricKey: utils.RandomString(32),
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"keys": []}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "PasetoPublic",
+			config: utils.Config{
+				TokenMaker:          token.MakerPasetoPublic,
+				TokenPrivateKeyFile: privateKeyFile,
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp token.JWKS
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.Keys, 1)
+
+				require.Equal(t, server.tokenMaker.PublicKeys()[0].KeyID, rsp.Keys[0].KeyID)
+				x, err := base64.RawURLEncoding.DecodeString(rsp.Keys[0].X)
+				require.NoError(t, err)
+				require.Equal(t, []byte(privateKey.Public().(ed25519.PublicKey)), x)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+
+			server, err := NewServer(tc.config, store)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, server)
+		})
+	}
+}
+
+func TestNewServerMissingPrivateKeyFile(t *testing.T) {
+	config := utils.Config{
+		TokenMaker:          token.MakerJWTEdDSA,
+		TokenPrivateKeyFile: filepath.Join(t.TempDir(), "missing.pem"),
+	}
+
+	server, err := NewServer(config, nil)
+	require.Error(t, err)
+	require.Nil(t, server)
+}
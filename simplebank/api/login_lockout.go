@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errInvalidCredentials = errors.New("invalid username or password")
+	errLoginLocked        = errors.New("too many failed login attempts, try again later")
+)
+
+// dummyPassword is checked against when the user doesn't exist, so the
+// response takes as long as for a wrong password. It is hashed with the hasher
+// of the server, the first time it is needed.
+type dummyPassword struct {
+	once sync.Once
+	hash string
+}
+
+func (server *Server) checkDummyPassword(password string) {
+	server.dummyPassword.once.Do(func() {
+		server.dummyPassword.hash, _ = server.hasher.HashPassword(utils.RandomString(16))
+	})
+	utils.CheckPassword(password, server.dummyPassword.hash)
+}
+
+func loginUserKey(username string) string {
+	return "user:" + username
+}
+
+func loginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// loginKeys returns the keys the failures of the login are tracked with,
+// along with the number of failures each key is locked after.
+// A key with a maximum of 0 is not tracked.
+func (server *Server) loginKeys(ctx *gin.Context, username string) map[string]int32 {
+	keys := make(map[string]int32, 2)
+	if server.config.LoginMaxUserFailures > 0 {
+		keys[loginUserKey(username)] = server.config.LoginMaxUserFailures
+	}
+	if server.config.LoginMaxIPFailures > 0 {
+		keys[loginIPKey(ctx.ClientIP())] = server.config.LoginMaxIPFailures
+	}
+	return keys
+}
+
+// countLoginAttempt counts the attempt as a failure against each key before
+// the credentials are checked, so that concurrent attempts can't all get past
+// the lockout. It returns until when the login is locked, the zero time if the
+// attempt may go on.
+func (server *Server) countLoginAttempt(ctx context.Context, keys map[string]int32) (time.Time, error) {
+	if len(keys) == 0 {
+		return time.Time{}, nil
+	}
+
+	lockedUntil, err := server.store.CountLoginAttemptTx(ctx, db.CountLoginAttemptTxParams{
+		MaxFailures: keys,
+		ResetBefore: time.Now().Add(-server.config.LoginFailureWindow),
+		LockoutBase: server.config.LoginLockoutBase,
+		LockoutMax:  server.config.LoginLockoutMax,
+	})
+	if err != nil && !errors.Is(err, db.ErrLoginLocked) {
+		return time.Time{}, err
+	}
+	return lockedUntil, nil
+}
+
+// forgiveLoginAttempt takes back the attempt counted against each key, once
+// the credentials were found right.
+func (server *Server) forgiveLoginAttempt(ctx context.Context, keys map[string]int32) error {
+	for key, maxFailures := range keys {
+		err := server.store.ForgiveLoginFailure(ctx, db.ForgiveLoginFailureParams{
+			Key:         key,
+			MaxFailures: maxFailures,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type unlockUserRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// unlockUser lifts the lockout of the user after failed logins.
+func (server *Server) unlockUser(ctx *gin.Context) {
+	var req unlockUserRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, req.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.DeleteLoginFailure(ctx, loginUserKey(user.Username))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+func retryAfterSeconds(until time.Time) string {
+	return strconv.Itoa(ceilSeconds(time.Until(until)))
+}
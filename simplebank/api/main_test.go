@@ -1,28 +1,72 @@
 package api
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
 
+	mockdb "github.com/ebaudet/simplebank/db/mock"
 	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/mail"
 	"github.com/ebaudet/simplebank/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 )
 
 func newTestServer(t *testing.T, store db.Store) *Server {
 	config := utils.Config{
-		TokenSymmetricKey:   utils.RandomString(32),
-		AccessTokenDuration: time.Minute,
+		TokenSymmetricKey:        utils.RandomString(32),
+		AccessTokenDuration:      time.Minute,
+		OAuthAccessTokenDuration: time.Minute,
+		OAuthCodeDuration:        time.Minute,
+		MFAChallengeDuration:     time.Minute,
+		TOTPIssuer:               "SimpleBank",
+		EmailVerifyURL:           "http://localhost:8080/users/verify_email",
+		EmailVerifyDuration:      time.Hour,
+		ResetPasswordURL:         "http://localhost:3000/reset_password",
+		ResetPasswordDuration:    time.Hour,
 	}
 
 	server, err := NewServer(config, store)
 	require.NoError(t, err)
+	server.mailer = &fakeMailer{}
 
 	return server
 }
 
+// fakeMailer records the emails instead of sending them.
+type fakeMailer struct {
+	emails []mail.Email
+	err    error
+}
+
+func (mailer *fakeMailer) SendEmail(ctx context.Context, email mail.Email) error {
+	mailer.emails = append(mailer.emails, email)
+	return mailer.err
+}
+
+// allowValidTokens lets the auth middleware find that the access tokens are not revoked.
+func allowValidTokens(store *mockdb.MockStore) {
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).AnyTimes().Return(false, nil)
+}
+
+// allowVerifiedEmails lets the users through the email verification check.
+func allowVerifiedEmails(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ interface{}, username string) (db.User, error) {
+			return db.User{Username: username, IsEmailVerified: true}, nil
+		})
+}
+
+// allowAuditLog lets the audit middleware record the mutating requests.
+func allowAuditLog(store *mockdb.MockStore) {
+	store.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).AnyTimes()
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
 	os.Exit(m.Run())
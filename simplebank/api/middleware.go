@@ -3,11 +3,19 @@ package api
 import (
 	"errors"
 	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/ratelimit"
 	"github.com/ebaudet/simplebank/token"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 // This file contains the code for the middleware used in the API.
@@ -27,13 +35,29 @@ import (
 // <------------------|  createAccount(ctx)  |
 //                    |______________________|
 
+var (
+	errRevokedToken  = errors.New("token has been revoked")
+	errInvalidAPIKey = errors.New("invalid API key")
+	errRevokedAPIKey = errors.New("API key has been revoked")
+)
+
 const (
 	authorizationHeaderKey  = "authorization"
 	authorizationTypeBearer = "bearer"
+	authorizationTypeAPIKey = "apikey"
 	authorizationPayloadKey = "authorization_payload"
+	requestIDHeaderKey      = "X-Request-ID"
+
+	rateLimitLimitHeaderKey     = "X-RateLimit-Limit"
+	rateLimitRemainingHeaderKey = "X-RateLimit-Remaining"
+	rateLimitResetHeaderKey     = "X-RateLimit-Reset"
+	retryAfterHeaderKey         = "Retry-After"
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+// authMiddleware only lets through the requests with a valid access token
+// which has not been revoked, or with a valid API key having the scope of the
+// route.
+func authMiddleware(tokenMaker token.Maker, store db.Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
@@ -49,21 +73,207 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 			return
 		}
 
+		var payload *token.Payload
+		var status int
+		var err error
 		authorizationType := strings.ToLower(fields[0])
-		if authorizationType != authorizationTypeBearer {
-			err := fmt.Errorf("unsupported authorization type %s", authorizationType)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		switch authorizationType {
+		case authorizationTypeBearer:
+			payload, status, err = authenticateAccessToken(ctx, tokenMaker, store, fields[1])
+		case authorizationTypeAPIKey:
+			payload, status, err = authenticateAPIKey(ctx, store, fields[1])
+		default:
+			status, err = http.StatusUnauthorized, fmt.Errorf("unsupported authorization type %s", authorizationType)
+		}
+		if err != nil {
+			ctx.AbortWithStatusJSON(status, errorResponse(err))
 			return
 		}
 
-		tokenString := fields[1]
-		payload, err := tokenMaker.VerifyToken(tokenString)
-		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		scope := routeScope(ctx)
+		if !payload.AllowsScope(scope) {
+			err := fmt.Errorf("token does not have the %s scope", scope)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
 			return
 		}
+
 		// add payload to the context
 		ctx.Set(authorizationPayloadKey, payload)
 		ctx.Next()
 	}
 }
+
+// authenticateAccessToken returns the payload of the access token, along with
+// the status to answer when it is not valid.
+func authenticateAccessToken(ctx *gin.Context, tokenMaker token.Maker, store db.Store, accessToken string) (*token.Payload, int, error) {
+	payload, err := tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		return nil, http.StatusUnauthorized, err
+	}
+
+	// read from the primary, a token revoked just before must not be let through
+	revoked, err := store.IsTokenRevoked(db.WithReadYourWrites(ctx.Request.Context()), db.IsTokenRevokedParams{
+		ID:       payload.ID,
+		Username: payload.Username,
+		IssuedAt: payload.IssuedAt,
+	})
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if revoked {
+		return nil, http.StatusUnauthorized, errRevokedToken
+	}
+	return payload, http.StatusOK, nil
+}
+
+// authenticateAPIKey returns a payload for the owner of the API key, limited
+// to the scopes of the key, along with the status to answer when it is not valid.
+func authenticateAPIKey(ctx *gin.Context, store db.Store, apiKey string) (*token.Payload, int, error) {
+	// read from the primary, a key revoked just before must not be let through
+	key, err := store.GetAPIKeyByHash(db.WithReadYourWrites(ctx.Request.Context()), token.HashAPIKey(apiKey))
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, http.StatusUnauthorized, errInvalidAPIKey
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+	if key.RevokedAt.Valid {
+		return nil, http.StatusUnauthorized, errRevokedAPIKey
+	}
+	if key.IsFrozen {
+		return nil, http.StatusForbidden, errFrozenUser
+	}
+
+	if err := store.TouchAPIKey(ctx, key.ID); err != nil {
+		log.Printf("cannot record the use of API key %d: %v", key.ID, err)
+	}
+
+	payload := &token.Payload{
+		Username: key.Owner,
+		Role:     key.Role,
+		IssuedAt: key.CreatedAt,
+		// never nil, which would reach every route
+		Scopes: append([]string{}, key.Scopes...),
+	}
+	return payload, http.StatusOK, nil
+}
+
+// auditMiddleware records every mutating request in the audit log, once it is
+// handled. It must run after authMiddleware, as the actor is the authenticated
+// user. The audit info is also passed down to the store through the request
+// context, so the changes it records are linked to the request.
+func auditMiddleware(store db.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		switch ctx.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			ctx.Next()
+			return
+		}
+
+		requestID := ctx.GetHeader(requestIDHeaderKey)
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		ctx.Header(requestIDHeaderKey, requestID)
+
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		info := db.AuditInfo{
+			Actor:     authPayload.Username,
+			IP:        ctx.ClientIP(),
+			UserAgent: ctx.Request.UserAgent(),
+			RequestID: requestID,
+		}
+		ctx.Request = ctx.Request.WithContext(db.WithAuditInfo(ctx.Request.Context(), info))
+
+		ctx.Next()
+
+		// the route is recorded rather than the path, e.g. PATCH /accounts/:id/debit
+		route := ctx.FullPath()
+		targetType := strings.SplitN(strings.TrimPrefix(route, "/"), "/", 2)[0]
+		var targetID pgtype.Int8
+		if id, err := strconv.ParseInt(ctx.Param("id"), 10, 64); err == nil {
+			targetID = pgtype.Int8{Int64: id, Valid: true}
+		}
+
+		_, err := store.CreateAuditLog(ctx, db.CreateAuditLogParams{
+			Actor:      info.Actor,
+			Action:     ctx.Request.Method + " " + route,
+			TargetType: targetType,
+			TargetID:   targetID,
+			StatusCode: pgtype.Int4{Int32: int32(ctx.Writer.Status()), Valid: true},
+			Ip:         info.IP,
+			UserAgent:  info.UserAgent,
+			RequestID:  info.RequestID,
+		})
+		if err != nil {
+			log.Printf("cannot write audit log of request %s: %v", requestID, err)
+		}
+	}
+}
+
+// requireRole only lets through the users having one of the roles.
+// It must run after authMiddleware.
+func requireRole(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		for _, role := range roles {
+			if authPayload.Role == role {
+				ctx.Next()
+				return
+			}
+		}
+
+		err := fmt.Errorf("role %q is not allowed", authPayload.Role)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+	}
+}
+
+// rateLimitMiddleware limits the requests of each client of the route group,
+// as identified by keyFunc, with a token bucket. The requests over the limit
+// are rejected with 429 Too Many Requests. If the limiter backend fails, the
+// requests are let through rather than taking the API down with it.
+func rateLimitMiddleware(limiter ratelimit.Limiter, group string, limit ratelimit.Limit, keyFunc func(ctx *gin.Context) string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !limit.Enabled() {
+			ctx.Next()
+			return
+		}
+
+		key := group + ":" + keyFunc(ctx)
+		result, err := limiter.Allow(ctx, key, limit)
+		if err != nil {
+			log.Printf("cannot check rate limit of %s: %v", key, err)
+			ctx.Next()
+			return
+		}
+
+		ctx.Header(rateLimitLimitHeaderKey, strconv.Itoa(limit.Requests))
+		ctx.Header(rateLimitRemainingHeaderKey, strconv.Itoa(result.Remaining))
+		ctx.Header(rateLimitResetHeaderKey, strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			ctx.Header(retryAfterHeaderKey, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			err := errors.New("rate limit exceeded")
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, errorResponse(err))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// clientIPKey identifies the clients of the public routes by IP.
+func clientIPKey(ctx *gin.Context) string {
+	return "ip:" + ctx.ClientIP()
+}
+
+// usernameKey identifies the clients of the authenticated routes by username.
+// It must run after authMiddleware.
+func usernameKey(ctx *gin.Context) string {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	return "user:" + authPayload.Username
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
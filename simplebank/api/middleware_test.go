@@ -1,15 +1,24 @@
 package api
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/ratelimit"
 	"github.com/ebaudet/simplebank/token"
 	"github.com/ebaudet/simplebank/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,9 +28,10 @@ func addAuthorizationHeader(
 	tokenMaker token.Maker,
 	authorizationType string,
 	username string,
+	role string,
 	duration time.Duration,
 ) {
-	accessToken, _, err := tokenMaker.CreateToken(username, duration)
+	accessToken, _, err := tokenMaker.CreateToken(username, role, duration)
 	require.NoError(t, err)
 
 	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, accessToken)
@@ -33,21 +43,68 @@ func TestAuthMiddleware(t *testing.T) {
 	testCases := []struct {
 		name          string
 		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
 			name: "OK",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					IsTokenRevoked(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.IsTokenRevokedParams) (bool, error) {
+						require.NotEmpty(t, arg.ID)
+						require.Equal(t, username, arg.Username)
+						require.WithinDuration(t, time.Now(), arg.IssuedAt, time.Second)
+						return false, nil
+					})
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
 			},
 		},
+		{
+			name: "RevokedToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					IsTokenRevoked(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(true, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "RevocationError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					IsTokenRevoked(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(false, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
 		{
 			name: "NoAuthorization",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					IsTokenRevoked(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
@@ -55,7 +112,12 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "InvalidAuthorizationFormat",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, "", username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, "", username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					IsTokenRevoked(gomock.Any(), gomock.Any()).
+					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -64,7 +126,12 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "UnsupportedAuthorizationType",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, "other", username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, "other", username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					IsTokenRevoked(gomock.Any(), gomock.Any()).
+					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -73,7 +140,12 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "ExpiredToken",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, username, -time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, username, utils.DepositorRole, -time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					IsTokenRevoked(gomock.Any(), gomock.Any()).
+					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -81,14 +153,22 @@ func TestAuthMiddleware(t *testing.T) {
 		},
 	}
 
-	for _, tc := range testCases {
+	for i := range testCases {
+		tc := testCases[i]
+
 		t.Run(tc.name, func(t *testing.T) {
-			server := newTestServer(t, nil)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
 
 			authPath := "/auth"
 			server.router.GET(
 				authPath,
-				authMiddleware(server.tokenMaker),
+				authMiddleware(server.tokenMaker, store),
 				func(c *gin.Context) {
 					c.JSON(http.StatusOK, gin.H{})
 				},
@@ -103,3 +183,376 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestAuditMiddleware(t *testing.T) {
+	username := utils.RandomOwner()
+	targetID := utils.RandomInt(1, 1000)
+
+	testCases := []struct {
+		name          string
+		method        string
+		requestID     string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "MutatingRequest",
+			method:    http.MethodPatch,
+			requestID: "request-id",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAuditLog(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateAuditLogParams) (db.AuditLog, error) {
+						require.Equal(t, username, arg.Actor)
+						require.Equal(t, "PATCH /audit/:id", arg.Action)
+						require.Equal(t, "audit", arg.TargetType)
+						require.Equal(t, targetID, arg.TargetID.Int64)
+						require.Equal(t, int32(http.StatusOK), arg.StatusCode.Int32)
+						require.Equal(t, "request-id", arg.RequestID)
+						return db.AuditLog{}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "request-id", recorder.Header().Get(requestIDHeaderKey))
+				require.Contains(t, recorder.Body.String(), username)
+			},
+		},
+		{
+			name:   "GeneratedRequestID",
+			method: http.MethodDelete,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get(requestIDHeaderKey))
+			},
+		},
+		{
+			name:   "ReadRequest",
+			method: http.MethodGet,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, recorder.Header().Get(requestIDHeaderKey))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+
+			// the handler echoes the actor the store would see
+			auditPath := "/audit/:id"
+			server.router.Handle(
+				tc.method,
+				auditPath,
+				authMiddleware(server.tokenMaker, server.store),
+				auditMiddleware(store),
+				func(c *gin.Context) {
+					c.JSON(http.StatusOK, gin.H{"actor": db.AuditInfoFromContext(c).Actor})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(tc.method, fmt.Sprintf("/audit/%d", targetID), nil)
+			require.NoError(t, err)
+			if tc.requestID != "" {
+				request.Header.Set(requestIDHeaderKey, tc.requestID)
+			}
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestReadYourWritesMiddleware(t *testing.T) {
+	username := utils.RandomOwner()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	allowValidTokens(store)
+
+	server := newTestServer(t, store)
+	tracker := newWriteTracker(5 * time.Second)
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+
+	// the handler echoes whether the store would read from the primary, and
+	// answers with the status asked for in the query
+	server.router.Handle(
+		http.MethodGet,
+		"/ryw",
+		authMiddleware(server.tokenMaker, server.store),
+		readYourWritesMiddleware(tracker),
+		func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"primary": db.ReadYourWritesFromContext(c)})
+		},
+	)
+	server.router.Handle(
+		http.MethodPost,
+		"/ryw",
+		authMiddleware(server.tokenMaker, server.store),
+		readYourWritesMiddleware(tracker),
+		func(c *gin.Context) {
+			status, _ := strconv.Atoi(c.DefaultQuery("status", "200"))
+			c.JSON(status, gin.H{"primary": db.ReadYourWritesFromContext(c)})
+		},
+	)
+
+	send := func(method, url string) string {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(method, url, nil)
+		require.NoError(t, err)
+		addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, username, utils.DepositorRole, time.Minute)
+		server.router.ServeHTTP(recorder, request)
+		return recorder.Body.String()
+	}
+
+	// reads go to the replica until the user writes something
+	require.JSONEq(t, `{"primary":false}`, send(http.MethodGet, "/ryw"))
+
+	// a mutation always reads from the primary, but only a successful one is remembered
+	require.JSONEq(t, `{"primary":true}`, send(http.MethodPost, "/ryw?status=400"))
+	require.JSONEq(t, `{"primary":false}`, send(http.MethodGet, "/ryw"))
+
+	require.JSONEq(t, `{"primary":true}`, send(http.MethodPost, "/ryw"))
+	require.JSONEq(t, `{"primary":true}`, send(http.MethodGet, "/ryw"))
+
+	// once the window is over, reads go back to the replica
+	now = now.Add(5 * time.Second)
+	require.JSONEq(t, `{"primary":false}`, send(http.MethodGet, "/ryw"))
+
+	// the users whose window is over are pruned at most once per window
+	tracker.recordWrite("other")
+	require.Len(t, tracker.lastWrites, 1)
+	now = now.Add(time.Second)
+	tracker.recordWrite(username)
+	now = now.Add(3 * time.Second)
+	tracker.recordWrite("another")
+	require.Len(t, tracker.lastWrites, 3)
+	now = now.Add(time.Second)
+	tracker.recordWrite("another")
+	require.Len(t, tracker.lastWrites, 2)
+}
+
+// failingLimiter is a rate limiting backend which is down.
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("backend is down")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 2, Per: time.Minute}
+
+	testCases := []struct {
+		name          string
+		limiter       ratelimit.Limiter
+		limit         ratelimit.Limit
+		checkResponse func(t *testing.T, send func(username string) *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "OK",
+			limiter: ratelimit.NewMemoryLimiter(),
+			limit:   limit,
+			checkResponse: func(t *testing.T, send func(username string) *httptest.ResponseRecorder) {
+				recorder := send("alice")
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "2", recorder.Header().Get(rateLimitLimitHeaderKey))
+				require.Equal(t, "1", recorder.Header().Get(rateLimitRemainingHeaderKey))
+				require.Equal(t, "30", recorder.Header().Get(rateLimitResetHeaderKey))
+				require.Empty(t, recorder.Header().Get(retryAfterHeaderKey))
+			},
+		},
+		{
+			name:    "TooManyRequests",
+			limiter: ratelimit.NewMemoryLimiter(),
+			limit:   limit,
+			checkResponse: func(t *testing.T, send func(username string) *httptest.ResponseRecorder) {
+				send("alice")
+				send("alice")
+
+				recorder := send("alice")
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "0", recorder.Header().Get(rateLimitRemainingHeaderKey))
+				require.Equal(t, "30", recorder.Header().Get(retryAfterHeaderKey))
+
+				// the other users have their own limit
+				recorder = send("bob")
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:    "Disabled",
+			limiter: ratelimit.NewMemoryLimiter(),
+			limit:   ratelimit.Limit{},
+			checkResponse: func(t *testing.T, send func(username string) *httptest.ResponseRecorder) {
+				for i := 0; i < 5; i++ {
+					recorder := send("alice")
+					require.Equal(t, http.StatusOK, recorder.Code)
+					require.Empty(t, recorder.Header().Get(rateLimitLimitHeaderKey))
+				}
+			},
+		},
+		{
+			name:    "BackendError",
+			limiter: failingLimiter{},
+			limit:   limit,
+			checkResponse: func(t *testing.T, send func(username string) *httptest.ResponseRecorder) {
+				recorder := send("alice")
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, recorder.Header().Get(rateLimitLimitHeaderKey))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			server.router.GET(
+				"/limited",
+				authMiddleware(server.tokenMaker, server.store),
+				rateLimitMiddleware(tc.limiter, "test", tc.limit, usernameKey),
+				func(c *gin.Context) {
+					c.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			send := func(username string) *httptest.ResponseRecorder {
+				recorder := httptest.NewRecorder()
+				request, err := http.NewRequest(http.MethodGet, "/limited", nil)
+				require.NoError(t, err)
+				addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, username, utils.DepositorRole, time.Minute)
+				server.router.ServeHTTP(recorder, request)
+				return recorder
+			}
+			tc.checkResponse(t, send)
+		})
+	}
+}
+
+func TestPublicRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.User{}, db.ErrRecordNotFound)
+
+	config := utils.Config{
+		TokenSymmetricKey: utils.RandomString(32),
+		RateLimitPublic:   "1/1m",
+	}
+	server, err := NewServer(config, store)
+	require.NoError(t, err)
+
+	for _, status := range []int{http.StatusUnauthorized, http.StatusTooManyRequests} {
+		body := `{"username": "alice", "password": "secret"}`
+		request, err := http.NewRequest(http.MethodPost, "/users/login", strings.NewReader(body))
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, status, recorder.Code)
+	}
+}
+
+func TestPublicRateLimitClientIP(t *testing.T) {
+	testCases := []struct {
+		name           string
+		trustedProxies string
+		statuses       []int
+	}{
+		{
+			// without trusted proxies, a spoofed X-Forwarded-For header is
+			// ignored and the requests share the bucket of the remote address
+			name:     "SpoofedHeader",
+			statuses: []int{http.StatusUnauthorized, http.StatusTooManyRequests},
+		},
+		{
+			name:           "TrustedProxy",
+			trustedProxies: "192.0.2.0/24, 198.51.100.7",
+			statuses:       []int{http.StatusUnauthorized, http.StatusUnauthorized},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return(db.User{}, db.ErrRecordNotFound)
+
+			config := utils.Config{
+				TokenSymmetricKey: utils.RandomString(32),
+				RateLimitPublic:   "1/1m",
+				TrustedProxies:    tc.trustedProxies,
+			}
+			server, err := NewServer(config, store)
+			require.NoError(t, err)
+
+			for i, status := range tc.statuses {
+				body := `{"username": "alice", "password": "secret"}`
+				request, err := http.NewRequest(http.MethodPost, "/users/login", strings.NewReader(body))
+				require.NoError(t, err)
+				request.RemoteAddr = "192.0.2.1:4321"
+				request.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i+1))
+
+				recorder := httptest.NewRecorder()
+				server.router.ServeHTTP(recorder, request)
+				require.Equal(t, status, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestNewServerInvalidTrustedProxies(t *testing.T) {
+	config := utils.Config{
+		TokenSymmetricKey: utils.RandomString(32),
+		TrustedProxies:    "not a proxy",
+	}
+	_, err := NewServer(config, mockdb.NewMockStore(gomock.NewController(t)))
+	require.Error(t, err)
+}
+
+func TestNewServerInvalidRateLimit(t *testing.T) {
+	config := utils.Config{
+		TokenSymmetricKey: utils.RandomString(32),
+		RateLimitUser:     "fast",
+	}
+	_, err := NewServer(config, mockdb.NewMockStore(gomock.NewController(t)))
+	require.Error(t, err)
+}
@@ -0,0 +1,577 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Error codes of the OAuth2 endpoints, RFC 6749 sections 4.1.2.1 and 5.2.
+const (
+	oauthInvalidRequest          = "invalid_request"
+	oauthInvalidClient           = "invalid_client"
+	oauthInvalidGrant            = "invalid_grant"
+	oauthInvalidScope            = "invalid_scope"
+	oauthAccessDenied            = "access_denied"
+	oauthUnsupportedGrantType    = "unsupported_grant_type"
+	oauthUnsupportedResponseType = "unsupported_response_type"
+)
+
+const (
+	oauthResponseTypeCode           = "code"
+	oauthGrantTypeAuthorizationCode = "authorization_code"
+	oauthTokenTypeBearer            = "Bearer"
+	oauthConsentApprove             = "approve"
+	cacheControlHeaderKey           = "Cache-Control"
+	oauthClientScopeForbiddenPrefix = "admin:"
+)
+
+// oauthErrorResponse is the error body of the OAuth2 endpoints, which differs
+// from the one of the rest of the API.
+func oauthErrorResponse(code string, description string) gin.H {
+	return gin.H{"error": code, "error_description": description}
+}
+
+// isSupportedClientScope returns true if a third-party client can be given the
+// scope. The admin routes are kept out of their reach.
+func isSupportedClientScope(scope string) bool {
+	return isSupportedScope(scope) && !strings.HasPrefix(scope, oauthClientScopeForbiddenPrefix)
+}
+
+type oauthClientResponse struct {
+	ID           uuid.UUID `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newOAuthClientResponse(client db.OauthClient) oauthClientResponse {
+	return oauthClientResponse{
+		ID:           client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Scopes:       client.Scopes,
+		CreatedBy:    client.CreatedBy,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+type createOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1,dive,url"`
+	Scopes       []string `json:"scopes" binding:"required,min=1,dive,client_scope"`
+}
+
+type createOAuthClientResponse struct {
+	// ClientSecret is only shown once, only its hash is stored
+	ClientSecret string `json:"client_secret"`
+	oauthClientResponse
+}
+
+// createOAuthClient registers a third-party client, which can then ask the
+// users to consent to some of its scopes.
+func (server *Server) createOAuthClient(ctx *gin.Context) {
+	var req createOAuthClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	secret, err := token.NewClientSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	client, err := server.store.CreateOAuthClient(ctx, db.CreateOAuthClientParams{
+		ID:           uuid.New(),
+		Name:         req.Name,
+		SecretHash:   token.HashClientSecret(secret),
+		RedirectUris: req.RedirectURIs,
+		Scopes:       req.Scopes,
+		CreatedBy:    authPayload.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := createOAuthClientResponse{
+		ClientSecret:        secret,
+		oauthClientResponse: newOAuthClientResponse(client),
+	}
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+// listOAuthClients returns the registered third-party clients.
+func (server *Server) listOAuthClients(ctx *gin.Context) {
+	clients, err := server.store.ListOAuthClients(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]oauthClientResponse, len(clients))
+	for i, client := range clients {
+		rsp[i] = newOAuthClientResponse(client)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type oauthAuthorizeRequest struct {
+	ResponseType        string `form:"response_type" binding:"required"`
+	ClientID            string `form:"client_id" binding:"required,uuid"`
+	RedirectURI         string `form:"redirect_uri" binding:"required"`
+	Scope               string `form:"scope" binding:"required"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge" binding:"required,min=43,max=128"`
+	CodeChallengeMethod string `form:"code_challenge_method" binding:"required"`
+}
+
+// oauthAuthorization is an authorization request checked against its client.
+type oauthAuthorization struct {
+	oauthAuthorizeRequest
+	client db.OauthClient
+	scopes []string
+}
+
+// bindAuthorization checks the authorization request of the query. Until the
+// client and its redirect URI are known to be valid, the errors are answered
+// to the user, as redirecting could send them anywhere. Afterwards, they are
+// answered along with the URI to redirect the user to, as the client expects.
+func (server *Server) bindAuthorization(ctx *gin.Context) (*oauthAuthorization, bool) {
+	var req oauthAuthorizeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidRequest, err.Error()))
+		return nil, false
+	}
+
+	client, err := server.store.GetOAuthClient(ctx, uuid.MustParse(req.ClientID))
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidClient, "unknown client"))
+			return nil, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, false
+	}
+	if !containsString(client.RedirectUris, req.RedirectURI) {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidRequest, "redirect_uri is not registered for the client"))
+		return nil, false
+	}
+
+	auth := &oauthAuthorization{oauthAuthorizeRequest: req, client: client}
+	switch {
+	case req.ResponseType != oauthResponseTypeCode:
+		auth.redirectError(ctx, oauthUnsupportedResponseType, "only the code response type is supported")
+		return nil, false
+	case req.CodeChallengeMethod != token.CodeChallengeMethodS256:
+		auth.redirectError(ctx, oauthInvalidRequest, "only the S256 code challenge method is supported")
+		return nil, false
+	}
+
+	for _, scope := range strings.Fields(req.Scope) {
+		if !containsString(client.Scopes, scope) {
+			auth.redirectError(ctx, oauthInvalidScope, "scope "+scope+" is not allowed for the client")
+			return nil, false
+		}
+		if !containsString(auth.scopes, scope) {
+			auth.scopes = append(auth.scopes, scope)
+		}
+	}
+	// a blank scope passes the binding, and no scope must not mean every route
+	if len(auth.scopes) == 0 {
+		auth.redirectError(ctx, oauthInvalidScope, "at least one scope is required")
+		return nil, false
+	}
+	return auth, true
+}
+
+// redirectURL returns the redirect URI of the authorization with the params
+// added to its query.
+func (auth *oauthAuthorization) redirectURL(params url.Values) string {
+	if auth.State != "" {
+		params.Set("state", auth.State)
+	}
+
+	// the registered redirect URIs are valid URLs
+	redirect, _ := url.Parse(auth.RedirectURI)
+	query := redirect.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	redirect.RawQuery = query.Encode()
+	return redirect.String()
+}
+
+func (auth *oauthAuthorization) redirectError(ctx *gin.Context, code string, description string) {
+	rsp := oauthErrorResponse(code, description)
+	rsp["redirect_to"] = auth.redirectURL(url.Values{
+		"error":             {code},
+		"error_description": {description},
+	})
+	ctx.JSON(http.StatusBadRequest, rsp)
+}
+
+type oauthConsentResponse struct {
+	Client oauthClientResponse `json:"client"`
+	Scopes []string            `json:"scopes"`
+}
+
+// getOAuthConsent checks an authorization request and returns what the user
+// is asked to consent to, for the consent screen to show it.
+func (server *Server) getOAuthConsent(ctx *gin.Context) {
+	auth, ok := server.bindAuthorization(ctx)
+	if !ok {
+		return
+	}
+
+	rsp := oauthConsentResponse{
+		Client: newOAuthClientResponse(auth.client),
+		Scopes: auth.scopes,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type oauthConsentRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve deny"`
+}
+
+type oauthRedirectResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// submitOAuthConsent records the decision of the user on an authorization
+// request. On approval an authorization code is issued to the client. Either
+// way the user is to be redirected to the client.
+func (server *Server) submitOAuthConsent(ctx *gin.Context) {
+	var req oauthConsentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	auth, ok := server.bindAuthorization(ctx)
+	if !ok {
+		return
+	}
+
+	if req.Decision != oauthConsentApprove {
+		ctx.JSON(http.StatusOK, oauthRedirectResponse{
+			RedirectTo: auth.redirectURL(url.Values{"error": {oauthAccessDenied}}),
+		})
+		return
+	}
+
+	code, err := token.NewAuthorizationCode()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	_, err = server.store.CreateOAuthAuthorizationCode(ctx, db.CreateOAuthAuthorizationCodeParams{
+		CodeHash:      token.HashAuthorizationCode(code),
+		ClientID:      auth.client.ID,
+		Username:      authPayload.Username,
+		RedirectUri:   auth.RedirectURI,
+		Scopes:        auth.scopes,
+		CodeChallenge: auth.CodeChallenge,
+		ExpiresAt:     time.Now().Add(server.config.OAuthCodeDuration),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, oauthRedirectResponse{
+		RedirectTo: auth.redirectURL(url.Values{"code": {code}}),
+	})
+}
+
+// oauthClientCredentials are the credentials of a client, sent either with
+// HTTP basic authentication or in the form.
+type oauthClientCredentials struct {
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// authenticateClient returns the client of the credentials. The failures are
+// answered with 401 and an invalid_client error.
+func (server *Server) authenticateClient(ctx *gin.Context, credentials oauthClientCredentials) (db.OauthClient, bool) {
+	if clientID, secret, ok := ctx.Request.BasicAuth(); ok {
+		credentials = oauthClientCredentials{ClientID: clientID, ClientSecret: secret}
+	}
+
+	clientID, err := uuid.Parse(credentials.ClientID)
+	if err != nil || credentials.ClientSecret == "" {
+		ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauthInvalidClient, "invalid client credentials"))
+		return db.OauthClient{}, false
+	}
+
+	client, err := server.store.GetOAuthClient(ctx, clientID)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.OauthClient{}, false
+	}
+	if err != nil || client.SecretHash != token.HashClientSecret(credentials.ClientSecret) {
+		ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauthInvalidClient, "invalid client credentials"))
+		return db.OauthClient{}, false
+	}
+	return client, true
+}
+
+type oauthTokenRequest struct {
+	oauthClientCredentials
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code" binding:"required"`
+	RedirectURI  string `form:"redirect_uri" binding:"required"`
+	CodeVerifier string `form:"code_verifier" binding:"required,min=43,max=128"`
+}
+
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// createOAuthToken exchanges an authorization code for an access token limited
+// to the scopes the user consented to. A code can only be exchanged once, by
+// the client it was issued to, with the verifier of its PKCE challenge.
+func (server *Server) createOAuthToken(ctx *gin.Context) {
+	ctx.Header(cacheControlHeaderKey, "no-store")
+
+	var req oauthTokenRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidRequest, err.Error()))
+		return
+	}
+	if req.GrantType != oauthGrantTypeAuthorizationCode {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthUnsupportedGrantType, "only the authorization_code grant type is supported"))
+		return
+	}
+
+	client, ok := server.authenticateClient(ctx, req.oauthClientCredentials)
+	if !ok {
+		return
+	}
+
+	// the code was just issued, it must be found even if the replica lags behind
+	ctx.Request = ctx.Request.WithContext(db.WithReadYourWrites(ctx.Request.Context()))
+
+	codeHash := token.HashAuthorizationCode(req.Code)
+	code, err := server.store.GetOAuthAuthorizationCode(ctx, codeHash)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidGrant, "invalid authorization code"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if code.ClientID != client.ID {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidGrant, "invalid authorization code"))
+		return
+	}
+	if code.UsedAt.Valid {
+		// the code may have been stolen, the token issued for it is revoked
+		if code.AccessTokenID.Valid {
+			err = server.store.RevokeToken(ctx, db.RevokeTokenParams{
+				ID:        code.AccessTokenID.UUID,
+				Username:  code.Username,
+				ExpiresAt: code.UsedAt.Time.Add(server.config.OAuthAccessTokenDuration),
+			})
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+		}
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidGrant, "authorization code already used"))
+		return
+	}
+	if time.Now().After(code.ExpiresAt) {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidGrant, "authorization code has expired"))
+		return
+	}
+	if code.RedirectUri != req.RedirectURI {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidGrant, "redirect_uri does not match the authorization request"))
+		return
+	}
+	if !token.VerifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidGrant, "invalid code_verifier"))
+		return
+	}
+	if len(code.Scopes) == 0 {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidScope, "the authorization code has no scope"))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, code.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if user.IsFrozen {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidGrant, errFrozenUser.Error()))
+		return
+	}
+
+	duration := server.config.OAuthAccessTokenDuration
+	accessToken, payload, err := server.tokenMaker.CreateClientToken(user.Username, user.Role, client.ID.String(), code.Scopes, duration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.UseOAuthAuthorizationCode(ctx, db.UseOAuthAuthorizationCodeParams{
+		CodeHash:      codeHash,
+		AccessTokenID: uuid.NullUUID{UUID: payload.ID, Valid: true},
+	})
+	if err != nil {
+		// a concurrent request exchanged it first
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidGrant, "authorization code already used"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := oauthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   oauthTokenTypeBearer,
+		ExpiresIn:   int64(duration / time.Second),
+		Scope:       strings.Join(code.Scopes, " "),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type oauthTokenOfClientRequest struct {
+	oauthClientCredentials
+	Token string `form:"token" binding:"required"`
+}
+
+// clientToken returns the payload of the token if it is valid and was issued
+// to the client.
+func (server *Server) clientToken(ctx *gin.Context, client db.OauthClient, accessToken string) (*token.Payload, bool, error) {
+	payload, err := server.tokenMaker.VerifyToken(accessToken)
+	if err != nil || payload.ClientID != client.ID.String() {
+		return nil, false, nil
+	}
+
+	// read from the primary, a token revoked just before must not be reported active
+	revoked, err := server.store.IsTokenRevoked(db.WithReadYourWrites(ctx.Request.Context()), db.IsTokenRevokedParams{
+		ID:       payload.ID,
+		Username: payload.Username,
+		IssuedAt: payload.IssuedAt,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return payload, !revoked, nil
+}
+
+type oauthIntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenID   string `json:"jti,omitempty"`
+}
+
+// introspectOAuthToken tells a client whether one of its tokens is active,
+// as of RFC 7662. The tokens of the other clients are reported inactive.
+func (server *Server) introspectOAuthToken(ctx *gin.Context) {
+	var req oauthTokenOfClientRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidRequest, err.Error()))
+		return
+	}
+
+	client, ok := server.authenticateClient(ctx, req.oauthClientCredentials)
+	if !ok {
+		return
+	}
+
+	payload, active, err := server.clientToken(ctx, client, req.Token)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !active {
+		ctx.JSON(http.StatusOK, oauthIntrospectionResponse{Active: false})
+		return
+	}
+
+	rsp := oauthIntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(payload.Scopes, " "),
+		ClientID:  payload.ClientID,
+		Username:  payload.Username,
+		TokenType: oauthTokenTypeBearer,
+		ExpiresAt: payload.ExpiredAt.Unix(),
+		IssuedAt:  payload.IssuedAt.Unix(),
+		Subject:   payload.Username,
+		TokenID:   payload.ID.String(),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// revokeOAuthToken revokes one of the tokens of a client, as of RFC 7009.
+// Invalid tokens, and the tokens of the other clients, are left alone but
+// answered the same, so that the client can't tell them apart.
+func (server *Server) revokeOAuthToken(ctx *gin.Context) {
+	var req oauthTokenOfClientRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidRequest, err.Error()))
+		return
+	}
+
+	client, ok := server.authenticateClient(ctx, req.oauthClientCredentials)
+	if !ok {
+		return
+	}
+
+	payload, active, err := server.clientToken(ctx, client, req.Token)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if active {
+		err = server.store.RevokeToken(ctx, db.RevokeTokenParams{
+			ID:        payload.ID,
+			Username:  payload.Username,
+			ExpiresAt: payload.ExpiredAt,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
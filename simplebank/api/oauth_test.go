@@ -0,0 +1,1048 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func randomOAuthClient(createdBy string) (db.OauthClient, string) {
+	secret, _ := token.NewClientSecret()
+
+	client := db.OauthClient{
+		ID:           uuid.New(),
+		Name:         utils.RandomString(8),
+		SecretHash:   token.HashClientSecret(secret),
+		RedirectUris: []string{"https://client.example.com/callback"},
+		Scopes:       []string{"accounts:read", "transfers:write"},
+		CreatedBy:    createdBy,
+		CreatedAt:    time.Now().Add(-time.Hour),
+	}
+	return client, secret
+}
+
+// randomCodeVerifier returns a PKCE code verifier and its S256 challenge.
+func randomCodeVerifier() (string, string) {
+	verifier := utils.RandomString(43)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomAuthorizationCode(client db.OauthClient, username string, challenge string) (db.OauthAuthorizationCode, string) {
+	code, _ := token.NewAuthorizationCode()
+
+	authorizationCode := db.OauthAuthorizationCode{
+		CodeHash:      token.HashAuthorizationCode(code),
+		ClientID:      client.ID,
+		Username:      username,
+		RedirectUri:   client.RedirectUris[0],
+		Scopes:        []string{"accounts:read"},
+		CodeChallenge: challenge,
+		ExpiresAt:     time.Now().Add(time.Minute),
+		CreatedAt:     time.Now(),
+	}
+	return authorizationCode, code
+}
+
+func TestCreateOAuthClientAPI(t *testing.T) {
+	admin, _ := randomUser()
+	client, _ := randomOAuthClient(admin.Username)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"name": client.Name, "redirect_uris": client.RedirectUris, "scopes": client.Scopes},
+			role: utils.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateOAuthClientParams) (db.OauthClient, error) {
+						require.Equal(t, client.Name, arg.Name)
+						require.Equal(t, client.RedirectUris, arg.RedirectUris)
+						require.Equal(t, client.Scopes, arg.Scopes)
+						require.Equal(t, admin.Username, arg.CreatedBy)
+						created := client
+						created.ID = arg.ID
+						created.SecretHash = arg.SecretHash
+						return created, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var rsp createOAuthClientResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.ClientSecret)
+				require.NotEqual(t, uuid.Nil, rsp.ID)
+				require.NotContains(t, recorder.Body.String(), token.HashClientSecret(rsp.ClientSecret))
+			},
+		},
+		{
+			name: "AdminScope",
+			body: gin.H{"name": client.Name, "redirect_uris": client.RedirectUris, "scopes": []string{"admin:read"}},
+			role: utils.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateOAuthClient(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidRedirectURI",
+			body: gin.H{"name": client.Name, "redirect_uris": []string{"callback"}, "scopes": client.Scopes},
+			role: utils.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateOAuthClient(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NotAdmin",
+			body: gin.H{"name": client.Name, "redirect_uris": client.RedirectUris, "scopes": client.Scopes},
+			role: utils.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateOAuthClient(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"name": client.Name, "redirect_uris": client.RedirectUris, "scopes": client.Scopes},
+			role: utils.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthClient{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/admin/oauth-clients", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, admin.Username, tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestOAuthConsentAPI(t *testing.T) {
+	user, _ := randomUser()
+	client, _ := randomOAuthClient(utils.RandomOwner())
+	_, challenge := randomCodeVerifier()
+	state := utils.RandomString(16)
+
+	query := func(changes map[string]string) url.Values {
+		values := url.Values{
+			"response_type":         {"code"},
+			"client_id":             {client.ID.String()},
+			"redirect_uri":          {client.RedirectUris[0]},
+			"scope":                 {"accounts:read transfers:write"},
+			"state":                 {state},
+			"code_challenge":        {challenge},
+			"code_challenge_method": {"S256"},
+		}
+		for key, value := range changes {
+			values.Set(key, value)
+		}
+		return values
+	}
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: query(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp oauthConsentResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, client.Name, rsp.Client.Name)
+				require.Equal(t, []string{"accounts:read", "transfers:write"}, rsp.Scopes)
+			},
+		},
+		{
+			name:  "UnknownClient",
+			query: query(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthClient{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "redirect_to")
+			},
+		},
+		{
+			name:  "UnregisteredRedirectURI",
+			query: query(map[string]string{"redirect_uri": "https://attacker.example.com/callback"}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "redirect_to")
+			},
+		},
+		{
+			name:  "ScopeNotAllowed",
+			query: query(map[string]string{"scope": "accounts:write"}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+				var rsp gin.H
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, oauthInvalidScope, rsp["error"])
+
+				redirect, err := url.Parse(rsp["redirect_to"].(string))
+				require.NoError(t, err)
+				require.Equal(t, oauthInvalidScope, redirect.Query().Get("error"))
+				require.Equal(t, state, redirect.Query().Get("state"))
+			},
+		},
+		{
+			name:  "BlankScope",
+			query: query(map[string]string{"scope": " "}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+				var rsp gin.H
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, oauthInvalidScope, rsp["error"])
+			},
+		},
+		{
+			name:  "PlainChallengeMethod",
+			query: query(map[string]string{"code_challenge_method": "plain"}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthInvalidRequest)
+			},
+		},
+		{
+			name:  "NoCodeChallenge",
+			query: query(map[string]string{"code_challenge": ""}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/oauth/authorize?"+tc.query.Encode(), nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestSubmitOAuthConsentAPI(t *testing.T) {
+	user, _ := randomUser()
+	client, _ := randomOAuthClient(utils.RandomOwner())
+	_, challenge := randomCodeVerifier()
+	state := utils.RandomString(16)
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ID.String()},
+		"redirect_uri":          {client.RedirectUris[0]},
+		"scope":                 {"accounts:read"},
+		"state":                 {state},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Approve",
+			body: gin.H{"decision": "approve"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+				store.EXPECT().
+					CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateOAuthAuthorizationCodeParams) (db.OauthAuthorizationCode, error) {
+						require.Equal(t, client.ID, arg.ClientID)
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, client.RedirectUris[0], arg.RedirectUri)
+						require.Equal(t, []string{"accounts:read"}, arg.Scopes)
+						require.Equal(t, challenge, arg.CodeChallenge)
+						require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiresAt, time.Second)
+						return db.OauthAuthorizationCode{CodeHash: arg.CodeHash}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp oauthRedirectResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+
+				redirect, err := url.Parse(rsp.RedirectTo)
+				require.NoError(t, err)
+				require.True(t, strings.HasPrefix(rsp.RedirectTo, client.RedirectUris[0]+"?"))
+				require.NotEmpty(t, redirect.Query().Get("code"))
+				require.Equal(t, state, redirect.Query().Get("state"))
+			},
+		},
+		{
+			name: "Deny",
+			body: gin.H{"decision": "deny"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp oauthRedirectResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+
+				redirect, err := url.Parse(rsp.RedirectTo)
+				require.NoError(t, err)
+				require.Equal(t, oauthAccessDenied, redirect.Query().Get("error"))
+				require.Empty(t, redirect.Query().Get("code"))
+			},
+		},
+		{
+			name: "InvalidDecision",
+			body: gin.H{"decision": "maybe"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"decision": "approve"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().
+					CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthAuthorizationCode{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/oauth/authorize?"+query.Encode(), bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateOAuthTokenAPI(t *testing.T) {
+	user, _ := randomUser()
+	client, secret := randomOAuthClient(utils.RandomOwner())
+	otherClient, _ := randomOAuthClient(utils.RandomOwner())
+	verifier, challenge := randomCodeVerifier()
+	code, plainCode := randomAuthorizationCode(client, user.Username, challenge)
+
+	usedCode := code
+	usedCode.UsedAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+	usedCode.AccessTokenID = uuid.NullUUID{UUID: uuid.New(), Valid: true}
+
+	expiredCode := code
+	expiredCode.ExpiresAt = time.Now().Add(-time.Second)
+
+	otherClientCode := code
+	otherClientCode.ClientID = otherClient.ID
+
+	noScopeCode := code
+	noScopeCode.Scopes = []string{}
+
+	frozenUser := user
+	frozenUser.IsFrozen = true
+
+	form := func(changes map[string]string) url.Values {
+		values := url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {plainCode},
+			"redirect_uri":  {code.RedirectUri},
+			"code_verifier": {verifier},
+			"client_id":     {client.ID.String()},
+			"client_secret": {secret},
+		}
+		for key, value := range changes {
+			if value == "" {
+				values.Del(key)
+				continue
+			}
+			values.Set(key, value)
+		}
+		return values
+	}
+
+	testCases := []struct {
+		name          string
+		form          url.Values
+		basicAuth     bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker)
+	}{
+		{
+			name: "OK",
+			form: form(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+				store.EXPECT().
+					GetOAuthAuthorizationCode(gomock.Any(), gomock.Eq(code.CodeHash)).
+					Times(1).
+					Return(code, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UseOAuthAuthorizationCodeParams) (db.OauthAuthorizationCode, error) {
+						require.Equal(t, code.CodeHash, arg.CodeHash)
+						require.True(t, arg.AccessTokenID.Valid)
+						return code, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "no-store", recorder.Header().Get(cacheControlHeaderKey))
+
+				var rsp oauthTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, oauthTokenTypeBearer, rsp.TokenType)
+				require.Equal(t, int64(60), rsp.ExpiresIn)
+				require.Equal(t, "accounts:read", rsp.Scope)
+
+				payload, err := tokenMaker.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, payload.Username)
+				require.Equal(t, client.ID.String(), payload.ClientID)
+				require.Equal(t, code.Scopes, payload.Scopes)
+			},
+		},
+		{
+			name:      "BasicAuth",
+			form:      form(map[string]string{"client_id": "", "client_secret": ""}),
+			basicAuth: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+				store.EXPECT().GetOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(code, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(code, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UnsupportedGrantType",
+			form: form(map[string]string{"grant_type": "password"}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthUnsupportedGrantType)
+			},
+		},
+		{
+			name: "WrongSecret",
+			form: form(map[string]string{"client_secret": "wrong"}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthInvalidClient)
+			},
+		},
+		{
+			name: "UnknownCode",
+			form: form(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().
+					GetOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthAuthorizationCode{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthInvalidGrant)
+			},
+		},
+		{
+			name: "CodeOfOtherClient",
+			form: form(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(otherClientCode, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthInvalidGrant)
+			},
+		},
+		{
+			name: "CodeReused",
+			form: form(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(usedCode, nil)
+				store.EXPECT().
+					RevokeToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.RevokeTokenParams) error {
+						require.Equal(t, usedCode.AccessTokenID.UUID, arg.ID)
+						require.Equal(t, user.Username, arg.Username)
+						return nil
+					})
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthInvalidGrant)
+			},
+		},
+		{
+			name: "ExpiredCode",
+			form: form(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(expiredCode, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthInvalidGrant)
+			},
+		},
+		{
+			name: "CodeWithoutScope",
+			form: form(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(noScopeCode, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthInvalidScope)
+			},
+		},
+		{
+			name: "RedirectURIMismatch",
+			form: form(map[string]string{"redirect_uri": "https://client.example.com/other"}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(code, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthInvalidGrant)
+			},
+		},
+		{
+			name: "WrongCodeVerifier",
+			form: form(map[string]string{"code_verifier": utils.RandomString(43)}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(code, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthInvalidGrant)
+			},
+		},
+		{
+			name: "FrozenUser",
+			form: form(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(code, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(frozenUser, nil)
+				store.EXPECT().UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), oauthInvalidGrant)
+			},
+		},
+		{
+			name: "ConcurrentUse",
+			form: form(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Any()).Times(1).Return(client, nil)
+				store.EXPECT().GetOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(code, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().
+					UseOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthAuthorizationCode{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "access_token")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(tc.form.Encode()))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tc.basicAuth {
+				request.SetBasicAuth(client.ID.String(), secret)
+			}
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, server.tokenMaker)
+		})
+	}
+}
+
+func TestIntrospectOAuthTokenAPI(t *testing.T) {
+	user, _ := randomUser()
+	client, secret := randomOAuthClient(utils.RandomOwner())
+	otherClient, _ := randomOAuthClient(utils.RandomOwner())
+
+	testCases := []struct {
+		name          string
+		createToken   func(t *testing.T, tokenMaker token.Maker) string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Active",
+			createToken: func(t *testing.T, tokenMaker token.Maker) string {
+				accessToken, _, err := tokenMaker.CreateClientToken(user.Username, user.Role, client.ID.String(), []string{"accounts:read"}, time.Minute)
+				require.NoError(t, err)
+				return accessToken
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp oauthIntrospectionResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.True(t, rsp.Active)
+				require.Equal(t, "accounts:read", rsp.Scope)
+				require.Equal(t, client.ID.String(), rsp.ClientID)
+				require.Equal(t, user.Username, rsp.Username)
+				require.NotEmpty(t, rsp.TokenID)
+			},
+		},
+		{
+			name: "Revoked",
+			createToken: func(t *testing.T, tokenMaker token.Maker) string {
+				accessToken, _, err := tokenMaker.CreateClientToken(user.Username, user.Role, client.ID.String(), []string{"accounts:read"}, time.Minute)
+				require.NoError(t, err)
+				return accessToken
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(true, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"active": false}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "TokenOfOtherClient",
+			createToken: func(t *testing.T, tokenMaker token.Maker) string {
+				accessToken, _, err := tokenMaker.CreateClientToken(user.Username, user.Role, otherClient.ID.String(), []string{"accounts:read"}, time.Minute)
+				require.NoError(t, err)
+				return accessToken
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"active": false}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "UserToken",
+			createToken: func(t *testing.T, tokenMaker token.Maker) string {
+				accessToken, _, err := tokenMaker.CreateToken(user.Username, user.Role, time.Minute)
+				require.NoError(t, err)
+				return accessToken
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"active": false}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "InvalidToken",
+			createToken: func(t *testing.T, tokenMaker token.Maker) string {
+				return "invalid"
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"active": false}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "InternalError",
+			createToken: func(t *testing.T, tokenMaker token.Maker) string {
+				accessToken, _, err := tokenMaker.CreateClientToken(user.Username, user.Role, client.ID.String(), []string{"accounts:read"}, time.Minute)
+				require.NoError(t, err)
+				return accessToken
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(false, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			form := url.Values{"token": {tc.createToken(t, server.tokenMaker)}}
+			request, err := http.NewRequest(http.MethodPost, "/oauth/introspect", strings.NewReader(form.Encode()))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			request.SetBasicAuth(client.ID.String(), secret)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRevokeOAuthTokenAPI(t *testing.T) {
+	user, _ := randomUser()
+	client, secret := randomOAuthClient(utils.RandomOwner())
+	otherClient, _ := randomOAuthClient(utils.RandomOwner())
+
+	testCases := []struct {
+		name          string
+		clientID      uuid.UUID
+		buildStubs    func(store *mockdb.MockStore, payload *token.Payload)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			clientID: client.ID,
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+				store.EXPECT().
+					RevokeToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.RevokeTokenParams) error {
+						require.Equal(t, payload.ID, arg.ID)
+						require.Equal(t, user.Username, arg.Username)
+						require.WithinDuration(t, payload.ExpiredAt, arg.ExpiresAt, time.Second)
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "TokenOfOtherClient",
+			clientID: otherClient.ID,
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().RevokeToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "AlreadyRevoked",
+			clientID: client.ID,
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(true, nil)
+				store.EXPECT().RevokeToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "InternalError",
+			clientID: client.ID,
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+				store.EXPECT().RevokeToken(gomock.Any(), gomock.Any()).Times(1).Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			accessToken, payload, err := server.tokenMaker.CreateClientToken(user.Username, user.Role, tc.clientID.String(), []string{"accounts:read"}, time.Minute)
+			require.NoError(t, err)
+
+			store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+			tc.buildStubs(store, payload)
+
+			recorder := httptest.NewRecorder()
+
+			form := url.Values{"token": {accessToken}}
+			request, err := http.NewRequest(http.MethodPost, "/oauth/revoke", strings.NewReader(form.Encode()))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			request.SetBasicAuth(client.ID.String(), secret)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestOAuthClientTokenAuthorization(t *testing.T) {
+	user, _ := randomUser()
+	client, _ := randomOAuthClient(utils.RandomOwner())
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "ScopeNotGranted",
+			method: http.MethodPost,
+			url:    "/transfers",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "transfers:write")
+			},
+		},
+		{
+			name:   "APIKeys",
+			method: http.MethodPost,
+			url:    "/api-keys",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "Consent",
+			method: http.MethodGet,
+			url:    "/oauth/authorize",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			accessToken, _, err := server.tokenMaker.CreateClientToken(user.Username, user.Role, client.ID.String(), []string{"accounts:read"}, time.Minute)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(tc.method, tc.url, nil)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestIsSupportedClientScope(t *testing.T) {
+	require.True(t, isSupportedClientScope("accounts:read"))
+	require.True(t, isSupportedClientScope("transfers:write"))
+	require.False(t, isSupportedClientScope("admin:read"))
+	require.False(t, isSupportedClientScope("api-keys:write"))
+}
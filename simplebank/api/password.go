@@ -0,0 +1,255 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/mail"
+	"github.com/ebaudet/simplebank/passwordpolicy"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errSamePassword         = errors.New("new password must differ from the current one")
+	errInvalidResetPassword = errors.New("invalid or expired reset link")
+)
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// changePassword changes the password of the user, who must give the current
+// one, so that a stolen access token isn't enough. The wrong passwords count
+// as failed logins. The tokens issued before are rejected and the sessions
+// blocked, so the user is answered new ones, as on login.
+func (server *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	keys := server.loginKeys(ctx, authPayload.Username)
+	lockedUntil, err := server.countLoginAttempt(ctx, keys)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if time.Now().Before(lockedUntil) {
+		ctx.Header(retryAfterHeaderKey, retryAfterSeconds(lockedUntil))
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(errLoginLocked))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err := utils.CheckPassword(req.CurrentPassword, user.HashedPassword); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+		return
+	}
+	if err := server.forgiveLoginAttempt(ctx, keys); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if utils.CheckPassword(req.NewPassword, user.HashedPassword) == nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errSamePassword))
+		return
+	}
+	if !server.checkPasswordPolicy(ctx, req.NewPassword, user.Username, user.Email) {
+		return
+	}
+
+	hashedPassword, err := server.hasher.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err = server.store.ChangePasswordTx(ctx, db.ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+		ChangedAt:      time.Now(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.openLoginSession(ctx, user)
+}
+
+// checkPasswordPolicy answers the rules of the policy the new password of
+// the user fails, if any.
+func (server *Server) checkPasswordPolicy(ctx *gin.Context, password string, username string, email string) bool {
+	err := server.policy.Check(password, username, email)
+	if err == nil {
+		return true
+	}
+
+	var policyErr *passwordpolicy.Error
+	if errors.As(err, &policyErr) {
+		ctx.JSON(http.StatusBadRequest, passwordPolicyResponse(policyErr))
+		return false
+	}
+	ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	return false
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// forgotPassword sends a link to reset the password to the email of the user.
+// The answer is the same whether a user has the email or not, so that the
+// emails of the users can't be found out. The link is made and sent after the
+// answer, which then takes as long in both cases.
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	// a frozen user can't log in anyway
+	if err == nil && !user.IsFrozen {
+		server.background.Add(1)
+		go func() {
+			defer server.background.Done()
+			server.sendResetPasswordLink(user)
+		}()
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
+
+// sendResetPasswordLink creates a reset link for the user and sends it. It
+// runs after the answer of the request, so the failures are only logged.
+func (server *Server) sendResetPasswordLink(user db.User) {
+	ctx := context.Background()
+
+	resetToken, err := token.NewResetPasswordToken()
+	if err != nil {
+		log.Printf("cannot create reset password token for user %s: %v", user.Username, err)
+		return
+	}
+	resetPassword, err := server.store.CreateResetPassword(ctx, db.CreateResetPasswordParams{
+		TokenHash: token.HashResetPasswordToken(resetToken),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(server.config.ResetPasswordDuration),
+	})
+	if err != nil {
+		log.Printf("cannot create reset password link for user %s: %v", user.Username, err)
+		return
+	}
+
+	if err := server.sendResetPasswordEmail(ctx, user, resetPassword, resetToken); err != nil {
+		log.Printf("cannot send reset password email to user %s: %v", user.Username, err)
+	}
+}
+
+// sendResetPasswordEmail sends the reset link to the email of the user.
+func (server *Server) sendResetPasswordEmail(ctx context.Context, user db.User, resetPassword db.ResetPassword, resetToken string) error {
+	link, err := url.Parse(server.config.ResetPasswordURL)
+	if err != nil {
+		return fmt.Errorf("invalid reset password url: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", resetToken)
+	link.RawQuery = query.Encode()
+
+	email := mail.Email{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"A reset of your password was requested. Please follow this link to choose a new one:\n%s\n\n"+
+			"The link expires on %s. If you didn't request it, you can ignore this email.\n",
+			user.FullName, link, resetPassword.ExpiresAt.UTC().Format(time.RFC1123)),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendEmailTimeout)
+	defer cancel()
+	return server.mailer.SendEmail(ctx, email)
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// resetPassword changes the password of the user of the reset link, which can
+// only be done once. The tokens issued before are rejected as on a password
+// change, and the login is unlocked.
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// the user is needed to check the password against the policy, the link
+	// is only used once it passes
+	tokenHash := token.HashResetPasswordToken(req.Token)
+	resetPassword, err := server.store.GetResetPassword(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidResetPassword))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	user, err := server.store.GetUser(ctx, resetPassword.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !server.checkPasswordPolicy(ctx, req.NewPassword, user.Username, user.Email) {
+		return
+	}
+
+	hashedPassword, err := server.hasher.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err = server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenHash:      tokenHash,
+		HashedPassword: hashedPassword,
+		ChangedAt:      time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidResetPassword))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.DeleteLoginFailure(ctx, loginUserKey(user.Username))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
@@ -0,0 +1,543 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/passwordpolicy"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// requirePasswordViolations checks the response lists the rules of the
+// password policy, in order.
+func requirePasswordViolations(t *testing.T, recorder *httptest.ResponseRecorder, rules ...string) {
+	var rsp struct {
+		Error      string                     `json:"error"`
+		Violations []passwordpolicy.Violation `json:"violations"`
+	}
+	err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.NotEmpty(t, rsp.Error)
+
+	require.Len(t, rsp.Violations, len(rules))
+	for i, rule := range rules {
+		require.Equal(t, rule, rsp.Violations[i].Rule)
+		require.NotEmpty(t, rsp.Violations[i].Message)
+	}
+}
+
+func TestChangePasswordAPI(t *testing.T) {
+	user, password := randomUser()
+	newPassword := utils.RandomString(8)
+
+	noFailures := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			CountLoginAttemptTx(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			Return(time.Time{}, nil)
+	}
+	// the attempt counted first is taken back once the credentials are right
+	forgiveAttempt := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			ForgiveLoginFailure(gomock.Any(), gomock.Any()).
+			Times(2).
+			Return(nil)
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"current_password": password, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				forgiveAttempt(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ChangePasswordTxParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NoError(t, utils.CheckPassword(newPassword, arg.HashedPassword))
+						require.WithinDuration(t, time.Now(), arg.ChangedAt, time.Second)
+						changed := user
+						changed.HashedPassword = arg.HashedPassword
+						changed.PasswordChangedAt = arg.ChangedAt
+						return changed, nil
+					})
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotEmpty(t, rsp.RefreshToken)
+				require.Equal(t, user.Username, rsp.User.Username)
+			},
+		},
+		{
+			name: "WrongPassword",
+			body: gin.H{"current_password": "wrong-password", "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ForgiveLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "SamePassword",
+			body: gin.H{"current_password": password, "new_password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				forgiveAttempt(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Locked",
+			body: gin.H{"current_password": password, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CountLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Now().Add(time.Minute), db.ErrLoginLocked)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get(retryAfterHeaderKey))
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"current_password": password, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				forgiveAttempt(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "TooShortPassword",
+			body: gin.H{"current_password": password, "new_password": "123"},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				forgiveAttempt(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requirePasswordViolations(t, recorder, passwordpolicy.RuleMinLength)
+			},
+		},
+		{
+			name: "MissingNewPassword",
+			body: gin.H{"current_password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			server := newLockoutTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPatch, "/users/password", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestForgotPasswordAPI(t *testing.T) {
+	user, _ := randomUser()
+	var tokenHash string
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateResetPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateResetPasswordParams) (db.ResetPassword, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Len(t, arg.TokenHash, 64)
+						require.WithinDuration(t, time.Now().Add(time.Hour), arg.ExpiresAt, time.Second)
+						tokenHash = arg.TokenHash
+						return db.ResetPassword{
+							TokenHash: arg.TokenHash,
+							Username:  arg.Username,
+							ExpiresAt: arg.ExpiresAt,
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				require.Len(t, mailer.emails, 1)
+				require.Equal(t, user.Email, mailer.emails[0].To)
+				link, err := url.Parse(verifyEmailLinkRegexp.FindString(mailer.emails[0].Body))
+				require.NoError(t, err)
+				require.Equal(t, "/reset_password", link.Path)
+				require.Equal(t, tokenHash, token.HashResetPasswordToken(link.Query().Get("token")))
+			},
+		},
+		{
+			name: "UnknownEmail",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					CreateResetPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, mailer.emails)
+			},
+		},
+		{
+			name: "FrozenUser",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				frozen := user
+				frozen.IsFrozen = true
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(frozen, nil)
+				store.EXPECT().
+					CreateResetPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, mailer.emails)
+			},
+		},
+		{
+			// the answer doesn't tell the link couldn't be made
+			name: "CreateResetPasswordError",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateResetPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ResetPassword{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, mailer.emails)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Empty(t, mailer.emails)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "invalid-email"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			mailer := server.mailer.(*fakeMailer)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/password/forgot", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			// the link is sent after the answer
+			server.background.Wait()
+			tc.checkResponse(t, recorder, mailer)
+		})
+	}
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	user, _ := randomUser()
+	resetToken, err := token.NewResetPasswordToken()
+	require.NoError(t, err)
+	newPassword := utils.RandomString(8)
+	resetPassword := db.ResetPassword{
+		TokenHash: token.HashResetPasswordToken(resetToken),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	getResetPassword := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetResetPassword(gomock.Any(), gomock.Eq(resetPassword.TokenHash)).
+			Times(1).
+			Return(resetPassword, nil)
+		store.EXPECT().
+			GetUser(gomock.Any(), gomock.Eq(user.Username)).
+			Times(1).
+			Return(user, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"token": resetToken, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				getResetPassword(store)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ResetPasswordTxParams) (db.User, error) {
+						require.Equal(t, token.HashResetPasswordToken(resetToken), arg.TokenHash)
+						require.NoError(t, utils.CheckPassword(newPassword, arg.HashedPassword))
+						require.WithinDuration(t, time.Now(), arg.ChangedAt, time.Second)
+						return user, nil
+					})
+				store.EXPECT().
+					DeleteLoginFailure(gomock.Any(), gomock.Eq(loginUserKey(user.Username))).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidLink",
+			body: gin.H{"token": resetToken, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetResetPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ResetPassword{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.JSONEq(t, `{"error": "invalid or expired reset link"}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "UsedMeanwhile",
+			body: gin.H{"token": resetToken, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				getResetPassword(store)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					DeleteLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.JSONEq(t, `{"error": "invalid or expired reset link"}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"token": resetToken, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				getResetPassword(store)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "PolicyViolation",
+			body: gin.H{"token": resetToken, "new_password": "123"},
+			buildStubs: func(store *mockdb.MockStore) {
+				getResetPassword(store)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requirePasswordViolations(t, recorder, passwordpolicy.RuleMinLength)
+			},
+		},
+		{
+			name: "MissingToken",
+			body: gin.H{"new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/password/reset", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var errNothingToUpdate = errors.New("nothing to update")
+
+// getMe returns the profile of the authenticated user.
+func (server *Server) getMe(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type updateUserRequest struct {
+	FullName string `json:"full_name" binding:"omitempty,max=255"`
+	Email    string `json:"email" binding:"omitempty,email"`
+}
+
+// updateMe updates the profile of the authenticated user. See updateUserProfile.
+func (server *Server) updateMe(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, ok := server.updateUserProfile(ctx, authPayload.Username)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// updateUserProfile updates the full name and the email of the user, the
+// fields left empty are kept. A changed email is no longer verified, and a
+// link to verify it is sent to the new one. It answers the errors itself.
+func (server *Server) updateUserProfile(ctx *gin.Context, username string) (db.User, bool) {
+	var req updateUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.User{}, false
+	}
+	if req.FullName == "" && req.Email == "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errNothingToUpdate))
+		return db.User{}, false
+	}
+
+	code, codeHash, expiresAt, err := server.newVerifyEmailCode()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.User{}, false
+	}
+
+	result, err := server.store.UpdateUserTx(ctx, db.UpdateUserTxParams{
+		UpdateUserParams: db.UpdateUserParams{
+			Username: username,
+			FullName: pgtype.Text{String: req.FullName, Valid: req.FullName != ""},
+			Email:    pgtype.Text{String: req.Email, Valid: req.Email != ""},
+		},
+		VerifyEmailSecretCodeHash: codeHash,
+		VerifyEmailExpiresAt:      expiresAt,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return db.User{}, false
+		}
+		switch db.ErrorCode(err) {
+		case db.UniqueViolation:
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return db.User{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.User{}, false
+	}
+	user := result.User
+
+	// the email is changed anyway, and the user can ask for a new link
+	if result.VerifyEmail != nil {
+		if err := server.sendVerifyEmail(ctx, user, *result.VerifyEmail, code); err != nil {
+			log.Printf("cannot send verification email to user %s: %v", user.Username, err)
+		}
+	}
+
+	return user, true
+}
@@ -0,0 +1,275 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMeAPI(t *testing.T) {
+	user, _ := randomUser()
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchUser(t, recorder.Body, user)
+			},
+		},
+		{
+			name: "NotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/users/me", nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateMeAPI(t *testing.T) {
+	user, _ := randomUser()
+	user.IsEmailVerified = true
+	newFullName := utils.RandomFullName()
+	newEmail := utils.RandomEmail()
+	verifyEmail := db.VerifyEmail{
+		ID:        utils.RandomInt(1, 1000),
+		Username:  user.Username,
+		Email:     newEmail,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	var codeHash string
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		sendErr       error
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer)
+	}{
+		{
+			name: "FullName",
+			body: gin.H{"full_name": newFullName},
+			buildStubs: func(store *mockdb.MockStore) {
+				updated := user
+				updated.FullName = newFullName
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.True(t, arg.FullName.Valid)
+						require.Equal(t, newFullName, arg.FullName.String)
+						require.False(t, arg.Email.Valid)
+						return db.UpdateUserTxResult{User: updated}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp userResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, newFullName, rsp.FullName)
+				require.True(t, rsp.IsEmailVerified)
+				require.Empty(t, mailer.emails)
+			},
+		},
+		{
+			name: "Email",
+			body: gin.H{"email": newEmail},
+			buildStubs: func(store *mockdb.MockStore) {
+				updated := user
+				updated.Email = newEmail
+				updated.IsEmailVerified = false
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UpdateUserTxParams) (db.UpdateUserTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.False(t, arg.FullName.Valid)
+						require.True(t, arg.Email.Valid)
+						require.Equal(t, newEmail, arg.Email.String)
+						require.Len(t, arg.VerifyEmailSecretCodeHash, 64)
+						require.WithinDuration(t, time.Now().Add(time.Hour), arg.VerifyEmailExpiresAt, time.Second)
+						codeHash = arg.VerifyEmailSecretCodeHash
+						return db.UpdateUserTxResult{User: updated, VerifyEmail: &verifyEmail}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp userResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, newEmail, rsp.Email)
+				require.False(t, rsp.IsEmailVerified)
+
+				require.Len(t, mailer.emails, 1)
+				require.Equal(t, newEmail, mailer.emails[0].To)
+				requireVerifyEmailLink(t, mailer.emails[0].Body, verifyEmail.ID, codeHash)
+			},
+		},
+		{
+			name: "SendEmailError",
+			body: gin.H{"email": newEmail},
+			buildStubs: func(store *mockdb.MockStore) {
+				updated := user
+				updated.Email = newEmail
+				updated.IsEmailVerified = false
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UpdateUserTxResult{User: updated, VerifyEmail: &verifyEmail}, nil)
+			},
+			sendErr: errors.New("smtp server is down"),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "DuplicateEmail",
+			body: gin.H{"email": newEmail},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UpdateUserTxResult{}, &pgconn.PgError{Code: db.UniqueViolation})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Empty(t, mailer.emails)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"full_name": newFullName},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UpdateUserTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "invalid-email"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NothingToUpdate",
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.JSONEq(t, `{"error": "nothing to update"}`, recorder.Body.String())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			mailer := server.mailer.(*fakeMailer)
+			mailer.err = tc.sendErr
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPatch, "/users/me", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, mailer)
+		})
+	}
+}
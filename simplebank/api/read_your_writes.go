@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+// writeTracker remembers when each user last changed something, so their
+// following requests can read from the primary while the replica catches up.
+type writeTracker struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu         sync.Mutex
+	lastWrites map[string]time.Time
+	prunedAt   time.Time
+}
+
+func newWriteTracker(window time.Duration) *writeTracker {
+	return &writeTracker{
+		window:     window,
+		now:        time.Now,
+		lastWrites: make(map[string]time.Time),
+	}
+}
+
+// recordWrite notes that the user just changed something.
+func (tracker *writeTracker) recordWrite(username string) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	now := tracker.now()
+	tracker.lastWrites[username] = now
+	tracker.prune(now)
+}
+
+// prune drops the users whose window is over, so the map does not grow forever.
+// It only walks the map once per window, as the writes are frequent.
+func (tracker *writeTracker) prune(now time.Time) {
+	if now.Sub(tracker.prunedAt) < tracker.window {
+		return
+	}
+	tracker.prunedAt = now
+
+	for user, lastWrite := range tracker.lastWrites {
+		if now.Sub(lastWrite) >= tracker.window {
+			delete(tracker.lastWrites, user)
+		}
+	}
+}
+
+// recentWrite reports whether the user changed something within the window.
+func (tracker *writeTracker) recentWrite(username string) bool {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	lastWrite, ok := tracker.lastWrites[username]
+	return ok && tracker.now().Sub(lastWrite) < tracker.window
+}
+
+// readYourWritesMiddleware sends the queries of the mutating requests, and of
+// the requests of a user who made one within the window, to the primary
+// database. It must run after authMiddleware.
+func readYourWritesMiddleware(tracker *writeTracker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		mutating := false
+		switch ctx.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			mutating = true
+		}
+
+		if mutating || tracker.recentWrite(authPayload.Username) {
+			ctx.Request = ctx.Request.WithContext(db.WithReadYourWrites(ctx.Request.Context()))
+		}
+
+		ctx.Next()
+
+		if mutating && ctx.Writer.Status() < http.StatusBadRequest {
+			tracker.recordWrite(authPayload.Username)
+		}
+	}
+}
@@ -1,9 +1,16 @@
 package api
 
 import (
+	"expvar"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
 
 	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/mail"
+	"github.com/ebaudet/simplebank/passwordpolicy"
+	"github.com/ebaudet/simplebank/ratelimit"
 	"github.com/ebaudet/simplebank/token"
 	"github.com/ebaudet/simplebank/utils"
 	"github.com/gin-gonic/gin"
@@ -16,37 +23,208 @@ type Server struct {
 	config     utils.Config
 	store      db.Store
 	tokenMaker token.Maker
+	keyring    *token.Keyring
+	mailer     mail.Sender
+	hasher     utils.PasswordHasher
+	policy     *passwordpolicy.Policy
+	writes     *writeTracker
+	limiter    ratelimit.Limiter
+	rateLimits rateLimits
 	router     *gin.Engine
+
+	// dummyPassword is checked on the login of unknown users
+	dummyPassword dummyPassword
+	// background tracks the work going on after the answer of a request
+	background sync.WaitGroup
+}
+
+// rateLimits are the limits of each route group.
+type rateLimits struct {
+	public ratelimit.Limit
+	user   ratelimit.Limit
+	admin  ratelimit.Limit
 }
 
 func NewServer(config utils.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	keyring, err := newKeyring(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create token maker: %w", err)
 	}
 
+	limits, err := newRateLimits(config)
+	if err != nil {
+		return nil, err
+	}
+
+	mailer, err := mail.NewSender(mail.Config{
+		Kind:         config.MailSender,
+		From:         config.MailFrom,
+		Dir:          config.MailDir,
+		SMTPHost:     config.SMTPHost,
+		SMTPPort:     config.SMTPPort,
+		SMTPUsername: config.SMTPUsername,
+		SMTPPassword: config.SMTPPassword,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create email sender: %w", err)
+	}
+
+	hasher, err := utils.NewPasswordHasher(utils.PasswordHasherConfig{
+		Algorithm:         config.PasswordHashAlgorithm,
+		BcryptCost:        config.BcryptCost,
+		Argon2Memory:      config.Argon2Memory,
+		Argon2Iterations:  config.Argon2Iterations,
+		Argon2Parallelism: config.Argon2Parallelism,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create password hasher: %w", err)
+	}
+
+	policy, err := passwordpolicy.NewPolicy(passwordpolicy.Config{
+		MinLength:        config.PasswordMinLength,
+		MaxLength:        config.PasswordMaxLength,
+		RequireUpper:     config.PasswordRequireUpper,
+		RequireLower:     config.PasswordRequireLower,
+		RequireDigit:     config.PasswordRequireDigit,
+		RequireSymbol:    config.PasswordRequireSymbol,
+		RejectUserInfo:   config.PasswordRejectUserInfo,
+		BreachedListFile: config.PasswordBreachedList,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create password policy: %w", err)
+	}
+
 	server := &Server{
 		config:     config,
 		store:      store,
-		tokenMaker: tokenMaker,
+		tokenMaker: keyring,
+		keyring:    keyring,
+		mailer:     mailer,
+		hasher:     hasher,
+		policy:     policy,
+		writes:     newWriteTracker(config.ReadYourWritesWindow),
+		limiter:    ratelimit.NewMemoryLimiter(),
+		rateLimits: limits,
 	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("event_type", validEventType)
+		v.RegisterValidation("role", validRole)
+		v.RegisterValidation("scope", validScope)
+		v.RegisterValidation("client_scope", validClientScope)
+		v.RegisterValidation("https_url", validHTTPSURL)
 	}
 
-	server.setupRouter()
+	if err := server.setupRouter(); err != nil {
+		return nil, err
+	}
 
 	return server, nil
 }
 
-func (server *Server) setupRouter() {
+// newKeyring creates the keyring of the config, starting with the configured
+// key, read from its private key file if any.
+func newKeyring(config utils.Config) (*token.Keyring, error) {
+	var privateKey []byte
+	if config.TokenPrivateKeyFile != "" {
+		var err error
+		privateKey, err = os.ReadFile(config.TokenPrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	maker, err := token.NewMaker(config.TokenMaker, config.TokenSymmetricKey, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	maxTokenLifetime := config.AccessTokenDuration
+	if config.OAuthAccessTokenDuration > maxTokenLifetime {
+		maxTokenLifetime = config.OAuthAccessTokenDuration
+	}
+	return token.NewKeyring(maker, token.KeyringConfig{
+		Kind:             config.TokenMaker,
+		Dir:              config.TokenKeyringDir,
+		MaxTokenLifetime: maxTokenLifetime,
+		RefreshInterval:  config.TokenKeyRefreshInterval,
+	})
+}
+
+func newRateLimits(config utils.Config) (limits rateLimits, err error) {
+	if limits.public, err = ratelimit.ParseLimit(config.RateLimitPublic); err != nil {
+		return
+	}
+	if limits.user, err = ratelimit.ParseLimit(config.RateLimitUser); err != nil {
+		return
+	}
+	limits.admin, err = ratelimit.ParseLimit(config.RateLimitAdmin)
+	return
+}
+
+// trustedProxies parses the comma-separated list of the trusted proxies.
+// None are trusted when it is empty.
+func trustedProxies(s string) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(s, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+func (server *Server) setupRouter() error {
 	router := gin.Default()
+	// the client IP identifies the clients of the public routes, so it is only
+	// read from the X-Forwarded-For header when set by a trusted proxy
+	if err := router.SetTrustedProxies(trustedProxies(server.config.TrustedProxies)); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	// let the store read the audit info from the request context
+	router.ContextWithFallback = true
+
+	publicRoutes := router.Group("/").Use(
+		rateLimitMiddleware(server.limiter, "public", server.rateLimits.public, clientIPKey),
+	)
+
+	publicRoutes.POST("/users", server.createUser)
+	publicRoutes.POST("/users/login", server.loginUser)
+	publicRoutes.POST("/users/login/mfa", server.loginMFA)
+	publicRoutes.GET("/users/verify_email", server.verifyEmail)
+	publicRoutes.POST("/users/password/forgot", server.forgotPassword)
+	publicRoutes.POST("/users/password/reset", server.resetPassword)
+	publicRoutes.POST("/tokens/renew_access", server.renewAccessToken)
+	publicRoutes.POST("/oauth/token", server.createOAuthToken)
+	publicRoutes.POST("/oauth/introspect", server.introspectOAuthToken)
+	publicRoutes.POST("/oauth/revoke", server.revokeOAuthToken)
+	publicRoutes.GET("/.well-known/jwks.json", server.getJWKS)
+
+	authRoutes := router.Group("/").Use(
+		authMiddleware(server.tokenMaker, server.store),
+		rateLimitMiddleware(server.limiter, "user", server.rateLimits.user, usernameKey),
+		readYourWritesMiddleware(server.writes),
+		auditMiddleware(server.store),
+	)
+
+	authRoutes.POST("/users/logout", server.logoutUser)
+	authRoutes.POST("/users/logout_all", server.logoutUserEverywhere)
+	authRoutes.POST("/users/totp", server.enrollTOTP)
+	authRoutes.POST("/users/totp/confirm", server.confirmTOTP)
+	authRoutes.POST("/users/totp/disable", server.disableTOTP)
+	authRoutes.POST("/users/verify_email", server.resendVerifyEmail)
+	authRoutes.PATCH("/users/password", server.changePassword)
+	authRoutes.GET("/users/me", server.getMe)
+	authRoutes.PATCH("/users/me", server.updateMe)
 
-	router.POST("/users", server.createUser)
-	router.POST("/users/login", server.loginUser)
+	authRoutes.POST("/api-keys", server.createAPIKey)
+	authRoutes.GET("/api-keys", server.listAPIKeys)
+	authRoutes.DELETE("/api-keys/:id", server.revokeAPIKey)
 
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	// consent screen of the OAuth2 authorization code flow
+	authRoutes.GET("/oauth/authorize", server.getOAuthConsent)
+	authRoutes.POST("/oauth/authorize", server.submitOAuthConsent)
 
 	authRoutes.POST("/accounts", server.createAccount)
 	authRoutes.GET("/accounts/:id", server.getAccount)
@@ -54,10 +232,72 @@ func (server *Server) setupRouter() {
 	authRoutes.DELETE("/accounts/:id", server.deleteAccount)
 	authRoutes.PATCH("/accounts/:id/debit", server.debitAccount)
 	authRoutes.PATCH("/accounts/:id/credit", server.creditAccount)
+	authRoutes.GET("/accounts/:id/balance-history", server.getBalanceHistory)
+
+	authRoutes.POST("/goals", server.createGoal)
+	authRoutes.GET("/goals", server.listGoals)
+	authRoutes.GET("/goals/:id", server.getGoal)
+	authRoutes.DELETE("/goals/:id", server.deleteGoal)
+	authRoutes.POST("/goals/:id/deposit", server.depositGoal)
+	authRoutes.POST("/goals/:id/withdraw", server.withdrawGoal)
+
+	authRoutes.PATCH("/entries/:id/category", server.updateEntryCategory)
+	authRoutes.GET("/entries/:id/tags", server.listEntryTags)
+	authRoutes.POST("/entries/:id/tags", server.addEntryTag)
+	authRoutes.DELETE("/entries/:id/tags/:tag", server.deleteEntryTag)
+
+	authRoutes.GET("/categories", server.listCategories)
+	authRoutes.POST("/categories", server.createCategory)
+	authRoutes.DELETE("/categories/:id", server.deleteCategory)
+	authRoutes.GET("/category-rules", server.listCategoryRules)
+	authRoutes.POST("/category-rules", server.createCategoryRule)
+	authRoutes.DELETE("/category-rules/:id", server.deleteCategoryRule)
+
+	authRoutes.GET("/analytics/spending", server.getSpending)
 
 	authRoutes.POST("/transfers", server.createTransfer)
 
+	authRoutes.POST("/webhooks", server.createWebhook)
+	authRoutes.GET("/webhooks", server.listWebhooks)
+	authRoutes.DELETE("/webhooks/:id", server.deleteWebhook)
+	authRoutes.GET("/webhooks/:id/deliveries", server.listWebhookDeliveries)
+	authRoutes.POST("/webhooks/:id/deliveries/:delivery_id/retry", server.retryWebhookDelivery)
+
+	bankerRoutes := router.Group("/admin").Use(
+		authMiddleware(server.tokenMaker, server.store),
+		requireRole(utils.BankerRole, utils.AdminRole),
+		rateLimitMiddleware(server.limiter, "admin", server.rateLimits.admin, usernameKey),
+		readYourWritesMiddleware(server.writes),
+		auditMiddleware(server.store),
+	)
+
+	bankerRoutes.GET("/users", server.listUsers)
+	bankerRoutes.GET("/accounts", server.listAllAccounts)
+	bankerRoutes.POST("/accounts/:id/adjust", server.adjustAccountBalance)
+
+	adminRoutes := router.Group("/admin").Use(
+		authMiddleware(server.tokenMaker, server.store),
+		requireRole(utils.AdminRole),
+		rateLimitMiddleware(server.limiter, "admin", server.rateLimits.admin, usernameKey),
+		readYourWritesMiddleware(server.writes),
+		auditMiddleware(server.store),
+	)
+
+	adminRoutes.GET("/audit-log", server.listAuditLog)
+	adminRoutes.PATCH("/users/:username", server.updateUser)
+	adminRoutes.PATCH("/users/:username/role", server.updateUserRole)
+	adminRoutes.POST("/users/:username/freeze", server.freezeUser)
+	adminRoutes.POST("/users/:username/unfreeze", server.unfreezeUser)
+	adminRoutes.POST("/users/:username/unlock", server.unlockUser)
+	adminRoutes.POST("/oauth-clients", server.createOAuthClient)
+	adminRoutes.GET("/oauth-clients", server.listOAuthClients)
+	adminRoutes.GET("/token-keys", server.listTokenKeys)
+	adminRoutes.POST("/token-keys/rotate", server.rotateTokenKeys)
+	// expvar metrics, including the db_tx_retries counters
+	adminRoutes.GET("/metrics", gin.WrapH(expvar.Handler()))
+
 	server.router = router
+	return nil
 }
 
 func (server *Server) Start(address string) error {
@@ -67,3 +307,8 @@ func (server *Server) Start(address string) error {
 func errorResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
 }
+
+// passwordPolicyResponse adds the rules the password fails to the error.
+func passwordPolicyResponse(err *passwordpolicy.Error) gin.H {
+	return gin.H{"error": err.Error(), "violations": err.Violations}
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	errInvalidRefreshToken = errors.New("invalid refresh token")
+	errBlockedSession      = errors.New("blocked session")
+	errExpiredSession      = errors.New("expired session")
+	errReusedRefreshToken  = errors.New("refresh token reused, the session is blocked")
+)
+
+// newSessionParams creates a new refresh token, along with the parameters of
+// the session storing it.
+func (server *Server) newSessionParams(ctx *gin.Context, username string, sessionID, familyID uuid.UUID) (string, db.CreateSessionParams, error) {
+	refreshToken, err := token.NewRefreshToken()
+	if err != nil {
+		return "", db.CreateSessionParams{}, err
+	}
+
+	arg := db.CreateSessionParams{
+		ID:               sessionID,
+		Username:         username,
+		RefreshTokenHash: token.HashRefreshToken(refreshToken),
+		FamilyID:         familyID,
+		UserAgent:        ctx.Request.UserAgent(),
+		ClientIp:         ctx.ClientIP(),
+		ExpiresAt:        time.Now().Add(server.config.RefreshTokenDuration),
+	}
+	return refreshToken, arg, nil
+}
+
+type renewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResponse struct {
+	SessionID             uuid.UUID `json:"session_id"`
+	AccessToken           string    `json:"access_token"`
+	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+// renewAccessToken issues a new access token for the session of the refresh
+// token. The refresh token is rotated: it can only be used once, and using it
+// again blocks every session of its family.
+func (server *Server) renewAccessToken(ctx *gin.Context) {
+	var req renewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// the rotation just made must be seen, even if the replica lags behind
+	ctx.Request = ctx.Request.WithContext(db.WithReadYourWrites(ctx.Request.Context()))
+
+	session, err := server.store.GetSessionByRefreshToken(ctx, token.HashRefreshToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidRefreshToken))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if session.IsBlocked {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errBlockedSession))
+		return
+	}
+	if session.ReplacedBy.Valid {
+		server.blockSessionFamily(ctx, session)
+		return
+	}
+	if time.Now().After(session.ExpiresAt) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errExpiredSession))
+		return
+	}
+
+	// the role is read again, it may have changed since the login
+	user, err := server.store.GetUser(ctx, session.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if user.IsFrozen {
+		ctx.JSON(http.StatusForbidden, errorResponse(errFrozenUser))
+		return
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.AccessTokenDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	refreshToken, arg, err := server.newSessionParams(ctx, session.Username, uuid.New(), session.FamilyID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	newSession, err := server.store.RotateSessionTx(ctx, db.RotateSessionTxParams{
+		SessionID: session.ID,
+		Session:   arg,
+	})
+	if err != nil {
+		// a concurrent request rotated it first
+		if errors.Is(err, db.ErrSessionReused) {
+			server.blockSessionFamily(ctx, session)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := renewAccessTokenResponse{
+		SessionID:             newSession.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: newSession.ExpiresAt,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// blockSessionFamily answers the reuse of a rotated refresh token by blocking
+// all the sessions of its family, as one of the tokens may have been stolen.
+func (server *Server) blockSessionFamily(ctx *gin.Context, session db.Session) {
+	_, err := server.store.BlockSessionFamily(ctx, session.FamilyID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusUnauthorized, errorResponse(errReusedRefreshToken))
+}
+
+type logoutUserRequest struct {
+	// RefreshToken is optional, its session is blocked along with the access token
+	RefreshToken string `json:"refresh_token"`
+}
+
+// logoutUser revokes the access token of the request, and blocks the session
+// of the refresh token when one is given.
+func (server *Server) logoutUser(ctx *gin.Context) {
+	var req logoutUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	err := server.store.RevokeToken(ctx, db.RevokeTokenParams{
+		ID:        authPayload.ID,
+		Username:  authPayload.Username,
+		ExpiresAt: authPayload.ExpiredAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if req.RefreshToken != "" {
+		session, err := server.store.GetSessionByRefreshToken(ctx, token.HashRefreshToken(req.RefreshToken))
+		if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		// the sessions of the other users are left alone
+		if err == nil && session.Username == authPayload.Username {
+			_, err = server.store.BlockSessionFamily(ctx, session.FamilyID)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+		}
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
+
+// logoutUserEverywhere revokes all the access tokens of the user issued until
+// now, and blocks all the sessions.
+func (server *Server) logoutUserEverywhere(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	_, err := server.store.RevokeUserTokensTx(ctx, db.RevokeUserTokensTxParams{
+		Username:     authPayload.Username,
+		IssuedBefore: time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
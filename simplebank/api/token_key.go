@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ebaudet/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+type tokenKeyResponse struct {
+	KeyID       string    `json:"kid"`
+	Kind        string    `json:"kind"`
+	ActivatesAt time.Time `json:"activates_at"`
+	// ExpiresAt is null until a newer key replaces it
+	ExpiresAt *time.Time `json:"expires_at"`
+	Primary   bool       `json:"primary"`
+}
+
+func newTokenKeyResponse(info token.KeyInfo) tokenKeyResponse {
+	rsp := tokenKeyResponse{
+		KeyID:       info.KeyID,
+		Kind:        info.Kind,
+		ActivatesAt: info.ActivatesAt,
+		Primary:     info.Primary,
+	}
+	if !info.ExpiresAt.IsZero() {
+		rsp.ExpiresAt = &info.ExpiresAt
+	}
+	return rsp
+}
+
+// listTokenKeys returns the keys signing and verifying the tokens. The key
+// material is never returned.
+func (server *Server) listTokenKeys(ctx *gin.Context) {
+	keys := server.keyring.Keys()
+
+	rsp := make([]tokenKeyResponse, len(keys))
+	for i, key := range keys {
+		rsp[i] = newTokenKeyResponse(key)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// rotateTokenKeys creates a new signing key. It takes over once every instance
+// could load it, and the keys it replaces keep verifying the tokens they
+// signed until these have expired.
+func (server *Server) rotateTokenKeys(ctx *gin.Context) {
+	key, err := server.keyring.Rotate()
+	if err != nil {
+		if errors.Is(err, token.ErrKeyringNotStored) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newTokenKeyResponse(key))
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateTokenKeysAPI(t *testing.T) {
+	admin := utils.RandomOwner()
+
+	testCases := []struct {
+		name          string
+		keyringDir    string
+		role          string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server)
+	}{
+		{
+			name:       "OK",
+			keyringDir: t.TempDir(),
+			role:       utils.AdminRole,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var rsp tokenKeyResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.KeyID)
+				require.False(t, rsp.Primary)
+				require.Nil(t, rsp.ExpiresAt)
+				require.NotContains(t, recorder.Body.String(), "key\":")
+
+				// the configured key keeps signing until the new key activates
+				keys := server.keyring.Keys()
+				require.Len(t, keys, 2)
+				require.True(t, keys[0].Primary)
+				require.False(t, keys[0].ExpiresAt.IsZero())
+			},
+		},
+		{
+			name:       "NotStored",
+			keyringDir: "",
+			role:       utils.AdminRole,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Len(t, server.keyring.Keys(), 1)
+			},
+		},
+		{
+			name:       "NotAdmin",
+			keyringDir: t.TempDir(),
+			role:       utils.BankerRole,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Len(t, server.keyring.Keys(), 1)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			config := utils.Config{
+				TokenSymmetricKey:       utils.RandomString(32),
+				TokenKeyringDir:         tc.keyringDir,
+				TokenKeyRefreshInterval: time.Minute,
+				AccessTokenDuration:     time.Minute,
+			}
+			server, err := NewServer(config, store)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/admin/token-keys/rotate", nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, admin, tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, server)
+		})
+	}
+}
+
+func TestListTokenKeysAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	allowValidTokens(store)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/admin/token-keys", nil)
+	require.NoError(t, err)
+
+	addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, utils.RandomOwner(), utils.AdminRole, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp []tokenKeyResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Len(t, rsp, 1)
+	require.True(t, rsp[0].Primary)
+	require.Nil(t, rsp[0].ExpiresAt)
+}
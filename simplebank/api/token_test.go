@@ -0,0 +1,490 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func randomSession(username string) (db.Session, string) {
+	refreshToken, _ := token.NewRefreshToken()
+	familyID := uuid.New()
+
+	session := db.Session{
+		ID:               uuid.New(),
+		Username:         username,
+		RefreshTokenHash: token.HashRefreshToken(refreshToken),
+		FamilyID:         familyID,
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}
+	return session, refreshToken
+}
+
+func TestRenewAccessTokenAPI(t *testing.T) {
+	user, _ := randomUser()
+	session, refreshToken := randomSession(user.Username)
+
+	blockedSession := session
+	blockedSession.IsBlocked = true
+
+	expiredSession := session
+	expiredSession.ExpiresAt = time.Now().Add(-time.Minute)
+
+	rotatedSession := session
+	rotatedSession.ReplacedBy = uuid.NullUUID{UUID: uuid.New(), Valid: true}
+
+	frozenUser := user
+	frozenUser.IsFrozen = true
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"refresh_token": refreshToken},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSessionByRefreshToken(gomock.Any(), gomock.Eq(session.RefreshTokenHash)).
+					Times(1).
+					Return(session, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.RotateSessionTxParams) (db.Session, error) {
+						require.Equal(t, session.ID, arg.SessionID)
+						require.NotEqual(t, session.ID, arg.Session.ID)
+						require.Equal(t, session.FamilyID, arg.Session.FamilyID)
+						require.Equal(t, user.Username, arg.Session.Username)
+						require.NotEqual(t, session.RefreshTokenHash, arg.Session.RefreshTokenHash)
+						return db.Session{ID: arg.Session.ID, ExpiresAt: arg.Session.ExpiresAt}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp renewAccessTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotEmpty(t, rsp.RefreshToken)
+				require.NotEqual(t, refreshToken, rsp.RefreshToken)
+				require.NotEqual(t, session.ID, rsp.SessionID)
+			},
+		},
+		{
+			name: "UnknownRefreshToken",
+			body: gin.H{"refresh_token": "unknown"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSessionByRefreshToken(gomock.Any(), gomock.Eq(token.HashRefreshToken("unknown"))).
+					Times(1).
+					Return(db.Session{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "BlockedSession",
+			body: gin.H{"refresh_token": refreshToken},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSessionByRefreshToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(blockedSession, nil)
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredSession",
+			body: gin.H{"refresh_token": refreshToken},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSessionByRefreshToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(expiredSession, nil)
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ReusedRefreshToken",
+			body: gin.H{"refresh_token": refreshToken},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSessionByRefreshToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(rotatedSession, nil)
+				store.EXPECT().
+					BlockSessionFamily(gomock.Any(), gomock.Eq(session.FamilyID)).
+					Times(1).
+					Return(int64(2), nil)
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ConcurrentRotation",
+			body: gin.H{"refresh_token": refreshToken},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSessionByRefreshToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(session, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, db.ErrSessionReused)
+				store.EXPECT().
+					BlockSessionFamily(gomock.Any(), gomock.Eq(session.FamilyID)).
+					Times(1).
+					Return(int64(2), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "FrozenUser",
+			body: gin.H{"refresh_token": refreshToken},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSessionByRefreshToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(session, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(frozenUser, nil)
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"refresh_token": refreshToken},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSessionByRefreshToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "RotateError",
+			body: gin.H{"refresh_token": refreshToken},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSessionByRefreshToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(session, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
+				store.EXPECT().
+					BlockSessionFamily(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "MissingRefreshToken",
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSessionByRefreshToken(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := utils.Config{
+				TokenSymmetricKey:    utils.RandomString(32),
+				AccessTokenDuration:  time.Minute,
+				RefreshTokenDuration: time.Hour,
+			}
+			server, err := NewServer(config, store)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLogoutUserAPI(t *testing.T) {
+	user, _ := randomUser()
+	session, refreshToken := randomSession(user.Username)
+
+	otherSession := session
+	otherSession.Username = utils.RandomOwner()
+
+	testCases := []struct {
+		name          string
+		body          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.RevokeTokenParams) error {
+						require.NotEmpty(t, arg.ID)
+						require.Equal(t, user.Username, arg.Username)
+						require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiresAt, time.Second)
+						return nil
+					})
+				store.EXPECT().
+					GetSessionByRefreshToken(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "WithRefreshToken",
+			body: `{"refresh_token": "` + refreshToken + `"}`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetSessionByRefreshToken(gomock.Any(), gomock.Eq(session.RefreshTokenHash)).
+					Times(1).
+					Return(session, nil)
+				store.EXPECT().
+					BlockSessionFamily(gomock.Any(), gomock.Eq(session.FamilyID)).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "OtherUserSession",
+			body: `{"refresh_token": "` + refreshToken + `"}`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetSessionByRefreshToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(otherSession, nil)
+				store.EXPECT().
+					BlockSessionFamily(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidBody",
+			body: "{",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeToken(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/users/logout", strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLogoutUserEverywhereAPI(t *testing.T) {
+	user, _ := randomUser()
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeUserTokensTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.RevokeUserTokensTxParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.WithinDuration(t, time.Now(), arg.IssuedBefore, time.Second)
+						return user, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeUserTokensTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeUserTokensTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/users/logout_all", nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
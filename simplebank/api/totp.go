@@ -0,0 +1,381 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// recoveryCodeCount is the number of recovery codes given when two-factor
+// authentication is enabled.
+const recoveryCodeCount = 10
+
+var (
+	errTOTPEnabled      = errors.New("two-factor authentication is already enabled")
+	errTOTPNotEnabled   = errors.New("two-factor authentication is not enabled")
+	errTOTPNotEnrolled  = errors.New("no two-factor authentication enrolment to confirm")
+	errInvalidTOTPCode  = errors.New("invalid two-factor authentication code")
+	errInvalidMFAToken  = errors.New("invalid or expired MFA token")
+	errMissingTOTPProof = errors.New("a code or a recovery code is required")
+)
+
+type mfaChallengeResponse struct {
+	MFARequired       bool      `json:"mfa_required"`
+	MFAToken          string    `json:"mfa_token"`
+	MFATokenExpiresAt time.Time `json:"mfa_token_expires_at"`
+}
+
+// startMFAChallenge answers the first step of the login of a user having
+// enabled two-factor authentication, with a short-lived token to exchange
+// along with a code for the access token.
+func (server *Server) startMFAChallenge(ctx *gin.Context, user db.User) {
+	mfaToken, err := token.NewMFAToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	challenge, err := server.store.CreateMFAChallenge(ctx, db.CreateMFAChallengeParams{
+		TokenHash: token.HashMFAToken(mfaToken),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(server.config.MFAChallengeDuration),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := mfaChallengeResponse{
+		MFARequired:       true,
+		MFAToken:          mfaToken,
+		MFATokenExpiresAt: challenge.ExpiresAt,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// totpProof is the second factor of a request, either a TOTP code or one of
+// the recovery codes.
+type totpProof struct {
+	Code         string `json:"code" binding:"omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" binding:"omitempty,max=32"`
+}
+
+// checkTOTPProof returns true if the code, or the recovery code, is valid for
+// the user. Either one is only accepted once.
+func (server *Server) checkTOTPProof(ctx context.Context, totp db.UserTotp, proof totpProof) (bool, error) {
+	if proof.Code != "" {
+		step, ok := token.VerifyTOTP(totp.Secret, proof.Code, time.Now(), totp.LastUsedStep)
+		if !ok {
+			return false, nil
+		}
+		// a concurrent request may have used the code meanwhile
+		_, err := server.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+			Step:     step,
+			Username: totp.Username,
+		})
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	_, err := server.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+		Username: totp.Username,
+		CodeHash: token.HashRecoveryCode(proof.RecoveryCode),
+	})
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	totpProof
+}
+
+// loginMFA completes the login of a user having enabled two-factor
+// authentication. The wrong codes count as failed logins, so that they can't
+// be guessed.
+func (server *Server) loginMFA(ctx *gin.Context) {
+	var req loginMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errMissingTOTPProof))
+		return
+	}
+
+	// the failures and the used codes just recorded must be seen, even if the
+	// replica lags behind
+	ctx.Request = ctx.Request.WithContext(db.WithReadYourWrites(ctx.Request.Context()))
+
+	challenge, err := server.store.GetMFAChallenge(ctx, token.HashMFAToken(req.MFAToken))
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFAToken))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if challenge.UsedAt.Valid || time.Now().After(challenge.ExpiresAt) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFAToken))
+		return
+	}
+
+	keys := server.loginKeys(ctx, challenge.Username)
+	lockedUntil, err := server.countLoginAttempt(ctx, keys)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if time.Now().Before(lockedUntil) {
+		ctx.Header(retryAfterHeaderKey, retryAfterSeconds(lockedUntil))
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(errLoginLocked))
+		return
+	}
+
+	totp, err := server.store.GetUserTOTP(ctx, challenge.Username)
+	if err != nil {
+		// two-factor authentication was disabled since the first step
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFAToken))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ok, err := server.checkTOTPProof(ctx, totp, req.totpProof)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidTOTPCode))
+		return
+	}
+	if err := server.forgiveLoginAttempt(ctx, keys); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.UseMFAChallenge(ctx, challenge.TokenHash)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFAToken))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if _, ok := keys[loginUserKey(challenge.Username)]; ok {
+		err = server.store.DeleteLoginFailure(ctx, loginUserKey(challenge.Username))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	user, err := server.store.GetUser(ctx, challenge.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	// the user may have been frozen since the first step
+	if user.IsFrozen {
+		ctx.JSON(http.StatusForbidden, errorResponse(errFrozenUser))
+		return
+	}
+
+	server.openLoginSession(ctx, user)
+}
+
+type enrollTOTPResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// enrollTOTP starts the enrolment of the user in two-factor authentication,
+// with a new secret to add to an authenticator app. Starting again before the
+// confirmation replaces the secret.
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	secret, err := token.NewTOTPSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	totp, err := server.store.UpsertUserTOTP(ctx, db.UpsertUserTOTPParams{
+		Username: authPayload.Username,
+		Secret:   secret,
+	})
+	if err != nil {
+		// a confirmed enrolment is left alone, so nothing is returned
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusForbidden, errorResponse(errTOTPEnabled))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := enrollTOTPResponse{
+		Secret:     totp.Secret,
+		OTPAuthURI: token.TOTPURI(server.config.TOTPIssuer, totp.Username, totp.Secret),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type confirmTOTPResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// confirmTOTP enables the two-factor authentication of the user, once a first
+// code shows the authenticator app is set up. The recovery codes are only
+// shown in this response.
+func (server *Server) confirmTOTP(ctx *gin.Context) {
+	var req confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	totp, err := server.store.GetUserTOTP(ctx, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(errTOTPNotEnrolled))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if totp.ConfirmedAt.Valid {
+		ctx.JSON(http.StatusForbidden, errorResponse(errTOTPEnabled))
+		return
+	}
+
+	step, ok := token.VerifyTOTP(totp.Secret, req.Code, time.Now(), totp.LastUsedStep)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidTOTPCode))
+		return
+	}
+
+	codes, err := token.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = token.HashRecoveryCode(code)
+	}
+
+	_, err = server.store.ConfirmTOTPTx(ctx, db.ConfirmTOTPTxParams{
+		Username:           authPayload.Username,
+		Step:               step,
+		RecoveryCodeHashes: hashes,
+	})
+	if err != nil {
+		// a concurrent request confirmed it meanwhile
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusForbidden, errorResponse(errTOTPEnabled))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, confirmTOTPResponse{RecoveryCodes: codes})
+}
+
+type disableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	totpProof
+}
+
+// disableTOTP disables the two-factor authentication of the user, who must
+// authenticate again with the password and a code, so that a stolen access
+// token isn't enough. The failures count as failed logins.
+func (server *Server) disableTOTP(ctx *gin.Context) {
+	var req disableTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errMissingTOTPProof))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	keys := server.loginKeys(ctx, authPayload.Username)
+	lockedUntil, err := server.countLoginAttempt(ctx, keys)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if time.Now().Before(lockedUntil) {
+		ctx.Header(retryAfterHeaderKey, retryAfterSeconds(lockedUntil))
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(errLoginLocked))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err := utils.CheckPassword(req.Password, user.HashedPassword); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+		return
+	}
+
+	totp, err := server.store.GetUserTOTP(ctx, authPayload.Username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err != nil || !totp.ConfirmedAt.Valid {
+		ctx.JSON(http.StatusForbidden, errorResponse(errTOTPNotEnabled))
+		return
+	}
+
+	ok, err := server.checkTOTPProof(ctx, totp, req.totpProof)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidTOTPCode))
+		return
+	}
+	if err := server.forgiveLoginAttempt(ctx, keys); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := server.store.DisableTOTPTx(ctx, authPayload.Username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
@@ -0,0 +1,865 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func randomUserTOTP(t *testing.T, username string, confirmed bool) db.UserTotp {
+	secret, err := token.NewTOTPSecret()
+	require.NoError(t, err)
+
+	totp := db.UserTotp{
+		Username:  username,
+		Secret:    secret,
+		CreatedAt: time.Now().Add(-time.Hour),
+	}
+	if confirmed {
+		totp.ConfirmedAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true}
+	}
+	return totp
+}
+
+func currentTOTPCode(t *testing.T, secret string) string {
+	code, err := token.TOTPCode(secret, token.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	user, _ := randomUser()
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertUserTOTP(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UpsertUserTOTPParams) (db.UserTotp, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Len(t, arg.Secret, 32)
+						return db.UserTotp{Username: arg.Username, Secret: arg.Secret}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp enrollTOTPResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.Secret)
+
+				uri, err := url.Parse(rsp.OTPAuthURI)
+				require.NoError(t, err)
+				require.Equal(t, "otpauth", uri.Scheme)
+				require.Equal(t, "/SimpleBank:"+user.Username, uri.Path)
+				require.Equal(t, rsp.Secret, uri.Query().Get("secret"))
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertUserTOTP(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertUserTOTP(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserTotp{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/users/totp", nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestConfirmTOTPAPI(t *testing.T) {
+	user, _ := randomUser()
+	pending := randomUserTOTP(t, user.Username, false)
+
+	testCases := []struct {
+		name          string
+		body          func(t *testing.T) gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"code": currentTOTPCode(t, pending.Secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(pending, nil)
+				store.EXPECT().
+					ConfirmTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ConfirmTOTPTxParams) (db.UserTotp, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, token.TOTPStep(time.Now()), arg.Step)
+						require.Len(t, arg.RecoveryCodeHashes, recoveryCodeCount)
+						return pending, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp confirmTOTPResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.RecoveryCodes, recoveryCodeCount)
+			},
+		},
+		{
+			name: "WrongCode",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"code": "000000"}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				wrong := pending
+				// the secret of another enrolment
+				wrong.Secret = randomUserTOTP(t, user.Username, false).Secret
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(wrong, nil)
+				store.EXPECT().
+					ConfirmTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnrolled",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"code": "123456"}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"code": "123456"}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(randomUserTOTP(t, user.Username, true), nil)
+				store.EXPECT().
+					ConfirmTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"code": "12ab56"}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body(t))
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/totp/confirm", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDisableTOTPAPI(t *testing.T) {
+	user, password := randomUser()
+	totp := randomUserTOTP(t, user.Username, true)
+	recoveryCode := "abcd-efgh-ijkl-mnop"
+
+	noFailures := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			CountLoginAttemptTx(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			Return(time.Time{}, nil)
+	}
+	// the attempt counted first is taken back once the credentials are right
+	forgiveAttempt := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			ForgiveLoginFailure(gomock.Any(), gomock.Any()).
+			Times(2).
+			Return(nil)
+	}
+
+	testCases := []struct {
+		name          string
+		body          func(t *testing.T) gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"password": password, "code": currentTOTPCode(t, totp.Secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				forgiveAttempt(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totp, nil)
+				arg := db.UseTOTPStepParams{
+					Step:     token.TOTPStep(time.Now()),
+					Username: user.Username,
+				}
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(totp, nil)
+				store.EXPECT().
+					DisableTOTPTx(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "RecoveryCode",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"password": password, "recovery_code": recoveryCode}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				forgiveAttempt(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totp, nil)
+				arg := db.UseRecoveryCodeParams{
+					Username: user.Username,
+					CodeHash: token.HashRecoveryCode(recoveryCode),
+				}
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.RecoveryCode{}, nil)
+				store.EXPECT().
+					DisableTOTPTx(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "WrongPassword",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"password": "wrong-password", "code": currentTOTPCode(t, totp.Secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ForgiveLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					DisableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UsedRecoveryCode",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"password": password, "recovery_code": recoveryCode}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totp, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RecoveryCode{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					ForgiveLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					DisableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnabled",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"password": password, "code": "123456"}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(randomUserTOTP(t, user.Username, false), nil)
+				store.EXPECT().
+					DisableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoCode",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			server := newLockoutTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body(t))
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/totp/disable", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginMFAAPI(t *testing.T) {
+	user, _ := randomUser()
+	totp := randomUserTOTP(t, user.Username, true)
+	mfaToken, err := token.NewMFAToken()
+	require.NoError(t, err)
+
+	challenge := db.MfaChallenge{
+		TokenHash: token.HashMFAToken(mfaToken),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Minute),
+		CreatedAt: time.Now(),
+	}
+
+	noFailures := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			CountLoginAttemptTx(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			Return(time.Time{}, nil)
+	}
+	// the attempt counted first is taken back once the credentials are right
+	forgiveAttempt := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			ForgiveLoginFailure(gomock.Any(), gomock.Any()).
+			Times(2).
+			Return(nil)
+	}
+	getChallenge := func(store *mockdb.MockStore, challenge db.MfaChallenge) {
+		store.EXPECT().
+			GetMFAChallenge(gomock.Any(), gomock.Eq(challenge.TokenHash)).
+			Times(1).
+			Return(challenge, nil)
+	}
+	requireNoSession := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			UseMFAChallenge(gomock.Any(), gomock.Any()).
+			Times(0)
+		store.EXPECT().
+			CreateSession(gomock.Any(), gomock.Any()).
+			Times(0)
+	}
+
+	testCases := []struct {
+		name          string
+		body          func(t *testing.T) gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken, "code": currentTOTPCode(t, totp.Secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				forgiveAttempt(store)
+				getChallenge(store, challenge)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totp, nil)
+				arg := db.UseTOTPStepParams{
+					Step:     token.TOTPStep(time.Now()),
+					Username: user.Username,
+				}
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(totp, nil)
+				store.EXPECT().
+					UseMFAChallenge(gomock.Any(), gomock.Eq(challenge.TokenHash)).
+					Times(1).
+					Return(challenge, nil)
+				store.EXPECT().
+					DeleteLoginFailure(gomock.Any(), gomock.Eq(loginUserKey(user.Username))).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateSessionParams) (db.Session, error) {
+						require.Equal(t, user.Username, arg.Username)
+						return db.Session{ID: arg.ID, ExpiresAt: arg.ExpiresAt}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotEmpty(t, rsp.RefreshToken)
+				require.Equal(t, user.Username, rsp.User.Username)
+			},
+		},
+		{
+			name: "RecoveryCode",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken, "recovery_code": "ABCD-EFGH-IJKL-MNOP"}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				forgiveAttempt(store)
+				getChallenge(store, challenge)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totp, nil)
+				arg := db.UseRecoveryCodeParams{
+					Username: user.Username,
+					CodeHash: token.HashRecoveryCode("abcd-efgh-ijkl-mnop"),
+				}
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.RecoveryCode{}, nil)
+				store.EXPECT().
+					UseMFAChallenge(gomock.Any(), gomock.Eq(challenge.TokenHash)).
+					Times(1).
+					Return(challenge, nil)
+				store.EXPECT().
+					DeleteLoginFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "WrongCode",
+			body: func(t *testing.T) gin.H {
+				code, err := token.TOTPCode(totp.Secret, token.TOTPStep(time.Now())+5)
+				require.NoError(t, err)
+				return gin.H{"mfa_token": mfaToken, "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				getChallenge(store, challenge)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totp, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					ForgiveLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+				requireNoSession(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.JSONEq(t, `{"error": "invalid two-factor authentication code"}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "ReplayedCode",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken, "code": currentTOTPCode(t, totp.Secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				getChallenge(store, challenge)
+				used := totp
+				used.LastUsedStep = token.TOTPStep(time.Now())
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(used, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					ForgiveLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+				requireNoSession(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ConcurrentlyUsedCode",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken, "code": currentTOTPCode(t, totp.Secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				getChallenge(store, challenge)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totp, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					ForgiveLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+				requireNoSession(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredChallenge",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken, "code": currentTOTPCode(t, totp.Secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := challenge
+				expired.ExpiresAt = time.Now().Add(-time.Second)
+				getChallenge(store, expired)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Any()).
+					Times(0)
+				requireNoSession(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.JSONEq(t, `{"error": "invalid or expired MFA token"}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "UsedChallenge",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken, "code": currentTOTPCode(t, totp.Secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				used := challenge
+				used.UsedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				getChallenge(store, used)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Any()).
+					Times(0)
+				requireNoSession(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownChallenge",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": "unknown", "code": "123456"}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetMFAChallenge(gomock.Any(), gomock.Eq(token.HashMFAToken("unknown"))).
+					Times(1).
+					Return(db.MfaChallenge{}, db.ErrRecordNotFound)
+				requireNoSession(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "Locked",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken, "code": currentTOTPCode(t, totp.Secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				getChallenge(store, challenge)
+				store.EXPECT().
+					CountLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Now().Add(time.Minute), db.ErrLoginLocked)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Any()).
+					Times(0)
+				requireNoSession(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get(retryAfterHeaderKey))
+			},
+		},
+		{
+			name: "FrozenUser",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken, "code": currentTOTPCode(t, totp.Secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				forgiveAttempt(store)
+				getChallenge(store, challenge)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totp, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(totp, nil)
+				store.EXPECT().
+					UseMFAChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(challenge, nil)
+				store.EXPECT().
+					DeleteLoginFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				frozen := user
+				frozen.IsFrozen = true
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(frozen, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoCode",
+			body: func(t *testing.T) gin.H {
+				return gin.H{"mfa_token": mfaToken}
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetMFAChallenge(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newLockoutTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body(t))
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login/mfa", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// newLockoutTestServer creates a test server tracking the login failures per
+// username and per IP.
+func newLockoutTestServer(t *testing.T, store db.Store) *Server {
+	config := utils.Config{
+		TokenSymmetricKey:    utils.RandomString(32),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: 24 * time.Hour,
+		LoginMaxUserFailures: 3,
+		LoginMaxIPFailures:   10,
+		LoginLockoutBase:     time.Minute,
+		LoginLockoutMax:      time.Hour,
+		LoginFailureWindow:   24 * time.Hour,
+	}
+	server, err := NewServer(config, store)
+	require.NoError(t, err)
+	return server
+}
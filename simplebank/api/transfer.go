@@ -1,7 +1,7 @@
 package api
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -15,6 +15,7 @@ type createTransferRequest struct {
 	ToAccountID   int64  `json:"to_account_id" binding:"required"`
 	Amount        int64  `json:"amount" binding:"required,gt=0"`
 	Currency      string `json:"currency" binding:"required,currency"`
+	Memo          string `json:"memo" binding:"max=140"`
 }
 
 func (server *Server) createTransfer(ctx *gin.Context) {
@@ -23,6 +24,9 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	if !server.requireVerifiedEmail(ctx) {
+		return
+	}
 	fromAccount, ok := server.validAccount(ctx, req.FromAccountID, req.Currency)
 	if !ok {
 		return
@@ -42,6 +46,7 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 		FromAccountID: req.FromAccountID,
 		ToAccountID:   req.ToAccountID,
 		Amount:        req.Amount,
+		Memo:          req.Memo,
 	}
 
 	result, err := server.store.TransferTx(ctx, arg)
@@ -56,7 +61,7 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
 	account, err := server.store.GetAccount(ctx, accountID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, db.ErrRecordNotFound) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return account, false
 		}
@@ -49,7 +49,7 @@ func TestCreateTransferAPI(t *testing.T) {
 				"currency":        utils.EUR,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
@@ -65,6 +65,27 @@ func TestCreateTransferAPI(t *testing.T) {
 				require.Equal(t, http.StatusCreated, recorder.Code)
 			},
 		},
+		{
+			name: "EmailNotVerified",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        utils.EUR,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user1.Username)).Times(1).Return(user1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.JSONEq(t, `{"error": "email is not verified"}`, recorder.Body.String())
+			},
+		},
 		{
 			name: "Unauthorized",
 			body: gin.H{
@@ -91,7 +112,7 @@ func TestCreateTransferAPI(t *testing.T) {
 				"currency":        utils.EUR,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user2.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user2.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
@@ -111,10 +132,10 @@ func TestCreateTransferAPI(t *testing.T) {
 				"currency":        utils.EUR,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(0)
 				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
@@ -131,7 +152,7 @@ func TestCreateTransferAPI(t *testing.T) {
 				"currency":        utils.EUR,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).Times(1).Return(account3, nil)
@@ -151,7 +172,7 @@ func TestCreateTransferAPI(t *testing.T) {
 				"currency":        utils.EUR,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
@@ -171,7 +192,7 @@ func TestCreateTransferAPI(t *testing.T) {
 				"currency":        "XYZ",
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
@@ -190,7 +211,7 @@ func TestCreateTransferAPI(t *testing.T) {
 				"currency":        utils.EUR,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
@@ -209,7 +230,7 @@ func TestCreateTransferAPI(t *testing.T) {
 				"currency":        utils.EUR,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+				addAuthorizationHeader(t, request, tokenMaker, authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
@@ -231,6 +252,9 @@ func TestCreateTransferAPI(t *testing.T) {
 
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+			allowVerifiedEmails(store)
 
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
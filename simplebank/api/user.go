@@ -1,19 +1,22 @@
 package api
 
 import (
-	"database/sql"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
 	db "github.com/ebaudet/simplebank/db/sqlc"
 	"github.com/ebaudet/simplebank/utils"
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
+	"github.com/google/uuid"
 )
 
+var errFrozenUser = errors.New("user account is frozen")
+
 type createUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6,max=25"`
+	Password string `json:"password" binding:"required"`
 	FullName string `json:"full_name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 }
@@ -22,6 +25,8 @@ type userResponse struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
 }
@@ -31,42 +36,63 @@ func newUserResponse(user db.User) userResponse {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
 	}
 }
 
+// createUser sends a link to the email of the new user to verify it. The
+// user can log in right away, but can't make transfers until then.
 func (server *Server) createUser(ctx *gin.Context) {
 	var req createUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	if !server.checkPasswordPolicy(ctx, req.Password, req.Username, req.Email) {
+		return
+	}
+
+	hash_password, err := server.hasher.HashPassword(req.Password)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
 
-	hash_password, err := utils.HashPassword(req.Password)
+	code, codeHash, expiresAt, err := server.newVerifyEmailCode()
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	arg := db.CreateUserParams{
-		Username:       req.Username,
-		HashedPassword: hash_password,
-		FullName:       req.FullName,
-		Email:          req.Email,
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.Username,
+			HashedPassword: hash_password,
+			FullName:       req.FullName,
+			Email:          req.Email,
+		},
+		VerifyEmailSecretCodeHash: codeHash,
+		VerifyEmailExpiresAt:      expiresAt,
 	}
-	user, err := server.store.CreateUser(ctx, arg)
+	result, err := server.store.CreateUserTx(ctx, arg)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
-				return
-			}
+		switch db.ErrorCode(err) {
+		case db.UniqueViolation:
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
+	user := result.User
+
+	// the user is created anyway, and can ask for a new link
+	if err := server.sendVerifyEmail(ctx, user, result.VerifyEmail, code); err != nil {
+		log.Printf("cannot send verification email to user %s: %v", user.Username, err)
+	}
 
 	rsp := newUserResponse(user)
 
@@ -75,14 +101,23 @@ func (server *Server) createUser(ctx *gin.Context) {
 
 type loginUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6,max=25"`
+	Password string `json:"password" binding:"required"`
 }
 
 type loginUserResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        userResponse `json:"user"`
+	SessionID             uuid.UUID    `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
 }
 
+// loginUser answers the same invalid credentials error whether the user
+// exists or not. The failures are counted per username and per IP, and lock
+// the login for a while once there are too many of them. A user having enabled
+// two-factor authentication is answered an MFA challenge instead of the
+// tokens, to complete with a code on /users/login/mfa.
 func (server *Server) loginUser(ctx *gin.Context) {
 	var req loginUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -90,31 +125,119 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
+	// the password just changed must be seen, even if the replica lags behind
+	ctx.Request = ctx.Request.WithContext(db.WithReadYourWrites(ctx.Request.Context()))
+
+	keys := server.loginKeys(ctx, req.Username)
+	lockedUntil, err := server.countLoginAttempt(ctx, keys)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if time.Now().Before(lockedUntil) {
+		ctx.Header(retryAfterHeaderKey, retryAfterSeconds(lockedUntil))
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(errLoginLocked))
+		return
+	}
+
 	user, err := server.store.GetUser(ctx, req.Username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err != nil {
+		server.checkDummyPassword(req.Password)
+	} else {
+		err = utils.CheckPassword(req.Password, user.HashedPassword)
+	}
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+		return
+	}
+	if err := server.forgiveLoginAttempt(ctx, keys); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.IsFrozen {
+		ctx.JSON(http.StatusForbidden, errorResponse(errFrozenUser))
+		return
+	}
+
+	server.rehashPassword(ctx, user, req.Password)
+
+	totp, err := server.store.GetUserTOTP(ctx, user.Username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == nil && totp.ConfirmedAt.Valid {
+		// the failures are only cleared once the second step succeeds
+		server.startMFAChallenge(ctx, user)
+		return
+	}
+
+	if _, ok := keys[loginUserKey(user.Username)]; ok {
+		err = server.store.DeleteLoginFailure(ctx, loginUserKey(user.Username))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 			return
 		}
+	}
+
+	server.openLoginSession(ctx, user)
+}
+
+// openLoginSession issues the access token of the authenticated user, along
+// with the refresh token of a new session.
+func (server *Server) openLoginSession(ctx *gin.Context, user db.User) {
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.AccessTokenDuration)
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	err = utils.CheckPassword(req.Password, user.HashedPassword)
+	// the session opened on login starts a new family of rotated sessions
+	sessionID := uuid.New()
+	refreshToken, arg, err := server.newSessionParams(ctx, user.Username, sessionID, sessionID)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
-
-	accessToken, _, err := server.tokenMaker.CreateToken(req.Username, server.config.AccessTokenDuration)
+	session, err := server.store.CreateSession(ctx, arg)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
 	rsp := loginUserResponse{
-		AccessToken: accessToken,
-		User:        newUserResponse(user),
+		SessionID:             session.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: session.ExpiresAt,
+		User:                  newUserResponse(user),
 	}
 	ctx.JSON(http.StatusOK, rsp)
 }
+
+// rehashPassword replaces the hash of the password just checked when it was
+// made with another algorithm or other parameters than the ones of the server,
+// which is the only time the password is known. The login goes on if it fails.
+func (server *Server) rehashPassword(ctx *gin.Context, user db.User, password string) {
+	if !server.hasher.NeedsRehash(user.HashedPassword) {
+		return
+	}
+
+	hashedPassword, err := server.hasher.HashPassword(password)
+	if err == nil {
+		err = server.store.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+			Username:          user.Username,
+			HashedPassword:    user.HashedPassword,
+			NewHashedPassword: hashedPassword,
+		})
+	}
+	if err != nil {
+		log.Printf("cannot rehash password of user %s: %v", user.Username, err)
+	}
+}
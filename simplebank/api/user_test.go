@@ -4,19 +4,23 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	mockdb "github.com/ebaudet/simplebank/db/mock"
 	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/passwordpolicy"
 	"github.com/ebaudet/simplebank/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
-	"github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,6 +33,7 @@ func randomUser() (db.User, string) {
 		HashedPassword: hash,
 		FullName:       utils.RandomFullName(),
 		Email:          utils.RandomEmail(),
+		Role:           utils.DepositorRole,
 	}
 	return user, password
 }
@@ -38,6 +43,8 @@ func requireBodyMatchUser(t *testing.T, body *bytes.Buffer, user db.User) {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
 	}
@@ -51,13 +58,13 @@ func requireBodyMatchUser(t *testing.T, body *bytes.Buffer, user db.User) {
 	require.Equal(t, userRsp, gotUserResponse)
 }
 
-type eqCreateUserParamsMatcher struct {
+type eqCreateUserTxParamsMatcher struct {
 	arg      db.CreateUserParams
 	password string
 }
 
-func (e eqCreateUserParamsMatcher) Matches(x interface{}) bool {
-	arg, ok := x.(db.CreateUserParams)
+func (e eqCreateUserTxParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateUserTxParams)
 	if !ok {
 		return false
 	}
@@ -65,27 +72,38 @@ func (e eqCreateUserParamsMatcher) Matches(x interface{}) bool {
 	if err != nil {
 		return false
 	}
+	if len(arg.VerifyEmailSecretCodeHash) != 64 || !arg.VerifyEmailExpiresAt.After(time.Now()) {
+		return false
+	}
 
 	e.arg.HashedPassword = arg.HashedPassword
-	return reflect.DeepEqual(e.arg, arg)
+	return reflect.DeepEqual(e.arg, arg.CreateUserParams)
 }
 
-func (e eqCreateUserParamsMatcher) String() string {
+func (e eqCreateUserTxParamsMatcher) String() string {
 	return fmt.Sprintf("matches arg %v and password %v", e.arg, e.password)
 }
 
-func EqCreateUserParams(arg db.CreateUserParams, password string) gomock.Matcher {
-	return eqCreateUserParamsMatcher{arg, password}
+func EqCreateUserTxParams(arg db.CreateUserParams, password string) gomock.Matcher {
+	return eqCreateUserTxParamsMatcher{arg, password}
 }
 
 func TestCreateUserAPI(t *testing.T) {
 	user, password := randomUser()
+	verifyEmail := db.VerifyEmail{
+		ID:        utils.RandomInt(1, 1000),
+		Username:  user.Username,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	var codeHash string
 
 	testCases := []struct {
 		name          string
 		body          gin.H
 		buildStubs    func(store *mockdb.MockStore)
-		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+		sendErr       error
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer)
 	}{
 		{
 			name: "Created",
@@ -103,11 +121,39 @@ func TestCreateUserAPI(t *testing.T) {
 					Email:          user.Email,
 				}
 				store.EXPECT().
-					CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
+					CreateUserTx(gomock.Any(), EqCreateUserTxParams(arg, password)).
 					Times(1).
-					Return(user, nil)
+					DoAndReturn(func(_ interface{}, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+						codeHash = arg.VerifyEmailSecretCodeHash
+						return db.CreateUserTxResult{User: user, VerifyEmail: verifyEmail}, nil
+					})
 			},
-			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				requireBodyMatchUser(t, recorder.Body, user)
+
+				require.Len(t, mailer.emails, 1)
+				require.Equal(t, user.Email, mailer.emails[0].To)
+				requireVerifyEmailLink(t, mailer.emails[0].Body, verifyEmail.ID, codeHash)
+			},
+		},
+		{
+			name: "SendEmailError",
+			body: gin.H{
+				"username":  user.Username,
+				"password":  password,
+				"full_name": user.FullName,
+				"email":     user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateUserTxResult{User: user, VerifyEmail: verifyEmail}, nil)
+			},
+			sendErr: errors.New("smtp server is down"),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				// the user can ask for a new link
 				require.Equal(t, http.StatusCreated, recorder.Code)
 				requireBodyMatchUser(t, recorder.Body, user)
 			},
@@ -122,11 +168,11 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(1).
-					Return(db.User{}, sql.ErrConnDone)
+					Return(db.CreateUserTxResult{}, sql.ErrConnDone)
 			},
-			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
@@ -140,11 +186,11 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(1).
-					Return(db.User{}, &pq.Error{Code: "23505"})
+					Return(db.CreateUserTxResult{}, &pgconn.PgError{Code: db.UniqueViolation})
 			},
-			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
 				require.Equal(t, http.StatusForbidden, recorder.Code)
 			},
 		},
@@ -158,10 +204,10 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
-			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
 			},
 		},
@@ -175,10 +221,10 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
-			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
 			},
 		},
@@ -192,11 +238,12 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
-			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requirePasswordViolations(t, recorder, passwordpolicy.RuleMinLength)
 			},
 		},
 	}
@@ -213,6 +260,8 @@ func TestCreateUserAPI(t *testing.T) {
 			tc.buildStubs(store)
 			// start test server and send request
 			server := newTestServer(t, store)
+			mailer := server.mailer.(*fakeMailer)
+			mailer.err = tc.sendErr
 			recorder := httptest.NewRecorder()
 
 			// Marshal body data to JSON
@@ -225,6 +274,485 @@ func TestCreateUserAPI(t *testing.T) {
 
 			server.router.ServeHTTP(recorder, request)
 			// check response
+			tc.checkResponse(t, recorder, mailer)
+		})
+	}
+}
+
+func TestLoginUserAPI(t *testing.T) {
+	user, password := randomUser()
+	userKey := loginUserKey(user.Username)
+
+	noFailures := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			CountLoginAttemptTx(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			Return(time.Time{}, nil)
+	}
+	// the attempt counted first is taken back once the credentials are right
+	forgiveAttempt := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			ForgiveLoginFailure(gomock.Any(), gomock.Any()).
+			Times(2).
+			Return(nil)
+	}
+	noTOTP := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+			Times(1).
+			Return(db.UserTotp{}, db.ErrRecordNotFound)
+	}
+	requireInvalidCredentials := func(t *testing.T, recorder *httptest.ResponseRecorder) {
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+		require.JSONEq(t, `{"error": "invalid username or password"}`, recorder.Body.String())
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				forgiveAttempt(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				noTOTP(store)
+				store.EXPECT().
+					DeleteLoginFailure(gomock.Any(), gomock.Eq(userKey)).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateSessionParams) (db.Session, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, arg.ID, arg.FamilyID)
+						require.Len(t, arg.RefreshTokenHash, 64)
+						require.WithinDuration(t, time.Now().Add(24*time.Hour), arg.ExpiresAt, time.Second)
+						return db.Session{ID: arg.ID, ExpiresAt: arg.ExpiresAt}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.SessionID)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotEmpty(t, rsp.RefreshToken)
+				require.WithinDuration(t, time.Now().Add(time.Minute), rsp.AccessTokenExpiresAt, time.Second)
+				require.WithinDuration(t, time.Now().Add(24*time.Hour), rsp.RefreshTokenExpiresAt, time.Second)
+			},
+		},
+		{
+			name: "MFARequired",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				forgiveAttempt(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserTotp{
+						Username:    user.Username,
+						ConfirmedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+					}, nil)
+				store.EXPECT().
+					DeleteLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateMFAChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateMFAChallengeParams) (db.MfaChallenge, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Len(t, arg.TokenHash, 64)
+						require.WithinDuration(t, time.Now().Add(5*time.Minute), arg.ExpiresAt, time.Second)
+						return db.MfaChallenge{TokenHash: arg.TokenHash, Username: arg.Username, ExpiresAt: arg.ExpiresAt}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp mfaChallengeResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.True(t, rsp.MFARequired)
+				require.NotEmpty(t, rsp.MFAToken)
+				require.WithinDuration(t, time.Now().Add(5*time.Minute), rsp.MFATokenExpiresAt, time.Second)
+				require.NotContains(t, recorder.Body.String(), "access_token")
+			},
+		},
+		{
+			name: "CreateSessionError",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				forgiveAttempt(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				noTOTP(store)
+				store.EXPECT().
+					DeleteLoginFailure(gomock.Any(), gomock.Eq(userKey)).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "FrozenUser",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				frozenUser := user
+				frozenUser.IsFrozen = true
+
+				noFailures(store)
+				forgiveAttempt(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(frozenUser, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "WrongPassword",
+			body: gin.H{
+				"username": user.Username,
+				"password": "wrong-password",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ForgiveLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					DeleteLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: requireInvalidCredentials,
+		},
+		{
+			// the length is checked by the password policy when the password is
+			// chosen, a login only tells whether it is the right one
+			name: "LongPassword",
+			body: gin.H{
+				"username": user.Username,
+				"password": utils.RandomString(100),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ForgiveLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: requireInvalidCredentials,
+		},
+		{
+			name: "UserNotFound",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					ForgiveLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: requireInvalidCredentials,
+		},
+		{
+			name: "CountedFirst",
+			body: gin.H{
+				"username": user.Username,
+				"password": "wrong-password",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						CountLoginAttemptTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						DoAndReturn(func(_ interface{}, arg db.CountLoginAttemptTxParams) (time.Time, error) {
+							require.Equal(t, map[string]int32{userKey: 3, loginIPKey(""): 10}, arg.MaxFailures)
+							require.WithinDuration(t, time.Now().Add(-24*time.Hour), arg.ResetBefore, time.Second)
+							require.Equal(t, time.Minute, arg.LockoutBase)
+							require.Equal(t, time.Hour, arg.LockoutMax)
+							return time.Time{}, nil
+						}),
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(user.Username)).
+						Times(1).
+						Return(user, nil),
+				)
+				store.EXPECT().
+					ForgiveLoginFailure(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: requireInvalidCredentials,
+		},
+		{
+			name: "Locked",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CountLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Now().Add(30*time.Second), db.ErrLoginLocked)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "30", recorder.Header().Get(retryAfterHeaderKey))
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CountLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Time{}, sql.ErrConnDone)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "ForgiveError",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				noFailures(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ForgiveLoginFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidUsername",
+			body: gin.H{
+				"username": "user#name",
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := utils.Config{
+				TokenSymmetricKey:    utils.RandomString(32),
+				AccessTokenDuration:  time.Minute,
+				RefreshTokenDuration: 24 * time.Hour,
+				LoginMaxUserFailures: 3,
+				LoginMaxIPFailures:   10,
+				LoginLockoutBase:     time.Minute,
+				LoginLockoutMax:      time.Hour,
+				LoginFailureWindow:   24 * time.Hour,
+				MFAChallengeDuration: 5 * time.Minute,
+			}
+			server, err := NewServer(config, store)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginUserRehashPasswordAPI(t *testing.T) {
+	bcryptUser, password := randomUser()
+
+	// cheap parameters, to keep the test fast
+	hasher := utils.NewArgon2idHasher(1024, 1, 1)
+	argon2idUser := bcryptUser
+	hash, err := hasher.HashPassword(password)
+	require.NoError(t, err)
+	argon2idUser.HashedPassword = hash
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		buildStubs    func(store *mockdb.MockStore, user db.User)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Rehash",
+			user: bcryptUser,
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					RehashUserPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.RehashUserPasswordParams) error {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, user.HashedPassword, arg.HashedPassword)
+						require.Contains(t, arg.NewHashedPassword, "$argon2id$v=19$m=1024,t=1,p=1$")
+						require.NoError(t, utils.CheckPassword(password, arg.NewHashedPassword))
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UpToDate",
+			user: argon2idUser,
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					RehashUserPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "RehashError",
+			user: bcryptUser,
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().
+					RehashUserPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store, tc.user)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(tc.user.Username)).
+				Times(1).
+				Return(tc.user, nil)
+			store.EXPECT().
+				GetUserTOTP(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.UserTotp{}, db.ErrRecordNotFound)
+			store.EXPECT().
+				CreateSession(gomock.Any(), gomock.Any()).
+				Times(1)
+
+			server := newTestServer(t, store)
+			server.hasher = hasher
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"username": tc.user.Username, "password": password})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
 		})
 	}
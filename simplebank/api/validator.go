@@ -1,6 +1,9 @@
 package api
 
 import (
+	"net/url"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
 	"github.com/ebaudet/simplebank/utils"
 	"github.com/go-playground/validator/v10"
 )
@@ -11,3 +14,45 @@ var validCurrency validator.Func = func(fl validator.FieldLevel) bool {
 	}
 	return false
 }
+
+var validEventType validator.Func = func(fl validator.FieldLevel) bool {
+	if eventType, ok := fl.Field().Interface().(string); ok {
+		for _, t := range db.EventTypes() {
+			if eventType == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var validRole validator.Func = func(fl validator.FieldLevel) bool {
+	if role, ok := fl.Field().Interface().(string); ok {
+		return utils.IsSupportedRole(role)
+	}
+	return false
+}
+
+var validScope validator.Func = func(fl validator.FieldLevel) bool {
+	if scope, ok := fl.Field().Interface().(string); ok {
+		return isSupportedScope(scope)
+	}
+	return false
+}
+
+var validClientScope validator.Func = func(fl validator.FieldLevel) bool {
+	if scope, ok := fl.Field().Interface().(string); ok {
+		return isSupportedClientScope(scope)
+	}
+	return false
+}
+
+// validHTTPSURL only accepts absolute https URLs, as the webhooks carry the
+// events of the accounts.
+var validHTTPSURL validator.Func = func(fl validator.FieldLevel) bool {
+	if s, ok := fl.Field().Interface().(string); ok {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme == "https" && u.Hostname() != ""
+	}
+	return false
+}
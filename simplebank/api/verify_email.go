@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/mail"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/gin-gonic/gin"
+)
+
+// sendEmailTimeout bounds the sending of an email within a request.
+const sendEmailTimeout = 10 * time.Second
+
+var (
+	errEmailNotVerified     = errors.New("email is not verified")
+	errEmailAlreadyVerified = errors.New("email is already verified")
+	errInvalidVerifyEmail   = errors.New("invalid or expired verification link")
+)
+
+// newVerifyEmailCode returns a new secret code for a verification link,
+// along with its hash and expiration time to store.
+func (server *Server) newVerifyEmailCode() (string, string, time.Time, error) {
+	code, err := token.NewVerifyEmailCode()
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return code, token.HashVerifyEmailCode(code), time.Now().Add(server.config.EmailVerifyDuration), nil
+}
+
+// verifyEmailLink returns the link of the verification, carrying its secret code.
+func (server *Server) verifyEmailLink(verifyEmail db.VerifyEmail, code string) (string, error) {
+	link, err := url.Parse(server.config.EmailVerifyURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid email verification url: %w", err)
+	}
+
+	query := link.Query()
+	query.Set("id", strconv.FormatInt(verifyEmail.ID, 10))
+	query.Set("secret_code", code)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
+
+// sendVerifyEmail sends the verification link to the email of the user.
+func (server *Server) sendVerifyEmail(ctx context.Context, user db.User, verifyEmail db.VerifyEmail, code string) error {
+	link, err := server.verifyEmailLink(verifyEmail, code)
+	if err != nil {
+		return err
+	}
+
+	email := mail.Email{
+		To:      verifyEmail.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Thank you for registering with us! Please follow this link to verify your email address:\n%s\n\n"+
+			"The link expires on %s.\n",
+			user.FullName, link, verifyEmail.ExpiresAt.UTC().Format(time.RFC1123)),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendEmailTimeout)
+	defer cancel()
+	return server.mailer.SendEmail(ctx, email)
+}
+
+type verifyEmailRequest struct {
+	ID         int64  `form:"id" binding:"required,min=1"`
+	SecretCode string `form:"secret_code" binding:"required"`
+}
+
+// verifyEmail follows the link sent to the user, which can only be done once.
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		ID:             req.ID,
+		SecretCodeHash: token.HashVerifyEmailCode(req.SecretCode),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidVerifyEmail))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// resendVerifyEmail sends a new verification link to the user, e.g. when the
+// previous one expired or got lost. The previous links are still valid.
+func (server *Server) resendVerifyEmail(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if user.IsEmailVerified {
+		ctx.JSON(http.StatusForbidden, errorResponse(errEmailAlreadyVerified))
+		return
+	}
+
+	code, codeHash, expiresAt, err := server.newVerifyEmailCode()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	verifyEmail, err := server.store.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+		Username:       user.Username,
+		Email:          user.Email,
+		SecretCodeHash: codeHash,
+		ExpiresAt:      expiresAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := server.sendVerifyEmail(ctx, user, verifyEmail, code); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
+
+// requireVerifiedEmail answers an error unless the authenticated user has
+// verified its email.
+func (server *Server) requireVerifiedEmail(ctx *gin.Context) bool {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if !user.IsEmailVerified {
+		ctx.JSON(http.StatusForbidden, errorResponse(errEmailNotVerified))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+var verifyEmailLinkRegexp = regexp.MustCompile(`http\S+`)
+
+// requireVerifyEmailLink checks the email body carries the link of the
+// verification, with the secret code of the hash.
+func requireVerifyEmailLink(t *testing.T, body string, id int64, codeHash string) {
+	link, err := url.Parse(verifyEmailLinkRegexp.FindString(body))
+	require.NoError(t, err)
+	require.Equal(t, "/users/verify_email", link.Path)
+	require.Equal(t, strconv.FormatInt(id, 10), link.Query().Get("id"))
+	require.Equal(t, codeHash, token.HashVerifyEmailCode(link.Query().Get("secret_code")))
+}
+
+func TestVerifyEmailAPI(t *testing.T) {
+	user, _ := randomUser()
+	user.IsEmailVerified = true
+	id := utils.RandomInt(1, 1000)
+	code, err := token.NewVerifyEmailCode()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: fmt.Sprintf("id=%d&secret_code=%s", id, code),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.VerifyEmailTxParams{
+					ID:             id,
+					SecretCodeHash: token.HashVerifyEmailCode(code),
+				}
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp userResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.Username)
+				require.True(t, rsp.IsEmailVerified)
+			},
+		},
+		{
+			name:  "InvalidLink",
+			query: fmt.Sprintf("id=%d&secret_code=%s", id, code),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.JSONEq(t, `{"error": "invalid or expired verification link"}`, recorder.Body.String())
+			},
+		},
+		{
+			name:  "InternalError",
+			query: fmt.Sprintf("id=%d&secret_code=%s", id, code),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:  "MissingSecretCode",
+			query: fmt.Sprintf("id=%d", id),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/users/verify_email?"+tc.query, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestResendVerifyEmailAPI(t *testing.T) {
+	user, _ := randomUser()
+	verifyEmail := db.VerifyEmail{
+		ID:        utils.RandomInt(1, 1000),
+		Username:  user.Username,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	var codeHash string
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		sendErr       error
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateVerifyEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateVerifyEmailParams) (db.VerifyEmail, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, user.Email, arg.Email)
+						require.Len(t, arg.SecretCodeHash, 64)
+						require.WithinDuration(t, time.Now().Add(time.Hour), arg.ExpiresAt, time.Second)
+						codeHash = arg.SecretCodeHash
+						return verifyEmail, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				require.Len(t, mailer.emails, 1)
+				require.Equal(t, user.Email, mailer.emails[0].To)
+				requireVerifyEmailLink(t, mailer.emails[0].Body, verifyEmail.ID, codeHash)
+			},
+		},
+		{
+			name: "AlreadyVerified",
+			buildStubs: func(store *mockdb.MockStore) {
+				verified := user
+				verified.IsEmailVerified = true
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(verified, nil)
+				store.EXPECT().
+					CreateVerifyEmail(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Empty(t, mailer.emails)
+			},
+		},
+		{
+			name: "SendEmailError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateVerifyEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(verifyEmail, nil)
+			},
+			sendErr: errors.New("smtp server is down"),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateVerifyEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmail{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *fakeMailer) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Empty(t, mailer.emails)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			allowAuditLog(store)
+			allowValidTokens(store)
+
+			server := newTestServer(t, store)
+			mailer := server.mailer.(*fakeMailer)
+			mailer.err = tc.sendErr
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/users/verify_email", nil)
+			require.NoError(t, err)
+
+			addAuthorizationHeader(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, mailer)
+		})
+	}
+}
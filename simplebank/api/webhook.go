@@ -0,0 +1,203 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/token"
+	"github.com/ebaudet/simplebank/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+type webhookResponse struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Secret is only returned once, when the subscription is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+func newWebhookResponse(subscription db.WebhookSubscription) webhookResponse {
+	return webhookResponse{
+		ID:         subscription.ID,
+		URL:        subscription.Url,
+		EventTypes: subscription.EventTypes,
+		Active:     subscription.Active,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+type createWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,https_url"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,event_type"`
+}
+
+func (server *Server) createWebhook(ctx *gin.Context) {
+	var req createWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.CreateWebhookSubscriptionParams{
+		Owner:      authPayload.Username,
+		Url:        req.URL,
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+	}
+	subscription, err := server.store.CreateWebhookSubscription(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := newWebhookResponse(subscription)
+	rsp.Secret = subscription.Secret
+	ctx.JSON(http.StatusCreated, rsp)
+}
+
+func (server *Server) listWebhooks(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	subscriptions, err := server.store.ListWebhookSubscriptions(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]webhookResponse, len(subscriptions))
+	for i, subscription := range subscriptions {
+		rsp[i] = newWebhookResponse(subscription)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type webhookUriRequest struct {
+	ID int64 `uri:"id" binding:"min=1,required"`
+}
+
+func (server *Server) deleteWebhook(ctx *gin.Context) {
+	var uri webhookUriRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.DeleteWebhookSubscriptionParams{
+		ID:    uri.ID,
+		Owner: authPayload.Username,
+	}
+	err := server.store.DeleteWebhookSubscription(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
+
+// ownedWebhook fetches the subscription and checks that it belongs to the
+// authenticated user.
+func (server *Server) ownedWebhook(ctx *gin.Context, subscriptionID int64) (db.WebhookSubscription, bool) {
+	subscription, err := server.store.GetWebhookSubscription(ctx, subscriptionID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return subscription, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return subscription, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if subscription.Owner != authPayload.Username {
+		err := errors.New("webhook doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return subscription, false
+	}
+	return subscription, true
+}
+
+type listWebhookDeliveriesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// listWebhookDeliveries returns the delivery log of a subscription, most recent first.
+func (server *Server) listWebhookDeliveries(ctx *gin.Context) {
+	var uri webhookUriRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req listWebhookDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.ownedWebhook(ctx, uri.ID); !ok {
+		return
+	}
+
+	arg := db.ListWebhookDeliveriesParams{
+		SubscriptionID: uri.ID,
+		Limit:          req.PageSize,
+		Offset:         (req.PageID - 1) * req.PageSize,
+	}
+	deliveries, err := server.store.ListWebhookDeliveries(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+type retryWebhookDeliveryRequest struct {
+	ID         int64 `uri:"id" binding:"min=1,required"`
+	DeliveryID int64 `uri:"delivery_id" binding:"min=1,required"`
+}
+
+// retryWebhookDelivery puts a dead-lettered delivery back in the queue.
+func (server *Server) retryWebhookDelivery(ctx *gin.Context) {
+	var uri retryWebhookDeliveryRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.ownedWebhook(ctx, uri.ID); !ok {
+		return
+	}
+
+	delivery, err := server.store.RetryWebhookDelivery(ctx, db.RetryWebhookDeliveryParams{
+		ID:             uri.DeliveryID,
+		SubscriptionID: uri.ID,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			err := errors.New("no dead delivery with this id for the webhook")
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, delivery)
+}
@@ -0,0 +1,16 @@
+CREATE TABLE "account_daily_balances" (
+  "account_id" bigint NOT NULL,
+  "day" date NOT NULL,
+  "opening_balance" bigint NOT NULL,
+  "closing_balance" bigint NOT NULL,
+  "min_balance" bigint NOT NULL,
+  "max_balance" bigint NOT NULL,
+  "refreshed_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "day")
+);
+
+COMMENT ON TABLE "account_daily_balances" IS 'daily snapshot of entries, refreshed by a background job';
+
+ALTER TABLE "account_daily_balances" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+CREATE INDEX ON "entries" ("account_id", "created_at");
@@ -0,0 +1,8 @@
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "category_overridden";
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "category_id";
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "counterparty";
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "memo";
+
+DROP TABLE IF EXISTS "entry_tags";
+DROP TABLE IF EXISTS "category_rules";
+DROP TABLE IF EXISTS "categories";
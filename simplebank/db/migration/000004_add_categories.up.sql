@@ -0,0 +1,67 @@
+CREATE TABLE "categories" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar,
+  "name" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "category_rules" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "category_id" bigint NOT NULL,
+  "field" varchar NOT NULL,
+  "pattern" varchar NOT NULL,
+  "priority" int NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "entry_tags" (
+  "entry_id" bigint NOT NULL,
+  "tag" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("entry_id", "tag")
+);
+
+ALTER TABLE "entries" ADD COLUMN "memo" varchar NOT NULL DEFAULT '';
+ALTER TABLE "entries" ADD COLUMN "counterparty" varchar NOT NULL DEFAULT '';
+ALTER TABLE "entries" ADD COLUMN "category_id" bigint;
+ALTER TABLE "entries" ADD COLUMN "category_overridden" boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN "categories"."owner" IS 'null for the built-in categories';
+
+COMMENT ON COLUMN "category_rules"."field" IS 'memo or counterparty';
+
+COMMENT ON COLUMN "category_rules"."pattern" IS 'case insensitive substring';
+
+COMMENT ON COLUMN "entries"."counterparty" IS 'owner of the other account of the transfer';
+
+COMMENT ON COLUMN "entries"."category_overridden" IS 'set by the user, ignored by the rules';
+
+ALTER TABLE "categories" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "category_rules" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "category_rules" ADD FOREIGN KEY ("category_id") REFERENCES "categories" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "entry_tags" ADD FOREIGN KEY ("entry_id") REFERENCES "entries" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("category_id") REFERENCES "categories" ("id") ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX ON "categories" ("owner", "name");
+
+CREATE UNIQUE INDEX ON "categories" ("name") WHERE "owner" IS NULL;
+
+CREATE INDEX ON "category_rules" ("owner");
+
+CREATE INDEX ON "entry_tags" ("tag");
+
+CREATE INDEX ON "entries" ("category_id");
+
+INSERT INTO "categories" ("name") VALUES
+  ('groceries'),
+  ('housing'),
+  ('transport'),
+  ('leisure'),
+  ('salary'),
+  ('savings'),
+  ('other');
@@ -0,0 +1,35 @@
+CREATE TABLE "goals" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "name" varchar NOT NULL,
+  "target_amount" bigint NOT NULL,
+  "balance" bigint NOT NULL DEFAULT 0,
+  "deadline" timestamptz,
+  "round_up_unit" bigint NOT NULL DEFAULT 0,
+  "contribution_amount" bigint NOT NULL DEFAULT 0,
+  "contribution_interval" varchar NOT NULL DEFAULT '',
+  "next_contribution_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "entries" ADD COLUMN "goal_id" bigint;
+
+COMMENT ON COLUMN "goals"."balance" IS 'ring-fenced from the account balance, must be positive';
+
+COMMENT ON COLUMN "goals"."round_up_unit" IS 'outgoing transfers are rounded up to this unit, 0 to disable';
+
+COMMENT ON COLUMN "goals"."contribution_interval" IS 'day, week or month, empty to disable';
+
+COMMENT ON COLUMN "entries"."goal_id" IS 'set when the money moves between the account and one of its goals';
+
+ALTER TABLE "goals" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "goals" ADD CONSTRAINT "goals_balance_check" CHECK ("balance" >= 0);
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("goal_id") REFERENCES "goals" ("id") ON DELETE SET NULL;
+
+CREATE INDEX ON "goals" ("account_id");
+
+CREATE UNIQUE INDEX "goals_round_up_account_id_key" ON "goals" ("account_id") WHERE "round_up_unit" > 0;
+
+CREATE INDEX ON "goals" ("next_contribution_at") WHERE "contribution_amount" > 0;
@@ -0,0 +1,39 @@
+CREATE TABLE "audit_log" (
+  "id" bigserial PRIMARY KEY,
+  "actor" varchar NOT NULL,
+  "action" varchar NOT NULL,
+  "target_type" varchar NOT NULL,
+  "target_id" bigint,
+  "before" jsonb,
+  "after" jsonb,
+  "status_code" int,
+  "ip" varchar NOT NULL DEFAULT '',
+  "user_agent" varchar NOT NULL DEFAULT '',
+  "request_id" varchar NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "audit_log"."actor" IS 'username from the access token, or system for the workers';
+
+COMMENT ON COLUMN "audit_log"."status_code" IS 'response status, only set on the request records';
+
+CREATE INDEX ON "audit_log" ("actor", "created_at");
+
+CREATE INDEX ON "audit_log" ("target_type", "target_id");
+
+CREATE INDEX ON "audit_log" ("request_id");
+
+-- The audit log is append-only: rows can't be changed nor removed.
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_log_append_only"
+BEFORE UPDATE OR DELETE ON "audit_log"
+FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER "audit_log_no_truncate"
+BEFORE TRUNCATE ON "audit_log"
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,71 @@
+ALTER TABLE "entries" RENAME TO "entries_partitioned";
+ALTER TABLE "entries_partitioned" RENAME CONSTRAINT "entries_pkey" TO "entries_partitioned_pkey";
+ALTER TABLE "transfers" RENAME TO "transfers_partitioned";
+ALTER TABLE "transfers_partitioned" RENAME CONSTRAINT "transfers_pkey" TO "transfers_partitioned_pkey";
+
+CREATE TABLE "entries" (
+  "id" bigint PRIMARY KEY DEFAULT nextval('entries_id_seq'),
+  "account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "memo" varchar NOT NULL DEFAULT '',
+  "counterparty" varchar NOT NULL DEFAULT '',
+  "category_id" bigint,
+  "category_overridden" boolean NOT NULL DEFAULT false,
+  "goal_id" bigint
+);
+
+CREATE TABLE "transfers" (
+  "id" bigint PRIMARY KEY DEFAULT nextval('transfers_id_seq'),
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+INSERT INTO "entries" SELECT * FROM "entries_partitioned";
+INSERT INTO "transfers" SELECT * FROM "transfers_partitioned";
+
+ALTER SEQUENCE "entries_id_seq" OWNED BY "entries"."id";
+ALTER SEQUENCE "transfers_id_seq" OWNED BY "transfers"."id";
+
+DROP TABLE "entries_partitioned";
+DROP TABLE "transfers_partitioned";
+DROP FUNCTION IF EXISTS delete_entry_tags();
+DROP FUNCTION IF EXISTS create_monthly_partition(text, date);
+
+CREATE INDEX ON "entries" ("account_id");
+
+CREATE INDEX ON "entries" ("account_id", "created_at");
+
+CREATE INDEX ON "entries" ("category_id");
+
+CREATE INDEX ON "transfers" ("from_account_id");
+
+CREATE INDEX ON "transfers" ("to_account_id");
+
+CREATE INDEX ON "transfers" ("from_account_id", "to_account_id");
+
+COMMENT ON COLUMN "entries"."amount" IS 'can be negative or positive';
+
+COMMENT ON COLUMN "entries"."counterparty" IS 'owner of the other account of the transfer';
+
+COMMENT ON COLUMN "entries"."category_overridden" IS 'set by the user, ignored by the rules';
+
+COMMENT ON COLUMN "entries"."goal_id" IS 'set when the money moves between the account and one of its goals';
+
+COMMENT ON COLUMN "transfers"."amount" IS 'must be positive';
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("category_id") REFERENCES "categories" ("id") ON DELETE SET NULL;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("goal_id") REFERENCES "goals" ("id") ON DELETE SET NULL;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+DELETE FROM "entry_tags" WHERE "entry_id" NOT IN (SELECT "id" FROM "entries");
+
+ALTER TABLE "entry_tags" ADD FOREIGN KEY ("entry_id") REFERENCES "entries" ("id") ON DELETE CASCADE;
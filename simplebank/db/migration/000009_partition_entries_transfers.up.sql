@@ -0,0 +1,124 @@
+-- entries and transfers become range-partitioned by month on created_at.
+-- The partitioned primary key must contain the partition key, so the entry
+-- tags can't reference the entries anymore: a trigger deletes them instead.
+ALTER TABLE "entry_tags" DROP CONSTRAINT "entry_tags_entry_id_fkey";
+
+ALTER TABLE "entries" RENAME TO "entries_unpartitioned";
+ALTER TABLE "entries_unpartitioned" RENAME CONSTRAINT "entries_pkey" TO "entries_unpartitioned_pkey";
+ALTER TABLE "transfers" RENAME TO "transfers_unpartitioned";
+ALTER TABLE "transfers_unpartitioned" RENAME CONSTRAINT "transfers_pkey" TO "transfers_unpartitioned_pkey";
+
+CREATE TABLE "entries" (
+  "id" bigint NOT NULL DEFAULT nextval('entries_id_seq'),
+  "account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "memo" varchar NOT NULL DEFAULT '',
+  "counterparty" varchar NOT NULL DEFAULT '',
+  "category_id" bigint,
+  "category_overridden" boolean NOT NULL DEFAULT false,
+  "goal_id" bigint,
+  PRIMARY KEY ("id", "created_at")
+) PARTITION BY RANGE ("created_at");
+
+CREATE TABLE "transfers" (
+  "id" bigint NOT NULL DEFAULT nextval('transfers_id_seq'),
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("id", "created_at")
+) PARTITION BY RANGE ("created_at");
+
+-- create_monthly_partition creates the partition of parent holding the rows
+-- of the month starting on month, named <parent>_pYYYYMM. It is also run by
+-- the partition maintenance job to create the partitions ahead of time.
+CREATE FUNCTION create_monthly_partition(parent text, month date) RETURNS text AS $$
+DECLARE
+  partition text := format('%s_p%s', parent, to_char(month, 'YYYYMM'));
+BEGIN
+  EXECUTE format(
+    'CREATE TABLE IF NOT EXISTS %I PARTITION OF %I FOR VALUES FROM (%L) TO (%L)',
+    partition, parent, month::timestamptz, (month + interval '1 month')::timestamptz
+  );
+  RETURN partition;
+END;
+$$ LANGUAGE plpgsql;
+
+-- The partitions are created in a block so that sqlc doesn't generate models for them.
+-- The default partitions catch the rows of the months not created yet.
+DO $$
+DECLARE
+  month date;
+BEGIN
+  CREATE TABLE "entries_default" PARTITION OF "entries" DEFAULT;
+  CREATE TABLE "transfers_default" PARTITION OF "transfers" DEFAULT;
+
+  FOR month IN
+    SELECT generate_series(
+      date_trunc('month', LEAST(
+        (SELECT min("created_at") FROM "entries_unpartitioned"),
+        (SELECT min("created_at") FROM "transfers_unpartitioned"),
+        now()
+      )),
+      date_trunc('month', now()) + interval '3 months',
+      interval '1 month'
+    )::date
+  LOOP
+    PERFORM create_monthly_partition('entries', month);
+    PERFORM create_monthly_partition('transfers', month);
+  END LOOP;
+END;
+$$;
+
+INSERT INTO "entries" SELECT * FROM "entries_unpartitioned";
+INSERT INTO "transfers" SELECT * FROM "transfers_unpartitioned";
+
+ALTER SEQUENCE "entries_id_seq" OWNED BY "entries"."id";
+ALTER SEQUENCE "transfers_id_seq" OWNED BY "transfers"."id";
+
+DROP TABLE "entries_unpartitioned";
+DROP TABLE "transfers_unpartitioned";
+
+CREATE INDEX ON "entries" ("account_id");
+
+CREATE INDEX ON "entries" ("account_id", "created_at");
+
+CREATE INDEX ON "entries" ("category_id");
+
+CREATE INDEX ON "transfers" ("from_account_id");
+
+CREATE INDEX ON "transfers" ("to_account_id");
+
+CREATE INDEX ON "transfers" ("from_account_id", "to_account_id");
+
+COMMENT ON COLUMN "entries"."amount" IS 'can be negative or positive';
+
+COMMENT ON COLUMN "entries"."counterparty" IS 'owner of the other account of the transfer';
+
+COMMENT ON COLUMN "entries"."category_overridden" IS 'set by the user, ignored by the rules';
+
+COMMENT ON COLUMN "entries"."goal_id" IS 'set when the money moves between the account and one of its goals';
+
+COMMENT ON COLUMN "transfers"."amount" IS 'must be positive';
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("category_id") REFERENCES "categories" ("id") ON DELETE SET NULL;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("goal_id") REFERENCES "goals" ("id") ON DELETE SET NULL;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+CREATE FUNCTION delete_entry_tags() RETURNS trigger AS $$
+BEGIN
+  DELETE FROM "entry_tags" WHERE "entry_id" = OLD."id";
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "entries_delete_tags"
+AFTER DELETE ON "entries"
+FOR EACH ROW EXECUTE FUNCTION delete_entry_tags();
@@ -0,0 +1,10 @@
+CREATE TABLE "login_failures" (
+  "key" varchar PRIMARY KEY,
+  "failed_attempts" int NOT NULL DEFAULT 0,
+  "locked_until" timestamptz,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "login_failures"."key" IS 'user:<username> or ip:<address>';
+
+COMMENT ON COLUMN "login_failures"."failed_attempts" IS 'consecutive failures, reset after a successful login';
@@ -0,0 +1,26 @@
+CREATE TABLE "sessions" (
+  "id" uuid PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "refresh_token_hash" varchar NOT NULL,
+  "family_id" uuid NOT NULL,
+  "replaced_by" uuid,
+  "user_agent" varchar NOT NULL DEFAULT '',
+  "client_ip" varchar NOT NULL DEFAULT '',
+  "is_blocked" boolean NOT NULL DEFAULT false,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "sessions"."refresh_token_hash" IS 'sha256 of the refresh token, the token itself is never stored';
+
+COMMENT ON COLUMN "sessions"."family_id" IS 'id of the session created on login, shared by the sessions rotated from it';
+
+COMMENT ON COLUMN "sessions"."replaced_by" IS 'set once the refresh token is rotated, its reuse then blocks the family';
+
+ALTER TABLE "sessions" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE UNIQUE INDEX ON "sessions" ("refresh_token_hash");
+
+CREATE INDEX ON "sessions" ("family_id");
+
+CREATE INDEX ON "sessions" ("username");
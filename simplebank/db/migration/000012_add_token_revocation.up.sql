@@ -0,0 +1,16 @@
+CREATE TABLE "revoked_tokens" (
+  "id" uuid PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "revoked_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "revoked_tokens"."id" IS 'id of the access token payload';
+
+COMMENT ON COLUMN "revoked_tokens"."expires_at" IS 'expiry of the token, the row is useless and purged afterwards';
+
+CREATE INDEX ON "revoked_tokens" ("expires_at");
+
+ALTER TABLE "users" ADD COLUMN "tokens_valid_after" timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00Z';
+
+COMMENT ON COLUMN "users"."tokens_valid_after" IS 'the tokens issued before are revoked, set when logging out everywhere';
@@ -0,0 +1,5 @@
+ALTER TABLE IF EXISTS "audit_log" DROP COLUMN IF EXISTS "reason";
+
+ALTER TABLE IF EXISTS "users" DROP COLUMN IF EXISTS "is_frozen";
+
+ALTER TABLE IF EXISTS "users" DROP COLUMN IF EXISTS "role";
@@ -0,0 +1,13 @@
+ALTER TABLE "users" ADD COLUMN "role" varchar NOT NULL DEFAULT 'depositor';
+
+ALTER TABLE "users" ADD CONSTRAINT "users_role_check" CHECK ("role" IN ('depositor', 'banker', 'admin'));
+
+ALTER TABLE "users" ADD COLUMN "is_frozen" boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN "users"."role" IS 'depositor, banker or admin, carried by the access tokens';
+
+COMMENT ON COLUMN "users"."is_frozen" IS 'a frozen user can neither log in nor renew an access token';
+
+ALTER TABLE "audit_log" ADD COLUMN "reason" varchar NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN "audit_log"."reason" IS 'why the change was made, required on the balance adjustments';
@@ -0,0 +1,23 @@
+CREATE TABLE "api_keys" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "name" varchar NOT NULL,
+  "prefix" varchar NOT NULL,
+  "key_hash" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "last_used_at" timestamptz,
+  "revoked_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "api_keys"."prefix" IS 'start of the key kept in clear to tell the keys apart';
+
+COMMENT ON COLUMN "api_keys"."key_hash" IS 'sha256 of the key, which is only shown once on creation';
+
+COMMENT ON COLUMN "api_keys"."scopes" IS 'routes reachable with the key, e.g. accounts:read or transfers:write';
+
+CREATE UNIQUE INDEX ON "api_keys" ("key_hash");
+
+CREATE UNIQUE INDEX ON "api_keys" ("owner", "name");
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
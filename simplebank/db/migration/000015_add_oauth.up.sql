@@ -0,0 +1,44 @@
+CREATE TABLE "oauth_clients" (
+  "id" uuid PRIMARY KEY,
+  "name" varchar NOT NULL,
+  "secret_hash" varchar NOT NULL,
+  "redirect_uris" varchar[] NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "created_by" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "oauth_clients"."secret_hash" IS 'sha256 of the secret, which is only shown once on registration';
+
+COMMENT ON COLUMN "oauth_clients"."redirect_uris" IS 'the redirect_uri of the authorization requests must be one of them exactly';
+
+COMMENT ON COLUMN "oauth_clients"."scopes" IS 'scopes the client can ask the users to consent to';
+
+COMMENT ON COLUMN "oauth_clients"."created_by" IS 'admin who registered the client';
+
+ALTER TABLE "oauth_clients" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("username");
+
+CREATE TABLE "oauth_authorization_codes" (
+  "code_hash" varchar PRIMARY KEY,
+  "client_id" uuid NOT NULL,
+  "username" varchar NOT NULL,
+  "redirect_uri" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "code_challenge" varchar NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "access_token_id" uuid,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "oauth_authorization_codes"."code_hash" IS 'sha256 of the code given to the client';
+
+COMMENT ON COLUMN "oauth_authorization_codes"."code_challenge" IS 'PKCE S256 challenge the code verifier is checked against';
+
+COMMENT ON COLUMN "oauth_authorization_codes"."access_token_id" IS 'token issued for the code, revoked if the code is used again';
+
+CREATE INDEX ON "oauth_authorization_codes" ("expires_at");
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
@@ -0,0 +1,43 @@
+CREATE TABLE "user_totps" (
+  "username" varchar PRIMARY KEY,
+  "secret" varchar NOT NULL,
+  "confirmed_at" timestamptz,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "user_totps"."secret" IS 'base32 TOTP secret shared with the authenticator app';
+
+COMMENT ON COLUMN "user_totps"."confirmed_at" IS 'null until a first code confirms the enrolment, the login only asks for codes afterwards';
+
+COMMENT ON COLUMN "user_totps"."last_used_step" IS 'time step of the last accepted code, a code can only be used once';
+
+ALTER TABLE "user_totps" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON DELETE CASCADE;
+
+CREATE TABLE "recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "code_hash" varchar NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "recovery_codes"."code_hash" IS 'sha256 of the code, which is only shown once when two-factor authentication is enabled';
+
+CREATE UNIQUE INDEX ON "recovery_codes" ("username", "code_hash");
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON DELETE CASCADE;
+
+CREATE TABLE "mfa_challenges" (
+  "token_hash" varchar PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "mfa_challenges"."token_hash" IS 'sha256 of the token given on login, exchanged with a code for the access token';
+
+CREATE INDEX ON "mfa_challenges" ("expires_at");
+
+ALTER TABLE "mfa_challenges" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON DELETE CASCADE;
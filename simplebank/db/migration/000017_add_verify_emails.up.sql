@@ -0,0 +1,26 @@
+ALTER TABLE "users" ADD COLUMN "is_email_verified" boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN "users"."is_email_verified" IS 'set once the link sent to the email is followed, the transfers are refused until then';
+
+-- the users created before the verification are not held back
+UPDATE "users" SET "is_email_verified" = true;
+
+CREATE TABLE "verify_emails" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "email" varchar NOT NULL,
+  "secret_code_hash" varchar NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "verify_emails"."email" IS 'the email the link was sent to, it no longer verifies the user once the email changed';
+
+COMMENT ON COLUMN "verify_emails"."secret_code_hash" IS 'sha256 of the secret code of the link, which is only sent by email';
+
+CREATE INDEX ON "verify_emails" ("username");
+
+CREATE INDEX ON "verify_emails" ("expires_at");
+
+ALTER TABLE "verify_emails" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON DELETE CASCADE;
@@ -0,0 +1,15 @@
+CREATE TABLE "reset_passwords" (
+  "token_hash" varchar PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "reset_passwords"."token_hash" IS 'sha256 of the token of the reset link, which is only sent by email';
+
+CREATE INDEX ON "reset_passwords" ("username");
+
+CREATE INDEX ON "reset_passwords" ("expires_at");
+
+ALTER TABLE "reset_passwords" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "accounts_owner_currency_key";
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_owner_currency_key" UNIQUE ("owner", "currency");
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "closed_at";
@@ -0,0 +1,7 @@
+ALTER TABLE "accounts" ADD COLUMN "closed_at" timestamptz;
+
+COMMENT ON COLUMN "accounts"."closed_at" IS 'the accounts are closed rather than deleted, their entries and transfers being kept';
+
+ALTER TABLE "accounts" DROP CONSTRAINT "accounts_owner_currency_key";
+
+CREATE UNIQUE INDEX "accounts_owner_currency_key" ON "accounts" ("owner", "currency") WHERE "closed_at" IS NULL;
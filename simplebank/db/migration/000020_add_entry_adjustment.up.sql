@@ -0,0 +1,3 @@
+ALTER TABLE "entries" ADD COLUMN "is_adjustment" boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN "entries"."is_adjustment" IS 'set on the corrections of the balance made by the admins, which are no spending';
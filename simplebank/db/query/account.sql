@@ -8,38 +8,45 @@ RETURNING *;
 
 -- name: GetAccount :one
 SELECT * FROM accounts
-WHERE id = $1 LIMIT 1;
+WHERE id = $1 AND closed_at IS NULL LIMIT 1;
 
 -- name: GetAccountForUpdate :one
 SELECT * FROM accounts
-WHERE id = $1 LIMIT 1
+WHERE id = $1 AND closed_at IS NULL LIMIT 1
 FOR NO KEY UPDATE;
 
 -- name: ListAccounts :many
 SELECT * FROM accounts
+WHERE closed_at IS NULL
 ORDER BY id
 LIMIT $1
 OFFSET $2;
 
 -- name: ListAccountsByOwner :many
 SELECT * FROM accounts
-WHERE owner = $1
+WHERE owner = $1 AND closed_at IS NULL
 ORDER BY id
 LIMIT $2
 OFFSET $3;
 
 -- name: UpdateAccount :one
 UPDATE accounts
-set balance = $2
+set balance = $2, version = version + 1
 WHERE id = $1
 RETURNING *;
 
 -- name: AddAccountBalance :one
 UPDATE accounts
-set balance = balance + sqlc.arg(amount)
+set balance = balance + sqlc.arg(amount), version = version + 1
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
+-- name: CloseAccount :one
+UPDATE accounts
+set closed_at = now(), version = version + 1
+WHERE id = $1 AND closed_at IS NULL
+RETURNING *;
+
 -- name: DeleteAccount :exec
 DELETE FROM accounts
 WHERE id = $1;
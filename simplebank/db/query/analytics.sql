@@ -0,0 +1,35 @@
+-- name: GetSpendingByCategory :many
+-- The money put aside into the goals and the adjustments of the admins are no spending.
+SELECT
+  COALESCE(c.name, 'uncategorized')::text AS category,
+  a.currency,
+  (-SUM(e.amount))::bigint AS total,
+  COUNT(*)::bigint AS count
+FROM entries e
+JOIN accounts a ON a.id = e.account_id
+LEFT JOIN categories c ON c.id = e.category_id
+WHERE a.owner = sqlc.arg(owner)
+  AND e.amount < 0
+  AND e.goal_id IS NULL
+  AND NOT e.is_adjustment
+  AND e.created_at >= sqlc.arg(from_time)
+  AND e.created_at < sqlc.arg(to_time)
+GROUP BY 1, 2
+ORDER BY 2, 3 DESC;
+
+-- name: GetSpendingByMonth :many
+SELECT
+  date_trunc('month', e.created_at)::timestamptz AS month,
+  a.currency,
+  (-SUM(e.amount))::bigint AS total,
+  COUNT(*)::bigint AS count
+FROM entries e
+JOIN accounts a ON a.id = e.account_id
+WHERE a.owner = sqlc.arg(owner)
+  AND e.amount < 0
+  AND e.goal_id IS NULL
+  AND NOT e.is_adjustment
+  AND e.created_at >= sqlc.arg(from_time)
+  AND e.created_at < sqlc.arg(to_time)
+GROUP BY 1, 2
+ORDER BY 1, 2;
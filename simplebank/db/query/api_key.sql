@@ -0,0 +1,31 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (
+  owner, name, prefix, key_hash, scopes
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: GetAPIKeyByHash :one
+-- The owner is read along with the key, as the key acts with the owner's role.
+SELECT k.id, k.owner, k.scopes, k.revoked_at, k.created_at, u.role, u.is_frozen
+FROM api_keys k
+JOIN users u ON u.username = k.owner
+WHERE k.key_hash = $1 LIMIT 1;
+
+-- name: ListAPIKeys :many
+SELECT * FROM api_keys
+WHERE owner = $1
+ORDER BY id;
+
+-- name: RevokeAPIKey :one
+UPDATE api_keys
+SET revoked_at = now()
+WHERE id = $1 AND owner = $2 AND revoked_at IS NULL
+RETURNING *;
+
+-- name: TouchAPIKey :exec
+-- last_used_at is only written once a minute, not on every request.
+UPDATE api_keys
+SET last_used_at = now()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
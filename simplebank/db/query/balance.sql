@@ -0,0 +1,76 @@
+-- name: GetBalanceHistory :many
+-- The balances are worked back from the current balance of the account through
+-- the entries since from_time, so that the older entries aren't needed.
+WITH running AS (
+  SELECT
+    entries.id,
+    entries.created_at,
+    accounts.balance - SUM(entries.amount) OVER newest_first + entries.amount AS balance,
+    accounts.balance - SUM(entries.amount) OVER newest_first AS previous_balance
+  FROM entries
+  JOIN accounts ON accounts.id = entries.account_id
+  WHERE entries.account_id = sqlc.arg(account_id) AND entries.created_at >= sqlc.arg(from_time)
+  WINDOW newest_first AS (ORDER BY entries.created_at DESC, entries.id DESC)
+)
+SELECT
+  date_trunc(sqlc.arg(bucket_interval)::text, created_at)::timestamptz AS bucket,
+  (array_agg(previous_balance ORDER BY created_at, id))[1]::bigint AS opening_balance,
+  (array_agg(balance ORDER BY created_at DESC, id DESC))[1]::bigint AS closing_balance,
+  MIN(LEAST(balance, previous_balance))::bigint AS min_balance,
+  MAX(GREATEST(balance, previous_balance))::bigint AS max_balance
+FROM running
+WHERE created_at < sqlc.arg(to_time)::timestamptz
+GROUP BY 1
+ORDER BY 1;
+
+-- name: GetBalanceHistoryFromSnapshots :many
+SELECT
+  date_trunc(sqlc.arg(bucket_interval)::text, day)::timestamptz AS bucket,
+  (array_agg(opening_balance ORDER BY day))[1]::bigint AS opening_balance,
+  (array_agg(closing_balance ORDER BY day DESC))[1]::bigint AS closing_balance,
+  MIN(min_balance)::bigint AS min_balance,
+  MAX(max_balance)::bigint AS max_balance
+FROM account_daily_balances
+WHERE account_id = sqlc.arg(account_id)
+  AND day >= sqlc.arg(from_time)::date
+  AND day < sqlc.arg(to_time)::date
+GROUP BY 1
+ORDER BY 1;
+
+-- name: GetLatestDailyBalanceDay :one
+SELECT COALESCE(MAX(day), '0001-01-01')::date AS day
+FROM account_daily_balances;
+
+-- name: RefreshDailyBalances :execrows
+-- Only the days since from_day of the accounts with entries on them are
+-- refreshed, worked back from the current balance as in GetBalanceHistory.
+WITH running AS (
+  SELECT
+    entries.account_id,
+    entries.id,
+    entries.created_at,
+    accounts.balance - SUM(entries.amount) OVER newest_first + entries.amount AS balance,
+    accounts.balance - SUM(entries.amount) OVER newest_first AS previous_balance
+  FROM entries
+  JOIN accounts ON accounts.id = entries.account_id
+  WHERE entries.created_at >= sqlc.arg(from_day)::date
+  WINDOW newest_first AS (PARTITION BY entries.account_id ORDER BY entries.created_at DESC, entries.id DESC)
+)
+INSERT INTO account_daily_balances (
+  account_id, day, opening_balance, closing_balance, min_balance, max_balance
+)
+SELECT
+  account_id,
+  created_at::date,
+  (array_agg(previous_balance ORDER BY created_at, id))[1],
+  (array_agg(balance ORDER BY created_at DESC, id DESC))[1],
+  MIN(LEAST(balance, previous_balance)),
+  MAX(GREATEST(balance, previous_balance))
+FROM running
+GROUP BY account_id, created_at::date
+ON CONFLICT (account_id, day) DO UPDATE SET
+  opening_balance = EXCLUDED.opening_balance,
+  closing_balance = EXCLUDED.closing_balance,
+  min_balance = EXCLUDED.min_balance,
+  max_balance = EXCLUDED.max_balance,
+  refreshed_at = now();
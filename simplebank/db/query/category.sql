@@ -0,0 +1,37 @@
+-- name: CreateCategory :one
+INSERT INTO categories (
+  owner, name
+) VALUES (
+  $1, $2
+)
+RETURNING *;
+
+-- name: GetCategory :one
+SELECT * FROM categories
+WHERE id = $1 LIMIT 1;
+
+-- name: ListCategories :many
+SELECT * FROM categories
+WHERE owner IS NULL OR owner = $1
+ORDER BY owner NULLS FIRST, name;
+
+-- name: DeleteCategory :exec
+DELETE FROM categories
+WHERE id = $1 AND owner = $2;
+
+-- name: CreateCategoryRule :one
+INSERT INTO category_rules (
+  owner, category_id, field, pattern, priority
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: ListCategoryRules :many
+SELECT * FROM category_rules
+WHERE owner = $1
+ORDER BY priority DESC, id;
+
+-- name: DeleteCategoryRule :exec
+DELETE FROM category_rules
+WHERE id = $1 AND owner = $2;
@@ -1,8 +1,8 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
-  account_id, amount
+  account_id, amount, memo, counterparty, goal_id, is_adjustment
 ) VALUES (
-  $1, $2
+  $1, $2, $3, $4, $5, $6
 )
 RETURNING *;
 
@@ -26,3 +26,42 @@ RETURNING *;
 -- name: DeleteEntry :exec
 DELETE FROM entries
 WHERE id = $1;
+
+-- name: ApplyCategoryRules :one
+-- The wildcards and the escape character of the patterns are escaped, so that
+-- the patterns only match as plain text.
+UPDATE entries e
+set category_id = (
+  SELECT r.category_id FROM category_rules r
+  JOIN accounts a ON a.owner = r.owner
+  WHERE a.id = e.account_id
+    AND (
+      (r.field = 'memo' AND e.memo ILIKE '%' || replace(replace(replace(r.pattern, '\', '\\'), '%', '\%'), '_', '\_') || '%')
+      OR (r.field = 'counterparty' AND e.counterparty ILIKE '%' || replace(replace(replace(r.pattern, '\', '\\'), '%', '\%'), '_', '\_') || '%')
+    )
+  ORDER BY r.priority DESC, r.id
+  LIMIT 1
+)
+WHERE e.id = $1 AND NOT e.category_overridden
+RETURNING *;
+
+-- name: ApplyCategoryRulesByOwner :execrows
+UPDATE entries e
+set category_id = (
+  SELECT r.category_id FROM category_rules r
+  WHERE r.owner = sqlc.arg(owner)
+    AND (
+      (r.field = 'memo' AND e.memo ILIKE '%' || replace(replace(replace(r.pattern, '\', '\\'), '%', '\%'), '_', '\_') || '%')
+      OR (r.field = 'counterparty' AND e.counterparty ILIKE '%' || replace(replace(replace(r.pattern, '\', '\\'), '%', '\%'), '_', '\_') || '%')
+    )
+  ORDER BY r.priority DESC, r.id
+  LIMIT 1
+)
+FROM accounts a
+WHERE a.id = e.account_id AND a.owner = sqlc.arg(owner) AND NOT e.category_overridden;
+
+-- name: SetEntryCategory :one
+UPDATE entries
+set category_id = sqlc.narg(category_id), category_overridden = sqlc.arg(category_overridden)
+WHERE id = sqlc.arg(id)
+RETURNING *;
@@ -0,0 +1,17 @@
+-- name: AddEntryTag :one
+INSERT INTO entry_tags (
+  entry_id, tag
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (entry_id, tag) DO UPDATE SET tag = EXCLUDED.tag
+RETURNING *;
+
+-- name: ListEntryTags :many
+SELECT * FROM entry_tags
+WHERE entry_id = $1
+ORDER BY tag;
+
+-- name: DeleteEntryTag :exec
+DELETE FROM entry_tags
+WHERE entry_id = $1 AND tag = $2;
@@ -0,0 +1,71 @@
+-- name: CreateGoal :one
+INSERT INTO goals (
+  account_id,
+  name,
+  target_amount,
+  deadline,
+  round_up_unit,
+  contribution_amount,
+  contribution_interval,
+  next_contribution_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+)
+RETURNING *;
+
+-- name: GetGoal :one
+SELECT * FROM goals
+WHERE id = $1 LIMIT 1;
+
+-- name: GetGoalForUpdate :one
+SELECT * FROM goals
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListGoalsByOwner :many
+SELECT goals.* FROM goals
+JOIN accounts ON accounts.id = goals.account_id
+WHERE accounts.owner = $1
+ORDER BY goals.id
+LIMIT $2
+OFFSET $3;
+
+-- name: GetRoundUpGoal :one
+SELECT * FROM goals
+WHERE account_id = $1 AND round_up_unit > 0
+LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListDueGoalContributions :many
+SELECT goals.* FROM goals
+JOIN accounts ON accounts.id = goals.account_id
+WHERE goals.contribution_amount > 0
+  AND goals.next_contribution_at <= sqlc.arg(now)::timestamptz
+  AND accounts.closed_at IS NULL
+ORDER BY goals.next_contribution_at
+LIMIT sqlc.arg(limit_count);
+
+-- name: AddGoalBalance :one
+UPDATE goals
+set balance = balance + sqlc.arg(amount)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ScheduleNextGoalContribution :one
+-- The next contribution is the first one of the schedule after now, the
+-- contributions missed meanwhile aren't made up.
+UPDATE goals
+set next_contribution_at = (
+  SELECT MIN(scheduled) FROM generate_series(
+    next_contribution_at,
+    sqlc.arg(now)::timestamptz + ('1 ' || contribution_interval)::interval,
+    ('1 ' || contribution_interval)::interval
+  ) AS scheduled
+  WHERE scheduled > sqlc.arg(now)::timestamptz
+)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: DeleteGoal :exec
+DELETE FROM goals
+WHERE id = $1;
@@ -0,0 +1,40 @@
+-- name: GetLoginFailure :one
+SELECT * FROM login_failures
+WHERE key = $1 LIMIT 1;
+
+-- name: RecordLoginFailure :one
+-- The count starts over when the previous failure is older than reset_before.
+INSERT INTO login_failures (
+  key, failed_attempts
+) VALUES (
+  sqlc.arg(key), 1
+)
+ON CONFLICT (key) DO UPDATE SET
+  failed_attempts = CASE
+    WHEN login_failures.updated_at < sqlc.arg(reset_before) THEN 1
+    ELSE login_failures.failed_attempts + 1
+  END,
+  updated_at = now()
+RETURNING *;
+
+-- name: LockLogin :one
+UPDATE login_failures
+SET locked_until = sqlc.arg(locked_until)
+WHERE key = sqlc.arg(key)
+RETURNING *;
+
+-- name: ForgiveLoginFailure :exec
+-- Takes back the failure counted before the credentials were found right, and
+-- lifts the lockout when the count is below max_failures again.
+UPDATE login_failures
+SET
+  failed_attempts = failed_attempts - 1,
+  locked_until = CASE
+    WHEN failed_attempts - 1 < sqlc.arg(max_failures)::int THEN NULL
+    ELSE locked_until
+  END
+WHERE key = sqlc.arg(key) AND failed_attempts > 0;
+
+-- name: DeleteLoginFailure :exec
+DELETE FROM login_failures
+WHERE key = $1;
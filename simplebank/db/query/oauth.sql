@@ -0,0 +1,38 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (
+  id, name, secret_hash, redirect_uris, scopes, created_by
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1 LIMIT 1;
+
+-- name: ListOAuthClients :many
+SELECT * FROM oauth_clients
+ORDER BY created_at;
+
+-- name: CreateOAuthAuthorizationCode :one
+INSERT INTO oauth_authorization_codes (
+  code_hash, client_id, username, redirect_uri, scopes, code_challenge, expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetOAuthAuthorizationCode :one
+SELECT * FROM oauth_authorization_codes
+WHERE code_hash = $1 LIMIT 1;
+
+-- name: UseOAuthAuthorizationCode :one
+-- A code can only be exchanged once: it is not found when already used.
+UPDATE oauth_authorization_codes
+SET used_at = now(), access_token_id = $2
+WHERE code_hash = $1 AND used_at IS NULL
+RETURNING *;
+
+-- name: DeleteExpiredOAuthAuthorizationCodes :execrows
+DELETE FROM oauth_authorization_codes
+WHERE expires_at < $1;
@@ -0,0 +1,19 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox_events (
+  owner, event_type, payload
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: ListUndispatchedOutboxEvents :many
+SELECT * FROM outbox_events
+WHERE dispatched_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxEventDispatched :exec
+UPDATE outbox_events
+set dispatched_at = now()
+WHERE id = $1;
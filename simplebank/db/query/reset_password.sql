@@ -0,0 +1,33 @@
+-- name: CreateResetPassword :one
+INSERT INTO reset_passwords (
+  token_hash, username, expires_at
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetResetPassword :one
+-- The reset link is only found while it can be followed.
+SELECT * FROM reset_passwords
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > now()
+LIMIT 1;
+
+-- name: UseResetPassword :one
+-- A reset link can only be followed once, before it expires: it is not found
+-- otherwise.
+UPDATE reset_passwords
+SET used_at = now()
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > now()
+RETURNING *;
+
+-- name: DeleteExpiredResetPasswords :execrows
+DELETE FROM reset_passwords
+WHERE expires_at < $1;
+
+-- name: DeleteUserResetPasswords :exec
+DELETE FROM reset_passwords
+WHERE username = $1;
@@ -0,0 +1,23 @@
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (
+  id, username, expires_at
+) VALUES (
+  $1, $2, $3
+)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: IsTokenRevoked :one
+-- A token is revoked on its own, or along with all the tokens of its user
+-- issued before the user logged out everywhere or changed its password.
+SELECT (EXISTS (
+  SELECT 1 FROM revoked_tokens r
+  WHERE r.id = sqlc.arg(id)
+) OR EXISTS (
+  SELECT 1 FROM users u
+  WHERE u.username = sqlc.arg(username)
+    AND (u.tokens_valid_after > sqlc.arg(issued_at) OR u.password_changed_at > sqlc.arg(issued_at))
+))::boolean AS revoked;
+
+-- name: DeleteExpiredRevokedTokens :execrows
+DELETE FROM revoked_tokens
+WHERE expires_at < $1;
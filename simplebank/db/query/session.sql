@@ -0,0 +1,32 @@
+-- name: CreateSession :one
+INSERT INTO sessions (
+  id, username, refresh_token_hash, family_id, user_agent, client_ip, expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetSession :one
+SELECT * FROM sessions
+WHERE id = $1 LIMIT 1;
+
+-- name: GetSessionByRefreshToken :one
+SELECT * FROM sessions
+WHERE refresh_token_hash = $1 LIMIT 1;
+
+-- name: RotateSession :one
+-- Only one rotation of a session can succeed, the others find no row.
+UPDATE sessions
+SET replaced_by = sqlc.arg(replaced_by)
+WHERE id = sqlc.arg(id) AND replaced_by IS NULL
+RETURNING *;
+
+-- name: BlockSessionFamily :execrows
+UPDATE sessions
+SET is_blocked = true
+WHERE family_id = $1;
+
+-- name: BlockUserSessions :execrows
+UPDATE sessions
+SET is_blocked = true
+WHERE username = $1 AND NOT is_blocked;
@@ -0,0 +1,76 @@
+-- name: GetUserTOTP :one
+SELECT * FROM user_totps
+WHERE username = $1 LIMIT 1;
+
+-- name: UpsertUserTOTP :one
+-- A pending enrolment is started over with a new secret, a confirmed one is
+-- left alone and not returned.
+INSERT INTO user_totps (
+  username, secret
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (username) DO UPDATE SET
+  secret = EXCLUDED.secret,
+  created_at = now()
+WHERE user_totps.confirmed_at IS NULL
+RETURNING *;
+
+-- name: ConfirmUserTOTP :one
+UPDATE user_totps
+SET confirmed_at = now(), last_used_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username) AND confirmed_at IS NULL
+RETURNING *;
+
+-- name: UseTOTPStep :one
+-- A code is only accepted once: it is not found when its step, or a later
+-- one, was already used.
+UPDATE user_totps
+SET last_used_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username) AND last_used_step < sqlc.arg(step)
+RETURNING *;
+
+-- name: DeleteUserTOTP :exec
+DELETE FROM user_totps
+WHERE username = $1;
+
+-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (
+  username, code_hash
+) VALUES (
+  $1, $2
+)
+RETURNING *;
+
+-- name: UseRecoveryCode :one
+UPDATE recovery_codes
+SET used_at = now()
+WHERE username = $1 AND code_hash = $2 AND used_at IS NULL
+RETURNING *;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1;
+
+-- name: CreateMFAChallenge :one
+INSERT INTO mfa_challenges (
+  token_hash, username, expires_at
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetMFAChallenge :one
+SELECT * FROM mfa_challenges
+WHERE token_hash = $1 LIMIT 1;
+
+-- name: UseMFAChallenge :one
+-- A challenge can only be completed once: it is not found when already used.
+UPDATE mfa_challenges
+SET used_at = now()
+WHERE token_hash = $1 AND used_at IS NULL
+RETURNING *;
+
+-- name: DeleteExpiredMFAChallenges :execrows
+DELETE FROM mfa_challenges
+WHERE expires_at < $1;
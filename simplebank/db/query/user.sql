@@ -9,3 +9,64 @@ RETURNING *;
 -- name: GetUser :one
 SELECT * FROM users
 WHERE username = $1 LIMIT 1;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 LIMIT 1;
+
+-- name: RevokeUserTokens :one
+UPDATE users
+SET tokens_valid_after = sqlc.arg(tokens_valid_after)
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: ListUsers :many
+SELECT * FROM users
+ORDER BY username
+LIMIT $1
+OFFSET $2;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateUserFrozen :one
+UPDATE users
+SET is_frozen = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: VerifyUserEmail :one
+-- The user is only verified if the email is still the one the link was sent to.
+UPDATE users
+SET is_email_verified = true
+WHERE username = $1 AND email = $2
+RETURNING *;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password = $2, password_changed_at = $3
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateUser :one
+-- The fields left null are kept. A changed email has to be verified again.
+UPDATE users
+SET
+  full_name = COALESCE(sqlc.narg(full_name), full_name),
+  email = COALESCE(sqlc.narg(email), email),
+  is_email_verified = CASE
+    WHEN sqlc.narg(email)::varchar IS NULL OR sqlc.narg(email) = email THEN is_email_verified
+    ELSE false
+  END
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: RehashUserPassword :exec
+-- The hash is only replaced if the password wasn't changed meanwhile. As the
+-- password stays the same, password_changed_at is kept and no token revoked.
+UPDATE users
+SET hashed_password = sqlc.arg(new_hashed_password)
+WHERE username = sqlc.arg(username) AND hashed_password = sqlc.arg(hashed_password);
@@ -0,0 +1,26 @@
+-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (
+  username, email, secret_code_hash, expires_at
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetVerifyEmail :one
+SELECT * FROM verify_emails
+WHERE id = $1 LIMIT 1;
+
+-- name: UseVerifyEmail :one
+-- A link can only be followed once, before it expires: it is not found
+-- otherwise.
+UPDATE verify_emails
+SET used_at = now()
+WHERE id = $1
+  AND secret_code_hash = $2
+  AND used_at IS NULL
+  AND expires_at > now()
+RETURNING *;
+
+-- name: DeleteExpiredVerifyEmails :execrows
+DELETE FROM verify_emails
+WHERE expires_at < $1;
@@ -0,0 +1,69 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (
+  owner, url, secret, event_types
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetWebhookSubscription :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1 LIMIT 1;
+
+-- name: ListWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE owner = $1
+ORDER BY id;
+
+-- name: DeleteWebhookSubscription :exec
+DELETE FROM webhook_subscriptions
+WHERE id = $1 AND owner = $2;
+
+-- name: CreateWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (
+  subscription_id, event_id
+)
+SELECT id, sqlc.arg(event_id) FROM webhook_subscriptions
+WHERE owner = sqlc.arg(owner)
+  AND active
+  AND sqlc.arg(event_type)::varchar = ANY(event_types);
+
+-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries d
+set next_attempt_at = now() + sqlc.arg(lease_seconds)::int * interval '1 second'
+FROM webhook_subscriptions s, outbox_events e
+WHERE d.id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= now()
+    ORDER BY next_attempt_at
+    LIMIT sqlc.arg(limit_count)
+    FOR UPDATE SKIP LOCKED
+  )
+  AND s.id = d.subscription_id
+  AND e.id = d.event_id
+RETURNING d.id, d.attempts, s.url, s.secret, e.id AS event_id, e.event_type, e.payload, e.created_at AS event_created_at;
+
+-- name: UpdateWebhookDelivery :one
+UPDATE webhook_deliveries
+set
+  status = $2,
+  attempts = attempts + 1,
+  next_attempt_at = $3,
+  response_status = $4,
+  last_error = $5,
+  delivered_at = CASE WHEN $2 = 'succeeded' THEN now() ELSE NULL END
+WHERE id = $1
+RETURNING *;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE subscription_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: RetryWebhookDelivery :one
+UPDATE webhook_deliveries
+set status = 'pending', attempts = 0, next_attempt_at = now()
+WHERE id = $1 AND subscription_id = $2 AND status = 'dead'
+RETURNING *;
@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 	"time"
 
@@ -76,7 +75,7 @@ func TestDeleteAccount(t *testing.T) {
 
 	account2, err := testQueries.GetAccount(context.Background(), account1.ID)
 	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.EqualError(t, err, ErrRecordNotFound.Error())
 	require.Empty(t, account2)
 }
 
@@ -124,3 +123,77 @@ func TestListAccountsByOwner(t *testing.T) {
 		require.NotZero(t, account.CreatedAt)
 	}
 }
+
+func TestAddAccountBalanceTxVersion(t *testing.T) {
+	store := NewStore(testPool)
+	account, _ := createRandomAccount(t)
+	require.Equal(t, int64(1), account.Version)
+
+	updated, err := store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{
+		ID:        account.ID,
+		Amount:    10,
+		IfVersion: account.Version,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.Version+1, updated.Version)
+
+	// the account changed since the first version
+	_, err = store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{
+		ID:        account.ID,
+		Amount:    10,
+		IfVersion: account.Version,
+	})
+	require.ErrorIs(t, err, ErrVersionMismatch)
+
+	err = store.DeleteAccountTx(context.Background(), DeleteAccountTxParams{
+		ID:        account.ID,
+		Owner:     account.Owner,
+		IfVersion: account.Version,
+	})
+	require.ErrorIs(t, err, ErrVersionMismatch)
+
+	err = store.DeleteAccountTx(context.Background(), DeleteAccountTxParams{
+		ID:        account.ID,
+		Owner:     account.Owner,
+		IfVersion: updated.Version,
+	})
+	require.NoError(t, err)
+
+	// the account is closed, its entry is kept
+	_, err = testQueries.GetAccount(context.Background(), account.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+	entries, err := testQueries.ListEntries(context.Background(), ListEntriesParams{
+		AccountID: account.ID,
+		Limit:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestAddAccountBalanceTxEntry(t *testing.T) {
+	store := NewStore(testPool)
+	account, _ := createRandomAccount(t)
+
+	_, err := store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{
+		ID:     account.ID,
+		Amount: -10,
+	})
+	require.NoError(t, err)
+	_, err = store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{
+		ID:     account.ID,
+		Amount: 25,
+		Reason: "chargeback",
+	})
+	require.NoError(t, err)
+
+	entries, err := testQueries.ListEntries(context.Background(), ListEntriesParams{
+		AccountID: account.ID,
+		Limit:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, int64(-10), entries[0].Amount)
+	require.Empty(t, entries[0].Memo)
+	require.Equal(t, int64(25), entries[1].Amount)
+	require.Equal(t, "chargeback", entries[1].Memo)
+}
@@ -0,0 +1,125 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: analytics.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const getSpendingByCategory = `-- name: GetSpendingByCategory :many
+SELECT
+  COALESCE(c.name, 'uncategorized')::text AS category,
+  a.currency,
+  (-SUM(e.amount))::bigint AS total,
+  COUNT(*)::bigint AS count
+FROM entries e
+JOIN accounts a ON a.id = e.account_id
+LEFT JOIN categories c ON c.id = e.category_id
+WHERE a.owner = $1
+  AND e.amount < 0
+  AND e.goal_id IS NULL
+  AND NOT e.is_adjustment
+  AND e.created_at >= $2
+  AND e.created_at < $3
+GROUP BY 1, 2
+ORDER BY 2, 3 DESC
+`
+
+type GetSpendingByCategoryParams struct {
+	Owner    string    `json:"owner"`
+	FromTime time.Time `json:"from_time"`
+	ToTime   time.Time `json:"to_time"`
+}
+
+type GetSpendingByCategoryRow struct {
+	Category string `json:"category"`
+	Currency string `json:"currency"`
+	Total    int64  `json:"total"`
+	Count    int64  `json:"count"`
+}
+
+// The money put aside into the goals and the adjustments of the admins are no spending.
+func (q *Queries) GetSpendingByCategory(ctx context.Context, arg GetSpendingByCategoryParams) ([]GetSpendingByCategoryRow, error) {
+	rows, err := q.db.Query(ctx, getSpendingByCategory, arg.Owner, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetSpendingByCategoryRow{}
+	for rows.Next() {
+		var i GetSpendingByCategoryRow
+		if err := rows.Scan(
+			&i.Category,
+			&i.Currency,
+			&i.Total,
+			&i.Count,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSpendingByMonth = `-- name: GetSpendingByMonth :many
+SELECT
+  date_trunc('month', e.created_at)::timestamptz AS month,
+  a.currency,
+  (-SUM(e.amount))::bigint AS total,
+  COUNT(*)::bigint AS count
+FROM entries e
+JOIN accounts a ON a.id = e.account_id
+WHERE a.owner = $1
+  AND e.amount < 0
+  AND e.goal_id IS NULL
+  AND NOT e.is_adjustment
+  AND e.created_at >= $2
+  AND e.created_at < $3
+GROUP BY 1, 2
+ORDER BY 1, 2
+`
+
+type GetSpendingByMonthParams struct {
+	Owner    string    `json:"owner"`
+	FromTime time.Time `json:"from_time"`
+	ToTime   time.Time `json:"to_time"`
+}
+
+type GetSpendingByMonthRow struct {
+	Month    time.Time `json:"month"`
+	Currency string    `json:"currency"`
+	Total    int64     `json:"total"`
+	Count    int64     `json:"count"`
+}
+
+func (q *Queries) GetSpendingByMonth(ctx context.Context, arg GetSpendingByMonthParams) ([]GetSpendingByMonthRow, error) {
+	rows, err := q.db.Query(ctx, getSpendingByMonth, arg.Owner, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetSpendingByMonthRow{}
+	for rows.Next() {
+		var i GetSpendingByMonthRow
+		if err := rows.Scan(
+			&i.Month,
+			&i.Currency,
+			&i.Total,
+			&i.Count,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,163 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: api_key.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createAPIKey = `-- name: CreateAPIKey :one
+INSERT INTO api_keys (
+  owner, name, prefix, key_hash, scopes
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING id, owner, name, prefix, key_hash, scopes, last_used_at, revoked_at, created_at
+`
+
+type CreateAPIKeyParams struct {
+	Owner   string   `json:"owner"`
+	Name    string   `json:"name"`
+	Prefix  string   `json:"prefix"`
+	KeyHash string   `json:"key_hash"`
+	Scopes  []string `json:"scopes"`
+}
+
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, createAPIKey,
+		arg.Owner,
+		arg.Name,
+		arg.Prefix,
+		arg.KeyHash,
+		arg.Scopes,
+	)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Name,
+		&i.Prefix,
+		&i.KeyHash,
+		&i.Scopes,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getAPIKeyByHash = `-- name: GetAPIKeyByHash :one
+SELECT k.id, k.owner, k.scopes, k.revoked_at, k.created_at, u.role, u.is_frozen
+FROM api_keys k
+JOIN users u ON u.username = k.owner
+WHERE k.key_hash = $1 LIMIT 1
+`
+
+type GetAPIKeyByHashRow struct {
+	ID        int64              `json:"id"`
+	Owner     string             `json:"owner"`
+	Scopes    []string           `json:"scopes"`
+	RevokedAt pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt time.Time          `json:"created_at"`
+	Role      string             `json:"role"`
+	IsFrozen  bool               `json:"is_frozen"`
+}
+
+// The owner is read along with the key, as the key acts with the owner's role.
+func (q *Queries) GetAPIKeyByHash(ctx context.Context, keyHash string) (GetAPIKeyByHashRow, error) {
+	row := q.db.QueryRow(ctx, getAPIKeyByHash, keyHash)
+	var i GetAPIKeyByHashRow
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Scopes,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsFrozen,
+	)
+	return i, err
+}
+
+const listAPIKeys = `-- name: ListAPIKeys :many
+SELECT id, owner, name, prefix, key_hash, scopes, last_used_at, revoked_at, created_at FROM api_keys
+WHERE owner = $1
+ORDER BY id
+`
+
+func (q *Queries) ListAPIKeys(ctx context.Context, owner string) ([]ApiKey, error) {
+	rows, err := q.db.Query(ctx, listAPIKeys, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ApiKey{}
+	for rows.Next() {
+		var i ApiKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Name,
+			&i.Prefix,
+			&i.KeyHash,
+			&i.Scopes,
+			&i.LastUsedAt,
+			&i.RevokedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeAPIKey = `-- name: RevokeAPIKey :one
+UPDATE api_keys
+SET revoked_at = now()
+WHERE id = $1 AND owner = $2 AND revoked_at IS NULL
+RETURNING id, owner, name, prefix, key_hash, scopes, last_used_at, revoked_at, created_at
+`
+
+type RevokeAPIKeyParams struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+}
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, revokeAPIKey, arg.ID, arg.Owner)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Name,
+		&i.Prefix,
+		&i.KeyHash,
+		&i.Scopes,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const touchAPIKey = `-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = now()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')
+`
+
+// last_used_at is only written once a minute, not on every request.
+func (q *Queries) TouchAPIKey(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, touchAPIKey, id)
+	return err
+}
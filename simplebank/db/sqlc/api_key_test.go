@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAPIKey(t *testing.T, owner string) ApiKey {
+	arg := CreateAPIKeyParams{
+		Owner:   owner,
+		Name:    utils.RandomString(8),
+		Prefix:  "sbk_" + utils.RandomString(8),
+		KeyHash: utils.RandomString(64),
+		Scopes:  []string{"accounts:read", "transfers:write"},
+	}
+
+	key, err := testQueries.CreateAPIKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Owner, key.Owner)
+	require.Equal(t, arg.Scopes, key.Scopes)
+	require.False(t, key.LastUsedAt.Valid)
+	require.False(t, key.RevokedAt.Valid)
+
+	return key
+}
+
+func TestGetAPIKeyByHash(t *testing.T) {
+	user, _ := createRandomUser(t)
+	key := createRandomAPIKey(t, user.Username)
+
+	got, err := testQueries.GetAPIKeyByHash(context.Background(), key.KeyHash)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, got.ID)
+	require.Equal(t, key.Scopes, got.Scopes)
+	require.Equal(t, user.Role, got.Role)
+	require.False(t, got.IsFrozen)
+
+	_, err = testQueries.GetAPIKeyByHash(context.Background(), utils.RandomString(64))
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestTouchAPIKey(t *testing.T) {
+	user, _ := createRandomUser(t)
+	key := createRandomAPIKey(t, user.Username)
+
+	err := testQueries.TouchAPIKey(context.Background(), key.ID)
+	require.NoError(t, err)
+
+	keys, err := testQueries.ListAPIKeys(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.True(t, keys[0].LastUsedAt.Valid)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	user, _ := createRandomUser(t)
+	other, _ := createRandomUser(t)
+	key := createRandomAPIKey(t, user.Username)
+
+	// only the owner can revoke the key
+	_, err := testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{ID: key.ID, Owner: other.Username})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	revoked, err := testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{ID: key.ID, Owner: user.Username})
+	require.NoError(t, err)
+	require.True(t, revoked.RevokedAt.Valid)
+
+	_, err = testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{ID: key.ID, Owner: user.Username})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// SystemActor is the actor of the changes made outside of a request, by the workers.
+const SystemActor = "system"
+
+// Actions recorded in the audit log by the store.
+const (
+	AuditAccountCreate  = "account.create"
+	AuditAccountDebit   = "account.debit"
+	AuditAccountCredit  = "account.credit"
+	AuditAccountDelete  = "account.delete"
+	AuditAccountAdjust  = "account.adjust"
+	AuditAccountGoal    = "account.goal"
+	AuditTransferCreate = "transfer.create"
+)
+
+// AuditInfo describes who is behind a change, it is recorded along with it in the audit log.
+type AuditInfo struct {
+	Actor     string
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+type auditInfoKey struct{}
+
+// WithAuditInfo returns a copy of the context carrying the audit info.
+func WithAuditInfo(ctx context.Context, info AuditInfo) context.Context {
+	return context.WithValue(ctx, auditInfoKey{}, info)
+}
+
+// AuditInfoFromContext returns the audit info of the context,
+// or the system actor when there is none.
+func AuditInfoFromContext(ctx context.Context) AuditInfo {
+	if info, ok := ctx.Value(auditInfoKey{}).(AuditInfo); ok {
+		return info
+	}
+	return AuditInfo{Actor: SystemActor}
+}
+
+// recordAudit writes the before and after snapshots of the target into the
+// audit log, along with the reason of the change if any. A nil snapshot is
+// stored as NULL.
+func recordAudit(
+	ctx context.Context,
+	q *Queries,
+	action string,
+	targetType string,
+	targetID int64,
+	before interface{},
+	after interface{},
+	reason string,
+) error {
+	beforeData, err := auditSnapshot(before)
+	if err != nil {
+		return fmt.Errorf("cannot marshal %s snapshot: %w", action, err)
+	}
+	afterData, err := auditSnapshot(after)
+	if err != nil {
+		return fmt.Errorf("cannot marshal %s snapshot: %w", action, err)
+	}
+
+	info := AuditInfoFromContext(ctx)
+	_, err = q.CreateAuditLog(ctx, CreateAuditLogParams{
+		Actor:      info.Actor,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   pgtype.Int8{Int64: targetID, Valid: true},
+		Before:     beforeData,
+		After:      afterData,
+		Ip:         info.IP,
+		UserAgent:  info.UserAgent,
+		RequestID:  info.RequestID,
+		Reason:     reason,
+	})
+	return err
+}
+
+func auditSnapshot(value interface{}) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
@@ -0,0 +1,139 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: audit.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createAuditLog = `-- name: CreateAuditLog :one
+INSERT INTO audit_log (
+  actor, action, target_type, target_id, before, after, status_code, ip, user_agent, request_id, reason
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+)
+RETURNING id, actor, action, target_type, target_id, before, after, status_code, ip, user_agent, request_id, created_at, reason
+`
+
+type CreateAuditLogParams struct {
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   pgtype.Int8     `json:"target_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	StatusCode pgtype.Int4     `json:"status_code"`
+	Ip         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	RequestID  string          `json:"request_id"`
+	Reason     string          `json:"reason"`
+}
+
+func (q *Queries) CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error) {
+	row := q.db.QueryRow(ctx, createAuditLog,
+		arg.Actor,
+		arg.Action,
+		arg.TargetType,
+		arg.TargetID,
+		arg.Before,
+		arg.After,
+		arg.StatusCode,
+		arg.Ip,
+		arg.UserAgent,
+		arg.RequestID,
+		arg.Reason,
+	)
+	var i AuditLog
+	err := row.Scan(
+		&i.ID,
+		&i.Actor,
+		&i.Action,
+		&i.TargetType,
+		&i.TargetID,
+		&i.Before,
+		&i.After,
+		&i.StatusCode,
+		&i.Ip,
+		&i.UserAgent,
+		&i.RequestID,
+		&i.CreatedAt,
+		&i.Reason,
+	)
+	return i, err
+}
+
+const listAuditLog = `-- name: ListAuditLog :many
+SELECT id, actor, action, target_type, target_id, before, after, status_code, ip, user_agent, request_id, created_at, reason FROM audit_log
+WHERE ($1::varchar IS NULL OR actor = $1)
+  AND ($2::varchar IS NULL OR action = $2)
+  AND ($3::varchar IS NULL OR target_type = $3)
+  AND ($4::bigint IS NULL OR target_id = $4)
+  AND ($5::varchar IS NULL OR request_id = $5)
+  AND ($6::timestamptz IS NULL OR created_at >= $6)
+  AND ($7::timestamptz IS NULL OR created_at < $7)
+ORDER BY id DESC
+LIMIT $9
+OFFSET $8
+`
+
+type ListAuditLogParams struct {
+	Actor       pgtype.Text        `json:"actor"`
+	Action      pgtype.Text        `json:"action"`
+	TargetType  pgtype.Text        `json:"target_type"`
+	TargetID    pgtype.Int8        `json:"target_id"`
+	RequestID   pgtype.Text        `json:"request_id"`
+	FromTime    pgtype.Timestamptz `json:"from_time"`
+	ToTime      pgtype.Timestamptz `json:"to_time"`
+	OffsetCount int32              `json:"offset_count"`
+	LimitCount  int32              `json:"limit_count"`
+}
+
+func (q *Queries) ListAuditLog(ctx context.Context, arg ListAuditLogParams) ([]AuditLog, error) {
+	rows, err := q.db.Query(ctx, listAuditLog,
+		arg.Actor,
+		arg.Action,
+		arg.TargetType,
+		arg.TargetID,
+		arg.RequestID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.OffsetCount,
+		arg.LimitCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditLog{}
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Actor,
+			&i.Action,
+			&i.TargetType,
+			&i.TargetID,
+			&i.Before,
+			&i.After,
+			&i.StatusCode,
+			&i.Ip,
+			&i.UserAgent,
+			&i.RequestID,
+			&i.CreatedAt,
+			&i.Reason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
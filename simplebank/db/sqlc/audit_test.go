@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddAccountBalanceTxAudit(t *testing.T) {
+	store := NewStore(testPool)
+	account, _ := createRandomAccount(t)
+
+	info := AuditInfo{
+		Actor:     account.Owner,
+		IP:        "127.0.0.1",
+		UserAgent: "test",
+		RequestID: utils.RandomString(16),
+	}
+	ctx := WithAuditInfo(context.Background(), info)
+
+	updated, err := store.AddAccountBalanceTx(ctx, AddAccountBalanceTxParams{ID: account.ID, Amount: -10})
+	require.NoError(t, err)
+
+	logs, err := testQueries.ListAuditLog(context.Background(), ListAuditLogParams{
+		RequestID:  pgtype.Text{String: info.RequestID, Valid: true},
+		LimitCount: 5,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+
+	log := logs[0]
+	require.Equal(t, info.Actor, log.Actor)
+	require.Equal(t, AuditAccountDebit, log.Action)
+	require.Equal(t, "account", log.TargetType)
+	require.Equal(t, account.ID, log.TargetID.Int64)
+	require.Equal(t, info.IP, log.Ip)
+
+	var before, after Account
+	require.NoError(t, json.Unmarshal(log.Before, &before))
+	require.NoError(t, json.Unmarshal(log.After, &after))
+	require.Equal(t, account.Balance, before.Balance)
+	require.Equal(t, updated.Balance, after.Balance)
+}
+
+func TestAdjustAccountBalanceTxAudit(t *testing.T) {
+	store := NewStore(testPool)
+	account, _ := createRandomAccount(t)
+
+	info := AuditInfo{
+		Actor:     utils.RandomOwner(),
+		RequestID: utils.RandomString(16),
+	}
+	ctx := WithAuditInfo(context.Background(), info)
+
+	_, err := store.AddAccountBalanceTx(ctx, AddAccountBalanceTxParams{
+		ID:     account.ID,
+		Amount: 10,
+		Reason: "missing interest",
+	})
+	require.NoError(t, err)
+
+	logs, err := testQueries.ListAuditLog(context.Background(), ListAuditLogParams{
+		RequestID:  pgtype.Text{String: info.RequestID, Valid: true},
+		LimitCount: 5,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, AuditAccountAdjust, logs[0].Action)
+	require.Equal(t, "missing interest", logs[0].Reason)
+}
+
+func TestGoalTransferTxAudit(t *testing.T) {
+	store := NewStore(testPool)
+	account, _ := createRandomAccount(t)
+	goal := createRandomGoal(t, account, 0)
+
+	info := AuditInfo{
+		Actor:     account.Owner,
+		RequestID: utils.RandomString(16),
+	}
+	ctx := WithAuditInfo(context.Background(), info)
+
+	result, err := store.GoalTransferTx(ctx, GoalTransferTxParams{
+		GoalID: goal.ID,
+		Amount: 10,
+	})
+	require.NoError(t, err)
+
+	logs, err := testQueries.ListAuditLog(context.Background(), ListAuditLogParams{
+		RequestID:  pgtype.Text{String: info.RequestID, Valid: true},
+		LimitCount: 5,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, AuditAccountGoal, logs[0].Action)
+	require.Equal(t, account.ID, logs[0].TargetID.Int64)
+
+	var before, after Account
+	require.NoError(t, json.Unmarshal(logs[0].Before, &before))
+	require.NoError(t, json.Unmarshal(logs[0].After, &after))
+	require.Equal(t, account.Balance, before.Balance)
+	require.Equal(t, result.Account.Balance, after.Balance)
+}
+
+func TestAuditLogAppendOnly(t *testing.T) {
+	store := NewStore(testPool)
+	user, _ := createRandomUser(t)
+
+	// without audit info, the change is attributed to the system
+	account, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  0,
+		Currency: utils.RandomCurrency(),
+	})
+	require.NoError(t, err)
+
+	logs, err := testQueries.ListAuditLog(context.Background(), ListAuditLogParams{
+		Action:     pgtype.Text{String: AuditAccountCreate, Valid: true},
+		TargetID:   pgtype.Int8{Int64: account.ID, Valid: true},
+		LimitCount: 5,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, SystemActor, logs[0].Actor)
+	require.Nil(t, logs[0].Before)
+
+	_, err = testPool.Exec(context.Background(), "UPDATE audit_log SET actor = 'someone' WHERE id = $1", logs[0].ID)
+	require.Error(t, err)
+
+	_, err = testPool.Exec(context.Background(), "DELETE FROM audit_log WHERE id = $1", logs[0].ID)
+	require.Error(t, err)
+}
@@ -0,0 +1,199 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: balance.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const getBalanceHistory = `-- name: GetBalanceHistory :many
+WITH running AS (
+  SELECT
+    entries.id,
+    entries.created_at,
+    accounts.balance - SUM(entries.amount) OVER newest_first + entries.amount AS balance,
+    accounts.balance - SUM(entries.amount) OVER newest_first AS previous_balance
+  FROM entries
+  JOIN accounts ON accounts.id = entries.account_id
+  WHERE entries.account_id = $3 AND entries.created_at >= $4
+  WINDOW newest_first AS (ORDER BY entries.created_at DESC, entries.id DESC)
+)
+SELECT
+  date_trunc($1::text, created_at)::timestamptz AS bucket,
+  (array_agg(previous_balance ORDER BY created_at, id))[1]::bigint AS opening_balance,
+  (array_agg(balance ORDER BY created_at DESC, id DESC))[1]::bigint AS closing_balance,
+  MIN(LEAST(balance, previous_balance))::bigint AS min_balance,
+  MAX(GREATEST(balance, previous_balance))::bigint AS max_balance
+FROM running
+WHERE created_at < $2::timestamptz
+GROUP BY 1
+ORDER BY 1
+`
+
+type GetBalanceHistoryParams struct {
+	BucketInterval string    `json:"bucket_interval"`
+	ToTime         time.Time `json:"to_time"`
+	AccountID      int64     `json:"account_id"`
+	FromTime       time.Time `json:"from_time"`
+}
+
+type GetBalanceHistoryRow struct {
+	Bucket         time.Time `json:"bucket"`
+	OpeningBalance int64     `json:"opening_balance"`
+	ClosingBalance int64     `json:"closing_balance"`
+	MinBalance     int64     `json:"min_balance"`
+	MaxBalance     int64     `json:"max_balance"`
+}
+
+// The balances are worked back from the current balance of the account through
+// the entries since from_time, so that the older entries aren't needed.
+func (q *Queries) GetBalanceHistory(ctx context.Context, arg GetBalanceHistoryParams) ([]GetBalanceHistoryRow, error) {
+	rows, err := q.db.Query(ctx, getBalanceHistory,
+		arg.BucketInterval,
+		arg.ToTime,
+		arg.AccountID,
+		arg.FromTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetBalanceHistoryRow{}
+	for rows.Next() {
+		var i GetBalanceHistoryRow
+		if err := rows.Scan(
+			&i.Bucket,
+			&i.OpeningBalance,
+			&i.ClosingBalance,
+			&i.MinBalance,
+			&i.MaxBalance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBalanceHistoryFromSnapshots = `-- name: GetBalanceHistoryFromSnapshots :many
+SELECT
+  date_trunc($1::text, day)::timestamptz AS bucket,
+  (array_agg(opening_balance ORDER BY day))[1]::bigint AS opening_balance,
+  (array_agg(closing_balance ORDER BY day DESC))[1]::bigint AS closing_balance,
+  MIN(min_balance)::bigint AS min_balance,
+  MAX(max_balance)::bigint AS max_balance
+FROM account_daily_balances
+WHERE account_id = $2
+  AND day >= $3::date
+  AND day < $4::date
+GROUP BY 1
+ORDER BY 1
+`
+
+type GetBalanceHistoryFromSnapshotsParams struct {
+	BucketInterval string    `json:"bucket_interval"`
+	AccountID      int64     `json:"account_id"`
+	FromTime       time.Time `json:"from_time"`
+	ToTime         time.Time `json:"to_time"`
+}
+
+type GetBalanceHistoryFromSnapshotsRow struct {
+	Bucket         time.Time `json:"bucket"`
+	OpeningBalance int64     `json:"opening_balance"`
+	ClosingBalance int64     `json:"closing_balance"`
+	MinBalance     int64     `json:"min_balance"`
+	MaxBalance     int64     `json:"max_balance"`
+}
+
+func (q *Queries) GetBalanceHistoryFromSnapshots(ctx context.Context, arg GetBalanceHistoryFromSnapshotsParams) ([]GetBalanceHistoryFromSnapshotsRow, error) {
+	rows, err := q.db.Query(ctx, getBalanceHistoryFromSnapshots,
+		arg.BucketInterval,
+		arg.AccountID,
+		arg.FromTime,
+		arg.ToTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetBalanceHistoryFromSnapshotsRow{}
+	for rows.Next() {
+		var i GetBalanceHistoryFromSnapshotsRow
+		if err := rows.Scan(
+			&i.Bucket,
+			&i.OpeningBalance,
+			&i.ClosingBalance,
+			&i.MinBalance,
+			&i.MaxBalance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLatestDailyBalanceDay = `-- name: GetLatestDailyBalanceDay :one
+SELECT COALESCE(MAX(day), '0001-01-01')::date AS day
+FROM account_daily_balances
+`
+
+func (q *Queries) GetLatestDailyBalanceDay(ctx context.Context) (time.Time, error) {
+	row := q.db.QueryRow(ctx, getLatestDailyBalanceDay)
+	var day time.Time
+	err := row.Scan(&day)
+	return day, err
+}
+
+const refreshDailyBalances = `-- name: RefreshDailyBalances :execrows
+WITH running AS (
+  SELECT
+    entries.account_id,
+    entries.id,
+    entries.created_at,
+    accounts.balance - SUM(entries.amount) OVER newest_first + entries.amount AS balance,
+    accounts.balance - SUM(entries.amount) OVER newest_first AS previous_balance
+  FROM entries
+  JOIN accounts ON accounts.id = entries.account_id
+  WHERE entries.created_at >= $1::date
+  WINDOW newest_first AS (PARTITION BY entries.account_id ORDER BY entries.created_at DESC, entries.id DESC)
+)
+INSERT INTO account_daily_balances (
+  account_id, day, opening_balance, closing_balance, min_balance, max_balance
+)
+SELECT
+  account_id,
+  created_at::date,
+  (array_agg(previous_balance ORDER BY created_at, id))[1],
+  (array_agg(balance ORDER BY created_at DESC, id DESC))[1],
+  MIN(LEAST(balance, previous_balance)),
+  MAX(GREATEST(balance, previous_balance))
+FROM running
+GROUP BY account_id, created_at::date
+ON CONFLICT (account_id, day) DO UPDATE SET
+  opening_balance = EXCLUDED.opening_balance,
+  closing_balance = EXCLUDED.closing_balance,
+  min_balance = EXCLUDED.min_balance,
+  max_balance = EXCLUDED.max_balance,
+  refreshed_at = now()
+`
+
+// Only the days since from_day of the accounts with entries on them are
+// refreshed, worked back from the current balance as in GetBalanceHistory.
+func (q *Queries) RefreshDailyBalances(ctx context.Context, fromDay time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, refreshDailyBalances, fromDay)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBalanceHistory(t *testing.T) {
+	store := NewStore(testPool)
+	account, _ := createRandomAccount(t)
+
+	amounts := []int64{100, -30, 50, -70}
+	for _, amount := range amounts {
+		_, err := store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{
+			ID:     account.ID,
+			Amount: amount,
+		})
+		require.NoError(t, err)
+	}
+
+	now := time.Now()
+	arg := GetBalanceHistoryParams{
+		AccountID:      account.ID,
+		BucketInterval: "day",
+		FromTime:       now.Add(-time.Hour),
+		ToTime:         now.Add(time.Hour),
+	}
+	buckets, err := testQueries.GetBalanceHistory(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, buckets)
+
+	first := buckets[0]
+	last := buckets[len(buckets)-1]
+	require.Equal(t, account.Balance, first.OpeningBalance)
+	require.Equal(t, account.Balance+50, last.ClosingBalance)
+	for _, bucket := range buckets {
+		require.LessOrEqual(t, bucket.MinBalance, bucket.MaxBalance)
+	}
+	require.Equal(t, account.Balance, first.MinBalance)
+	require.Equal(t, account.Balance+120, last.MaxBalance)
+}
+
+func TestRefreshDailyBalances(t *testing.T) {
+	store := NewStore(testPool)
+	account, _ := createRandomAccount(t)
+
+	amounts := []int64{40, 60, -25}
+	for _, amount := range amounts {
+		_, err := store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{
+			ID:     account.ID,
+			Amount: amount,
+		})
+		require.NoError(t, err)
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	rows, err := testQueries.RefreshDailyBalances(context.Background(), today.Add(-24*time.Hour))
+	require.NoError(t, err)
+	require.NotZero(t, rows)
+
+	latestDay, err := testQueries.GetLatestDailyBalanceDay(context.Background())
+	require.NoError(t, err)
+	require.False(t, latestDay.Before(today.Add(-24*time.Hour)))
+
+	arg := GetBalanceHistoryFromSnapshotsParams{
+		AccountID:      account.ID,
+		BucketInterval: "month",
+		FromTime:       today.Add(-24 * time.Hour),
+		ToTime:         today.Add(48 * time.Hour),
+	}
+	buckets, err := testQueries.GetBalanceHistoryFromSnapshots(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, buckets)
+
+	last := buckets[len(buckets)-1]
+	require.Equal(t, account.Balance+75, last.ClosingBalance)
+	require.Equal(t, account.Balance+100, last.MaxBalance)
+}
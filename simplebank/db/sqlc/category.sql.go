@@ -0,0 +1,188 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: category.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const createCategory = `-- name: CreateCategory :one
+INSERT INTO categories (
+  owner, name
+) VALUES (
+  $1, $2
+)
+RETURNING id, owner, name, created_at
+`
+
+type CreateCategoryParams struct {
+	Owner pgtype.Text `json:"owner"`
+	Name  string      `json:"name"`
+}
+
+func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error) {
+	row := q.db.QueryRow(ctx, createCategory, arg.Owner, arg.Name)
+	var i Category
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Name,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createCategoryRule = `-- name: CreateCategoryRule :one
+INSERT INTO category_rules (
+  owner, category_id, field, pattern, priority
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING id, owner, category_id, field, pattern, priority, created_at
+`
+
+type CreateCategoryRuleParams struct {
+	Owner      string `json:"owner"`
+	CategoryID int64  `json:"category_id"`
+	Field      string `json:"field"`
+	Pattern    string `json:"pattern"`
+	Priority   int32  `json:"priority"`
+}
+
+func (q *Queries) CreateCategoryRule(ctx context.Context, arg CreateCategoryRuleParams) (CategoryRule, error) {
+	row := q.db.QueryRow(ctx, createCategoryRule,
+		arg.Owner,
+		arg.CategoryID,
+		arg.Field,
+		arg.Pattern,
+		arg.Priority,
+	)
+	var i CategoryRule
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.CategoryID,
+		&i.Field,
+		&i.Pattern,
+		&i.Priority,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteCategory = `-- name: DeleteCategory :exec
+DELETE FROM categories
+WHERE id = $1 AND owner = $2
+`
+
+type DeleteCategoryParams struct {
+	ID    int64       `json:"id"`
+	Owner pgtype.Text `json:"owner"`
+}
+
+func (q *Queries) DeleteCategory(ctx context.Context, arg DeleteCategoryParams) error {
+	_, err := q.db.Exec(ctx, deleteCategory, arg.ID, arg.Owner)
+	return err
+}
+
+const deleteCategoryRule = `-- name: DeleteCategoryRule :exec
+DELETE FROM category_rules
+WHERE id = $1 AND owner = $2
+`
+
+type DeleteCategoryRuleParams struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+}
+
+func (q *Queries) DeleteCategoryRule(ctx context.Context, arg DeleteCategoryRuleParams) error {
+	_, err := q.db.Exec(ctx, deleteCategoryRule, arg.ID, arg.Owner)
+	return err
+}
+
+const getCategory = `-- name: GetCategory :one
+SELECT id, owner, name, created_at FROM categories
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetCategory(ctx context.Context, id int64) (Category, error) {
+	row := q.db.QueryRow(ctx, getCategory, id)
+	var i Category
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Name,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listCategories = `-- name: ListCategories :many
+SELECT id, owner, name, created_at FROM categories
+WHERE owner IS NULL OR owner = $1
+ORDER BY owner NULLS FIRST, name
+`
+
+func (q *Queries) ListCategories(ctx context.Context, owner pgtype.Text) ([]Category, error) {
+	rows, err := q.db.Query(ctx, listCategories, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Category{}
+	for rows.Next() {
+		var i Category
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Name,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCategoryRules = `-- name: ListCategoryRules :many
+SELECT id, owner, category_id, field, pattern, priority, created_at FROM category_rules
+WHERE owner = $1
+ORDER BY priority DESC, id
+`
+
+func (q *Queries) ListCategoryRules(ctx context.Context, owner string) ([]CategoryRule, error) {
+	rows, err := q.db.Query(ctx, listCategoryRules, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CategoryRule{}
+	for rows.Next() {
+		var i CategoryRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.CategoryID,
+			&i.Field,
+			&i.Pattern,
+			&i.Priority,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
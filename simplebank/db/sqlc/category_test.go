@@ -0,0 +1,222 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomCategory(t *testing.T, owner string) Category {
+	arg := CreateCategoryParams{
+		Owner: pgtype.Text{String: owner, Valid: true},
+		Name:  utils.RandomString(10),
+	}
+
+	category, err := testQueries.CreateCategory(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Owner, category.Owner)
+	require.Equal(t, arg.Name, category.Name)
+	require.NotZero(t, category.ID)
+
+	return category
+}
+
+func TestListCategories(t *testing.T) {
+	user, _ := createRandomUser(t)
+	category := createRandomCategory(t, user.Username)
+
+	categories, err := testQueries.ListCategories(context.Background(), category.Owner)
+	require.NoError(t, err)
+
+	var builtIn, own int
+	for _, c := range categories {
+		if c.Owner.Valid {
+			require.Equal(t, user.Username, c.Owner.String)
+			own++
+		} else {
+			builtIn++
+		}
+	}
+	require.Equal(t, 1, own)
+	require.NotZero(t, builtIn)
+}
+
+func TestApplyCategoryRules(t *testing.T) {
+	user, _ := createRandomUser(t)
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: utils.RandomCurrency(),
+	})
+	require.NoError(t, err)
+
+	groceries := createRandomCategory(t, user.Username)
+	rent := createRandomCategory(t, user.Username)
+
+	_, err = testQueries.CreateCategoryRule(context.Background(), CreateCategoryRuleParams{
+		Owner:      user.Username,
+		CategoryID: groceries.ID,
+		Field:      "memo",
+		Pattern:    "market",
+	})
+	require.NoError(t, err)
+	_, err = testQueries.CreateCategoryRule(context.Background(), CreateCategoryRuleParams{
+		Owner:      user.Username,
+		CategoryID: rent.ID,
+		Field:      "memo",
+		Pattern:    "super",
+		Priority:   10,
+	})
+	require.NoError(t, err)
+
+	rules, err := testQueries.ListCategoryRules(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.Equal(t, rent.ID, rules[0].CategoryID)
+
+	entry, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+		AccountID: account.ID,
+		Amount:    -utils.RandomMoney(),
+		Memo:      "SuperMarket downtown",
+	})
+	require.NoError(t, err)
+	require.False(t, entry.CategoryID.Valid)
+
+	// the rule with the highest priority wins
+	entry, err = testQueries.ApplyCategoryRules(context.Background(), entry.ID)
+	require.NoError(t, err)
+	require.True(t, entry.CategoryID.Valid)
+	require.Equal(t, rent.ID, entry.CategoryID.Int64)
+
+	// overridden entries are left untouched by the rules
+	entry, err = testQueries.SetEntryCategory(context.Background(), SetEntryCategoryParams{
+		ID:                 entry.ID,
+		CategoryID:         pgtype.Int8{Int64: groceries.ID, Valid: true},
+		CategoryOverridden: true,
+	})
+	require.NoError(t, err)
+
+	rows, err := testQueries.ApplyCategoryRulesByOwner(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Zero(t, rows)
+
+	entry, err = testQueries.GetEntry(context.Background(), entry.ID)
+	require.NoError(t, err)
+	require.Equal(t, groceries.ID, entry.CategoryID.Int64)
+
+	spending, err := testQueries.GetSpendingByCategory(context.Background(), GetSpendingByCategoryParams{
+		Owner:    user.Username,
+		FromTime: time.Now().Add(-time.Hour),
+		ToTime:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Len(t, spending, 1)
+	require.Equal(t, groceries.Name, spending[0].Category)
+	require.Equal(t, -entry.Amount, spending[0].Total)
+	require.Equal(t, int64(1), spending[0].Count)
+
+	monthly, err := testQueries.GetSpendingByMonth(context.Background(), GetSpendingByMonthParams{
+		Owner:    user.Username,
+		FromTime: time.Now().Add(-time.Hour),
+		ToTime:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Len(t, monthly, 1)
+	require.Equal(t, account.Currency, monthly[0].Currency)
+	require.Equal(t, -entry.Amount, monthly[0].Total)
+}
+
+func TestApplyCategoryRulesLiteralPattern(t *testing.T) {
+	user, _ := createRandomUser(t)
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: utils.RandomCurrency(),
+	})
+	require.NoError(t, err)
+
+	category := createRandomCategory(t, user.Username)
+	for _, pattern := range []string{"50%", "a_b", `c\d`} {
+		_, err = testQueries.CreateCategoryRule(context.Background(), CreateCategoryRuleParams{
+			Owner:      user.Username,
+			CategoryID: category.ID,
+			Field:      "memo",
+			Pattern:    pattern,
+		})
+		require.NoError(t, err)
+	}
+
+	testCases := []struct {
+		memo    string
+		matches bool
+	}{
+		{memo: "50% off", matches: true},
+		{memo: "500 off", matches: false},
+		{memo: "pay a_b", matches: true},
+		{memo: "pay axb", matches: false},
+		{memo: `c\d receipt`, matches: true},
+		{memo: "cd receipt", matches: false},
+	}
+
+	for _, tc := range testCases {
+		entry, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    -utils.RandomMoney(),
+			Memo:      tc.memo,
+		})
+		require.NoError(t, err)
+
+		entry, err = testQueries.ApplyCategoryRules(context.Background(), entry.ID)
+		require.NoError(t, err)
+		require.Equal(t, tc.matches, entry.CategoryID.Valid, tc.memo)
+	}
+}
+
+func TestGetSpendingExcludesSavingsAndAdjustments(t *testing.T) {
+	store := NewStore(testPool)
+	user, _ := createRandomUser(t)
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  1000,
+		Currency: utils.RandomCurrency(),
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.CreateEntry(context.Background(), CreateEntryParams{
+		AccountID: account.ID,
+		Amount:    -30,
+		Memo:      "groceries",
+	})
+	require.NoError(t, err)
+
+	// the money put into a goal stays the user's
+	goal := createRandomGoal(t, account, 0)
+	_, err = store.GoalTransferTx(context.Background(), GoalTransferTxParams{GoalID: goal.ID, Amount: 100})
+	require.NoError(t, err)
+
+	// the corrections of the admins are no spending either
+	_, err = store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{
+		ID:     account.ID,
+		Amount: -50,
+		Reason: "duplicate deposit",
+	})
+	require.NoError(t, err)
+
+	arg := GetSpendingByCategoryParams{
+		Owner:    user.Username,
+		FromTime: time.Now().Add(-time.Hour),
+		ToTime:   time.Now().Add(time.Hour),
+	}
+	spending, err := testQueries.GetSpendingByCategory(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, spending, 1)
+	require.Equal(t, int64(30), spending[0].Total)
+	require.Equal(t, int64(1), spending[0].Count)
+
+	monthly, err := testQueries.GetSpendingByMonth(context.Background(), GetSpendingByMonthParams(arg))
+	require.NoError(t, err)
+	require.Len(t, monthly, 1)
+	require.Equal(t, int64(30), monthly[0].Total)
+}
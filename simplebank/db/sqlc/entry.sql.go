@@ -1,36 +1,116 @@
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
-//   sqlc v1.14.0
+//   sqlc v1.18.0
 // source: entry.sql
 
 package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const applyCategoryRules = `-- name: ApplyCategoryRules :one
+UPDATE entries e
+set category_id = (
+  SELECT r.category_id FROM category_rules r
+  JOIN accounts a ON a.owner = r.owner
+  WHERE a.id = e.account_id
+    AND (
+      (r.field = 'memo' AND e.memo ILIKE '%' || replace(replace(replace(r.pattern, '\', '\\'), '%', '\%'), '_', '\_') || '%')
+      OR (r.field = 'counterparty' AND e.counterparty ILIKE '%' || replace(replace(replace(r.pattern, '\', '\\'), '%', '\%'), '_', '\_') || '%')
+    )
+  ORDER BY r.priority DESC, r.id
+  LIMIT 1
+)
+WHERE e.id = $1 AND NOT e.category_overridden
+RETURNING id, account_id, amount, created_at, memo, counterparty, category_id, category_overridden, goal_id, is_adjustment
+`
+
+// The wildcards and the escape character of the patterns are escaped, so that
+// the patterns only match as plain text.
+func (q *Queries) ApplyCategoryRules(ctx context.Context, id int64) (Entry, error) {
+	row := q.db.QueryRow(ctx, applyCategoryRules, id)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Memo,
+		&i.Counterparty,
+		&i.CategoryID,
+		&i.CategoryOverridden,
+		&i.GoalID,
+		&i.IsAdjustment,
+	)
+	return i, err
+}
+
+const applyCategoryRulesByOwner = `-- name: ApplyCategoryRulesByOwner :execrows
+UPDATE entries e
+set category_id = (
+  SELECT r.category_id FROM category_rules r
+  WHERE r.owner = $1
+    AND (
+      (r.field = 'memo' AND e.memo ILIKE '%' || replace(replace(replace(r.pattern, '\', '\\'), '%', '\%'), '_', '\_') || '%')
+      OR (r.field = 'counterparty' AND e.counterparty ILIKE '%' || replace(replace(replace(r.pattern, '\', '\\'), '%', '\%'), '_', '\_') || '%')
+    )
+  ORDER BY r.priority DESC, r.id
+  LIMIT 1
 )
+FROM accounts a
+WHERE a.id = e.account_id AND a.owner = $1 AND NOT e.category_overridden
+`
+
+func (q *Queries) ApplyCategoryRulesByOwner(ctx context.Context, owner string) (int64, error) {
+	result, err := q.db.Exec(ctx, applyCategoryRulesByOwner, owner)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
 
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
-  account_id, amount
+  account_id, amount, memo, counterparty, goal_id, is_adjustment
 ) VALUES (
-  $1, $2
+  $1, $2, $3, $4, $5, $6
 )
-RETURNING id, account_id, amount, created_at
+RETURNING id, account_id, amount, created_at, memo, counterparty, category_id, category_overridden, goal_id, is_adjustment
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID    int64       `json:"account_id"`
+	Amount       int64       `json:"amount"`
+	Memo         string      `json:"memo"`
+	Counterparty string      `json:"counterparty"`
+	GoalID       pgtype.Int8 `json:"goal_id"`
+	IsAdjustment bool        `json:"is_adjustment"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRow(ctx, createEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.Memo,
+		arg.Counterparty,
+		arg.GoalID,
+		arg.IsAdjustment,
+	)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Memo,
+		&i.Counterparty,
+		&i.CategoryID,
+		&i.CategoryOverridden,
+		&i.GoalID,
+		&i.IsAdjustment,
 	)
 	return i, err
 }
@@ -41,29 +121,35 @@ WHERE id = $1
 `
 
 func (q *Queries) DeleteEntry(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteEntry, id)
+	_, err := q.db.Exec(ctx, deleteEntry, id)
 	return err
 }
 
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, memo, counterparty, category_id, category_overridden, goal_id, is_adjustment FROM entries
 WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, getEntry, id)
+	row := q.db.QueryRow(ctx, getEntry, id)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Memo,
+		&i.Counterparty,
+		&i.CategoryID,
+		&i.CategoryOverridden,
+		&i.GoalID,
+		&i.IsAdjustment,
 	)
 	return i, err
 }
 
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, memo, counterparty, category_id, category_overridden, goal_id, is_adjustment FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
@@ -77,7 +163,7 @@ type ListEntriesParams struct {
 }
 
 func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
-	rows, err := q.db.QueryContext(ctx, listEntries, arg.AccountID, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listEntries, arg.AccountID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -90,25 +176,59 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.Memo,
+			&i.Counterparty,
+			&i.CategoryID,
+			&i.CategoryOverridden,
+			&i.GoalID,
+			&i.IsAdjustment,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return items, nil
 }
 
+const setEntryCategory = `-- name: SetEntryCategory :one
+UPDATE entries
+set category_id = $1, category_overridden = $2
+WHERE id = $3
+RETURNING id, account_id, amount, created_at, memo, counterparty, category_id, category_overridden, goal_id, is_adjustment
+`
+
+type SetEntryCategoryParams struct {
+	CategoryID         pgtype.Int8 `json:"category_id"`
+	CategoryOverridden bool        `json:"category_overridden"`
+	ID                 int64       `json:"id"`
+}
+
+func (q *Queries) SetEntryCategory(ctx context.Context, arg SetEntryCategoryParams) (Entry, error) {
+	row := q.db.QueryRow(ctx, setEntryCategory, arg.CategoryID, arg.CategoryOverridden, arg.ID)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Memo,
+		&i.Counterparty,
+		&i.CategoryID,
+		&i.CategoryOverridden,
+		&i.GoalID,
+		&i.IsAdjustment,
+	)
+	return i, err
+}
+
 const updateEntry = `-- name: UpdateEntry :one
 UPDATE entries
 set amount = $2
 WHERE id = $1
-RETURNING id, account_id, amount, created_at
+RETURNING id, account_id, amount, created_at, memo, counterparty, category_id, category_overridden, goal_id, is_adjustment
 `
 
 type UpdateEntryParams struct {
@@ -117,13 +237,19 @@ type UpdateEntryParams struct {
 }
 
 func (q *Queries) UpdateEntry(ctx context.Context, arg UpdateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, updateEntry, arg.ID, arg.Amount)
+	row := q.db.QueryRow(ctx, updateEntry, arg.ID, arg.Amount)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Memo,
+		&i.Counterparty,
+		&i.CategoryID,
+		&i.CategoryOverridden,
+		&i.GoalID,
+		&i.IsAdjustment,
 	)
 	return i, err
 }
@@ -0,0 +1,73 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: entry_tag.sql
+
+package db
+
+import (
+	"context"
+)
+
+const addEntryTag = `-- name: AddEntryTag :one
+INSERT INTO entry_tags (
+  entry_id, tag
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (entry_id, tag) DO UPDATE SET tag = EXCLUDED.tag
+RETURNING entry_id, tag, created_at
+`
+
+type AddEntryTagParams struct {
+	EntryID int64  `json:"entry_id"`
+	Tag     string `json:"tag"`
+}
+
+func (q *Queries) AddEntryTag(ctx context.Context, arg AddEntryTagParams) (EntryTag, error) {
+	row := q.db.QueryRow(ctx, addEntryTag, arg.EntryID, arg.Tag)
+	var i EntryTag
+	err := row.Scan(&i.EntryID, &i.Tag, &i.CreatedAt)
+	return i, err
+}
+
+const deleteEntryTag = `-- name: DeleteEntryTag :exec
+DELETE FROM entry_tags
+WHERE entry_id = $1 AND tag = $2
+`
+
+type DeleteEntryTagParams struct {
+	EntryID int64  `json:"entry_id"`
+	Tag     string `json:"tag"`
+}
+
+func (q *Queries) DeleteEntryTag(ctx context.Context, arg DeleteEntryTagParams) error {
+	_, err := q.db.Exec(ctx, deleteEntryTag, arg.EntryID, arg.Tag)
+	return err
+}
+
+const listEntryTags = `-- name: ListEntryTags :many
+SELECT entry_id, tag, created_at FROM entry_tags
+WHERE entry_id = $1
+ORDER BY tag
+`
+
+func (q *Queries) ListEntryTags(ctx context.Context, entryID int64) ([]EntryTag, error) {
+	rows, err := q.db.Query(ctx, listEntryTags, entryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []EntryTag{}
+	for rows.Next() {
+		var i EntryTag
+		if err := rows.Scan(&i.EntryID, &i.Tag, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
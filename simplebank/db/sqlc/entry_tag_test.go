@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntryTags(t *testing.T) {
+	account, _ := createRandomAccount(t)
+	entry, _ := createRandomEntry(t, account)
+
+	for _, tag := range []string{"travel", "holidays", "travel"} {
+		entryTag, err := testQueries.AddEntryTag(context.Background(), AddEntryTagParams{
+			EntryID: entry.ID,
+			Tag:     tag,
+		})
+		require.NoError(t, err)
+		require.Equal(t, entry.ID, entryTag.EntryID)
+		require.Equal(t, tag, entryTag.Tag)
+	}
+
+	tags, err := testQueries.ListEntryTags(context.Background(), entry.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	require.Equal(t, "holidays", tags[0].Tag)
+	require.Equal(t, "travel", tags[1].Tag)
+
+	err = testQueries.DeleteEntryTag(context.Background(), DeleteEntryTagParams{
+		EntryID: entry.ID,
+		Tag:     "travel",
+	})
+	require.NoError(t, err)
+
+	tags, err = testQueries.ListEntryTags(context.Background(), entry.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+}
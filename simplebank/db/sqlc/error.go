@@ -0,0 +1,28 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes of the constraint violations the API reports to the clients.
+const (
+	ForeignKeyViolation = "23503"
+	UniqueViolation     = "23505"
+	CheckViolation      = "23514"
+)
+
+// ErrRecordNotFound is returned when a query expecting one row finds none.
+var ErrRecordNotFound = pgx.ErrNoRows
+
+// ErrorCode returns the SQLSTATE code of a database error, or an empty string
+// when err doesn't come from the database.
+func ErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
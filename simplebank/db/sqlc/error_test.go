@@ -0,0 +1,17 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorCode(t *testing.T) {
+	require.Equal(t, UniqueViolation, ErrorCode(&pgconn.PgError{Code: UniqueViolation}))
+	require.Equal(t, ForeignKeyViolation, ErrorCode(fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: ForeignKeyViolation})))
+	require.Empty(t, ErrorCode(ErrRecordNotFound))
+	require.Empty(t, ErrorCode(errors.New("not a database error")))
+}
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Domain events written to the outbox within the transaction of the change.
+const (
+	EventTransferCreated       = "transfer.created"
+	EventAccountCreated        = "account.created"
+	EventAccountBalanceChanged = "account.balance_changed"
+)
+
+// EventTypes returns the event types a webhook can subscribe to.
+func EventTypes() []string {
+	return []string{EventTransferCreated, EventAccountCreated, EventAccountBalanceChanged}
+}
+
+// BalanceChangedEvent is the payload of the account.balance_changed event.
+type BalanceChangedEvent struct {
+	Account Account `json:"account"`
+	Amount  int64   `json:"amount"`
+}
+
+// publishEvent writes an event for the owner into the outbox.
+func publishEvent(ctx context.Context, q *Queries, owner string, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot marshal %s event: %w", eventType, err)
+	}
+
+	_, err = q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		Owner:     owner,
+		EventType: eventType,
+		Payload:   data,
+	})
+	return err
+}
+
+func publishBalanceChanged(ctx context.Context, q *Queries, account Account, amount int64) error {
+	return publishEvent(ctx, q, account.Owner, EventAccountBalanceChanged, BalanceChangedEvent{
+		Account: account,
+		Amount:  amount,
+	})
+}
+
+// CreateAccountTx creates an account, records it in the audit log and
+// publishes the account.created event.
+func (store *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, TxCreateAccount, func(q *Queries) error {
+		var err error
+
+		account, err = q.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		err = recordAudit(ctx, q, AuditAccountCreate, "account", account.ID, nil, account, "")
+		if err != nil {
+			return err
+		}
+
+		return publishEvent(ctx, q, account.Owner, EventAccountCreated, account)
+	})
+
+	return account, err
+}
+
+// ErrVersionMismatch is returned when the account was changed since the
+// version the caller expected.
+var ErrVersionMismatch = errors.New("account version mismatch")
+
+// AddAccountBalanceTxParams contains the input parameters of the add account balance transaction.
+type AddAccountBalanceTxParams struct {
+	ID     int64 `json:"id"`
+	Amount int64 `json:"amount"`
+	// IfVersion, when not zero, is the version the account must still have.
+	IfVersion int64 `json:"if_version"`
+	// Reason, when set, makes the change an adjustment recorded with its reason.
+	Reason string `json:"reason"`
+}
+
+// AddAccountBalanceTx updates the balance of an account, writes the entry of
+// the debit, credit or adjustment, so that the balance history built from the
+// entries stays in line with the balance, records it in the audit log and
+// publishes the account.balance_changed event.
+// It returns ErrVersionMismatch when the account is no longer at arg.IfVersion.
+func (store *SQLStore) AddAccountBalanceTx(ctx context.Context, arg AddAccountBalanceTxParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, TxAddAccountBalance, func(q *Queries) error {
+		before, err := q.GetAccountForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+		if arg.IfVersion != 0 && before.Version != arg.IfVersion {
+			return ErrVersionMismatch
+		}
+
+		account, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+			ID:     arg.ID,
+			Amount: arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = createCategorizedEntry(ctx, q, CreateEntryParams{
+			AccountID:    arg.ID,
+			Amount:       arg.Amount,
+			Memo:         arg.Reason,
+			IsAdjustment: arg.Reason != "",
+		})
+		if err != nil {
+			return err
+		}
+
+		action := AuditAccountCredit
+		switch {
+		case arg.Reason != "":
+			action = AuditAccountAdjust
+		case arg.Amount < 0:
+			action = AuditAccountDebit
+		}
+		err = recordAudit(ctx, q, action, "account", account.ID, before, account, arg.Reason)
+		if err != nil {
+			return err
+		}
+
+		return publishBalanceChanged(ctx, q, account, arg.Amount)
+	})
+
+	return account, err
+}
+
+// DeleteAccountTxParams contains the input parameters of the delete account transaction.
+type DeleteAccountTxParams struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	// IfVersion, when not zero, is the version the account must still have.
+	IfVersion int64 `json:"if_version"`
+}
+
+// DeleteAccountTx deletes an account of the owner and records it in the audit log.
+// The account is closed rather than removed, so that its entries and transfers
+// are kept, and is no longer found by the queries of the accounts.
+// It returns ErrRecordNotFound when the owner has no such account, and
+// ErrVersionMismatch when the account is no longer at arg.IfVersion.
+func (store *SQLStore) DeleteAccountTx(ctx context.Context, arg DeleteAccountTxParams) error {
+	return store.execTx(ctx, TxDeleteAccount, func(q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+		if account.Owner != arg.Owner {
+			return ErrRecordNotFound
+		}
+		if arg.IfVersion != 0 && account.Version != arg.IfVersion {
+			return ErrVersionMismatch
+		}
+
+		closed, err := q.CloseAccount(ctx, account.ID)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, AuditAccountDelete, "account", account.ID, account, closed, "")
+	})
+}
+
+// DispatchOutboxTx creates the webhook deliveries of up to limit pending outbox
+// events and marks them as dispatched. It returns the number of dispatched events.
+// Concurrent dispatchers skip the events locked by each other.
+func (store *SQLStore) DispatchOutboxTx(ctx context.Context, limit int32) (int, error) {
+	var dispatched int
+
+	err := store.execTx(ctx, TxDispatchOutbox, func(q *Queries) error {
+		events, err := q.ListUndispatchedOutboxEvents(ctx, limit)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			_, err = q.CreateWebhookDeliveries(ctx, CreateWebhookDeliveriesParams{
+				EventID:   event.ID,
+				Owner:     event.Owner,
+				EventType: event.EventType,
+			})
+			if err != nil {
+				return err
+			}
+
+			err = q.MarkOutboxEventDispatched(ctx, event.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		dispatched = len(events)
+		return nil
+	})
+
+	return dispatched, err
+}
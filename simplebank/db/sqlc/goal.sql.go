@@ -0,0 +1,318 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: goal.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const addGoalBalance = `-- name: AddGoalBalance :one
+UPDATE goals
+set balance = balance + $1
+WHERE id = $2
+RETURNING id, account_id, name, target_amount, balance, deadline, round_up_unit, contribution_amount, contribution_interval, next_contribution_at, created_at
+`
+
+type AddGoalBalanceParams struct {
+	Amount int64 `json:"amount"`
+	ID     int64 `json:"id"`
+}
+
+func (q *Queries) AddGoalBalance(ctx context.Context, arg AddGoalBalanceParams) (Goal, error) {
+	row := q.db.QueryRow(ctx, addGoalBalance, arg.Amount, arg.ID)
+	var i Goal
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Name,
+		&i.TargetAmount,
+		&i.Balance,
+		&i.Deadline,
+		&i.RoundUpUnit,
+		&i.ContributionAmount,
+		&i.ContributionInterval,
+		&i.NextContributionAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createGoal = `-- name: CreateGoal :one
+INSERT INTO goals (
+  account_id,
+  name,
+  target_amount,
+  deadline,
+  round_up_unit,
+  contribution_amount,
+  contribution_interval,
+  next_contribution_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+)
+RETURNING id, account_id, name, target_amount, balance, deadline, round_up_unit, contribution_amount, contribution_interval, next_contribution_at, created_at
+`
+
+type CreateGoalParams struct {
+	AccountID            int64              `json:"account_id"`
+	Name                 string             `json:"name"`
+	TargetAmount         int64              `json:"target_amount"`
+	Deadline             pgtype.Timestamptz `json:"deadline"`
+	RoundUpUnit          int64              `json:"round_up_unit"`
+	ContributionAmount   int64              `json:"contribution_amount"`
+	ContributionInterval string             `json:"contribution_interval"`
+	NextContributionAt   pgtype.Timestamptz `json:"next_contribution_at"`
+}
+
+func (q *Queries) CreateGoal(ctx context.Context, arg CreateGoalParams) (Goal, error) {
+	row := q.db.QueryRow(ctx, createGoal,
+		arg.AccountID,
+		arg.Name,
+		arg.TargetAmount,
+		arg.Deadline,
+		arg.RoundUpUnit,
+		arg.ContributionAmount,
+		arg.ContributionInterval,
+		arg.NextContributionAt,
+	)
+	var i Goal
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Name,
+		&i.TargetAmount,
+		&i.Balance,
+		&i.Deadline,
+		&i.RoundUpUnit,
+		&i.ContributionAmount,
+		&i.ContributionInterval,
+		&i.NextContributionAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteGoal = `-- name: DeleteGoal :exec
+DELETE FROM goals
+WHERE id = $1
+`
+
+func (q *Queries) DeleteGoal(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, deleteGoal, id)
+	return err
+}
+
+const getGoal = `-- name: GetGoal :one
+SELECT id, account_id, name, target_amount, balance, deadline, round_up_unit, contribution_amount, contribution_interval, next_contribution_at, created_at FROM goals
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetGoal(ctx context.Context, id int64) (Goal, error) {
+	row := q.db.QueryRow(ctx, getGoal, id)
+	var i Goal
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Name,
+		&i.TargetAmount,
+		&i.Balance,
+		&i.Deadline,
+		&i.RoundUpUnit,
+		&i.ContributionAmount,
+		&i.ContributionInterval,
+		&i.NextContributionAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getGoalForUpdate = `-- name: GetGoalForUpdate :one
+SELECT id, account_id, name, target_amount, balance, deadline, round_up_unit, contribution_amount, contribution_interval, next_contribution_at, created_at FROM goals
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetGoalForUpdate(ctx context.Context, id int64) (Goal, error) {
+	row := q.db.QueryRow(ctx, getGoalForUpdate, id)
+	var i Goal
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Name,
+		&i.TargetAmount,
+		&i.Balance,
+		&i.Deadline,
+		&i.RoundUpUnit,
+		&i.ContributionAmount,
+		&i.ContributionInterval,
+		&i.NextContributionAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getRoundUpGoal = `-- name: GetRoundUpGoal :one
+SELECT id, account_id, name, target_amount, balance, deadline, round_up_unit, contribution_amount, contribution_interval, next_contribution_at, created_at FROM goals
+WHERE account_id = $1 AND round_up_unit > 0
+LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetRoundUpGoal(ctx context.Context, accountID int64) (Goal, error) {
+	row := q.db.QueryRow(ctx, getRoundUpGoal, accountID)
+	var i Goal
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Name,
+		&i.TargetAmount,
+		&i.Balance,
+		&i.Deadline,
+		&i.RoundUpUnit,
+		&i.ContributionAmount,
+		&i.ContributionInterval,
+		&i.NextContributionAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listDueGoalContributions = `-- name: ListDueGoalContributions :many
+SELECT goals.id, goals.account_id, goals.name, goals.target_amount, goals.balance, goals.deadline, goals.round_up_unit, goals.contribution_amount, goals.contribution_interval, goals.next_contribution_at, goals.created_at FROM goals
+JOIN accounts ON accounts.id = goals.account_id
+WHERE goals.contribution_amount > 0
+  AND goals.next_contribution_at <= $1::timestamptz
+  AND accounts.closed_at IS NULL
+ORDER BY goals.next_contribution_at
+LIMIT $2
+`
+
+type ListDueGoalContributionsParams struct {
+	Now        time.Time `json:"now"`
+	LimitCount int32     `json:"limit_count"`
+}
+
+func (q *Queries) ListDueGoalContributions(ctx context.Context, arg ListDueGoalContributionsParams) ([]Goal, error) {
+	rows, err := q.db.Query(ctx, listDueGoalContributions, arg.Now, arg.LimitCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Goal{}
+	for rows.Next() {
+		var i Goal
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Name,
+			&i.TargetAmount,
+			&i.Balance,
+			&i.Deadline,
+			&i.RoundUpUnit,
+			&i.ContributionAmount,
+			&i.ContributionInterval,
+			&i.NextContributionAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGoalsByOwner = `-- name: ListGoalsByOwner :many
+SELECT goals.id, goals.account_id, goals.name, goals.target_amount, goals.balance, goals.deadline, goals.round_up_unit, goals.contribution_amount, goals.contribution_interval, goals.next_contribution_at, goals.created_at FROM goals
+JOIN accounts ON accounts.id = goals.account_id
+WHERE accounts.owner = $1
+ORDER BY goals.id
+LIMIT $2
+OFFSET $3
+`
+
+type ListGoalsByOwnerParams struct {
+	Owner  string `json:"owner"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+func (q *Queries) ListGoalsByOwner(ctx context.Context, arg ListGoalsByOwnerParams) ([]Goal, error) {
+	rows, err := q.db.Query(ctx, listGoalsByOwner, arg.Owner, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Goal{}
+	for rows.Next() {
+		var i Goal
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Name,
+			&i.TargetAmount,
+			&i.Balance,
+			&i.Deadline,
+			&i.RoundUpUnit,
+			&i.ContributionAmount,
+			&i.ContributionInterval,
+			&i.NextContributionAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const scheduleNextGoalContribution = `-- name: ScheduleNextGoalContribution :one
+UPDATE goals
+set next_contribution_at = (
+  SELECT MIN(scheduled) FROM generate_series(
+    next_contribution_at,
+    $1::timestamptz + ('1 ' || contribution_interval)::interval,
+    ('1 ' || contribution_interval)::interval
+  ) AS scheduled
+  WHERE scheduled > $1::timestamptz
+)
+WHERE id = $2
+RETURNING id, account_id, name, target_amount, balance, deadline, round_up_unit, contribution_amount, contribution_interval, next_contribution_at, created_at
+`
+
+type ScheduleNextGoalContributionParams struct {
+	Now time.Time `json:"now"`
+	ID  int64     `json:"id"`
+}
+
+// The next contribution is the first one of the schedule after now, the
+// contributions missed meanwhile aren't made up.
+func (q *Queries) ScheduleNextGoalContribution(ctx context.Context, arg ScheduleNextGoalContributionParams) (Goal, error) {
+	row := q.db.QueryRow(ctx, scheduleNextGoalContribution, arg.Now, arg.ID)
+	var i Goal
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Name,
+		&i.TargetAmount,
+		&i.Balance,
+		&i.Deadline,
+		&i.RoundUpUnit,
+		&i.ContributionAmount,
+		&i.ContributionInterval,
+		&i.NextContributionAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
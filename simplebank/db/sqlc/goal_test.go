@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomGoal(t *testing.T, account Account, roundUpUnit int64) Goal {
+	arg := CreateGoalParams{
+		AccountID:    account.ID,
+		Name:         utils.RandomString(8),
+		TargetAmount: utils.RandomInt(1000, 5000),
+		RoundUpUnit:  roundUpUnit,
+	}
+
+	goal, err := testQueries.CreateGoal(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.AccountID, goal.AccountID)
+	require.Equal(t, arg.Name, goal.Name)
+	require.Equal(t, arg.TargetAmount, goal.TargetAmount)
+	require.Zero(t, goal.Balance)
+	require.NotZero(t, goal.ID)
+
+	return goal
+}
+
+func TestGoalTransferTx(t *testing.T) {
+	store := NewStore(testPool)
+	account, _ := createRandomAccount(t)
+	goal := createRandomGoal(t, account, 0)
+
+	amount := account.Balance / 2
+	result, err := store.GoalTransferTx(context.Background(), GoalTransferTxParams{
+		GoalID: goal.ID,
+		Amount: amount,
+	})
+	require.NoError(t, err)
+	require.Equal(t, amount, result.Goal.Balance)
+	require.Equal(t, account.Balance-amount, result.Account.Balance)
+	require.Equal(t, -amount, result.Entry.Amount)
+	require.Equal(t, goal.ID, result.Entry.GoalID.Int64)
+
+	// can't take more than the goal holds
+	_, err = store.GoalTransferTx(context.Background(), GoalTransferTxParams{
+		GoalID: goal.ID,
+		Amount: -amount - 1,
+	})
+	require.True(t, errors.Is(err, ErrInsufficientFunds))
+
+	// closing the goal gives the money back
+	updatedAccount, err := store.DeleteGoalTx(context.Background(), goal.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, updatedAccount.Balance)
+
+	_, err = testQueries.GetGoal(context.Background(), goal.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestTransferTxRoundUp(t *testing.T) {
+	store := NewStore(testPool)
+	account1, _ := createRandomAccount(t)
+	account2, _ := createRandomAccount(t)
+	account1, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account1.ID,
+		Balance: 1000,
+	})
+	require.NoError(t, err)
+
+	goal := createRandomGoal(t, account1, 100)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        130,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result.RoundUpGoal)
+	require.Equal(t, goal.ID, result.RoundUpGoal.ID)
+	require.Equal(t, int64(70), result.RoundUpGoal.Balance)
+	require.Equal(t, int64(800), result.FromAccount.Balance)
+}
+
+func TestContributeGoalTx(t *testing.T) {
+	store := NewStore(testPool)
+	account, _ := createRandomAccount(t)
+
+	next := time.Now().Add(-time.Minute)
+	goal, err := testQueries.CreateGoal(context.Background(), CreateGoalParams{
+		AccountID:            account.ID,
+		Name:                 utils.RandomString(8),
+		TargetAmount:         1000,
+		ContributionAmount:   account.Balance + 1,
+		ContributionInterval: "week",
+		NextContributionAt:   pgtype.Timestamptz{Time: next, Valid: true},
+	})
+	require.NoError(t, err)
+
+	due, err := testQueries.ListDueGoalContributions(context.Background(), ListDueGoalContributionsParams{
+		Now:        time.Now(),
+		LimitCount: 1000,
+	})
+	require.NoError(t, err)
+	var found bool
+	for _, g := range due {
+		found = found || g.ID == goal.ID
+	}
+	require.True(t, found)
+
+	// the account can't afford the contribution
+	result, err := store.ContributeGoalTx(context.Background(), goal.ID)
+	require.NoError(t, err)
+	require.True(t, result.Skipped)
+	require.Zero(t, result.Goal.Balance)
+	require.WithinDuration(t, next.AddDate(0, 0, 7), result.Goal.NextContributionAt.Time, time.Second)
+
+	// the contribution was made, a concurrent run doesn't make it again
+	_, err = store.ContributeGoalTx(context.Background(), goal.ID)
+	require.ErrorIs(t, err, ErrGoalNotDue)
+}
+
+func TestContributeGoalTxCatchUp(t *testing.T) {
+	store := NewStore(testPool)
+	account, _ := createRandomAccount(t)
+
+	// three contributions were missed
+	next := time.Now().Add(-15 * 24 * time.Hour)
+	goal, err := testQueries.CreateGoal(context.Background(), CreateGoalParams{
+		AccountID:            account.ID,
+		Name:                 utils.RandomString(8),
+		TargetAmount:         1000,
+		ContributionAmount:   1,
+		ContributionInterval: "week",
+		NextContributionAt:   pgtype.Timestamptz{Time: next, Valid: true},
+	})
+	require.NoError(t, err)
+
+	// only one is made, and the next one is the first after now
+	result, err := store.ContributeGoalTx(context.Background(), goal.ID)
+	require.NoError(t, err)
+	require.False(t, result.Skipped)
+	require.Equal(t, int64(1), result.Goal.Balance)
+	require.WithinDuration(t, next.AddDate(0, 0, 21), result.Goal.NextContributionAt.Time, time.Second)
+	require.True(t, result.Goal.NextContributionAt.Time.After(time.Now()))
+}
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrInsufficientFunds is returned when the account or the goal doesn't hold
+// enough money for the requested movement.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrGoalNotDue is returned when the contribution of a goal isn't due.
+var ErrGoalNotDue = errors.New("goal contribution not due")
+
+// GoalTransferTxParams contains the input parameters of the goal transfer transaction.
+// A positive amount moves money from the account into the goal, a negative one
+// gives it back to the account.
+type GoalTransferTxParams struct {
+	GoalID int64 `json:"goal_id"`
+	Amount int64 `json:"amount"`
+}
+
+// GoalTransferTxResult is the result of the goal transfer transaction.
+type GoalTransferTxResult struct {
+	Goal    Goal    `json:"goal"`
+	Account Account `json:"account"`
+	Entry   Entry   `json:"entry"`
+	// Skipped is set when a scheduled contribution was not made for lack of funds.
+	Skipped bool `json:"skipped"`
+}
+
+// GoalTransferTx moves money between an account and one of its goals, and
+// records the movement as an account entry.
+func (store *SQLStore) GoalTransferTx(ctx context.Context, arg GoalTransferTxParams) (GoalTransferTxResult, error) {
+	var result GoalTransferTxResult
+
+	err := store.execTx(ctx, TxGoalTransfer, func(q *Queries) error {
+		goal, err := q.GetGoal(ctx, arg.GoalID)
+		if err != nil {
+			return err
+		}
+
+		result, err = moveToGoal(ctx, q, goal, arg.Amount, "goal: "+goal.Name)
+		return err
+	})
+
+	return result, err
+}
+
+// ContributeGoalTx makes the scheduled contribution of a goal and schedules the
+// next one after now. When the account can't afford it, the contribution is
+// skipped but the next one is still scheduled. Only one contribution is made
+// however many were missed, e.g. while the job was down.
+// It returns ErrGoalNotDue when the contribution was already made meanwhile
+// or is no longer scheduled.
+func (store *SQLStore) ContributeGoalTx(ctx context.Context, goalID int64) (GoalTransferTxResult, error) {
+	var result GoalTransferTxResult
+
+	err := store.execTx(ctx, TxContributeGoal, func(q *Queries) error {
+		goal, err := q.GetGoal(ctx, goalID)
+		if err != nil {
+			return err
+		}
+
+		// the goal is locked, after its account as in moveToGoal, before
+		// checking it is still due, so that concurrent runs contribute once
+		_, err = q.GetAccountForUpdate(ctx, goal.AccountID)
+		if err != nil {
+			return err
+		}
+		goal, err = q.GetGoalForUpdate(ctx, goalID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if goal.ContributionAmount <= 0 || !goal.NextContributionAt.Valid || goal.NextContributionAt.Time.After(now) {
+			return ErrGoalNotDue
+		}
+
+		result, err = moveToGoal(ctx, q, goal, goal.ContributionAmount, "contribution: "+goal.Name)
+		if errors.Is(err, ErrInsufficientFunds) {
+			result.Skipped = true
+		} else if err != nil {
+			return err
+		}
+
+		result.Goal, err = q.ScheduleNextGoalContribution(ctx, ScheduleNextGoalContributionParams{
+			ID:  goalID,
+			Now: now,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// DeleteGoalTx gives the goal balance back to its account and deletes the goal.
+func (store *SQLStore) DeleteGoalTx(ctx context.Context, goalID int64) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, TxDeleteGoal, func(q *Queries) error {
+		goal, err := q.GetGoal(ctx, goalID)
+		if err != nil {
+			return err
+		}
+
+		result, err := moveToGoal(ctx, q, goal, -goal.Balance, "goal closed: "+goal.Name)
+		if err != nil {
+			return err
+		}
+		account = result.Account
+
+		return q.DeleteGoal(ctx, goalID)
+	})
+
+	return account, err
+}
+
+// moveToGoal moves amount from the account of the goal into the goal. The
+// change of the account balance is recorded like the others, as a categorized
+// entry, in the audit log and by the account.balance_changed event.
+// The account is always locked before the goal, like in TransferTx, to avoid deadlocks.
+func moveToGoal(ctx context.Context, q *Queries, goal Goal, amount int64, memo string) (result GoalTransferTxResult, err error) {
+	result.Account, err = q.GetAccountForUpdate(ctx, goal.AccountID)
+	if err != nil {
+		return
+	}
+	before := result.Account
+	result.Goal, err = q.GetGoalForUpdate(ctx, goal.ID)
+	if err != nil {
+		return
+	}
+
+	if result.Account.Balance < amount || result.Goal.Balance < -amount {
+		err = fmt.Errorf("cannot move %d to goal %d: %w", amount, goal.ID, ErrInsufficientFunds)
+		return
+	}
+	if amount == 0 {
+		return
+	}
+
+	result.Account, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+		ID:     goal.AccountID,
+		Amount: -amount,
+	})
+	if err != nil {
+		return
+	}
+
+	result.Goal, err = q.AddGoalBalance(ctx, AddGoalBalanceParams{
+		ID:     goal.ID,
+		Amount: amount,
+	})
+	if err != nil {
+		return
+	}
+
+	result.Entry, err = createCategorizedEntry(ctx, q, CreateEntryParams{
+		AccountID: goal.AccountID,
+		Amount:    -amount,
+		Memo:      memo,
+		GoalID:    pgtype.Int8{Int64: goal.ID, Valid: true},
+	})
+	if err != nil {
+		return
+	}
+
+	err = recordAudit(ctx, q, AuditAccountGoal, "account", result.Account.ID, before, result.Account, "")
+	if err != nil {
+		return
+	}
+
+	err = publishBalanceChanged(ctx, q, result.Account, -amount)
+	return
+}
+
+// roundUpToGoal moves the difference between the amount and the next multiple
+// of the round-up unit into the round-up goal of the account, if any.
+// Nothing happens when the account can't afford it.
+func roundUpToGoal(ctx context.Context, q *Queries, accountID int64, amount int64) (*Goal, Account, error) {
+	goal, err := q.GetRoundUpGoal(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			err = nil
+		}
+		return nil, Account{}, err
+	}
+
+	roundUp := (goal.RoundUpUnit - amount%goal.RoundUpUnit) % goal.RoundUpUnit
+	result, err := moveToGoal(ctx, q, goal, roundUp, "round-up: "+goal.Name)
+	if err != nil {
+		if errors.Is(err, ErrInsufficientFunds) {
+			err = nil
+		}
+		return nil, Account{}, err
+	}
+	return &result.Goal, result.Account, nil
+}
@@ -0,0 +1,120 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: login_failure.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const deleteLoginFailure = `-- name: DeleteLoginFailure :exec
+DELETE FROM login_failures
+WHERE key = $1
+`
+
+func (q *Queries) DeleteLoginFailure(ctx context.Context, key string) error {
+	_, err := q.db.Exec(ctx, deleteLoginFailure, key)
+	return err
+}
+
+const forgiveLoginFailure = `-- name: ForgiveLoginFailure :exec
+UPDATE login_failures
+SET
+  failed_attempts = failed_attempts - 1,
+  locked_until = CASE
+    WHEN failed_attempts - 1 < $1::int THEN NULL
+    ELSE locked_until
+  END
+WHERE key = $2 AND failed_attempts > 0
+`
+
+type ForgiveLoginFailureParams struct {
+	MaxFailures int32  `json:"max_failures"`
+	Key         string `json:"key"`
+}
+
+// Takes back the failure counted before the credentials were found right, and
+// lifts the lockout when the count is below max_failures again.
+func (q *Queries) ForgiveLoginFailure(ctx context.Context, arg ForgiveLoginFailureParams) error {
+	_, err := q.db.Exec(ctx, forgiveLoginFailure, arg.MaxFailures, arg.Key)
+	return err
+}
+
+const getLoginFailure = `-- name: GetLoginFailure :one
+SELECT key, failed_attempts, locked_until, updated_at FROM login_failures
+WHERE key = $1 LIMIT 1
+`
+
+func (q *Queries) GetLoginFailure(ctx context.Context, key string) (LoginFailure, error) {
+	row := q.db.QueryRow(ctx, getLoginFailure, key)
+	var i LoginFailure
+	err := row.Scan(
+		&i.Key,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const lockLogin = `-- name: LockLogin :one
+UPDATE login_failures
+SET locked_until = $1
+WHERE key = $2
+RETURNING key, failed_attempts, locked_until, updated_at
+`
+
+type LockLoginParams struct {
+	LockedUntil pgtype.Timestamptz `json:"locked_until"`
+	Key         string             `json:"key"`
+}
+
+func (q *Queries) LockLogin(ctx context.Context, arg LockLoginParams) (LoginFailure, error) {
+	row := q.db.QueryRow(ctx, lockLogin, arg.LockedUntil, arg.Key)
+	var i LoginFailure
+	err := row.Scan(
+		&i.Key,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const recordLoginFailure = `-- name: RecordLoginFailure :one
+INSERT INTO login_failures (
+  key, failed_attempts
+) VALUES (
+  $1, 1
+)
+ON CONFLICT (key) DO UPDATE SET
+  failed_attempts = CASE
+    WHEN login_failures.updated_at < $2 THEN 1
+    ELSE login_failures.failed_attempts + 1
+  END,
+  updated_at = now()
+RETURNING key, failed_attempts, locked_until, updated_at
+`
+
+type RecordLoginFailureParams struct {
+	Key         string    `json:"key"`
+	ResetBefore time.Time `json:"reset_before"`
+}
+
+// The count starts over when the previous failure is older than reset_before.
+func (q *Queries) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginFailure, error) {
+	row := q.db.QueryRow(ctx, recordLoginFailure, arg.Key, arg.ResetBefore)
+	var i LoginFailure
+	err := row.Scan(
+		&i.Key,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginFailure(t *testing.T) {
+	ctx := context.Background()
+	key := "user:" + utils.RandomOwner()
+	resetBefore := time.Now().Add(-time.Hour)
+
+	_, err := testQueries.GetLoginFailure(ctx, key)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	for i := int32(1); i <= 2; i++ {
+		failure, err := testQueries.RecordLoginFailure(ctx, RecordLoginFailureParams{Key: key, ResetBefore: resetBefore})
+		require.NoError(t, err)
+		require.Equal(t, i, failure.FailedAttempts)
+	}
+
+	// the count starts over after the window
+	failure, err := testQueries.RecordLoginFailure(ctx, RecordLoginFailureParams{Key: key, ResetBefore: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), failure.FailedAttempts)
+
+	lockedUntil := time.Now().Add(time.Minute)
+	failure, err = testQueries.LockLogin(ctx, LockLoginParams{
+		Key:         key,
+		LockedUntil: pgtype.Timestamptz{Time: lockedUntil, Valid: true},
+	})
+	require.NoError(t, err)
+	require.WithinDuration(t, lockedUntil, failure.LockedUntil.Time, time.Second)
+
+	err = testQueries.DeleteLoginFailure(ctx, key)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetLoginFailure(ctx, key)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestCountLoginAttemptTx(t *testing.T) {
+	store := NewStore(testPool)
+	ctx := context.Background()
+	userKey := "user:" + utils.RandomOwner()
+	ipKey := "ip:" + utils.RandomString(8)
+
+	arg := CountLoginAttemptTxParams{
+		MaxFailures: map[string]int32{userKey: 2, ipKey: 10},
+		ResetBefore: time.Now().Add(-time.Hour),
+		LockoutBase: time.Minute,
+		LockoutMax:  time.Hour,
+	}
+
+	// an attempt with the right credentials is taken back
+	lockedUntil, err := store.CountLoginAttemptTx(ctx, arg)
+	require.NoError(t, err)
+	require.True(t, lockedUntil.IsZero())
+	for key, maxFailures := range arg.MaxFailures {
+		err = testQueries.ForgiveLoginFailure(ctx, ForgiveLoginFailureParams{Key: key, MaxFailures: maxFailures})
+		require.NoError(t, err)
+	}
+	failure, err := testQueries.GetLoginFailure(ctx, userKey)
+	require.NoError(t, err)
+	require.Zero(t, failure.FailedAttempts)
+
+	// the attempt reaching the maximum goes on, but locks the key at once
+	for i := 0; i < 2; i++ {
+		lockedUntil, err = store.CountLoginAttemptTx(ctx, arg)
+		require.NoError(t, err)
+		require.True(t, lockedUntil.IsZero())
+	}
+	failure, err = testQueries.GetLoginFailure(ctx, userKey)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), failure.FailedAttempts)
+	require.WithinDuration(t, time.Now().Add(time.Minute), failure.LockedUntil.Time, time.Second)
+
+	// the attempts made while locked are refused and not counted
+	lockedUntil, err = store.CountLoginAttemptTx(ctx, arg)
+	require.ErrorIs(t, err, ErrLoginLocked)
+	require.WithinDuration(t, failure.LockedUntil.Time, lockedUntil, time.Millisecond)
+
+	ipFailure, err := testQueries.GetLoginFailure(ctx, ipKey)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), ipFailure.FailedAttempts)
+
+	// the right credentials lift the lockout started by their attempt
+	err = testQueries.ForgiveLoginFailure(ctx, ForgiveLoginFailureParams{Key: userKey, MaxFailures: 2})
+	require.NoError(t, err)
+	failure, err = testQueries.GetLoginFailure(ctx, userKey)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), failure.FailedAttempts)
+	require.False(t, failure.LockedUntil.Valid)
+}
+
+func TestLockoutDuration(t *testing.T) {
+	require.Equal(t, time.Minute, lockoutDuration(0, time.Minute, time.Hour))
+	require.Equal(t, 2*time.Minute, lockoutDuration(1, time.Minute, time.Hour))
+	require.Equal(t, 32*time.Minute, lockoutDuration(5, time.Minute, time.Hour))
+	require.Equal(t, time.Hour, lockoutDuration(6, time.Minute, time.Hour))
+	require.Equal(t, time.Hour, lockoutDuration(1000, time.Minute, time.Hour))
+}
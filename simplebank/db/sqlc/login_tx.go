@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrLoginLocked is returned when the login is locked by one of the keys.
+var ErrLoginLocked = errors.New("login locked")
+
+// CountLoginAttemptTxParams contains the input parameters of the count login attempt transaction.
+type CountLoginAttemptTxParams struct {
+	// MaxFailures are the keys the attempt is counted against, with the
+	// number of failures each one is locked after
+	MaxFailures map[string]int32 `json:"max_failures"`
+	// ResetBefore is the cutoff, the count of a key starts over when its
+	// previous failure is older
+	ResetBefore time.Time `json:"reset_before"`
+	// LockoutBase is how long a key is locked once it reached its maximum,
+	// doubled for every failure past it up to LockoutMax
+	LockoutBase time.Duration `json:"lockout_base"`
+	LockoutMax  time.Duration `json:"lockout_max"`
+}
+
+// CountLoginAttemptTx counts a login attempt as a failure against each key,
+// before the credentials are checked, so that concurrent attempts can't all
+// get past the lockout. The key which reaches its maximum is locked at once,
+// the failure being taken back by ForgiveLoginFailure if the credentials are
+// right. When a key is already locked, nothing is counted and ErrLoginLocked
+// is returned, along with until when the login is locked.
+func (store *SQLStore) CountLoginAttemptTx(ctx context.Context, arg CountLoginAttemptTxParams) (time.Time, error) {
+	var lockedUntil time.Time
+
+	// the keys are always locked in the same order to avoid deadlocks
+	keys := make([]string, 0, len(arg.MaxFailures))
+	for key := range arg.MaxFailures {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	err := store.execTx(ctx, TxCountLoginAttempt, func(q *Queries) error {
+		lockedUntil = time.Time{}
+		now := time.Now()
+
+		for _, key := range keys {
+			failure, err := q.RecordLoginFailure(ctx, RecordLoginFailureParams{
+				Key:         key,
+				ResetBefore: arg.ResetBefore,
+			})
+			if err != nil {
+				return err
+			}
+			if failure.LockedUntil.Valid && failure.LockedUntil.Time.After(now) {
+				if failure.LockedUntil.Time.After(lockedUntil) {
+					lockedUntil = failure.LockedUntil.Time
+				}
+				continue
+			}
+
+			maxFailures := arg.MaxFailures[key]
+			if failure.FailedAttempts < maxFailures {
+				continue
+			}
+			lockout := lockoutDuration(failure.FailedAttempts-maxFailures, arg.LockoutBase, arg.LockoutMax)
+			_, err = q.LockLogin(ctx, LockLoginParams{
+				Key:         key,
+				LockedUntil: pgtype.Timestamptz{Time: now.Add(lockout), Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if !lockedUntil.IsZero() {
+			// rolls back the attempt, which isn't counted while locked
+			return ErrLoginLocked
+		}
+		return nil
+	})
+
+	return lockedUntil, err
+}
+
+// lockoutDuration doubles the base duration for every failure past the
+// maximum, up to the max duration.
+func lockoutDuration(extraFailures int32, base, max time.Duration) time.Duration {
+	lockout := base
+	for i := int32(0); i < extraFailures && lockout < max; i++ {
+		lockout *= 2
+	}
+	if max > 0 && lockout > max {
+		lockout = max
+	}
+	return lockout
+}
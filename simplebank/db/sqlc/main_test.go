@@ -1,17 +1,17 @@
 package db
 
 import (
-	"database/sql"
+	"context"
 	"log"
 	"os"
 	"testing"
 
 	"github.com/ebaudet/simplebank/utils"
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var testQueries *Queries
-var testDB *sql.DB
+var testPool *pgxpool.Pool
 
 func TestMain(m *testing.M) {
 	config, err := utils.LoadConfig("../..")
@@ -19,12 +19,12 @@ func TestMain(m *testing.M) {
 		log.Fatal("cannot load config:", err)
 	}
 
-	testDB, err = sql.Open(config.DBDriver, config.DBSource)
+	testPool, err = pgxpool.New(context.Background(), config.DBSource)
 	if err != nil {
 		log.Fatal("cannot connect to database: ", err)
 	}
 
-	testQueries = New(testDB)
+	testQueries = New(testPool)
 
 	os.Exit(m.Run())
 }
@@ -1,11 +1,15 @@
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
-//   sqlc v1.14.0
+//   sqlc v1.18.0
 
 package db
 
 import (
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Account struct {
@@ -14,6 +18,75 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// bumped on every balance change, exposed as the ETag of the account
+	Version int64 `json:"version"`
+	// the accounts are closed rather than deleted, their entries and transfers being kept
+	ClosedAt pgtype.Timestamptz `json:"closed_at"`
+}
+
+// daily snapshot of entries, refreshed by a background job
+type AccountDailyBalance struct {
+	AccountID      int64     `json:"account_id"`
+	Day            time.Time `json:"day"`
+	OpeningBalance int64     `json:"opening_balance"`
+	ClosingBalance int64     `json:"closing_balance"`
+	MinBalance     int64     `json:"min_balance"`
+	MaxBalance     int64     `json:"max_balance"`
+	RefreshedAt    time.Time `json:"refreshed_at"`
+}
+
+type ApiKey struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	// start of the key kept in clear to tell the keys apart
+	Prefix string `json:"prefix"`
+	// sha256 of the key, which is only shown once on creation
+	KeyHash string `json:"key_hash"`
+	// routes reachable with the key, e.g. accounts:read or transfers:write
+	Scopes     []string           `json:"scopes"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+type AuditLog struct {
+	ID int64 `json:"id"`
+	// username from the access token, or system for the workers
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   pgtype.Int8     `json:"target_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	// response status, only set on the request records
+	StatusCode pgtype.Int4 `json:"status_code"`
+	Ip         string      `json:"ip"`
+	UserAgent  string      `json:"user_agent"`
+	RequestID  string      `json:"request_id"`
+	CreatedAt  time.Time   `json:"created_at"`
+	// why the change was made, required on the balance adjustments
+	Reason string `json:"reason"`
+}
+
+type Category struct {
+	ID int64 `json:"id"`
+	// null for the built-in categories
+	Owner     pgtype.Text `json:"owner"`
+	Name      string      `json:"name"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type CategoryRule struct {
+	ID         int64  `json:"id"`
+	Owner      string `json:"owner"`
+	CategoryID int64  `json:"category_id"`
+	// memo or counterparty
+	Field string `json:"field"`
+	// case insensitive substring
+	Pattern   string    `json:"pattern"`
+	Priority  int32     `json:"priority"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Entry struct {
@@ -22,6 +95,141 @@ type Entry struct {
 	// can be negative or positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	Memo      string    `json:"memo"`
+	// owner of the other account of the transfer
+	Counterparty string      `json:"counterparty"`
+	CategoryID   pgtype.Int8 `json:"category_id"`
+	// set by the user, ignored by the rules
+	CategoryOverridden bool `json:"category_overridden"`
+	// set when the money moves between the account and one of its goals
+	GoalID pgtype.Int8 `json:"goal_id"`
+	// set on the corrections of the balance made by the admins, which are no spending
+	IsAdjustment bool `json:"is_adjustment"`
+}
+
+type EntryTag struct {
+	EntryID   int64     `json:"entry_id"`
+	Tag       string    `json:"tag"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Goal struct {
+	ID           int64  `json:"id"`
+	AccountID    int64  `json:"account_id"`
+	Name         string `json:"name"`
+	TargetAmount int64  `json:"target_amount"`
+	// ring-fenced from the account balance, must be positive
+	Balance  int64              `json:"balance"`
+	Deadline pgtype.Timestamptz `json:"deadline"`
+	// outgoing transfers are rounded up to this unit, 0 to disable
+	RoundUpUnit        int64 `json:"round_up_unit"`
+	ContributionAmount int64 `json:"contribution_amount"`
+	// day, week or month, empty to disable
+	ContributionInterval string             `json:"contribution_interval"`
+	NextContributionAt   pgtype.Timestamptz `json:"next_contribution_at"`
+	CreatedAt            time.Time          `json:"created_at"`
+}
+
+type LoginFailure struct {
+	// user:<username> or ip:<address>
+	Key string `json:"key"`
+	// consecutive failures, reset after a successful login
+	FailedAttempts int32              `json:"failed_attempts"`
+	LockedUntil    pgtype.Timestamptz `json:"locked_until"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
+
+type MfaChallenge struct {
+	// sha256 of the token given on login, exchanged with a code for the access token
+	TokenHash string             `json:"token_hash"`
+	Username  string             `json:"username"`
+	ExpiresAt time.Time          `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type OauthAuthorizationCode struct {
+	// sha256 of the code given to the client
+	CodeHash    string    `json:"code_hash"`
+	ClientID    uuid.UUID `json:"client_id"`
+	Username    string    `json:"username"`
+	RedirectUri string    `json:"redirect_uri"`
+	Scopes      []string  `json:"scopes"`
+	// PKCE S256 challenge the code verifier is checked against
+	CodeChallenge string             `json:"code_challenge"`
+	ExpiresAt     time.Time          `json:"expires_at"`
+	UsedAt        pgtype.Timestamptz `json:"used_at"`
+	// token issued for the code, revoked if the code is used again
+	AccessTokenID uuid.NullUUID `json:"access_token_id"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+type OauthClient struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// sha256 of the secret, which is only shown once on registration
+	SecretHash string `json:"secret_hash"`
+	// the redirect_uri of the authorization requests must be one of them exactly
+	RedirectUris []string `json:"redirect_uris"`
+	// scopes the client can ask the users to consent to
+	Scopes []string `json:"scopes"`
+	// admin who registered the client
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OutboxEvent struct {
+	ID int64 `json:"id"`
+	// user notified of the event
+	Owner     string          `json:"owner"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+	// set once the deliveries of the event are created
+	DispatchedAt pgtype.Timestamptz `json:"dispatched_at"`
+}
+
+type RecoveryCode struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// sha256 of the code, which is only shown once when two-factor authentication is enabled
+	CodeHash  string             `json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type ResetPassword struct {
+	// sha256 of the token of the reset link, which is only sent by email
+	TokenHash string             `json:"token_hash"`
+	Username  string             `json:"username"`
+	ExpiresAt time.Time          `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type RevokedToken struct {
+	// id of the access token payload
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	// expiry of the token, the row is useless and purged afterwards
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type Session struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	// sha256 of the refresh token, the token itself is never stored
+	RefreshTokenHash string `json:"refresh_token_hash"`
+	// id of the session created on login, shared by the sessions rotated from it
+	FamilyID uuid.UUID `json:"family_id"`
+	// set once the refresh token is rotated, its reuse then blocks the family
+	ReplacedBy uuid.NullUUID `json:"replaced_by"`
+	UserAgent  string        `json:"user_agent"`
+	ClientIp   string        `json:"client_ip"`
+	IsBlocked  bool          `json:"is_blocked"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+	CreatedAt  time.Time     `json:"created_at"`
 }
 
 type Transfer struct {
@@ -40,4 +248,60 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	// the tokens issued before are revoked, set when logging out everywhere
+	TokensValidAfter time.Time `json:"tokens_valid_after"`
+	// depositor, banker or admin, carried by the access tokens
+	Role string `json:"role"`
+	// a frozen user can neither log in nor renew an access token
+	IsFrozen bool `json:"is_frozen"`
+	// set once the link sent to the email is followed, the transfers are refused until then
+	IsEmailVerified bool `json:"is_email_verified"`
+}
+
+type UserTotp struct {
+	Username string `json:"username"`
+	// base32 TOTP secret shared with the authenticator app
+	Secret string `json:"secret"`
+	// null until a first code confirms the enrolment, the login only asks for codes afterwards
+	ConfirmedAt pgtype.Timestamptz `json:"confirmed_at"`
+	// time step of the last accepted code, a code can only be used once
+	LastUsedStep int64     `json:"last_used_step"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type VerifyEmail struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// the email the link was sent to, it no longer verifies the user once the email changed
+	Email string `json:"email"`
+	// sha256 of the secret code of the link, which is only sent by email
+	SecretCodeHash string             `json:"secret_code_hash"`
+	ExpiresAt      time.Time          `json:"expires_at"`
+	UsedAt         pgtype.Timestamptz `json:"used_at"`
+	CreatedAt      time.Time          `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64 `json:"id"`
+	SubscriptionID int64 `json:"subscription_id"`
+	EventID        int64 `json:"event_id"`
+	// pending, succeeded or dead
+	Status         string             `json:"status"`
+	Attempts       int32              `json:"attempts"`
+	NextAttemptAt  time.Time          `json:"next_attempt_at"`
+	ResponseStatus int32              `json:"response_status"`
+	LastError      string             `json:"last_error"`
+	CreatedAt      time.Time          `json:"created_at"`
+	DeliveredAt    pgtype.Timestamptz `json:"delivered_at"`
+}
+
+type WebhookSubscription struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	Url   string `json:"url"`
+	// HMAC key used to sign the payloads
+	Secret     string    `json:"secret"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
 }
@@ -0,0 +1,218 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: oauth.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createOAuthAuthorizationCode = `-- name: CreateOAuthAuthorizationCode :one
+INSERT INTO oauth_authorization_codes (
+  code_hash, client_id, username, redirect_uri, scopes, code_challenge, expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING code_hash, client_id, username, redirect_uri, scopes, code_challenge, expires_at, used_at, access_token_id, created_at
+`
+
+type CreateOAuthAuthorizationCodeParams struct {
+	CodeHash      string    `json:"code_hash"`
+	ClientID      uuid.UUID `json:"client_id"`
+	Username      string    `json:"username"`
+	RedirectUri   string    `json:"redirect_uri"`
+	Scopes        []string  `json:"scopes"`
+	CodeChallenge string    `json:"code_challenge"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateOAuthAuthorizationCode(ctx context.Context, arg CreateOAuthAuthorizationCodeParams) (OauthAuthorizationCode, error) {
+	row := q.db.QueryRow(ctx, createOAuthAuthorizationCode,
+		arg.CodeHash,
+		arg.ClientID,
+		arg.Username,
+		arg.RedirectUri,
+		arg.Scopes,
+		arg.CodeChallenge,
+		arg.ExpiresAt,
+	)
+	var i OauthAuthorizationCode
+	err := row.Scan(
+		&i.CodeHash,
+		&i.ClientID,
+		&i.Username,
+		&i.RedirectUri,
+		&i.Scopes,
+		&i.CodeChallenge,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.AccessTokenID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createOAuthClient = `-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (
+  id, name, secret_hash, redirect_uris, scopes, created_by
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING id, name, secret_hash, redirect_uris, scopes, created_by, created_at
+`
+
+type CreateOAuthClientParams struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	SecretHash   string    `json:"secret_hash"`
+	RedirectUris []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	CreatedBy    string    `json:"created_by"`
+}
+
+func (q *Queries) CreateOAuthClient(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error) {
+	row := q.db.QueryRow(ctx, createOAuthClient,
+		arg.ID,
+		arg.Name,
+		arg.SecretHash,
+		arg.RedirectUris,
+		arg.Scopes,
+		arg.CreatedBy,
+	)
+	var i OauthClient
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.SecretHash,
+		&i.RedirectUris,
+		&i.Scopes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteExpiredOAuthAuthorizationCodes = `-- name: DeleteExpiredOAuthAuthorizationCodes :execrows
+DELETE FROM oauth_authorization_codes
+WHERE expires_at < $1
+`
+
+func (q *Queries) DeleteExpiredOAuthAuthorizationCodes(ctx context.Context, expiresAt time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredOAuthAuthorizationCodes, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getOAuthAuthorizationCode = `-- name: GetOAuthAuthorizationCode :one
+SELECT code_hash, client_id, username, redirect_uri, scopes, code_challenge, expires_at, used_at, access_token_id, created_at FROM oauth_authorization_codes
+WHERE code_hash = $1 LIMIT 1
+`
+
+func (q *Queries) GetOAuthAuthorizationCode(ctx context.Context, codeHash string) (OauthAuthorizationCode, error) {
+	row := q.db.QueryRow(ctx, getOAuthAuthorizationCode, codeHash)
+	var i OauthAuthorizationCode
+	err := row.Scan(
+		&i.CodeHash,
+		&i.ClientID,
+		&i.Username,
+		&i.RedirectUri,
+		&i.Scopes,
+		&i.CodeChallenge,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.AccessTokenID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getOAuthClient = `-- name: GetOAuthClient :one
+SELECT id, name, secret_hash, redirect_uris, scopes, created_by, created_at FROM oauth_clients
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetOAuthClient(ctx context.Context, id uuid.UUID) (OauthClient, error) {
+	row := q.db.QueryRow(ctx, getOAuthClient, id)
+	var i OauthClient
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.SecretHash,
+		&i.RedirectUris,
+		&i.Scopes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listOAuthClients = `-- name: ListOAuthClients :many
+SELECT id, name, secret_hash, redirect_uris, scopes, created_by, created_at FROM oauth_clients
+ORDER BY created_at
+`
+
+func (q *Queries) ListOAuthClients(ctx context.Context) ([]OauthClient, error) {
+	rows, err := q.db.Query(ctx, listOAuthClients)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OauthClient{}
+	for rows.Next() {
+		var i OauthClient
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.SecretHash,
+			&i.RedirectUris,
+			&i.Scopes,
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const useOAuthAuthorizationCode = `-- name: UseOAuthAuthorizationCode :one
+UPDATE oauth_authorization_codes
+SET used_at = now(), access_token_id = $2
+WHERE code_hash = $1 AND used_at IS NULL
+RETURNING code_hash, client_id, username, redirect_uri, scopes, code_challenge, expires_at, used_at, access_token_id, created_at
+`
+
+type UseOAuthAuthorizationCodeParams struct {
+	CodeHash      string        `json:"code_hash"`
+	AccessTokenID uuid.NullUUID `json:"access_token_id"`
+}
+
+// A code can only be exchanged once: it is not found when already used.
+func (q *Queries) UseOAuthAuthorizationCode(ctx context.Context, arg UseOAuthAuthorizationCodeParams) (OauthAuthorizationCode, error) {
+	row := q.db.QueryRow(ctx, useOAuthAuthorizationCode, arg.CodeHash, arg.AccessTokenID)
+	var i OauthAuthorizationCode
+	err := row.Scan(
+		&i.CodeHash,
+		&i.ClientID,
+		&i.Username,
+		&i.RedirectUri,
+		&i.Scopes,
+		&i.CodeChallenge,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.AccessTokenID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
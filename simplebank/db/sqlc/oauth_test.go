@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomOAuthClient(t *testing.T, createdBy string) OauthClient {
+	arg := CreateOAuthClientParams{
+		ID:           uuid.New(),
+		Name:         utils.RandomString(8),
+		SecretHash:   utils.RandomString(64),
+		RedirectUris: []string{"https://client.example.com/callback"},
+		Scopes:       []string{"accounts:read"},
+		CreatedBy:    createdBy,
+	}
+
+	client, err := testQueries.CreateOAuthClient(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, client.ID)
+	require.Equal(t, arg.RedirectUris, client.RedirectUris)
+	require.Equal(t, arg.Scopes, client.Scopes)
+
+	return client
+}
+
+func createRandomOAuthAuthorizationCode(t *testing.T, client OauthClient, username string, expiresAt time.Time) OauthAuthorizationCode {
+	arg := CreateOAuthAuthorizationCodeParams{
+		CodeHash:      utils.RandomString(64),
+		ClientID:      client.ID,
+		Username:      username,
+		RedirectUri:   client.RedirectUris[0],
+		Scopes:        client.Scopes,
+		CodeChallenge: utils.RandomString(43),
+		ExpiresAt:     expiresAt,
+	}
+
+	code, err := testQueries.CreateOAuthAuthorizationCode(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.CodeHash, code.CodeHash)
+	require.False(t, code.UsedAt.Valid)
+	require.False(t, code.AccessTokenID.Valid)
+
+	return code
+}
+
+func TestGetOAuthClient(t *testing.T) {
+	admin, _ := createRandomUser(t)
+	client := createRandomOAuthClient(t, admin.Username)
+
+	got, err := testQueries.GetOAuthClient(context.Background(), client.ID)
+	require.NoError(t, err)
+	require.Equal(t, client.Name, got.Name)
+	require.Equal(t, client.SecretHash, got.SecretHash)
+
+	_, err = testQueries.GetOAuthClient(context.Background(), uuid.New())
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUseOAuthAuthorizationCode(t *testing.T) {
+	admin, _ := createRandomUser(t)
+	user, _ := createRandomUser(t)
+	client := createRandomOAuthClient(t, admin.Username)
+	code := createRandomOAuthAuthorizationCode(t, client, user.Username, time.Now().Add(time.Minute))
+
+	arg := UseOAuthAuthorizationCodeParams{
+		CodeHash:      code.CodeHash,
+		AccessTokenID: uuid.NullUUID{UUID: uuid.New(), Valid: true},
+	}
+	used, err := testQueries.UseOAuthAuthorizationCode(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, used.UsedAt.Valid)
+	require.Equal(t, arg.AccessTokenID, used.AccessTokenID)
+
+	// a code can only be used once
+	_, err = testQueries.UseOAuthAuthorizationCode(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestDeleteExpiredOAuthAuthorizationCodes(t *testing.T) {
+	admin, _ := createRandomUser(t)
+	user, _ := createRandomUser(t)
+	client := createRandomOAuthClient(t, admin.Username)
+	expired := createRandomOAuthAuthorizationCode(t, client, user.Username, time.Now().Add(-time.Hour))
+	valid := createRandomOAuthAuthorizationCode(t, client, user.Username, time.Now().Add(time.Minute))
+
+	deleted, err := testQueries.DeleteExpiredOAuthAuthorizationCodes(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(1))
+
+	_, err = testQueries.GetOAuthAuthorizationCode(context.Background(), expired.CodeHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = testQueries.GetOAuthAuthorizationCode(context.Background(), valid.CodeHash)
+	require.NoError(t, err)
+}
@@ -0,0 +1,86 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: outbox.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+)
+
+const createOutboxEvent = `-- name: CreateOutboxEvent :one
+INSERT INTO outbox_events (
+  owner, event_type, payload
+) VALUES (
+  $1, $2, $3
+)
+RETURNING id, owner, event_type, payload, created_at, dispatched_at
+`
+
+type CreateOutboxEventParams struct {
+	Owner     string          `json:"owner"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+func (q *Queries) CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (OutboxEvent, error) {
+	row := q.db.QueryRow(ctx, createOutboxEvent, arg.Owner, arg.EventType, arg.Payload)
+	var i OutboxEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.EventType,
+		&i.Payload,
+		&i.CreatedAt,
+		&i.DispatchedAt,
+	)
+	return i, err
+}
+
+const listUndispatchedOutboxEvents = `-- name: ListUndispatchedOutboxEvents :many
+SELECT id, owner, event_type, payload, created_at, dispatched_at FROM outbox_events
+WHERE dispatched_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED
+`
+
+func (q *Queries) ListUndispatchedOutboxEvents(ctx context.Context, limit int32) ([]OutboxEvent, error) {
+	rows, err := q.db.Query(ctx, listUndispatchedOutboxEvents, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OutboxEvent{}
+	for rows.Next() {
+		var i OutboxEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.EventType,
+			&i.Payload,
+			&i.CreatedAt,
+			&i.DispatchedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markOutboxEventDispatched = `-- name: MarkOutboxEventDispatched :exec
+UPDATE outbox_events
+set dispatched_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) MarkOutboxEventDispatched(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, markOutboxEventDispatched, id)
+	return err
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PartitionedTables are the tables range-partitioned by month on created_at.
+var PartitionedTables = []string{"entries", "transfers"}
+
+// partitionMonthLayout is the suffix of the monthly partitions, e.g. entries_p202210.
+const partitionMonthLayout = "200601"
+
+// Partition is the monthly partition of a partitioned table.
+type Partition struct {
+	Table string
+	Name  string
+	// Month is the first day of the month of the rows it holds
+	Month time.Time
+}
+
+// parsePartition parses the name of a monthly partition of the table.
+// It returns false for the default partition and for the unknown tables.
+func parsePartition(table, name string) (Partition, bool) {
+	suffix := strings.TrimPrefix(name, table+"_p")
+	if suffix == name {
+		return Partition{}, false
+	}
+	month, err := time.Parse(partitionMonthLayout, suffix)
+	if err != nil {
+		return Partition{}, false
+	}
+	return Partition{Table: table, Name: name, Month: month}, true
+}
+
+// CreateMonthlyPartition creates the partition of the table for the month of
+// the given time, unless it already exists. It returns the partition name.
+func (store *SQLStore) CreateMonthlyPartition(ctx context.Context, table string, month time.Time) (string, error) {
+	month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var name string
+	err := store.connPool.QueryRow(ctx, "SELECT create_monthly_partition($1, $2)", table, month).Scan(&name)
+	return name, err
+}
+
+// ListPartitions lists the monthly partitions attached to the table, oldest first.
+func (store *SQLStore) ListPartitions(ctx context.Context, table string) ([]Partition, error) {
+	rows, err := store.connPool.Query(ctx, `SELECT c.relname FROM pg_inherits i
+JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent = $1::regclass
+ORDER BY c.relname`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	partitions := []Partition{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if partition, ok := parsePartition(table, name); ok {
+			partitions = append(partitions, partition)
+		}
+	}
+	return partitions, rows.Err()
+}
+
+// ArchivePartition writes the rows of the partition to w as CSV with a header.
+// The tags of the entries are archived along with them, in a tags column.
+func (store *SQLStore) ArchivePartition(ctx context.Context, partition Partition, w io.Writer) (int64, error) {
+	name := pgx.Identifier{partition.Name}.Sanitize()
+	query := fmt.Sprintf("SELECT * FROM %s ORDER BY id", name)
+	if partition.Table == "entries" {
+		query = fmt.Sprintf(`SELECT e.*, ARRAY(
+  SELECT t.tag FROM entry_tags t WHERE t.entry_id = e.id ORDER BY t.tag
+) AS tags FROM %s e ORDER BY e.id`, name)
+	}
+
+	conn, err := store.connPool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Conn().PgConn().CopyTo(ctx, w, fmt.Sprintf("COPY (%s) TO STDOUT WITH (FORMAT csv, HEADER)", query))
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DropPartition detaches the partition from its table and drops it, with the
+// tags of its entries. The partition must be archived beforehand.
+func (store *SQLStore) DropPartition(ctx context.Context, partition Partition) error {
+	table := pgx.Identifier{partition.Table}.Sanitize()
+	name := pgx.Identifier{partition.Name}.Sanitize()
+
+	return store.execTx(ctx, TxDropPartition, func(q *Queries) error {
+		_, err := q.db.Exec(ctx, fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s", table, name))
+		if err != nil {
+			return err
+		}
+
+		if partition.Table == "entries" {
+			_, err = q.db.Exec(ctx, fmt.Sprintf("DELETE FROM entry_tags WHERE entry_id IN (SELECT id FROM %s)", name))
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = q.db.Exec(ctx, fmt.Sprintf("DROP TABLE %s", name))
+		return err
+	})
+}
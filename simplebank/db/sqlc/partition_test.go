@@ -0,0 +1,83 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePartition(t *testing.T) {
+	partition, ok := parsePartition("entries", "entries_p202210")
+	require.True(t, ok)
+	require.Equal(t, Partition{
+		Table: "entries",
+		Name:  "entries_p202210",
+		Month: time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC),
+	}, partition)
+
+	_, ok = parsePartition("entries", "entries_default")
+	require.False(t, ok)
+
+	_, ok = parsePartition("transfers", "entries_p202210")
+	require.False(t, ok)
+}
+
+func TestPartitionLifecycle(t *testing.T) {
+	store := NewStore(testPool).(*SQLStore)
+	ctx := context.Background()
+	month := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	name, err := store.CreateMonthlyPartition(ctx, "entries", month.Add(36*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, "entries_p200001", name)
+
+	// creating it again is a no-op
+	_, err = store.CreateMonthlyPartition(ctx, "entries", month)
+	require.NoError(t, err)
+
+	partitions, err := store.ListPartitions(ctx, "entries")
+	require.NoError(t, err)
+	partition := Partition{Table: "entries", Name: name, Month: month}
+	require.Contains(t, partitions, partition)
+
+	// the entries are listed across the partitions
+	account, _ := createRandomAccount(t)
+	var oldEntryID int64
+	err = testPool.QueryRow(ctx,
+		"INSERT INTO entries (account_id, amount, created_at) VALUES ($1, 10, $2) RETURNING id",
+		account.ID, month.Add(time.Hour),
+	).Scan(&oldEntryID)
+	require.NoError(t, err)
+	_, err = testQueries.AddEntryTag(ctx, AddEntryTagParams{EntryID: oldEntryID, Tag: "archived"})
+	require.NoError(t, err)
+	newEntry, _ := createRandomEntry(t, account)
+
+	entries, err := testQueries.ListEntries(ctx, ListEntriesParams{AccountID: account.ID, Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, oldEntryID, entries[0].ID)
+	require.Equal(t, newEntry.ID, entries[1].ID)
+
+	var archive bytes.Buffer
+	rows, err := store.ArchivePartition(ctx, partition, &archive)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+	require.Contains(t, archive.String(), "tags")
+	require.Contains(t, archive.String(), "{archived}")
+
+	err = store.DropPartition(ctx, partition)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetEntry(ctx, oldEntryID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+	tags, err := testQueries.ListEntryTags(ctx, oldEntryID)
+	require.NoError(t, err)
+	require.Empty(t, tags)
+
+	partitions, err = store.ListPartitions(ctx, "entries")
+	require.NoError(t, err)
+	require.NotContains(t, partitions, partition)
+}
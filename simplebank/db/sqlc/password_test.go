@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangePasswordTx(t *testing.T) {
+	store := NewStore(testPool)
+	user, _ := createRandomUser(t)
+	session, err := testQueries.CreateSession(context.Background(), randomSessionParams(user.Username, uuid.Nil))
+	require.NoError(t, err)
+	resetPassword, err := testQueries.CreateResetPassword(context.Background(), CreateResetPasswordParams{
+		TokenHash: utils.RandomString(64),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	issuedAt := time.Now().Add(-time.Minute)
+	hash, err := utils.HashPassword(utils.RandomPassword(6, 25))
+	require.NoError(t, err)
+
+	changed, err := store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hash,
+		ChangedAt:      time.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, hash, changed.HashedPassword)
+	require.True(t, changed.PasswordChangedAt.After(user.PasswordChangedAt))
+
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+
+	// a reset link sent before the change can't be used anymore
+	_, err = testQueries.GetResetPassword(context.Background(), resetPassword.TokenHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// the tokens issued before the change are revoked
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: issuedAt,
+	})
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testPool)
+	user, _ := createRandomUser(t)
+
+	resetPassword, err := testQueries.CreateResetPassword(context.Background(), CreateResetPasswordParams{
+		TokenHash: utils.RandomString(64),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	// the user asked for another link in the meantime
+	otherResetPassword, err := testQueries.CreateResetPassword(context.Background(), CreateResetPasswordParams{
+		TokenHash: utils.RandomString(64),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	hash, err := utils.HashPassword(utils.RandomPassword(6, 25))
+	require.NoError(t, err)
+	arg := ResetPasswordTxParams{
+		TokenHash:      resetPassword.TokenHash,
+		HashedPassword: hash,
+		ChangedAt:      time.Now(),
+	}
+
+	got, err := testQueries.GetResetPassword(context.Background(), resetPassword.TokenHash)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, got.Username)
+
+	changed, err := store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, changed.Username)
+	require.Equal(t, hash, changed.HashedPassword)
+
+	// a link can only be used once
+	_, err = testQueries.GetResetPassword(context.Background(), resetPassword.TokenHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// and the other links of the user are gone along with the password
+	_, err = testQueries.GetResetPassword(context.Background(), otherResetPassword.TokenHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestResetPasswordTxExpired(t *testing.T) {
+	store := NewStore(testPool)
+	user, _ := createRandomUser(t)
+
+	resetPassword, err := testQueries.CreateResetPassword(context.Background(), CreateResetPasswordParams{
+		TokenHash: utils.RandomString(64),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(-time.Second),
+	})
+	require.NoError(t, err)
+
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      resetPassword.TokenHash,
+		HashedPassword: user.HashedPassword,
+		ChangedAt:      time.Now(),
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	deleted, err := testQueries.DeleteExpiredResetPasswords(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(1))
+}
+
+func TestGetUserByEmail(t *testing.T) {
+	user, _ := createRandomUser(t)
+
+	got, err := testQueries.GetUserByEmail(context.Background(), user.Email)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, got.Username)
+
+	_, err = testQueries.GetUserByEmail(context.Background(), utils.RandomEmail())
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
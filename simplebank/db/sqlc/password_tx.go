@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// ChangePasswordTxParams contains the input parameters of the change password transaction.
+type ChangePasswordTxParams struct {
+	Username       string `json:"username"`
+	HashedPassword string `json:"hashed_password"`
+	// ChangedAt is the cutoff, the access tokens issued before it are rejected
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// ChangePasswordTx changes the password of the user. The access tokens issued
+// before the change are rejected and all the sessions blocked, so that a
+// stolen one can't be used anymore.
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, TxChangePassword, func(q *Queries) error {
+		var err error
+		user, err = changePasswordOf(ctx, q, arg.Username, arg.HashedPassword, arg.ChangedAt)
+		return err
+	})
+
+	return user, err
+}
+
+// ResetPasswordTxParams contains the input parameters of the reset password transaction.
+type ResetPasswordTxParams struct {
+	// TokenHash is the hash of the token of the reset link
+	TokenHash      string    `json:"token_hash"`
+	HashedPassword string    `json:"hashed_password"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
+
+// ResetPasswordTx uses the reset link and changes the password of its user,
+// as ChangePasswordTx does. It returns ErrRecordNotFound if the link is
+// unknown, used or expired.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, TxResetPassword, func(q *Queries) error {
+		resetPassword, err := q.UseResetPassword(ctx, arg.TokenHash)
+		if err != nil {
+			return err
+		}
+
+		user, err = changePasswordOf(ctx, q, resetPassword.Username, arg.HashedPassword, arg.ChangedAt)
+		return err
+	})
+
+	return user, err
+}
+
+// changePasswordOf changes the password of the user, blocks all its sessions
+// and deletes its reset links, which must not outlive the password either.
+func changePasswordOf(ctx context.Context, q *Queries, username string, hashedPassword string, changedAt time.Time) (User, error) {
+	user, err := q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+		Username:          username,
+		HashedPassword:    hashedPassword,
+		PasswordChangedAt: changedAt,
+	})
+	if err != nil {
+		return user, err
+	}
+
+	_, err = q.BlockUserSessions(ctx, username)
+	if err != nil {
+		return user, err
+	}
+
+	err = q.DeleteUserResetPasswords(ctx, username)
+	return user, err
+}
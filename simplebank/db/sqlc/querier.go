@@ -1,35 +1,170 @@
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
-//   sqlc v1.14.0
+//   sqlc v1.18.0
 
 package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	AddEntryTag(ctx context.Context, arg AddEntryTagParams) (EntryTag, error)
+	AddGoalBalance(ctx context.Context, arg AddGoalBalanceParams) (Goal, error)
+	// The wildcards and the escape character of the patterns are escaped, so that
+	// the patterns only match as plain text.
+	ApplyCategoryRules(ctx context.Context, id int64) (Entry, error)
+	ApplyCategoryRulesByOwner(ctx context.Context, owner string) (int64, error)
+	BlockSessionFamily(ctx context.Context, familyID uuid.UUID) (int64, error)
+	BlockUserSessions(ctx context.Context, username string) (int64, error)
+	ClaimDueWebhookDeliveries(ctx context.Context, arg ClaimDueWebhookDeliveriesParams) ([]ClaimDueWebhookDeliveriesRow, error)
+	CloseAccount(ctx context.Context, id int64) (Account, error)
+	ConfirmUserTOTP(ctx context.Context, arg ConfirmUserTOTPParams) (UserTotp, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error)
+	CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error)
+	CreateCategoryRule(ctx context.Context, arg CreateCategoryRuleParams) (CategoryRule, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateGoal(ctx context.Context, arg CreateGoalParams) (Goal, error)
+	CreateMFAChallenge(ctx context.Context, arg CreateMFAChallengeParams) (MfaChallenge, error)
+	CreateOAuthAuthorizationCode(ctx context.Context, arg CreateOAuthAuthorizationCodeParams) (OauthAuthorizationCode, error)
+	CreateOAuthClient(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (OutboxEvent, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error)
+	CreateResetPassword(ctx context.Context, arg CreateResetPasswordParams) (ResetPassword, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
+	CreateWebhookDeliveries(ctx context.Context, arg CreateWebhookDeliveriesParams) (int64, error)
+	CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error)
 	DeleteAccount(ctx context.Context, id int64) error
 	DeleteAccountOwner(ctx context.Context, arg DeleteAccountOwnerParams) error
+	DeleteCategory(ctx context.Context, arg DeleteCategoryParams) error
+	DeleteCategoryRule(ctx context.Context, arg DeleteCategoryRuleParams) error
 	DeleteEntry(ctx context.Context, id int64) error
+	DeleteEntryTag(ctx context.Context, arg DeleteEntryTagParams) error
+	DeleteExpiredMFAChallenges(ctx context.Context, expiresAt time.Time) (int64, error)
+	DeleteExpiredOAuthAuthorizationCodes(ctx context.Context, expiresAt time.Time) (int64, error)
+	DeleteExpiredResetPasswords(ctx context.Context, expiresAt time.Time) (int64, error)
+	DeleteExpiredRevokedTokens(ctx context.Context, expiresAt time.Time) (int64, error)
+	DeleteExpiredVerifyEmails(ctx context.Context, expiresAt time.Time) (int64, error)
+	DeleteGoal(ctx context.Context, id int64) error
+	DeleteLoginFailure(ctx context.Context, key string) error
+	DeleteRecoveryCodes(ctx context.Context, username string) error
 	DeleteTransfer(ctx context.Context, id int64) error
+	DeleteUserResetPasswords(ctx context.Context, username string) error
+	DeleteUserTOTP(ctx context.Context, username string) error
+	DeleteWebhookSubscription(ctx context.Context, arg DeleteWebhookSubscriptionParams) error
+	// Takes back the failure counted before the credentials were found right, and
+	// lifts the lockout when the count is below max_failures again.
+	ForgiveLoginFailure(ctx context.Context, arg ForgiveLoginFailureParams) error
+	// The owner is read along with the key, as the key acts with the owner's role.
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (GetAPIKeyByHashRow, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	// The balances are worked back from the current balance of the account through
+	// the entries since from_time, so that the older entries aren't needed.
+	GetBalanceHistory(ctx context.Context, arg GetBalanceHistoryParams) ([]GetBalanceHistoryRow, error)
+	GetBalanceHistoryFromSnapshots(ctx context.Context, arg GetBalanceHistoryFromSnapshotsParams) ([]GetBalanceHistoryFromSnapshotsRow, error)
+	GetCategory(ctx context.Context, id int64) (Category, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetGoal(ctx context.Context, id int64) (Goal, error)
+	GetGoalForUpdate(ctx context.Context, id int64) (Goal, error)
+	GetLatestDailyBalanceDay(ctx context.Context) (time.Time, error)
+	GetLoginFailure(ctx context.Context, key string) (LoginFailure, error)
+	GetMFAChallenge(ctx context.Context, tokenHash string) (MfaChallenge, error)
+	GetOAuthAuthorizationCode(ctx context.Context, codeHash string) (OauthAuthorizationCode, error)
+	GetOAuthClient(ctx context.Context, id uuid.UUID) (OauthClient, error)
+	// The reset link is only found while it can be followed.
+	GetResetPassword(ctx context.Context, tokenHash string) (ResetPassword, error)
+	GetRoundUpGoal(ctx context.Context, accountID int64) (Goal, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
+	GetSessionByRefreshToken(ctx context.Context, refreshTokenHash string) (Session, error)
+	// The money put aside into the goals and the adjustments of the admins are no spending.
+	GetSpendingByCategory(ctx context.Context, arg GetSpendingByCategoryParams) ([]GetSpendingByCategoryRow, error)
+	GetSpendingByMonth(ctx context.Context, arg GetSpendingByMonthParams) ([]GetSpendingByMonthRow, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserTOTP(ctx context.Context, username string) (UserTotp, error)
+	GetVerifyEmail(ctx context.Context, id int64) (VerifyEmail, error)
+	GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error)
+	// A token is revoked on its own, or along with all the tokens of its user
+	// issued before the user logged out everywhere or changed its password.
+	IsTokenRevoked(ctx context.Context, arg IsTokenRevokedParams) (bool, error)
+	ListAPIKeys(ctx context.Context, owner string) ([]ApiKey, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
 	ListAccountsByOwner(ctx context.Context, arg ListAccountsByOwnerParams) ([]Account, error)
+	ListAuditLog(ctx context.Context, arg ListAuditLogParams) ([]AuditLog, error)
+	ListCategories(ctx context.Context, owner pgtype.Text) ([]Category, error)
+	ListCategoryRules(ctx context.Context, owner string) ([]CategoryRule, error)
+	ListDueGoalContributions(ctx context.Context, arg ListDueGoalContributionsParams) ([]Goal, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListEntryTags(ctx context.Context, entryID int64) ([]EntryTag, error)
+	ListGoalsByOwner(ctx context.Context, arg ListGoalsByOwnerParams) ([]Goal, error)
+	ListOAuthClients(ctx context.Context) ([]OauthClient, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	ListUndispatchedOutboxEvents(ctx context.Context, limit int32) ([]OutboxEvent, error)
+	ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhookSubscriptions(ctx context.Context, owner string) ([]WebhookSubscription, error)
+	LockLogin(ctx context.Context, arg LockLoginParams) (LoginFailure, error)
+	MarkOutboxEventDispatched(ctx context.Context, id int64) error
+	// The count starts over when the previous failure is older than reset_before.
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginFailure, error)
+	// Only the days since from_day of the accounts with entries on them are
+	// refreshed, worked back from the current balance as in GetBalanceHistory.
+	RefreshDailyBalances(ctx context.Context, fromDay time.Time) (int64, error)
+	// The hash is only replaced if the password wasn't changed meanwhile. As the
+	// password stays the same, password_changed_at is kept and no token revoked.
+	RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) error
+	RetryWebhookDelivery(ctx context.Context, arg RetryWebhookDeliveryParams) (WebhookDelivery, error)
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKey, error)
+	RevokeToken(ctx context.Context, arg RevokeTokenParams) error
+	RevokeUserTokens(ctx context.Context, arg RevokeUserTokensParams) (User, error)
+	// Only one rotation of a session can succeed, the others find no row.
+	RotateSession(ctx context.Context, arg RotateSessionParams) (Session, error)
+	// The next contribution is the first one of the schedule after now, the
+	// contributions missed meanwhile aren't made up.
+	ScheduleNextGoalContribution(ctx context.Context, arg ScheduleNextGoalContributionParams) (Goal, error)
+	SetEntryCategory(ctx context.Context, arg SetEntryCategoryParams) (Entry, error)
+	// last_used_at is only written once a minute, not on every request.
+	TouchAPIKey(ctx context.Context, id int64) error
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
 	UpdateEntry(ctx context.Context, arg UpdateEntryParams) (Entry, error)
 	UpdateTransfer(ctx context.Context, arg UpdateTransferParams) (Transfer, error)
+	// The fields left null are kept. A changed email has to be verified again.
+	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserFrozen(ctx context.Context, arg UpdateUserFrozenParams) (User, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpdateWebhookDelivery(ctx context.Context, arg UpdateWebhookDeliveryParams) (WebhookDelivery, error)
+	// A pending enrolment is started over with a new secret, a confirmed one is
+	// left alone and not returned.
+	UpsertUserTOTP(ctx context.Context, arg UpsertUserTOTPParams) (UserTotp, error)
+	// A challenge can only be completed once: it is not found when already used.
+	UseMFAChallenge(ctx context.Context, tokenHash string) (MfaChallenge, error)
+	// A code can only be exchanged once: it is not found when already used.
+	UseOAuthAuthorizationCode(ctx context.Context, arg UseOAuthAuthorizationCodeParams) (OauthAuthorizationCode, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error)
+	// A reset link can only be followed once, before it expires: it is not found
+	// otherwise.
+	UseResetPassword(ctx context.Context, tokenHash string) (ResetPassword, error)
+	// A code is only accepted once: it is not found when its step, or a later
+	// one, was already used.
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (UserTotp, error)
+	// A link can only be followed once, before it expires: it is not found
+	// otherwise.
+	UseVerifyEmail(ctx context.Context, arg UseVerifyEmailParams) (VerifyEmail, error)
+	// The user is only verified if the email is still the one the link was sent to.
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type readYourWritesKey struct{}
+
+// WithReadYourWrites returns a copy of the context whose queries all go to the
+// primary, so they see the writes just made even if the replica lags behind.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, true)
+}
+
+// ReadYourWritesFromContext reports whether the queries of the context must go to the primary.
+func ReadYourWritesFromContext(ctx context.Context) bool {
+	value, _ := ctx.Value(readYourWritesKey{}).(bool)
+	return value
+}
+
+// writeKeywords match the statements, or the row locking clauses, needing the primary.
+var writeKeywords = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|SHARE)\b`)
+
+// readOnlyQuery reports whether the query only reads data and can be served by a replica.
+func readOnlyQuery(query string) bool {
+	// skip the `-- name: GetAccount :one` header of the sqlc queries
+	lines := strings.Split(query, "\n")
+	for len(lines) > 0 && strings.HasPrefix(strings.TrimSpace(lines[0]), "--") {
+		lines = lines[1:]
+	}
+	statement := strings.ToUpper(strings.TrimSpace(strings.Join(lines, "\n")))
+
+	if !strings.HasPrefix(statement, "SELECT") && !strings.HasPrefix(statement, "WITH") {
+		return false
+	}
+	return !writeKeywords.MatchString(statement)
+}
+
+// routingDB sends the read-only queries to the replica and everything else,
+// including the queries of a context asking to read its writes, to the primary.
+type routingDB struct {
+	primary DBTX
+	replica DBTX
+}
+
+func (db *routingDB) route(ctx context.Context, query string) DBTX {
+	if db.replica == nil || ReadYourWritesFromContext(ctx) || !readOnlyQuery(query) {
+		return db.primary
+	}
+	return db.replica
+}
+
+func (db *routingDB) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	return db.primary.Exec(ctx, query, args...)
+}
+
+func (db *routingDB) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	return db.route(ctx, query).Query(ctx, query, args...)
+}
+
+func (db *routingDB) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	return db.route(ctx, query).QueryRow(ctx, query, args...)
+}
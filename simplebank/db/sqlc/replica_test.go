@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyQuery(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		readOnly bool
+	}{
+		{"Select", getAccount, true},
+		{"SelectForUpdate", getAccountForUpdate, false},
+		{"Insert", createAccount, false},
+		{"Update", addAccountBalance, false},
+		{"Delete", deleteAccount, false},
+		{"WithSelect", "-- name: X :many\nWITH t AS (SELECT 1) SELECT * FROM t", true},
+		{"WithInsert", "WITH t AS (SELECT 1) INSERT INTO x SELECT * FROM t", false},
+		{"SelectForShare", "select * from accounts for share", false},
+		{"UpdatedAtColumn", "SELECT updated_at FROM accounts", true},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.readOnly, readOnlyQuery(tc.query))
+		})
+	}
+}
+
+// fakeDBTX records the queries it runs.
+type fakeDBTX struct {
+	queries []string
+}
+
+func (db *fakeDBTX) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	db.queries = append(db.queries, query)
+	return pgconn.CommandTag{}, nil
+}
+
+func (db *fakeDBTX) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	db.queries = append(db.queries, query)
+	return nil, nil
+}
+
+func (db *fakeDBTX) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	db.queries = append(db.queries, query)
+	return nil
+}
+
+func TestRoutingDB(t *testing.T) {
+	primary := &fakeDBTX{}
+	replica := &fakeDBTX{}
+	db := &routingDB{primary: primary, replica: replica}
+	ctx := context.Background()
+
+	db.QueryRow(ctx, getAccount)
+	db.QueryRow(ctx, getAccountForUpdate)
+	db.QueryRow(WithReadYourWrites(ctx), getAccount)
+	db.Exec(ctx, deleteAccount)
+
+	require.Equal(t, []string{getAccount}, replica.queries)
+	require.Equal(t, []string{getAccountForUpdate, getAccount, deleteAccount}, primary.queries)
+
+	// without a replica, everything goes to the primary
+	primary = &fakeDBTX{}
+	db = &routingDB{primary: primary}
+	db.QueryRow(ctx, getAccount)
+	require.Equal(t, []string{getAccount}, primary.queries)
+}
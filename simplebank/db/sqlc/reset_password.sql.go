@@ -0,0 +1,108 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: reset_password.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createResetPassword = `-- name: CreateResetPassword :one
+INSERT INTO reset_passwords (
+  token_hash, username, expires_at
+) VALUES (
+  $1, $2, $3
+)
+RETURNING token_hash, username, expires_at, used_at, created_at
+`
+
+type CreateResetPasswordParams struct {
+	TokenHash string    `json:"token_hash"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateResetPassword(ctx context.Context, arg CreateResetPasswordParams) (ResetPassword, error) {
+	row := q.db.QueryRow(ctx, createResetPassword, arg.TokenHash, arg.Username, arg.ExpiresAt)
+	var i ResetPassword
+	err := row.Scan(
+		&i.TokenHash,
+		&i.Username,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteExpiredResetPasswords = `-- name: DeleteExpiredResetPasswords :execrows
+DELETE FROM reset_passwords
+WHERE expires_at < $1
+`
+
+func (q *Queries) DeleteExpiredResetPasswords(ctx context.Context, expiresAt time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredResetPasswords, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUserResetPasswords = `-- name: DeleteUserResetPasswords :exec
+DELETE FROM reset_passwords
+WHERE username = $1
+`
+
+func (q *Queries) DeleteUserResetPasswords(ctx context.Context, username string) error {
+	_, err := q.db.Exec(ctx, deleteUserResetPasswords, username)
+	return err
+}
+
+const getResetPassword = `-- name: GetResetPassword :one
+SELECT token_hash, username, expires_at, used_at, created_at FROM reset_passwords
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > now()
+LIMIT 1
+`
+
+// The reset link is only found while it can be followed.
+func (q *Queries) GetResetPassword(ctx context.Context, tokenHash string) (ResetPassword, error) {
+	row := q.db.QueryRow(ctx, getResetPassword, tokenHash)
+	var i ResetPassword
+	err := row.Scan(
+		&i.TokenHash,
+		&i.Username,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useResetPassword = `-- name: UseResetPassword :one
+UPDATE reset_passwords
+SET used_at = now()
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > now()
+RETURNING token_hash, username, expires_at, used_at, created_at
+`
+
+// A reset link can only be followed once, before it expires: it is not found
+// otherwise.
+func (q *Queries) UseResetPassword(ctx context.Context, tokenHash string) (ResetPassword, error) {
+	row := q.db.QueryRow(ctx, useResetPassword, tokenHash)
+	var i ResetPassword
+	err := row.Scan(
+		&i.TokenHash,
+		&i.Username,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
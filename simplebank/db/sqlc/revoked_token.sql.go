@@ -0,0 +1,72 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: revoked_token.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deleteExpiredRevokedTokens = `-- name: DeleteExpiredRevokedTokens :execrows
+DELETE FROM revoked_tokens
+WHERE expires_at < $1
+`
+
+func (q *Queries) DeleteExpiredRevokedTokens(ctx context.Context, expiresAt time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredRevokedTokens, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const isTokenRevoked = `-- name: IsTokenRevoked :one
+SELECT (EXISTS (
+  SELECT 1 FROM revoked_tokens r
+  WHERE r.id = $1
+) OR EXISTS (
+  SELECT 1 FROM users u
+  WHERE u.username = $2
+    AND (u.tokens_valid_after > $3 OR u.password_changed_at > $3)
+))::boolean AS revoked
+`
+
+type IsTokenRevokedParams struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+// A token is revoked on its own, or along with all the tokens of its user
+// issued before the user logged out everywhere or changed its password.
+func (q *Queries) IsTokenRevoked(ctx context.Context, arg IsTokenRevokedParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isTokenRevoked, arg.ID, arg.Username, arg.IssuedAt)
+	var revoked bool
+	err := row.Scan(&revoked)
+	return revoked, err
+}
+
+const revokeToken = `-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (
+  id, username, expires_at
+) VALUES (
+  $1, $2, $3
+)
+ON CONFLICT (id) DO NOTHING
+`
+
+type RevokeTokenParams struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) RevokeToken(ctx context.Context, arg RevokeTokenParams) error {
+	_, err := q.db.Exec(ctx, revokeToken, arg.ID, arg.Username, arg.ExpiresAt)
+	return err
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokeToken(t *testing.T) {
+	ctx := context.Background()
+	user, _ := createRandomUser(t)
+
+	arg := IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: time.Now(),
+	}
+	revoked, err := testQueries.IsTokenRevoked(ctx, arg)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = testQueries.RevokeToken(ctx, RevokeTokenParams{
+		ID:        arg.ID,
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(-time.Second),
+	})
+	require.NoError(t, err)
+
+	revoked, err = testQueries.IsTokenRevoked(ctx, arg)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// the expired revocations are purged
+	deleted, err := testQueries.DeleteExpiredRevokedTokens(ctx, time.Now())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(1))
+
+	revoked, err = testQueries.IsTokenRevoked(ctx, arg)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestRevokeUserTokensTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testPool)
+	user, _ := createRandomUser(t)
+
+	session, err := testQueries.CreateSession(ctx, randomSessionParams(user.Username, uuid.Nil))
+	require.NoError(t, err)
+
+	issuedBefore := time.Now()
+	oldToken := IsTokenRevokedParams{ID: uuid.New(), Username: user.Username, IssuedAt: issuedBefore.Add(-time.Minute)}
+	newToken := IsTokenRevokedParams{ID: uuid.New(), Username: user.Username, IssuedAt: issuedBefore.Add(time.Minute)}
+
+	user, err = store.RevokeUserTokensTx(ctx, RevokeUserTokensTxParams{Username: user.Username, IssuedBefore: issuedBefore})
+	require.NoError(t, err)
+	require.WithinDuration(t, issuedBefore, user.TokensValidAfter, time.Millisecond)
+
+	revoked, err := testQueries.IsTokenRevoked(ctx, oldToken)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = testQueries.IsTokenRevoked(ctx, newToken)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	session, err = testQueries.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+}
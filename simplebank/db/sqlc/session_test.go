@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func randomSessionParams(username string, familyID uuid.UUID) CreateSessionParams {
+	id := uuid.New()
+	if familyID == uuid.Nil {
+		familyID = id
+	}
+	return CreateSessionParams{
+		ID:               id,
+		Username:         username,
+		RefreshTokenHash: utils.RandomString(64),
+		FamilyID:         familyID,
+		UserAgent:        "test",
+		ClientIp:         "127.0.0.1",
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}
+}
+
+func TestCreateSession(t *testing.T) {
+	user, _ := createRandomUser(t)
+	arg := randomSessionParams(user.Username, uuid.Nil)
+
+	session, err := testQueries.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, session.ID)
+	require.Equal(t, arg.FamilyID, session.FamilyID)
+	require.False(t, session.IsBlocked)
+	require.False(t, session.ReplacedBy.Valid)
+
+	got, err := testQueries.GetSessionByRefreshToken(context.Background(), arg.RefreshTokenHash)
+	require.NoError(t, err)
+	require.Equal(t, session.ID, got.ID)
+}
+
+func TestRotateSessionTx(t *testing.T) {
+	store := NewStore(testPool)
+	user, _ := createRandomUser(t)
+
+	session, err := testQueries.CreateSession(context.Background(), randomSessionParams(user.Username, uuid.Nil))
+	require.NoError(t, err)
+
+	arg := RotateSessionTxParams{
+		SessionID: session.ID,
+		Session:   randomSessionParams(user.Username, session.FamilyID),
+	}
+	newSession, err := store.RotateSessionTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, session.FamilyID, newSession.FamilyID)
+
+	rotated, err := testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.Equal(t, newSession.ID, rotated.ReplacedBy.UUID)
+
+	// the session can only be rotated once
+	arg.Session = randomSessionParams(user.Username, session.FamilyID)
+	_, err = store.RotateSessionTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrSessionReused)
+
+	blocked, err := testQueries.BlockSessionFamily(context.Background(), session.FamilyID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), blocked)
+
+	newSession, err = testQueries.GetSession(context.Background(), newSession.ID)
+	require.NoError(t, err)
+	require.True(t, newSession.IsBlocked)
+}
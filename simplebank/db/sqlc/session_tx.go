@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrSessionReused is returned when the refresh token of a session is used
+// again after having been rotated, which hints at a stolen token.
+var ErrSessionReused = errors.New("session refresh token reused")
+
+// RotateSessionTxParams contains the input parameters of the session rotation transaction.
+type RotateSessionTxParams struct {
+	// SessionID is the session whose refresh token is rotated
+	SessionID uuid.UUID `json:"session_id"`
+	// Session replaces it, in the same family
+	Session CreateSessionParams `json:"session"`
+}
+
+// RotateSessionTx replaces a session by a new one with a fresh refresh token.
+// When the session was already rotated, ErrSessionReused is returned.
+func (store *SQLStore) RotateSessionTx(ctx context.Context, arg RotateSessionTxParams) (Session, error) {
+	var session Session
+
+	err := store.execTx(ctx, TxRotateSession, func(q *Queries) error {
+		_, err := q.RotateSession(ctx, RotateSessionParams{
+			ID:         arg.SessionID,
+			ReplacedBy: uuid.NullUUID{UUID: arg.Session.ID, Valid: true},
+		})
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return ErrSessionReused
+			}
+			return err
+		}
+
+		session, err = q.CreateSession(ctx, arg.Session)
+		return err
+	})
+
+	return session, err
+}
+
+// RevokeUserTokensTxParams contains the input parameters of the revoke user tokens transaction.
+type RevokeUserTokensTxParams struct {
+	Username string `json:"username"`
+	// IssuedBefore is the cutoff, the tokens issued before it are revoked
+	IssuedBefore time.Time `json:"issued_before"`
+}
+
+// RevokeUserTokensTx logs the user out everywhere: the access tokens issued
+// before the cutoff are revoked and all the sessions are blocked, so that no
+// new access token can be issued from their refresh tokens either.
+func (store *SQLStore) RevokeUserTokensTx(ctx context.Context, arg RevokeUserTokensTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, TxRevokeUserTokens, func(q *Queries) error {
+		var err error
+		user, err = revokeUserTokensOf(ctx, q, arg.Username, arg.IssuedBefore)
+		return err
+	})
+
+	return user, err
+}
+
+// revokeUserTokensOf revokes the access tokens of the user issued before the
+// cutoff, and blocks all the sessions.
+func revokeUserTokensOf(ctx context.Context, q *Queries, username string, issuedBefore time.Time) (User, error) {
+	user, err := q.RevokeUserTokens(ctx, RevokeUserTokensParams{
+		Username:         username,
+		TokensValidAfter: issuedBefore,
+	})
+	if err != nil {
+		return user, err
+	}
+
+	_, err = q.BlockUserSessions(ctx, username)
+	return user, err
+}
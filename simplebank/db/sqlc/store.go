@@ -2,35 +2,122 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"io"
+	"time"
 
 	_ "github.com/golang/mock/mockgen/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Store provides all functions to execute db queries and transactions.
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error)
+	AddAccountBalanceTx(ctx context.Context, arg AddAccountBalanceTxParams) (Account, error)
+	DeleteAccountTx(ctx context.Context, arg DeleteAccountTxParams) error
+	DispatchOutboxTx(ctx context.Context, limit int32) (int, error)
+	GoalTransferTx(ctx context.Context, arg GoalTransferTxParams) (GoalTransferTxResult, error)
+	ContributeGoalTx(ctx context.Context, goalID int64) (GoalTransferTxResult, error)
+	DeleteGoalTx(ctx context.Context, goalID int64) (Account, error)
+	RotateSessionTx(ctx context.Context, arg RotateSessionTxParams) (Session, error)
+	RevokeUserTokensTx(ctx context.Context, arg RevokeUserTokensTxParams) (User, error)
+	UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleTxParams) (User, error)
+	FreezeUserTx(ctx context.Context, arg FreezeUserTxParams) (User, error)
+	UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error)
+	ConfirmTOTPTx(ctx context.Context, arg ConfirmTOTPTxParams) (UserTotp, error)
+	DisableTOTPTx(ctx context.Context, username string) error
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (User, error)
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (User, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
+	CountLoginAttemptTx(ctx context.Context, arg CountLoginAttemptTxParams) (time.Time, error)
+	CreateMonthlyPartition(ctx context.Context, table string, month time.Time) (string, error)
+	ListPartitions(ctx context.Context, table string) ([]Partition, error)
+	ArchivePartition(ctx context.Context, partition Partition, w io.Writer) (int64, error)
+	DropPartition(ctx context.Context, partition Partition) error
 }
 
 // SQLStore provides all functions to execute SQL queries and transactions.
 type SQLStore struct {
 	*Queries
-	db *sql.DB
+	connPool    *pgxpool.Pool
+	replicaPool *pgxpool.Pool
+	retryPolicy TxRetryPolicy
+	isolation   pgx.TxIsoLevel
+	isolations  map[string]pgx.TxIsoLevel
 }
 
-// NewStore creates a new Store.
-func NewStore(db *sql.DB) Store {
-	return &SQLStore{
-		Queries: New(db),
-		db:      db,
+// StoreOption configures the Store created by NewStore.
+type StoreOption func(store *SQLStore)
+
+// WithRetryPolicy makes the Store retry its transactions with the given policy.
+func WithRetryPolicy(retryPolicy TxRetryPolicy) StoreOption {
+	return func(store *SQLStore) {
+		store.retryPolicy = retryPolicy
+	}
+}
+
+// WithReplica makes the Store run its read-only queries on the replica pool.
+// The transactions and the writes always run on the primary pool.
+func WithReplica(replicaPool *pgxpool.Pool) StoreOption {
+	return func(store *SQLStore) {
+		store.replicaPool = replicaPool
+	}
+}
+
+// NewStore creates a new Store on the primary connPool. The transactions are
+// retried with the DefaultTxRetryPolicy unless WithRetryPolicy is given, and
+// run at the DefaultTxIsolation unless WithTxIsolation or WithTxIsolationFor is given.
+func NewStore(connPool *pgxpool.Pool, opts ...StoreOption) Store {
+	store := &SQLStore{
+		connPool:    connPool,
+		retryPolicy: DefaultTxRetryPolicy,
+		isolation:   DefaultTxIsolation,
+		isolations:  make(map[string]pgx.TxIsoLevel),
 	}
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	db := &routingDB{primary: connPool}
+	if store.replicaPool != nil {
+		db.replica = store.replicaPool
+	}
+	store.Queries = New(db)
+	return store
 }
 
-// execTx executes a function within a database transaction.
-func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil)
+// execTx executes a function within a database transaction, at the isolation
+// level set for the transaction of the given name. When Postgres aborts the transaction with a serialization failure or a
+// deadlock, the whole transaction is run again, up to the attempts of the
+// retry policy. fn must therefore reset any state it sets outside of the transaction.
+func (store *SQLStore) execTx(ctx context.Context, name string, fn func(*Queries) error) error {
+	isolation := store.txIsolation(name)
+	for attempt := 1; ; attempt++ {
+		err := store.runTx(ctx, isolation, fn)
+
+		name, retryable := retryableTxError(err)
+		if !retryable {
+			return err
+		}
+		if attempt >= store.retryPolicy.MaxAttempts {
+			txRetries.Add("exhausted", 1)
+			return err
+		}
+
+		txRetries.Add(name, 1)
+		if waitErr := store.retryPolicy.wait(ctx, attempt); waitErr != nil {
+			return err
+		}
+	}
+}
+
+// runTx makes a single attempt of a transaction.
+func (store *SQLStore) runTx(ctx context.Context, isolation pgx.TxIsoLevel, fn func(*Queries) error) error {
+	tx, err := store.connPool.BeginTx(ctx, pgx.TxOptions{IsoLevel: isolation})
 	if err != nil {
 		return err
 	}
@@ -38,20 +125,21 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 	q := New(tx)
 	err = fn(q)
 	if err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
 			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
 		}
 		return err
 	}
 
-	return tx.Commit()
+	return tx.Commit(ctx)
 }
 
 // TransferTxParams contains the input parameters of the transfer transaction
 type TransferTxParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Memo          string `json:"memo"`
 }
 
 // TransferTxResult is the result of the transfer transaction.
@@ -61,16 +149,19 @@ type TransferTxResult struct {
 	ToAccount   Account  `json:"to_account"`
 	FromEntry   Entry    `json:"from_entry"`
 	ToEntry     Entry    `json:"to_entry"`
+	RoundUpGoal *Goal    `json:"round_up_goal,omitempty"`
 }
 
 // TransferTx performs a money transfer from one account to another.
-// It creates a transfer record, add account entries, and update account's
-// balance within a single database transaction.
+// It creates a transfer record, add categorized account entries, update
+// account's balance, record it in the audit log and publish the events within a
+// single database transaction.
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
-	err := store.execTx(ctx, func(q *Queries) error {
+	err := store.execTx(ctx, TxTransfer, func(q *Queries) error {
 		var err error
+		result = TransferTxResult{}
 
 		// create transfer record
 		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
@@ -82,18 +173,33 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 			return err
 		}
 
-		// add entries to accounts
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
+		// the owners are read ahead, as the entries are added before the
+		// balances are updated
+		fromOwner, err := getAccountOwner(ctx, q, arg.FromAccountID)
+		if err != nil {
+			return err
+		}
+		toOwner, err := getAccountOwner(ctx, q, arg.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		// add entries to accounts, each one referencing the other party
+		result.FromEntry, err = createCategorizedEntry(ctx, q, CreateEntryParams{
+			AccountID:    arg.FromAccountID,
+			Amount:       -arg.Amount,
+			Memo:         arg.Memo,
+			Counterparty: toOwner,
 		})
 		if err != nil {
 			return err
 		}
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
+		result.ToEntry, err = createCategorizedEntry(ctx, q, CreateEntryParams{
+			AccountID:    arg.ToAccountID,
+			Amount:       arg.Amount,
+			Memo:         arg.Memo,
+			Counterparty: fromOwner,
 		})
 		if err != nil {
 			return err
@@ -112,6 +218,37 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 			}
 		}
 
+		err = recordAudit(ctx, q, AuditTransferCreate, "transfer", result.Transfer.ID, nil, result.Transfer, "")
+		if err != nil {
+			return err
+		}
+
+		// notify both parties
+		for _, owner := range []string{result.FromAccount.Owner, result.ToAccount.Owner} {
+			err = publishEvent(ctx, q, owner, EventTransferCreated, result.Transfer)
+			if err != nil {
+				return err
+			}
+		}
+		err = publishBalanceChanged(ctx, q, result.FromAccount, -arg.Amount)
+		if err != nil {
+			return err
+		}
+		err = publishBalanceChanged(ctx, q, result.ToAccount, arg.Amount)
+		if err != nil {
+			return err
+		}
+
+		// round up the transfer into the savings goal of the sender
+		roundUpGoal, fromAccount, err := roundUpToGoal(ctx, q, arg.FromAccountID, arg.Amount)
+		if err != nil {
+			return err
+		}
+		if roundUpGoal != nil {
+			result.RoundUpGoal = roundUpGoal
+			result.FromAccount = fromAccount
+		}
+
 		return nil
 	})
 
@@ -140,3 +277,19 @@ func addMoney(
 	})
 	return
 }
+
+// getAccountOwner returns the owner of an account, which never changes.
+func getAccountOwner(ctx context.Context, q *Queries, accountID int64) (string, error) {
+	account, err := q.GetAccount(ctx, accountID)
+	return account.Owner, err
+}
+
+// createCategorizedEntry creates an entry and assigns it a category from the
+// rules of the account owner.
+func createCategorizedEntry(ctx context.Context, q *Queries, arg CreateEntryParams) (Entry, error) {
+	entry, err := q.CreateEntry(ctx, arg)
+	if err != nil {
+		return entry, err
+	}
+	return q.ApplyCategoryRules(ctx, entry.ID)
+}
@@ -0,0 +1,266 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: totp.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const confirmUserTOTP = `-- name: ConfirmUserTOTP :one
+UPDATE user_totps
+SET confirmed_at = now(), last_used_step = $1
+WHERE username = $2 AND confirmed_at IS NULL
+RETURNING username, secret, confirmed_at, last_used_step, created_at
+`
+
+type ConfirmUserTOTPParams struct {
+	Step     int64  `json:"step"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) ConfirmUserTOTP(ctx context.Context, arg ConfirmUserTOTPParams) (UserTotp, error) {
+	row := q.db.QueryRow(ctx, confirmUserTOTP, arg.Step, arg.Username)
+	var i UserTotp
+	err := row.Scan(
+		&i.Username,
+		&i.Secret,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createMFAChallenge = `-- name: CreateMFAChallenge :one
+INSERT INTO mfa_challenges (
+  token_hash, username, expires_at
+) VALUES (
+  $1, $2, $3
+)
+RETURNING token_hash, username, expires_at, used_at, created_at
+`
+
+type CreateMFAChallengeParams struct {
+	TokenHash string    `json:"token_hash"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateMFAChallenge(ctx context.Context, arg CreateMFAChallengeParams) (MfaChallenge, error) {
+	row := q.db.QueryRow(ctx, createMFAChallenge, arg.TokenHash, arg.Username, arg.ExpiresAt)
+	var i MfaChallenge
+	err := row.Scan(
+		&i.TokenHash,
+		&i.Username,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createRecoveryCode = `-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (
+  username, code_hash
+) VALUES (
+  $1, $2
+)
+RETURNING id, username, code_hash, used_at, created_at
+`
+
+type CreateRecoveryCodeParams struct {
+	Username string `json:"username"`
+	CodeHash string `json:"code_hash"`
+}
+
+func (q *Queries) CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error) {
+	row := q.db.QueryRow(ctx, createRecoveryCode, arg.Username, arg.CodeHash)
+	var i RecoveryCode
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.CodeHash,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteExpiredMFAChallenges = `-- name: DeleteExpiredMFAChallenges :execrows
+DELETE FROM mfa_challenges
+WHERE expires_at < $1
+`
+
+func (q *Queries) DeleteExpiredMFAChallenges(ctx context.Context, expiresAt time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredMFAChallenges, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteRecoveryCodes = `-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1
+`
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, username string) error {
+	_, err := q.db.Exec(ctx, deleteRecoveryCodes, username)
+	return err
+}
+
+const deleteUserTOTP = `-- name: DeleteUserTOTP :exec
+DELETE FROM user_totps
+WHERE username = $1
+`
+
+func (q *Queries) DeleteUserTOTP(ctx context.Context, username string) error {
+	_, err := q.db.Exec(ctx, deleteUserTOTP, username)
+	return err
+}
+
+const getMFAChallenge = `-- name: GetMFAChallenge :one
+SELECT token_hash, username, expires_at, used_at, created_at FROM mfa_challenges
+WHERE token_hash = $1 LIMIT 1
+`
+
+func (q *Queries) GetMFAChallenge(ctx context.Context, tokenHash string) (MfaChallenge, error) {
+	row := q.db.QueryRow(ctx, getMFAChallenge, tokenHash)
+	var i MfaChallenge
+	err := row.Scan(
+		&i.TokenHash,
+		&i.Username,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUserTOTP = `-- name: GetUserTOTP :one
+SELECT username, secret, confirmed_at, last_used_step, created_at FROM user_totps
+WHERE username = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserTOTP(ctx context.Context, username string) (UserTotp, error) {
+	row := q.db.QueryRow(ctx, getUserTOTP, username)
+	var i UserTotp
+	err := row.Scan(
+		&i.Username,
+		&i.Secret,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const upsertUserTOTP = `-- name: UpsertUserTOTP :one
+INSERT INTO user_totps (
+  username, secret
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (username) DO UPDATE SET
+  secret = EXCLUDED.secret,
+  created_at = now()
+WHERE user_totps.confirmed_at IS NULL
+RETURNING username, secret, confirmed_at, last_used_step, created_at
+`
+
+type UpsertUserTOTPParams struct {
+	Username string `json:"username"`
+	Secret   string `json:"secret"`
+}
+
+// A pending enrolment is started over with a new secret, a confirmed one is
+// left alone and not returned.
+func (q *Queries) UpsertUserTOTP(ctx context.Context, arg UpsertUserTOTPParams) (UserTotp, error) {
+	row := q.db.QueryRow(ctx, upsertUserTOTP, arg.Username, arg.Secret)
+	var i UserTotp
+	err := row.Scan(
+		&i.Username,
+		&i.Secret,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useMFAChallenge = `-- name: UseMFAChallenge :one
+UPDATE mfa_challenges
+SET used_at = now()
+WHERE token_hash = $1 AND used_at IS NULL
+RETURNING token_hash, username, expires_at, used_at, created_at
+`
+
+// A challenge can only be completed once: it is not found when already used.
+func (q *Queries) UseMFAChallenge(ctx context.Context, tokenHash string) (MfaChallenge, error) {
+	row := q.db.QueryRow(ctx, useMFAChallenge, tokenHash)
+	var i MfaChallenge
+	err := row.Scan(
+		&i.TokenHash,
+		&i.Username,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :one
+UPDATE recovery_codes
+SET used_at = now()
+WHERE username = $1 AND code_hash = $2 AND used_at IS NULL
+RETURNING id, username, code_hash, used_at, created_at
+`
+
+type UseRecoveryCodeParams struct {
+	Username string `json:"username"`
+	CodeHash string `json:"code_hash"`
+}
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error) {
+	row := q.db.QueryRow(ctx, useRecoveryCode, arg.Username, arg.CodeHash)
+	var i RecoveryCode
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.CodeHash,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useTOTPStep = `-- name: UseTOTPStep :one
+UPDATE user_totps
+SET last_used_step = $1
+WHERE username = $2 AND last_used_step < $1
+RETURNING username, secret, confirmed_at, last_used_step, created_at
+`
+
+type UseTOTPStepParams struct {
+	Step     int64  `json:"step"`
+	Username string `json:"username"`
+}
+
+// A code is only accepted once: it is not found when its step, or a later
+// one, was already used.
+func (q *Queries) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (UserTotp, error) {
+	row := q.db.QueryRow(ctx, useTOTPStep, arg.Step, arg.Username)
+	var i UserTotp
+	err := row.Scan(
+		&i.Username,
+		&i.Secret,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmTOTPTx(t *testing.T) {
+	store := NewStore(testPool)
+	user, _ := createRandomUser(t)
+
+	totp, err := testQueries.UpsertUserTOTP(context.Background(), UpsertUserTOTPParams{
+		Username: user.Username,
+		Secret:   utils.RandomString(32),
+	})
+	require.NoError(t, err)
+	require.False(t, totp.ConfirmedAt.Valid)
+
+	// a pending enrolment is started over with the new secret
+	secret := utils.RandomString(32)
+	totp, err = testQueries.UpsertUserTOTP(context.Background(), UpsertUserTOTPParams{
+		Username: user.Username,
+		Secret:   secret,
+	})
+	require.NoError(t, err)
+	require.Equal(t, secret, totp.Secret)
+
+	hashes := []string{utils.RandomString(64), utils.RandomString(64)}
+	confirmed, err := store.ConfirmTOTPTx(context.Background(), ConfirmTOTPTxParams{
+		Username:           user.Username,
+		Step:               100,
+		RecoveryCodeHashes: hashes,
+	})
+	require.NoError(t, err)
+	require.True(t, confirmed.ConfirmedAt.Valid)
+	require.Equal(t, int64(100), confirmed.LastUsedStep)
+
+	// a confirmed enrolment is left alone
+	_, err = testQueries.UpsertUserTOTP(context.Background(), UpsertUserTOTPParams{
+		Username: user.Username,
+		Secret:   utils.RandomString(32),
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = store.ConfirmTOTPTx(context.Background(), ConfirmTOTPTxParams{
+		Username: user.Username,
+		Step:     101,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	code, err := testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: hashes[0],
+	})
+	require.NoError(t, err)
+	require.True(t, code.UsedAt.Valid)
+
+	// a recovery code is only used once
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: hashes[0],
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	user, _ := createRandomUser(t)
+
+	_, err := testQueries.UpsertUserTOTP(context.Background(), UpsertUserTOTPParams{
+		Username: user.Username,
+		Secret:   utils.RandomString(32),
+	})
+	require.NoError(t, err)
+
+	totp, err := testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{
+		Step:     10,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(10), totp.LastUsedStep)
+
+	// the same step, or an earlier one, is refused
+	for _, step := range []int64{10, 9} {
+		_, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{
+			Step:     step,
+			Username: user.Username,
+		})
+		require.ErrorIs(t, err, ErrRecordNotFound)
+	}
+}
+
+func TestDisableTOTPTx(t *testing.T) {
+	store := NewStore(testPool)
+	user, _ := createRandomUser(t)
+
+	_, err := testQueries.UpsertUserTOTP(context.Background(), UpsertUserTOTPParams{
+		Username: user.Username,
+		Secret:   utils.RandomString(32),
+	})
+	require.NoError(t, err)
+	hash := utils.RandomString(64)
+	_, err = store.ConfirmTOTPTx(context.Background(), ConfirmTOTPTxParams{
+		Username:           user.Username,
+		Step:               1,
+		RecoveryCodeHashes: []string{hash},
+	})
+	require.NoError(t, err)
+
+	err = store.DisableTOTPTx(context.Background(), user.Username)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetUserTOTP(context.Background(), user.Username)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: hash,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestMFAChallenge(t *testing.T) {
+	user, _ := createRandomUser(t)
+
+	arg := CreateMFAChallengeParams{
+		TokenHash: utils.RandomString(64),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}
+	challenge, err := testQueries.CreateMFAChallenge(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, challenge.UsedAt.Valid)
+
+	used, err := testQueries.UseMFAChallenge(context.Background(), arg.TokenHash)
+	require.NoError(t, err)
+	require.True(t, used.UsedAt.Valid)
+
+	// a challenge is only completed once
+	_, err = testQueries.UseMFAChallenge(context.Background(), arg.TokenHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	deleted, err := testQueries.DeleteExpiredMFAChallenges(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(1))
+
+	_, err = testQueries.GetMFAChallenge(context.Background(), arg.TokenHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
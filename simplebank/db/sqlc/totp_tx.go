@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+)
+
+// ConfirmTOTPTxParams contains the input parameters of the confirm TOTP transaction.
+type ConfirmTOTPTxParams struct {
+	Username string `json:"username"`
+	// Step is the time step of the code confirming the enrolment
+	Step int64 `json:"step"`
+	// RecoveryCodeHashes are the hashes of the new recovery codes
+	RecoveryCodeHashes []string `json:"recovery_code_hashes"`
+}
+
+// ConfirmTOTPTx enables the two-factor authentication of the user, whose
+// enrolment a first code confirmed. The recovery codes of a previous
+// enrolment are replaced by the new ones.
+func (store *SQLStore) ConfirmTOTPTx(ctx context.Context, arg ConfirmTOTPTxParams) (UserTotp, error) {
+	var totp UserTotp
+
+	err := store.execTx(ctx, TxConfirmTOTP, func(q *Queries) error {
+		var err error
+		totp, err = q.ConfirmUserTOTP(ctx, ConfirmUserTOTPParams{
+			Username: arg.Username,
+			Step:     arg.Step,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := q.DeleteRecoveryCodes(ctx, arg.Username); err != nil {
+			return err
+		}
+		for _, codeHash := range arg.RecoveryCodeHashes {
+			_, err := q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				Username: arg.Username,
+				CodeHash: codeHash,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return totp, err
+}
+
+// DisableTOTPTx disables the two-factor authentication of the user, deleting
+// its secret along with its recovery codes.
+func (store *SQLStore) DisableTOTPTx(ctx context.Context, username string) error {
+	return store.execTx(ctx, TxDisableTOTP, func(q *Queries) error {
+		if err := q.DeleteUserTOTP(ctx, username); err != nil {
+			return err
+		}
+		return q.DeleteRecoveryCodes(ctx, username)
+	})
+}
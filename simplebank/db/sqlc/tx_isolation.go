@@ -0,0 +1,126 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultTxIsolation is the isolation level of the transactions of the stores
+// created by NewStore, unless WithTxIsolation is given.
+const DefaultTxIsolation = pgx.ReadCommitted
+
+// The names of the transactions of the SQLStore, the ones of their Store
+// methods, by which their isolation level can be set with WithTxIsolationFor
+// or in the overrides parsed by ParseTxIsolationOverrides.
+const (
+	TxTransfer          = "TransferTx"
+	TxCreateAccount     = "CreateAccountTx"
+	TxAddAccountBalance = "AddAccountBalanceTx"
+	TxDeleteAccount     = "DeleteAccountTx"
+	TxDispatchOutbox    = "DispatchOutboxTx"
+	TxGoalTransfer      = "GoalTransferTx"
+	TxContributeGoal    = "ContributeGoalTx"
+	TxDeleteGoal        = "DeleteGoalTx"
+	TxRotateSession     = "RotateSessionTx"
+	TxRevokeUserTokens  = "RevokeUserTokensTx"
+	TxUpdateUserRole    = "UpdateUserRoleTx"
+	TxFreezeUser        = "FreezeUserTx"
+	TxUpdateUser        = "UpdateUserTx"
+	TxConfirmTOTP       = "ConfirmTOTPTx"
+	TxDisableTOTP       = "DisableTOTPTx"
+	TxCreateUser        = "CreateUserTx"
+	TxVerifyEmail       = "VerifyEmailTx"
+	TxChangePassword    = "ChangePasswordTx"
+	TxResetPassword     = "ResetPasswordTx"
+	TxCountLoginAttempt = "CountLoginAttemptTx"
+	TxDropPartition     = "DropPartition"
+)
+
+var txNames = map[string]bool{
+	TxTransfer:          true,
+	TxCreateAccount:     true,
+	TxAddAccountBalance: true,
+	TxDeleteAccount:     true,
+	TxDispatchOutbox:    true,
+	TxGoalTransfer:      true,
+	TxContributeGoal:    true,
+	TxDeleteGoal:        true,
+	TxRotateSession:     true,
+	TxRevokeUserTokens:  true,
+	TxUpdateUserRole:    true,
+	TxFreezeUser:        true,
+	TxUpdateUser:        true,
+	TxConfirmTOTP:       true,
+	TxDisableTOTP:       true,
+	TxCreateUser:        true,
+	TxVerifyEmail:       true,
+	TxChangePassword:    true,
+	TxResetPassword:     true,
+	TxCountLoginAttempt: true,
+	TxDropPartition:     true,
+}
+
+var txIsolationLevels = map[string]pgx.TxIsoLevel{
+	string(pgx.Serializable):    pgx.Serializable,
+	string(pgx.RepeatableRead):  pgx.RepeatableRead,
+	string(pgx.ReadCommitted):   pgx.ReadCommitted,
+	string(pgx.ReadUncommitted): pgx.ReadUncommitted,
+}
+
+// WithTxIsolation sets the isolation level of the transactions of the Store
+// which have none set by WithTxIsolationFor.
+func WithTxIsolation(isolation pgx.TxIsoLevel) StoreOption {
+	return func(store *SQLStore) {
+		store.isolation = isolation
+	}
+}
+
+// WithTxIsolationFor sets the isolation level of a transaction of the Store,
+// named after its Store method, e.g. TransferTx.
+func WithTxIsolationFor(name string, isolation pgx.TxIsoLevel) StoreOption {
+	return func(store *SQLStore) {
+		store.isolations[name] = isolation
+	}
+}
+
+// txIsolation returns the isolation level of the named transaction.
+func (store *SQLStore) txIsolation(name string) pgx.TxIsoLevel {
+	if isolation, ok := store.isolations[name]; ok {
+		return isolation
+	}
+	return store.isolation
+}
+
+// ParseTxIsolation parses an isolation level written as in SQL, e.g. "repeatable read".
+func ParseTxIsolation(s string) (pgx.TxIsoLevel, error) {
+	isolation, ok := txIsolationLevels[strings.Join(strings.Fields(strings.ToLower(s)), " ")]
+	if !ok {
+		return "", fmt.Errorf("invalid isolation level %q", s)
+	}
+	return isolation, nil
+}
+
+// ParseTxIsolationOverrides parses a comma-separated list of the isolation
+// levels of some transactions, e.g. "TransferTx=serializable,DeleteGoalTx=repeatable read".
+func ParseTxIsolationOverrides(s string) (map[string]pgx.TxIsoLevel, error) {
+	overrides := make(map[string]pgx.TxIsoLevel)
+	for _, override := range strings.Split(s, ",") {
+		if strings.TrimSpace(override) == "" {
+			continue
+		}
+
+		name, level, ok := strings.Cut(override, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !txNames[name] {
+			return nil, fmt.Errorf("invalid transaction isolation %q: expected name=level with the name of a transaction", override)
+		}
+		isolation, err := ParseTxIsolation(level)
+		if err != nil {
+			return nil, err
+		}
+		overrides[name] = isolation
+	}
+	return overrides, nil
+}
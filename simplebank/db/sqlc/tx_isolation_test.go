@@ -0,0 +1,53 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTxIsolation(t *testing.T) {
+	isolation, err := ParseTxIsolation("serializable")
+	require.NoError(t, err)
+	require.Equal(t, pgx.Serializable, isolation)
+
+	isolation, err = ParseTxIsolation(" Repeatable  Read ")
+	require.NoError(t, err)
+	require.Equal(t, pgx.RepeatableRead, isolation)
+
+	_, err = ParseTxIsolation("snapshot")
+	require.Error(t, err)
+}
+
+func TestParseTxIsolationOverrides(t *testing.T) {
+	overrides, err := ParseTxIsolationOverrides("TransferTx=serializable, DeleteGoalTx=repeatable read")
+	require.NoError(t, err)
+	require.Equal(t, map[string]pgx.TxIsoLevel{
+		TxTransfer:   pgx.Serializable,
+		TxDeleteGoal: pgx.RepeatableRead,
+	}, overrides)
+
+	overrides, err = ParseTxIsolationOverrides("")
+	require.NoError(t, err)
+	require.Empty(t, overrides)
+
+	_, err = ParseTxIsolationOverrides("UnknownTx=serializable")
+	require.Error(t, err)
+	_, err = ParseTxIsolationOverrides("TransferTx")
+	require.Error(t, err)
+	_, err = ParseTxIsolationOverrides("TransferTx=snapshot")
+	require.Error(t, err)
+}
+
+func TestTxIsolation(t *testing.T) {
+	store := NewStore(testPool).(*SQLStore)
+	require.Equal(t, DefaultTxIsolation, store.txIsolation(TxTransfer))
+
+	store = NewStore(testPool,
+		WithTxIsolation(pgx.RepeatableRead),
+		WithTxIsolationFor(TxTransfer, pgx.Serializable),
+	).(*SQLStore)
+	require.Equal(t, pgx.Serializable, store.txIsolation(TxTransfer))
+	require.Equal(t, pgx.RepeatableRead, store.txIsolation(TxDeleteGoal))
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// TxRetryPolicy bounds the retries of the transactions aborted by Postgres
+// because of a serialization failure or a deadlock.
+type TxRetryPolicy struct {
+	// MaxAttempts is the total number of attempts, the first one included
+	MaxAttempts int
+	// BaseDelay is the maximum wait before the first retry, it doubles at each retry
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between two attempts
+	MaxDelay time.Duration
+}
+
+// DefaultTxRetryPolicy is the retry policy of the stores created by NewStore.
+var DefaultTxRetryPolicy = TxRetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    200 * time.Millisecond,
+}
+
+// retryableTxErrors are the SQLSTATE of the errors after which the whole
+// transaction can safely be run again.
+var retryableTxErrors = map[string]string{
+	"40001": "serialization_failure",
+	"40P01": "deadlock_detected",
+}
+
+// txRetries counts the transaction retries per error, and the transactions
+// which still failed after the last attempt. It is published by expvar as db_tx_retries.
+var txRetries = expvar.NewMap("db_tx_retries")
+
+// retryableTxError returns the name of the error when the transaction can be retried.
+func retryableTxError(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return "", false
+	}
+	name, ok := retryableTxErrors[pgErr.Code]
+	return name, ok
+}
+
+// delay returns the wait before the given retry, with full jitter.
+func (policy TxRetryPolicy) delay(retry int) time.Duration {
+	delay := policy.BaseDelay << (retry - 1)
+	if delay <= 0 || delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// wait sleeps before the given retry, unless the context is done first.
+func (policy TxRetryPolicy) wait(ctx context.Context, retry int) error {
+	timer := time.NewTimer(policy.delay(retry))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxRetryPolicyDelay(t *testing.T) {
+	policy := TxRetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   10 * time.Millisecond,
+		MaxDelay:    25 * time.Millisecond,
+	}
+
+	for i := 0; i < 100; i++ {
+		require.LessOrEqual(t, policy.delay(1), 10*time.Millisecond)
+		require.LessOrEqual(t, policy.delay(2), 20*time.Millisecond)
+		require.LessOrEqual(t, policy.delay(3), 25*time.Millisecond)
+		require.LessOrEqual(t, policy.delay(64), 25*time.Millisecond)
+		require.GreaterOrEqual(t, policy.delay(64), time.Duration(0))
+	}
+}
+
+func TestRetryableTxError(t *testing.T) {
+	name, ok := retryableTxError(&pgconn.PgError{Code: "40001"})
+	require.True(t, ok)
+	require.Equal(t, "serialization_failure", name)
+
+	name, ok = retryableTxError(fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: "40P01"}))
+	require.True(t, ok)
+	require.Equal(t, "deadlock_detected", name)
+
+	_, ok = retryableTxError(&pgconn.PgError{Code: UniqueViolation})
+	require.False(t, ok)
+
+	_, ok = retryableTxError(ErrRecordNotFound)
+	require.False(t, ok)
+}
+
+func TestExecTxRetry(t *testing.T) {
+	store := NewStore(testPool, WithRetryPolicy(TxRetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Millisecond,
+	})).(*SQLStore)
+
+	// succeeds on the last attempt
+	attempts := 0
+	err := store.execTx(context.Background(), TxTransfer, func(q *Queries) error {
+		attempts++
+		if attempts < 3 {
+			return &pgconn.PgError{Code: "40001"}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+
+	// gives up after the last attempt
+	attempts = 0
+	err = store.execTx(context.Background(), TxTransfer, func(q *Queries) error {
+		attempts++
+		return &pgconn.PgError{Code: "40P01"}
+	})
+	require.Error(t, err)
+	require.Equal(t, 3, attempts)
+
+	// other errors are not retried
+	attempts = 0
+	err = store.execTx(context.Background(), TxTransfer, func(q *Queries) error {
+		attempts++
+		return ErrRecordNotFound
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+	require.Equal(t, 1, attempts)
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/ebaudet/simplebank/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
@@ -35,6 +37,8 @@ func TestCreateUser(t *testing.T) {
 	require.Equal(t, arg.HashedPassword, user.HashedPassword)
 	require.Equal(t, arg.FullName, user.FullName)
 	require.Equal(t, arg.Email, user.Email)
+	require.Equal(t, utils.DepositorRole, user.Role)
+	require.False(t, user.IsFrozen)
 
 	require.NotZero(t, user.CreatedAt)
 }
@@ -52,3 +56,146 @@ func TestGetUser(t *testing.T) {
 	require.WithinDuration(t, user1.PasswordChangedAt, user2.PasswordChangedAt, time.Second)
 	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
 }
+
+func TestUpdateUserRoleTx(t *testing.T) {
+	store := NewStore(testPool)
+	user, _ := createRandomUser(t)
+	session, err := testQueries.CreateSession(context.Background(), randomSessionParams(user.Username, uuid.Nil))
+	require.NoError(t, err)
+
+	updated, err := store.UpdateUserRoleTx(context.Background(), UpdateUserRoleTxParams{
+		Username:     user.Username,
+		Role:         utils.BankerRole,
+		IssuedBefore: time.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, utils.BankerRole, updated.Role)
+	require.True(t, updated.TokensValidAfter.After(user.TokensValidAfter))
+
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+
+	_, err = store.UpdateUserRoleTx(context.Background(), UpdateUserRoleTxParams{
+		Username:     user.Username,
+		Role:         "superuser",
+		IssuedBefore: time.Now(),
+	})
+	require.Error(t, err)
+	require.Equal(t, CheckViolation, ErrorCode(err))
+}
+
+func TestFreezeUserTx(t *testing.T) {
+	store := NewStore(testPool)
+	user, _ := createRandomUser(t)
+	session, err := testQueries.CreateSession(context.Background(), randomSessionParams(user.Username, uuid.Nil))
+	require.NoError(t, err)
+
+	frozen, err := store.FreezeUserTx(context.Background(), FreezeUserTxParams{
+		Username:     user.Username,
+		Frozen:       true,
+		IssuedBefore: time.Now(),
+	})
+	require.NoError(t, err)
+	require.True(t, frozen.IsFrozen)
+	require.True(t, frozen.TokensValidAfter.After(user.TokensValidAfter))
+
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+
+	unfrozen, err := store.FreezeUserTx(context.Background(), FreezeUserTxParams{
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+	require.False(t, unfrozen.IsFrozen)
+	require.Equal(t, frozen.TokensValidAfter, unfrozen.TokensValidAfter)
+
+	_, err = store.FreezeUserTx(context.Background(), FreezeUserTxParams{
+		Username: utils.RandomOwner(),
+		Frozen:   true,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUpdateUserTx(t *testing.T) {
+	store := NewStore(testPool)
+	user := createRandomUserTx(t).User
+
+	user, err := testQueries.VerifyUserEmail(context.Background(), VerifyUserEmailParams{
+		Username: user.Username,
+		Email:    user.Email,
+	})
+	require.NoError(t, err)
+
+	// only the full name changes
+	fullName := utils.RandomFullName()
+	result, err := store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username: user.Username,
+			FullName: pgtype.Text{String: fullName, Valid: true},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, fullName, result.User.FullName)
+	require.Equal(t, user.Email, result.User.Email)
+	require.True(t, result.User.IsEmailVerified)
+	require.Nil(t, result.VerifyEmail)
+
+	// the same email keeps it verified
+	result, err = store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username: user.Username,
+			Email:    pgtype.Text{String: user.Email, Valid: true},
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, result.User.IsEmailVerified)
+	require.Nil(t, result.VerifyEmail)
+
+	// a new email has to be verified again
+	email := utils.RandomEmail()
+	arg := UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username: user.Username,
+			Email:    pgtype.Text{String: email, Valid: true},
+		},
+		VerifyEmailSecretCodeHash: utils.RandomString(64),
+		VerifyEmailExpiresAt:      time.Now().Add(time.Hour),
+	}
+	result, err = store.UpdateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, fullName, result.User.FullName)
+	require.Equal(t, email, result.User.Email)
+	require.False(t, result.User.IsEmailVerified)
+	require.NotNil(t, result.VerifyEmail)
+	require.Equal(t, email, result.VerifyEmail.Email)
+	require.Equal(t, arg.VerifyEmailSecretCodeHash, result.VerifyEmail.SecretCodeHash)
+}
+
+func TestRehashUserPassword(t *testing.T) {
+	user, _ := createRandomUser(t)
+
+	// the hash is kept if the password was changed meanwhile
+	err := testQueries.RehashUserPassword(context.Background(), RehashUserPasswordParams{
+		Username:          user.Username,
+		HashedPassword:    utils.RandomString(60),
+		NewHashedPassword: utils.RandomString(60),
+	})
+	require.NoError(t, err)
+	got, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.HashedPassword, got.HashedPassword)
+
+	arg := RehashUserPasswordParams{
+		Username:          user.Username,
+		HashedPassword:    user.HashedPassword,
+		NewHashedPassword: utils.RandomString(60),
+	}
+	err = testQueries.RehashUserPassword(context.Background(), arg)
+	require.NoError(t, err)
+	got, err = testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, arg.NewHashedPassword, got.HashedPassword)
+	require.Equal(t, user.PasswordChangedAt, got.PasswordChangedAt)
+}
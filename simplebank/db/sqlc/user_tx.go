@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// UpdateUserRoleTxParams contains the input parameters of the update user role transaction.
+type UpdateUserRoleTxParams struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	// IssuedBefore is the cutoff, the tokens issued before it carry the old role and are revoked
+	IssuedBefore time.Time `json:"issued_before"`
+}
+
+// UpdateUserRoleTx changes the role of the user. As the access tokens carry
+// the role, the ones issued before the change are revoked and the sessions
+// blocked, so that the user has to log in again to get the new role.
+func (store *SQLStore) UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, TxUpdateUserRole, func(q *Queries) error {
+		_, err := q.UpdateUserRole(ctx, UpdateUserRoleParams{
+			Username: arg.Username,
+			Role:     arg.Role,
+		})
+		if err != nil {
+			return err
+		}
+
+		user, err = revokeUserTokensOf(ctx, q, arg.Username, arg.IssuedBefore)
+		return err
+	})
+
+	return user, err
+}
+
+// FreezeUserTxParams contains the input parameters of the freeze user transaction.
+type FreezeUserTxParams struct {
+	Username string `json:"username"`
+	// Frozen is false to unfreeze the user
+	Frozen bool `json:"frozen"`
+	// IssuedBefore is the cutoff, the tokens issued before it are revoked when freezing
+	IssuedBefore time.Time `json:"issued_before"`
+}
+
+// FreezeUserTx freezes or unfreezes the user. Freezing also revokes the
+// access tokens issued before the cutoff and blocks all the sessions, so that
+// the user is shut out right away.
+func (store *SQLStore) FreezeUserTx(ctx context.Context, arg FreezeUserTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, TxFreezeUser, func(q *Queries) error {
+		var err error
+		user, err = q.UpdateUserFrozen(ctx, UpdateUserFrozenParams{
+			Username: arg.Username,
+			IsFrozen: arg.Frozen,
+		})
+		if err != nil || !arg.Frozen {
+			return err
+		}
+
+		user, err = revokeUserTokensOf(ctx, q, arg.Username, arg.IssuedBefore)
+		return err
+	})
+
+	return user, err
+}
+
+// UpdateUserTxParams contains the input parameters of the update user transaction.
+type UpdateUserTxParams struct {
+	UpdateUserParams
+	// VerifyEmailSecretCodeHash is the hash of the secret code of the link sent when the email changes
+	VerifyEmailSecretCodeHash string    `json:"verify_email_secret_code_hash"`
+	VerifyEmailExpiresAt      time.Time `json:"verify_email_expires_at"`
+}
+
+// UpdateUserTxResult is the result of the update user transaction.
+type UpdateUserTxResult struct {
+	User User `json:"user"`
+	// VerifyEmail is the link verifying the new email, nil if the email is unchanged
+	VerifyEmail *VerifyEmail `json:"verify_email,omitempty"`
+}
+
+// UpdateUserTx updates the profile of the user. When the email is changed,
+// it has to be verified again, and the link verifying it is created along.
+// Giving again the email of a user still unverified creates a new link too.
+func (store *SQLStore) UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error) {
+	var result UpdateUserTxResult
+
+	err := store.execTx(ctx, TxUpdateUser, func(q *Queries) error {
+		result = UpdateUserTxResult{}
+
+		var err error
+		result.User, err = q.UpdateUser(ctx, arg.UpdateUserParams)
+		if err != nil {
+			return err
+		}
+		if !arg.Email.Valid || result.User.IsEmailVerified {
+			return nil
+		}
+
+		verifyEmail, err := q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:       result.User.Username,
+			Email:          result.User.Email,
+			SecretCodeHash: arg.VerifyEmailSecretCodeHash,
+			ExpiresAt:      arg.VerifyEmailExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+		result.VerifyEmail = &verifyEmail
+		return nil
+	})
+
+	return result, err
+}
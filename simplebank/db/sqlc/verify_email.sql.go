@@ -0,0 +1,112 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: verify_email.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createVerifyEmail = `-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (
+  username, email, secret_code_hash, expires_at
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING id, username, email, secret_code_hash, expires_at, used_at, created_at
+`
+
+type CreateVerifyEmailParams struct {
+	Username       string    `json:"username"`
+	Email          string    `json:"email"`
+	SecretCodeHash string    `json:"secret_code_hash"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error) {
+	row := q.db.QueryRow(ctx, createVerifyEmail,
+		arg.Username,
+		arg.Email,
+		arg.SecretCodeHash,
+		arg.ExpiresAt,
+	)
+	var i VerifyEmail
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.SecretCodeHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteExpiredVerifyEmails = `-- name: DeleteExpiredVerifyEmails :execrows
+DELETE FROM verify_emails
+WHERE expires_at < $1
+`
+
+func (q *Queries) DeleteExpiredVerifyEmails(ctx context.Context, expiresAt time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredVerifyEmails, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getVerifyEmail = `-- name: GetVerifyEmail :one
+SELECT id, username, email, secret_code_hash, expires_at, used_at, created_at FROM verify_emails
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetVerifyEmail(ctx context.Context, id int64) (VerifyEmail, error) {
+	row := q.db.QueryRow(ctx, getVerifyEmail, id)
+	var i VerifyEmail
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.SecretCodeHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useVerifyEmail = `-- name: UseVerifyEmail :one
+UPDATE verify_emails
+SET used_at = now()
+WHERE id = $1
+  AND secret_code_hash = $2
+  AND used_at IS NULL
+  AND expires_at > now()
+RETURNING id, username, email, secret_code_hash, expires_at, used_at, created_at
+`
+
+type UseVerifyEmailParams struct {
+	ID             int64  `json:"id"`
+	SecretCodeHash string `json:"secret_code_hash"`
+}
+
+// A link can only be followed once, before it expires: it is not found
+// otherwise.
+func (q *Queries) UseVerifyEmail(ctx context.Context, arg UseVerifyEmailParams) (VerifyEmail, error) {
+	row := q.db.QueryRow(ctx, useVerifyEmail, arg.ID, arg.SecretCodeHash)
+	var i VerifyEmail
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.SecretCodeHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomUserTx(t *testing.T) CreateUserTxResult {
+	store := NewStore(testPool)
+
+	hash, err := utils.HashPassword(utils.RandomPassword(6, 25))
+	require.NoError(t, err)
+
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       utils.RandomOwner(),
+			HashedPassword: hash,
+			FullName:       utils.RandomFullName(),
+			Email:          utils.RandomEmail(),
+		},
+		VerifyEmailSecretCodeHash: utils.RandomString(64),
+		VerifyEmailExpiresAt:      time.Now().Add(time.Hour),
+	}
+
+	result, err := store.CreateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, result.User.Username)
+	require.False(t, result.User.IsEmailVerified)
+	require.Equal(t, arg.Username, result.VerifyEmail.Username)
+	require.Equal(t, arg.Email, result.VerifyEmail.Email)
+	require.Equal(t, arg.VerifyEmailSecretCodeHash, result.VerifyEmail.SecretCodeHash)
+	require.False(t, result.VerifyEmail.UsedAt.Valid)
+
+	return result
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testPool)
+	result := createRandomUserTx(t)
+
+	// the secret code must match
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		ID:             result.VerifyEmail.ID,
+		SecretCodeHash: utils.RandomString(64),
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	arg := VerifyEmailTxParams{
+		ID:             result.VerifyEmail.ID,
+		SecretCodeHash: result.VerifyEmail.SecretCodeHash,
+	}
+	user, err := store.VerifyEmailTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, user.IsEmailVerified)
+
+	// a link can only be followed once
+	_, err = store.VerifyEmailTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestVerifyEmailTxExpired(t *testing.T) {
+	store := NewStore(testPool)
+	user, _ := createRandomUser(t)
+
+	verifyEmail, err := testQueries.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:       user.Username,
+		Email:          user.Email,
+		SecretCodeHash: utils.RandomString(64),
+		ExpiresAt:      time.Now().Add(-time.Second),
+	})
+	require.NoError(t, err)
+
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		ID:             verifyEmail.ID,
+		SecretCodeHash: verifyEmail.SecretCodeHash,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	deleted, err := testQueries.DeleteExpiredVerifyEmails(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(1))
+}
+
+func TestVerifyEmailTxOtherEmail(t *testing.T) {
+	store := NewStore(testPool)
+	user, _ := createRandomUser(t)
+
+	// the link was sent to an email the user no longer has
+	verifyEmail, err := testQueries.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:       user.Username,
+		Email:          utils.RandomEmail(),
+		SecretCodeHash: utils.RandomString(64),
+		ExpiresAt:      time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	arg := VerifyEmailTxParams{
+		ID:             verifyEmail.ID,
+		SecretCodeHash: verifyEmail.SecretCodeHash,
+	}
+	_, err = store.VerifyEmailTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// the link is left unused, as the transaction was rolled back
+	got, err := testQueries.GetVerifyEmail(context.Background(), verifyEmail.ID)
+	require.NoError(t, err)
+	require.False(t, got.UsedAt.Valid)
+
+	unverified, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.False(t, unverified.IsEmailVerified)
+}
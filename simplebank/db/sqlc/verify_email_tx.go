@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// CreateUserTxParams contains the input parameters of the create user transaction.
+type CreateUserTxParams struct {
+	CreateUserParams
+	// VerifyEmailSecretCodeHash is the hash of the secret code of the link sent to verify the email
+	VerifyEmailSecretCodeHash string    `json:"verify_email_secret_code_hash"`
+	VerifyEmailExpiresAt      time.Time `json:"verify_email_expires_at"`
+}
+
+// CreateUserTxResult is the result of the create user transaction.
+type CreateUserTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// CreateUserTx creates the user along with the link verifying its email, so
+// that a user can't be left without one.
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, TxCreateUser, func(q *Queries) error {
+		var err error
+		result.User, err = q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:       result.User.Username,
+			Email:          result.User.Email,
+			SecretCodeHash: arg.VerifyEmailSecretCodeHash,
+			ExpiresAt:      arg.VerifyEmailExpiresAt,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// VerifyEmailTxParams contains the input parameters of the verify email transaction.
+type VerifyEmailTxParams struct {
+	ID             int64  `json:"id"`
+	SecretCodeHash string `json:"secret_code_hash"`
+}
+
+// VerifyEmailTx uses the link and marks the email of its user as verified.
+// It returns ErrRecordNotFound if the link is unknown, used or expired, or if
+// the user changed its email since the link was sent.
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, TxVerifyEmail, func(q *Queries) error {
+		verifyEmail, err := q.UseVerifyEmail(ctx, UseVerifyEmailParams{
+			ID:             arg.ID,
+			SecretCodeHash: arg.SecretCodeHash,
+		})
+		if err != nil {
+			return err
+		}
+
+		user, err = q.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			Username: verifyEmail.Username,
+			Email:    verifyEmail.Email,
+		})
+		return err
+	})
+
+	return user, err
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomWebhookSubscription(t *testing.T, owner string, eventTypes ...string) WebhookSubscription {
+	arg := CreateWebhookSubscriptionParams{
+		Owner:      owner,
+		Url:        "https://example.com/" + utils.RandomString(8),
+		Secret:     utils.RandomString(32),
+		EventTypes: eventTypes,
+	}
+
+	subscription, err := testQueries.CreateWebhookSubscription(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Owner, subscription.Owner)
+	require.Equal(t, arg.Url, subscription.Url)
+	require.Equal(t, arg.EventTypes, subscription.EventTypes)
+	require.True(t, subscription.Active)
+
+	return subscription
+}
+
+func dispatchOutbox(t *testing.T, store Store) {
+	for {
+		dispatched, err := store.DispatchOutboxTx(context.Background(), 100)
+		require.NoError(t, err)
+		if dispatched < 100 {
+			return
+		}
+	}
+}
+
+func TestDispatchOutboxTx(t *testing.T) {
+	store := NewStore(testPool)
+	user, _ := createRandomUser(t)
+	subscribed := createRandomWebhookSubscription(t, user.Username, EventAccountCreated)
+	other := createRandomWebhookSubscription(t, user.Username, EventTransferCreated)
+
+	_, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  0,
+		Currency: utils.RandomCurrency(),
+	})
+	require.NoError(t, err)
+
+	dispatchOutbox(t, store)
+
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		SubscriptionID: subscribed.ID,
+		Limit:          5,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, "pending", deliveries[0].Status)
+	require.Zero(t, deliveries[0].Attempts)
+
+	deliveries, err = testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		SubscriptionID: other.ID,
+		Limit:          5,
+	})
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+
+	// dispatching again doesn't duplicate the deliveries
+	dispatchOutbox(t, store)
+	deliveries, err = testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		SubscriptionID: subscribed.ID,
+		Limit:          5,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+}
+
+func TestRetryWebhookDelivery(t *testing.T) {
+	store := NewStore(testPool)
+	user, _ := createRandomUser(t)
+	subscription := createRandomWebhookSubscription(t, user.Username, EventAccountBalanceChanged)
+	account, _ := createRandomAccount(t)
+
+	// the balance change of someone else's account doesn't notify the user
+	_, err := store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{ID: account.ID, Amount: 10})
+	require.NoError(t, err)
+
+	account, err = store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  0,
+		Currency: utils.RandomCurrency(),
+	})
+	require.NoError(t, err)
+	_, err = store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{ID: account.ID, Amount: 10})
+	require.NoError(t, err)
+
+	dispatchOutbox(t, store)
+
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		SubscriptionID: subscription.ID,
+		Limit:          5,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	delivery, err := testQueries.UpdateWebhookDelivery(context.Background(), UpdateWebhookDeliveryParams{
+		ID:             deliveries[0].ID,
+		Status:         "dead",
+		NextAttemptAt:  time.Now(),
+		ResponseStatus: 500,
+		LastError:      "unexpected status 500",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "dead", delivery.Status)
+	require.Equal(t, int32(1), delivery.Attempts)
+	require.False(t, delivery.DeliveredAt.Valid)
+
+	delivery, err = testQueries.RetryWebhookDelivery(context.Background(), RetryWebhookDeliveryParams{
+		ID:             delivery.ID,
+		SubscriptionID: subscription.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "pending", delivery.Status)
+	require.Zero(t, delivery.Attempts)
+
+	// only dead deliveries can be retried
+	_, err = testQueries.RetryWebhookDelivery(context.Background(), RetryWebhookDeliveryParams{
+		ID:             delivery.ID,
+		SubscriptionID: subscription.ID,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
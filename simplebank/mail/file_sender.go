@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileSender is a stand-in for development, writing the emails in a
+// directory instead of sending them, or to the log.
+type FileSender struct {
+	from string
+	dir  string
+}
+
+// NewFileSender creates a new FileSender writing in dir, or to the log if dir is empty.
+func NewFileSender(from string, dir string) *FileSender {
+	return &FileSender{from: from, dir: dir}
+}
+
+// SendEmail writes the email to a new .eml file.
+func (sender *FileSender) SendEmail(ctx context.Context, email Email) error {
+	msg, err := message(sender.from, email, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if sender.dir == "" {
+		log.Printf("email to %s:\n%s", email.To, msg)
+		return nil
+	}
+
+	if err := os.MkdirAll(sender.dir, 0700); err != nil {
+		return fmt.Errorf("cannot create email directory: %w", err)
+	}
+	path := filepath.Join(sender.dir, fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.NewString()))
+	if err := os.WriteFile(path, msg, 0600); err != nil {
+		return fmt.Errorf("cannot write email: %w", err)
+	}
+	log.Printf("email to %s written to %s", email.To, path)
+	return nil
+}
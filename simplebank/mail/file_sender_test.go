@@ -0,0 +1,38 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSender(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mails")
+	sender := NewFileSender(testFrom, dir)
+
+	email := Email{To: "john@example.com", Subject: "hello", Body: "welcome"}
+	err := sender.SendEmail(context.Background(), email)
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	require.Contains(t, string(data), "To: john@example.com\r\n")
+	require.Contains(t, string(data), "\r\n\r\nwelcome")
+}
+
+func TestFileSenderLog(t *testing.T) {
+	sender := NewFileSender(testFrom, "")
+
+	err := sender.SendEmail(context.Background(), Email{To: "john@example.com", Subject: "hello", Body: "welcome"})
+	require.NoError(t, err)
+
+	err = sender.SendEmail(context.Background(), Email{To: "john@example.com", Subject: "hello\r\nBcc: eve@example.com"})
+	require.ErrorIs(t, err, errHeaderInjection)
+}
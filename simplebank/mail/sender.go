@@ -0,0 +1,93 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Kinds of Sender.
+const (
+	SenderSMTP = "smtp"
+	SenderFile = "file"
+)
+
+// defaultFrom is the address the emails are sent from when none is set up.
+const defaultFrom = "SimpleBank <no-reply@localhost>"
+
+var errHeaderInjection = errors.New("email header must not contain line breaks")
+
+// Email is a plain text email.
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender is an interface for sending emails.
+type Sender interface {
+	// SendEmail sends the email, from the address the sender is set up with
+	SendEmail(ctx context.Context, email Email) error
+}
+
+// Config sets up the Sender created by NewSender.
+type Config struct {
+	// Kind is smtp, or file for development
+	Kind string
+	// From is the address the emails are sent from, e.g. SimpleBank <no-reply@simplebank.com>,
+	// defaultFrom if empty
+	From string
+	// Dir is where the file sender writes the emails, the emails are logged if it's empty
+	Dir string
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// NewSender creates a new Sender of the kind of the config.
+func NewSender(config Config) (Sender, error) {
+	if config.From == "" {
+		config.From = defaultFrom
+	}
+	if _, err := mail.ParseAddress(config.From); err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", config.From, err)
+	}
+
+	switch config.Kind {
+	case SenderFile, "":
+		return NewFileSender(config.From, config.Dir), nil
+	case SenderSMTP:
+		return NewSMTPSender(config.From, config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword)
+	default:
+		return nil, fmt.Errorf("unsupported email sender %q", config.Kind)
+	}
+}
+
+// message returns the email in the Internet Message Format of RFC 5322.
+func message(from string, email Email, date time.Time) ([]byte, error) {
+	for _, header := range []string{from, email.To, email.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, errHeaderInjection
+		}
+	}
+	if _, err := mail.ParseAddress(email.To); err != nil {
+		return nil, fmt.Errorf("invalid recipient address %q: %w", email.To, err)
+	}
+
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + email.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", email.Subject) + "\r\n")
+	b.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(email.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String()), nil
+}
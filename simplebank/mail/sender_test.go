@@ -0,0 +1,85 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testFrom = "SimpleBank <no-reply@simplebank.com>"
+
+func TestMessage(t *testing.T) {
+	date := time.Date(2022, 10, 15, 12, 0, 0, 0, time.UTC)
+	email := Email{
+		To:      "john@example.com",
+		Subject: "Vérifiez votre email",
+		Body:    "Hello,\nwelcome.",
+	}
+
+	msg, err := message(testFrom, email, date)
+	require.NoError(t, err)
+
+	header, body, ok := strings.Cut(string(msg), "\r\n\r\n")
+	require.True(t, ok)
+	require.Contains(t, header, "From: "+testFrom+"\r\n")
+	require.Contains(t, header, "To: john@example.com\r\n")
+	require.Contains(t, header, "Subject: =?utf-8?q?")
+	require.Contains(t, header, "Date: Sat, 15 Oct 2022 12:00:00 +0000\r\n")
+	require.Contains(t, header, "Content-Type: text/plain; charset=utf-8")
+	require.Equal(t, "Hello,\r\nwelcome.", body)
+}
+
+func TestMessageHeaderInjection(t *testing.T) {
+	testCases := []struct {
+		name  string
+		email Email
+	}{
+		{
+			name:  "To",
+			email: Email{To: "john@example.com\r\nBcc: eve@example.com", Subject: "hello"},
+		},
+		{
+			name:  "Subject",
+			email: Email{To: "john@example.com", Subject: "hello\nBcc: eve@example.com"},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := message(testFrom, tc.email, time.Now())
+			require.ErrorIs(t, err, errHeaderInjection)
+		})
+	}
+}
+
+func TestMessageInvalidRecipient(t *testing.T) {
+	_, err := message(testFrom, Email{To: "not an email", Subject: "hello"}, time.Now())
+	require.Error(t, err)
+}
+
+func TestNewSender(t *testing.T) {
+	sender, err := NewSender(Config{From: testFrom})
+	require.NoError(t, err)
+	require.IsType(t, &FileSender{}, sender)
+
+	sender, err = NewSender(Config{Kind: SenderFile})
+	require.NoError(t, err)
+	require.Equal(t, defaultFrom, sender.(*FileSender).from)
+
+	sender, err = NewSender(Config{Kind: SenderSMTP, From: testFrom, SMTPHost: "localhost", SMTPPort: 25})
+	require.NoError(t, err)
+	require.IsType(t, &SMTPSender{}, sender)
+
+	_, err = NewSender(Config{Kind: SenderSMTP, From: testFrom})
+	require.Error(t, err)
+
+	_, err = NewSender(Config{Kind: "carrier-pigeon", From: testFrom})
+	require.Error(t, err)
+
+	_, err = NewSender(Config{Kind: SenderFile, From: "not an email"})
+	require.Error(t, err)
+}
@@ -0,0 +1,105 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPSender sends the emails through an SMTP server. The connection is
+// upgraded with STARTTLS when the server supports it, and must be for the
+// credentials to be sent.
+type SMTPSender struct {
+	from     string
+	fromAddr string
+	addr     string
+	host     string
+	auth     smtp.Auth
+}
+
+// NewSMTPSender creates a new SMTPSender. The username can be empty if the
+// server doesn't require authentication.
+func NewSMTPSender(from string, host string, port int, username string, password string) (*SMTPSender, error) {
+	if host == "" {
+		return nil, errors.New("smtp sender requires a host")
+	}
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", from, err)
+	}
+
+	sender := &SMTPSender{
+		from:     from,
+		fromAddr: fromAddr.Address,
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		host:     host,
+	}
+	if username != "" {
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return sender, nil
+}
+
+// SendEmail sends the email. The context bounds the whole exchange with the server.
+func (sender *SMTPSender) SendEmail(ctx context.Context, email Email) error {
+	msg, err := message(sender.from, email, time.Now())
+	if err != nil {
+		return err
+	}
+	toAddr, err := mail.ParseAddress(email.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address %q: %w", email.To, err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", sender.addr)
+	if err != nil {
+		return fmt.Errorf("cannot connect to smtp server: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, sender.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: sender.host}); err != nil {
+			return fmt.Errorf("cannot start tls: %w", err)
+		}
+	}
+	if sender.auth != nil {
+		// PlainAuth refuses to send the credentials over an unencrypted connection
+		if err := client.Auth(sender.auth); err != nil {
+			return fmt.Errorf("cannot authenticate to smtp server: %w", err)
+		}
+	}
+
+	if err := client.Mail(sender.fromAddr); err != nil {
+		return err
+	}
+	if err := client.Rcpt(toAddr.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
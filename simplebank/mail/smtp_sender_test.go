@@ -0,0 +1,81 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts a single session without TLS nor authentication,
+// and returns the commands and the data it received.
+func fakeSMTPServer(t *testing.T) (int, <-chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var lines []string
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch {
+			case inData && line == ".":
+				inData = false
+				reply("250 OK")
+			case inData:
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case line == "DATA":
+				inData = true
+				reply("354 go ahead")
+			case line == "QUIT":
+				reply("221 bye")
+				received <- lines
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+		received <- lines
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestSMTPSender(t *testing.T) {
+	port, received := fakeSMTPServer(t)
+
+	sender, err := NewSMTPSender(testFrom, "127.0.0.1", port, "", "")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = sender.SendEmail(ctx, Email{To: "John <john@example.com>", Subject: "hello", Body: "welcome"})
+	require.NoError(t, err)
+
+	lines := <-received
+	require.Contains(t, lines, "MAIL FROM:<no-reply@simplebank.com>")
+	require.Contains(t, lines, "RCPT TO:<john@example.com>")
+	require.Contains(t, lines, "Subject: hello")
+	require.Contains(t, lines, "welcome")
+}
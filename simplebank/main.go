@@ -1,13 +1,15 @@
 package main
 
 import (
-	"database/sql"
+	"context"
 	"log"
 
 	"github.com/ebaudet/simplebank/api"
 	db "github.com/ebaudet/simplebank/db/sqlc"
 	"github.com/ebaudet/simplebank/utils"
-	_ "github.com/lib/pq"
+	"github.com/ebaudet/simplebank/webhook"
+	"github.com/ebaudet/simplebank/worker"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func main() {
@@ -16,12 +18,41 @@ func main() {
 		log.Fatal("cannot load config:", err)
 	}
 
-	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	connPool, err := newConnPool(context.Background(), config.DBSource, config)
 	if err != nil {
 		log.Fatal("cannot connect to database: ", err)
 	}
 
-	store := db.NewStore(conn)
+	storeOpts := []db.StoreOption{
+		db.WithRetryPolicy(db.TxRetryPolicy{
+			MaxAttempts: config.TxMaxAttempts,
+			BaseDelay:   config.TxRetryBaseDelay,
+			MaxDelay:    config.TxRetryMaxDelay,
+		}),
+	}
+	isolationOpts, err := txIsolationOptions(config)
+	if err != nil {
+		log.Fatal("cannot configure transactions: ", err)
+	}
+	storeOpts = append(storeOpts, isolationOpts...)
+	if config.DBReplicaSource != "" {
+		replicaPool, err := newConnPool(context.Background(), config.DBReplicaSource, config)
+		if err != nil {
+			log.Fatal("cannot connect to replica database: ", err)
+		}
+		storeOpts = append(storeOpts, db.WithReplica(replicaPool))
+	}
+	store := db.NewStore(connPool, storeOpts...)
+
+	go worker.RunPeriodic(context.Background(), worker.NewBalanceSnapshotJob(store), config.BalanceSnapshotInterval)
+	go worker.RunPeriodic(context.Background(), worker.NewGoalContributionJob(store), config.GoalContributionInterval)
+	go worker.RunPeriodic(context.Background(), worker.NewOutboxDispatchJob(store), config.OutboxDispatchInterval)
+	webhookClient := webhook.NewClient(config.WebhookTimeout)
+	go worker.RunPeriodic(context.Background(), worker.NewWebhookDeliveryJob(store, webhookClient, config.WebhookMaxAttempts), config.WebhookDeliveryInterval)
+	go worker.RunPeriodic(context.Background(), worker.NewRevokedTokenCleanupJob(store, config.OAuthAccessTokenDuration), config.RevokedTokenCleanup)
+	partitionJob := worker.NewPartitionMaintenanceJob(store, config.PartitionArchiveDir, config.PartitionPremakeMonths, config.PartitionRetentionMonths)
+	go worker.RunPeriodic(context.Background(), partitionJob, config.PartitionInterval)
+
 	server, err := api.NewServer(config, store)
 	if err != nil {
 		log.Fatal("cannot create server: ", err)
@@ -32,3 +63,52 @@ func main() {
 		log.Fatal("cannot start server: ", err)
 	}
 }
+
+// newConnPool creates a connection pool to the database of dataSource, tuned from the config.
+// The pool defaults are kept for the settings left to zero.
+func newConnPool(ctx context.Context, dataSource string, config utils.Config) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(dataSource)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.DBMaxConns > 0 {
+		poolConfig.MaxConns = config.DBMaxConns
+	}
+	if config.DBMinConns > 0 {
+		poolConfig.MinConns = config.DBMinConns
+	}
+	if config.DBMaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = config.DBMaxConnLifetime
+	}
+	if config.DBMaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = config.DBMaxConnIdleTime
+	}
+	if config.DBHealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = config.DBHealthCheckPeriod
+	}
+
+	return pgxpool.NewWithConfig(ctx, poolConfig)
+}
+
+// txIsolationOptions sets the isolation level of the transactions from the
+// config. The DefaultTxIsolation is kept when none is set.
+func txIsolationOptions(config utils.Config) ([]db.StoreOption, error) {
+	var opts []db.StoreOption
+	if config.TxIsolation != "" {
+		isolation, err := db.ParseTxIsolation(config.TxIsolation)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, db.WithTxIsolation(isolation))
+	}
+
+	overrides, err := db.ParseTxIsolationOverrides(config.TxIsolationOverrides)
+	if err != nil {
+		return nil, err
+	}
+	for name, isolation := range overrides {
+		opts = append(opts, db.WithTxIsolationFor(name, isolation))
+	}
+	return opts, nil
+}
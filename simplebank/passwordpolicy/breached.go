@@ -0,0 +1,80 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// prefixLength is the length of the prefix of the hashes the list is bucketed by.
+const prefixLength = 5
+
+// BreachedList is a local list of the breached passwords, known by their
+// SHA-1 hash as in the Pwned Passwords of Have I Been Pwned. As in its
+// k-anonymity range API, the hashes are bucketed by their first 5 hex
+// characters, and a password is looked up by the suffix of its hash within
+// the bucket of its prefix.
+type BreachedList struct {
+	buckets map[string]map[string]struct{}
+}
+
+// LoadBreachedList reads the list from the file. See ReadBreachedList.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open breached passwords list: %w", err)
+	}
+	defer file.Close()
+
+	return ReadBreachedList(file)
+}
+
+// ReadBreachedList reads a list of a SHA-1 hash in hex per line, optionally
+// followed by :count as in the downloads of Pwned Passwords. The empty lines
+// and the ones starting with # are skipped.
+func ReadBreachedList(r io.Reader) (*BreachedList, error) {
+	list := &BreachedList{buckets: make(map[string]map[string]struct{})}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 2*sha1.Size {
+			return nil, fmt.Errorf("invalid breached passwords list: line %d is not a SHA-1 hash", n)
+		}
+		list.add(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read breached passwords list: %w", err)
+	}
+
+	return list, nil
+}
+
+func (list *BreachedList) add(hash string) {
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+	bucket, ok := list.buckets[prefix]
+	if !ok {
+		bucket = make(map[string]struct{})
+		list.buckets[prefix] = bucket
+	}
+	bucket[suffix] = struct{}{}
+}
+
+// Contains reports whether the password is in the list.
+func (list *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	_, ok := list.buckets[hash[:prefixLength]][hash[prefixLength:]]
+	return ok
+}
@@ -0,0 +1,50 @@
+package passwordpolicy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// SHA-1 hashes of "password" and "P@ssw0rd".
+const breachedList = `# breached passwords
+5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824
+21bd12dc183f740ee76f27b78eb39c8ad972a757
+
+`
+
+func TestReadBreachedList(t *testing.T) {
+	list, err := ReadBreachedList(strings.NewReader(breachedList))
+	require.NoError(t, err)
+
+	require.True(t, list.Contains("password"))
+	require.True(t, list.Contains("P@ssw0rd"))
+	require.False(t, list.Contains("Password"))
+	require.False(t, list.Contains("correct horse battery staple"))
+}
+
+func TestReadBreachedListInvalid(t *testing.T) {
+	_, err := ReadBreachedList(strings.NewReader("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\nnot a hash\n"))
+	require.EqualError(t, err, "invalid breached passwords list: line 2 is not a SHA-1 hash")
+
+	_, err = ReadBreachedList(strings.NewReader("5BAA61E4C9B93F3F0682250B6CF8331B7EE6\n"))
+	require.Error(t, err)
+}
+
+func TestPolicyBreached(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	err := os.WriteFile(path, []byte(breachedList), 0600)
+	require.NoError(t, err)
+
+	policy, err := NewPolicy(Config{BreachedListFile: path})
+	require.NoError(t, err)
+
+	err = policy.Check("P@ssw0rd", "", "")
+	requireViolations(t, err, RuleBreached)
+
+	err = policy.Check("correct horse battery staple", "", "")
+	require.NoError(t, err)
+}
@@ -0,0 +1,178 @@
+package passwordpolicy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Default lengths, used when the config leaves them zero.
+const (
+	defaultMinLength = 6
+	maxLength        = 72
+)
+
+// maxBytes is the limit of bcrypt, which ignores the bytes after it. It is
+// checked on its own as a character may take up to 4 bytes in UTF-8.
+const maxBytes = 72
+
+// minUserInfoLength is the length from which the username or the email can't
+// be contained in the password, so that a short username doesn't reject most passwords.
+const minUserInfoLength = 3
+
+// Rules of the policy, as reported in the violations.
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleUpper     = "upper"
+	RuleLower     = "lower"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RuleUsername  = "username"
+	RuleEmail     = "email"
+	RuleBreached  = "breached"
+)
+
+// Violation is a rule of the policy a password fails.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error lists all the rules of the policy a password fails.
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return "password " + strings.Join(messages, ", ")
+}
+
+// Config sets up the Policy created by NewPolicy.
+type Config struct {
+	// MinLength is the minimum number of characters, defaultMinLength if zero
+	MinLength int
+	// MaxLength is the maximum number of characters, and at most maxLength
+	MaxLength int
+
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	// RejectUserInfo rejects the passwords containing the username or the email
+	RejectUserInfo bool
+
+	// BreachedListFile is the list of the hashes of the breached passwords,
+	// see LoadBreachedList. No password is checked against it if empty.
+	BreachedListFile string
+}
+
+// Policy checks that the passwords chosen by the users follow its rules.
+type Policy struct {
+	config   Config
+	breached *BreachedList
+}
+
+// NewPolicy creates a new Policy, loading the breached passwords list of the config.
+func NewPolicy(config Config) (*Policy, error) {
+	if config.MinLength == 0 {
+		config.MinLength = defaultMinLength
+	}
+	if config.MaxLength == 0 {
+		config.MaxLength = maxLength
+	}
+	if config.MinLength < 1 || config.MaxLength > maxLength || config.MinLength > config.MaxLength {
+		return nil, fmt.Errorf("invalid password length: must be between 1 and %d, the minimum below the maximum", maxLength)
+	}
+
+	policy := &Policy{config: config}
+	if config.BreachedListFile != "" {
+		breached, err := LoadBreachedList(config.BreachedListFile)
+		if err != nil {
+			return nil, err
+		}
+		policy.breached = breached
+	}
+	return policy, nil
+}
+
+// Check returns an *Error listing the rules the password of the user fails,
+// or nil if it follows all of them.
+func (policy *Policy) Check(password string, username string, email string) error {
+	config := policy.config
+	var violations []Violation
+	fail := func(rule string, format string, args ...interface{}) {
+		violations = append(violations, Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < config.MinLength {
+		fail(RuleMinLength, "must be at least %d characters long", config.MinLength)
+	}
+	if length > config.MaxLength {
+		fail(RuleMaxLength, "must be at most %d characters long", config.MaxLength)
+	} else if len(password) > maxBytes {
+		fail(RuleMaxLength, "must be at most %d bytes long", maxBytes)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if config.RequireUpper && !hasUpper {
+		fail(RuleUpper, "must contain an uppercase letter")
+	}
+	if config.RequireLower && !hasLower {
+		fail(RuleLower, "must contain a lowercase letter")
+	}
+	if config.RequireDigit && !hasDigit {
+		fail(RuleDigit, "must contain a digit")
+	}
+	if config.RequireSymbol && !hasSymbol {
+		fail(RuleSymbol, "must contain a symbol")
+	}
+
+	if config.RejectUserInfo {
+		if containsFold(password, username) {
+			fail(RuleUsername, "must not contain the username")
+		}
+		// the local part is checked as it is what people reuse of their email
+		localPart, _, _ := strings.Cut(email, "@")
+		if containsFold(password, localPart) {
+			fail(RuleEmail, "must not contain the email address")
+		}
+	}
+
+	if policy.breached != nil && policy.breached.Contains(password) {
+		fail(RuleBreached, "has appeared in a data breach and must not be used")
+	}
+
+	if len(violations) > 0 {
+		return &Error{Violations: violations}
+	}
+	return nil
+}
+
+// containsFold reports whether s contains substr, ignoring the case. A substr
+// shorter than minUserInfoLength is never contained.
+func containsFold(s, substr string) bool {
+	if utf8.RuneCountInString(substr) < minUserInfoLength {
+		return false
+	}
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,117 @@
+package passwordpolicy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requireViolations(t *testing.T, err error, rules ...string) {
+	if len(rules) == 0 {
+		require.NoError(t, err)
+		return
+	}
+
+	var policyErr *Error
+	require.ErrorAs(t, err, &policyErr)
+	require.Len(t, policyErr.Violations, len(rules))
+	for i, rule := range rules {
+		require.Equal(t, rule, policyErr.Violations[i].Rule)
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy, err := NewPolicy(Config{
+		MinLength:      8,
+		MaxLength:      16,
+		RequireUpper:   true,
+		RequireLower:   true,
+		RequireDigit:   true,
+		RequireSymbol:  true,
+		RejectUserInfo: true,
+	})
+	require.NoError(t, err)
+
+	username := "johndoe"
+	email := "jdoe@example.com"
+
+	testCases := []struct {
+		name     string
+		password string
+		rules    []string
+	}{
+		{name: "OK", password: "Tr0ub4dor&3"},
+		{name: "TooShort", password: "Ab1!", rules: []string{RuleMinLength}},
+		{name: "TooLong", password: "Tr0ub4dor&3Tr0ub4dor&3", rules: []string{RuleMaxLength}},
+		{name: "NoUpper", password: "tr0ub4dor&3", rules: []string{RuleUpper}},
+		{name: "NoLower", password: "TR0UB4DOR&3", rules: []string{RuleLower}},
+		{name: "NoDigit", password: "Troubador&!", rules: []string{RuleDigit}},
+		{name: "NoSymbol", password: "Tr0ub4dor33", rules: []string{RuleSymbol}},
+		{name: "Username", password: "JohnDoe&2024", rules: []string{RuleUsername}},
+		{name: "Email", password: "X-jdoe-2024", rules: []string{RuleEmail}},
+		{name: "Unicode", password: "Ünïcödé-ß9"},
+		{name: "Many", password: "abc", rules: []string{RuleMinLength, RuleUpper, RuleDigit, RuleSymbol}},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Check(tc.password, username, email)
+			requireViolations(t, err, tc.rules...)
+		})
+	}
+}
+
+func TestPolicyErrorMessage(t *testing.T) {
+	policy, err := NewPolicy(Config{MinLength: 8, RequireDigit: true})
+	require.NoError(t, err)
+
+	err = policy.Check("abc", "", "")
+	require.EqualError(t, err, "password must be at least 8 characters long, must contain a digit")
+}
+
+func TestPolicyMaxBytes(t *testing.T) {
+	policy, err := NewPolicy(Config{})
+	require.NoError(t, err)
+
+	// 72 characters, but 144 bytes that bcrypt would cut in half
+	password := strings.Repeat("é", maxLength)
+	err = policy.Check(password, "", "")
+	requireViolations(t, err, RuleMaxLength)
+	require.EqualError(t, err, "password must be at most 72 bytes long")
+
+	err = policy.Check(strings.Repeat("é", maxBytes/2), "", "")
+	require.NoError(t, err)
+}
+
+func TestPolicyUserInfo(t *testing.T) {
+	policy, err := NewPolicy(Config{})
+	require.NoError(t, err)
+
+	// the username and the email are only checked when set up
+	err = policy.Check("johndoe", "johndoe", "johndoe@example.com")
+	require.NoError(t, err)
+
+	policy, err = NewPolicy(Config{RejectUserInfo: true})
+	require.NoError(t, err)
+
+	// too short to be checked
+	err = policy.Check("password-ab", "ab", "ab@example.com")
+	require.NoError(t, err)
+}
+
+func TestNewPolicyInvalidConfig(t *testing.T) {
+	_, err := NewPolicy(Config{MinLength: 10, MaxLength: 8})
+	require.Error(t, err)
+
+	_, err = NewPolicy(Config{MaxLength: maxLength + 1})
+	require.Error(t, err)
+
+	_, err = NewPolicy(Config{MinLength: -1})
+	require.Error(t, err)
+
+	_, err = NewPolicy(Config{BreachedListFile: "does/not/exist.txt"})
+	require.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket of Requests tokens, refilled evenly over Per.
+// A client can burst up to Requests requests, then make Requests per Per.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Enabled reports whether the limit restricts anything.
+func (limit Limit) Enabled() bool {
+	return limit.Requests > 0 && limit.Per > 0
+}
+
+// ParseLimit parses a limit written as requests/duration, e.g. 20/1m.
+// An empty string is the disabled limit.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" {
+		return Limit{}, nil
+	}
+
+	fields := strings.SplitN(s, "/", 2)
+	if len(fields) != 2 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: must be requests/duration", s)
+	}
+	requests, err := strconv.Atoi(fields[0])
+	if err != nil || requests < 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: invalid number of requests", s)
+	}
+	per, err := time.ParseDuration(fields[1])
+	if err != nil || per <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: invalid duration", s)
+	}
+	return Limit{Requests: requests, Per: per}, nil
+}
+
+// Result is the outcome of a request against a limit.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of requests still allowed right away
+	Remaining int
+	// RetryAfter is the time to wait before the next request is allowed, 0 when allowed
+	RetryAfter time.Duration
+	// ResetAfter is the time until the bucket is full again
+	ResetAfter time.Duration
+}
+
+// Limiter is an interface for rate limiting backends
+type Limiter interface {
+	// Allow takes a token from the bucket of the key, if there is one left
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
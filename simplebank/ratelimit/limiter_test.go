@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	testCases := []struct {
+		name    string
+		value   string
+		limit   Limit
+		isValid bool
+	}{
+		{"OK", "20/1m", Limit{Requests: 20, Per: time.Minute}, true},
+		{"Empty", "", Limit{}, true},
+		{"NoDuration", "20", Limit{}, false},
+		{"InvalidRequests", "abc/1m", Limit{}, false},
+		{"NegativeRequests", "-1/1m", Limit{}, false},
+		{"InvalidDuration", "20/minute", Limit{}, false},
+		{"ZeroDuration", "20/0s", Limit{}, false},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			limit, err := ParseLimit(tc.value)
+			if !tc.isValid {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.limit, limit)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// memoryPruneInterval is how often the full buckets are dropped.
+const memoryPruneInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	// updatedAt is when the tokens were last refilled
+	updatedAt time.Time
+	// fullAt is when the bucket will be full again
+	fullAt time.Time
+}
+
+// MemoryLimiter is a Limiter keeping the buckets in memory. The limits are
+// therefore per process: use a shared backend when running several replicas.
+type MemoryLimiter struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	prunedAt time.Time
+}
+
+// NewMemoryLimiter creates a new MemoryLimiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the bucket of the key, if there is one left
+func (limiter *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if !limit.Enabled() {
+		return Result{Allowed: true, Remaining: math.MaxInt32}, nil
+	}
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := limiter.now()
+	limiter.prune(now)
+
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Per.Seconds()
+
+	b, ok := limiter.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updatedAt: now}
+		limiter.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updatedAt).Seconds()*rate)
+	b.updatedAt = now
+
+	result := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = secondsToDuration((capacity - b.tokens) / rate)
+	b.fullAt = now.Add(result.ResetAfter)
+
+	return result, nil
+}
+
+// prune drops the buckets which are full again, as they are the same as new ones.
+func (limiter *MemoryLimiter) prune(now time.Time) {
+	if now.Sub(limiter.prunedAt) < memoryPruneInterval {
+		return
+	}
+	limiter.prunedAt = now
+
+	for key, b := range limiter.buckets {
+		if !now.Before(b.fullAt) {
+			delete(limiter.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
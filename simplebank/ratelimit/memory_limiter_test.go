@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	limiter := NewMemoryLimiter()
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	limit := Limit{Requests: 3, Per: 3 * time.Second}
+
+	// the bucket allows a burst of 3 requests
+	for i := 2; i >= 0; i-- {
+		result, err := limiter.Allow(ctx, "key", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, i, result.Remaining)
+		require.Zero(t, result.RetryAfter)
+	}
+
+	result, err := limiter.Allow(ctx, "key", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+	require.Equal(t, time.Second, result.RetryAfter)
+	require.Equal(t, 3*time.Second, result.ResetAfter)
+
+	// the other keys have their own bucket
+	result, err = limiter.Allow(ctx, "other", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// one token is refilled every second
+	now = now.Add(time.Second)
+	result, err = limiter.Allow(ctx, "key", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+
+	// the full buckets are pruned
+	now = now.Add(time.Minute)
+	_, err = limiter.Allow(ctx, "key", limit)
+	require.NoError(t, err)
+	require.Len(t, limiter.buckets, 1)
+}
+
+func TestMemoryLimiterDisabled(t *testing.T) {
+	limiter := NewMemoryLimiter()
+
+	for i := 0; i < 10; i++ {
+		result, err := limiter.Allow(context.Background(), "key", Limit{})
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+	require.Empty(t, limiter.buckets)
+}
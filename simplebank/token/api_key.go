@@ -0,0 +1,34 @@
+package token
+
+const (
+	// apiKeySize is the number of random bytes of an API key.
+	apiKeySize = 32
+	// APIKeyPrefix starts every API key, so that a leaked one is easy to spot.
+	APIKeyPrefix = "sbk_"
+	// apiKeyDisplayLength is the length of the start of the key kept in clear
+	// to tell the keys apart.
+	apiKeyDisplayLength = len(APIKeyPrefix) + 8
+)
+
+// NewAPIKey creates a new opaque API key. Like the refresh tokens it carries
+// no payload, the scopes and the owner are stored with its hash.
+func NewAPIKey() (string, error) {
+	apiKey, err := newOpaqueToken(apiKeySize)
+	if err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + apiKey, nil
+}
+
+// HashAPIKey returns the hash the API key is stored and looked up with.
+func HashAPIKey(apiKey string) string {
+	return hashOpaqueToken(apiKey)
+}
+
+// APIKeyDisplayPrefix returns the start of the API key, which is safe to show.
+func APIKeyDisplayPrefix(apiKey string) string {
+	if len(apiKey) < apiKeyDisplayLength {
+		return apiKey
+	}
+	return apiKey[:apiKeyDisplayLength]
+}
@@ -0,0 +1,29 @@
+package token
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKey(t *testing.T) {
+	apiKey1, err := NewAPIKey()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(apiKey1, APIKeyPrefix))
+	require.Len(t, apiKey1, len(APIKeyPrefix)+43)
+
+	apiKey2, err := NewAPIKey()
+	require.NoError(t, err)
+	require.NotEqual(t, apiKey1, apiKey2)
+
+	hash := HashAPIKey(apiKey1)
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, HashAPIKey(apiKey1))
+	require.NotEqual(t, hash, HashAPIKey(apiKey2))
+
+	prefix := APIKeyDisplayPrefix(apiKey1)
+	require.Len(t, prefix, len(APIKeyPrefix)+8)
+	require.True(t, strings.HasPrefix(apiKey1, prefix))
+	require.Equal(t, "short", APIKeyDisplayPrefix("short"))
+}
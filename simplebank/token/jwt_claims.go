@@ -0,0 +1,99 @@
+package token
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Registered claims of the JWTs, so that the services verifying them with the
+// public keys can check they were issued by the bank for its API.
+const (
+	JWTIssuer   = "simplebank"
+	JWTAudience = "simplebank-api"
+)
+
+// jwtLeeway is the clock skew allowed between the bank and the services
+// verifying its tokens, on the times the tokens are valid from.
+const jwtLeeway = 30 * time.Second
+
+// jwtClaims are the claims of the JWTs. The ID and the times of the payload
+// are the registered claims jti, iat and exp, and the token is valid from its
+// issue, nbf. The times are NumericDates with microseconds, as the issue time
+// is compared with the times the tokens of the user were revoked.
+type jwtClaims struct {
+	ID        string  `json:"jti"`
+	Issuer    string  `json:"iss"`
+	Audience  string  `json:"aud"`
+	IssuedAt  float64 `json:"iat"`
+	NotBefore float64 `json:"nbf"`
+	ExpiresAt float64 `json:"exp"`
+
+	Username string   `json:"username"`
+	Role     string   `json:"role"`
+	ClientID string   `json:"client_id,omitempty"`
+	Scopes   []string `json:"scopes"`
+}
+
+func newJWTClaims(payload *Payload) *jwtClaims {
+	return &jwtClaims{
+		ID:        payload.ID.String(),
+		Issuer:    JWTIssuer,
+		Audience:  JWTAudience,
+		IssuedAt:  numericDate(payload.IssuedAt),
+		NotBefore: numericDate(payload.IssuedAt),
+		ExpiresAt: numericDate(payload.ExpiredAt),
+		Username:  payload.Username,
+		Role:      payload.Role,
+		ClientID:  payload.ClientID,
+		Scopes:    payload.Scopes,
+	}
+}
+
+// Valid checks the registered claims, all of which are required.
+func (claims *jwtClaims) Valid() error {
+	if claims.Issuer != JWTIssuer || claims.Audience != JWTAudience {
+		return ErrInvalidToken
+	}
+	if claims.IssuedAt == 0 || claims.NotBefore == 0 || claims.ExpiresAt == 0 {
+		return ErrInvalidToken
+	}
+
+	now := time.Now()
+	if now.Add(jwtLeeway).Before(numericDateTime(claims.IssuedAt)) || now.Add(jwtLeeway).Before(numericDateTime(claims.NotBefore)) {
+		return ErrInvalidToken
+	}
+	if now.After(numericDateTime(claims.ExpiresAt)) {
+		return ErrExpiredToken
+	}
+	return nil
+}
+
+// payload returns the payload of the verified claims.
+func (claims *jwtClaims) payload() (*Payload, error) {
+	id, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return &Payload{
+		ID:        id,
+		Username:  claims.Username,
+		Role:      claims.Role,
+		IssuedAt:  numericDateTime(claims.IssuedAt),
+		ExpiredAt: numericDateTime(claims.ExpiresAt),
+		ClientID:  claims.ClientID,
+		Scopes:    claims.Scopes,
+	}, nil
+}
+
+// numericDate returns the seconds since the epoch of the time, as in the JWT
+// claims, to the microsecond.
+func numericDate(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
+
+func numericDateTime(date float64) time.Time {
+	return time.UnixMicro(int64(math.Round(date * 1e6)))
+}
@@ -11,6 +11,7 @@ import (
 // JWTMaker is a JSON Web Token maker
 type JWTMaker struct {
 	secretKey string
+	kid       string
 }
 
 const minSecretKeySize = 32
@@ -20,21 +21,37 @@ func NewJWTMaker(secretKey string) (Maker, error) {
 	if len(secretKey) < minSecretKeySize {
 		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minSecretKeySize)
 	}
-	return &JWTMaker{secretKey}, nil
+	return &JWTMaker{secretKey: secretKey, kid: symmetricKeyID(secretKey)}, nil
 }
 
-// CreateToken creates a new token for a specific username and duration
-func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, *Payload, error) {
-	payload, err := NewPayload(username, duration)
+// CreateToken creates a new token for a specific username, role and duration
+func (maker *JWTMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
 	if err != nil {
 		return "", payload, err
 	}
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	token, err := jwtToken.SignedString([]byte(maker.secretKey))
+	token, err := maker.sign(payload)
 	return token, payload, err
 }
 
+// CreateClientToken creates a new token for a third-party client acting for the user
+func (maker *JWTMaker) CreateClientToken(username string, role string, clientID string, scopes []string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewClientPayload(username, role, clientID, scopes, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	token, err := maker.sign(payload)
+	return token, payload, err
+}
+
+func (maker *JWTMaker) sign(payload *Payload) (string, error) {
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, newJWTClaims(payload))
+	jwtToken.Header["kid"] = maker.kid
+	return jwtToken.SignedString([]byte(maker.secretKey))
+}
+
 // VerifyToken checks if the token is valid or not
 func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 	keyFunc := func(token *jwt.Token) (interface{}, error) {
@@ -46,7 +63,7 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return []byte(maker.secretKey), nil
 	}
 
-	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
+	jwtToken, err := jwt.ParseWithClaims(token, &jwtClaims{}, keyFunc)
 	if err != nil {
 		verr, ok := err.(*jwt.ValidationError)
 		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
@@ -55,10 +72,19 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
-	payload, ok := jwtToken.Claims.(*Payload)
+	claims, ok := jwtToken.Claims.(*jwtClaims)
 	if !ok {
 		return nil, ErrInvalidToken
 	}
 
-	return payload, nil
+	return claims.payload()
+}
+
+// PublicKeys returns no keys, the tokens are verified with the symmetric key
+func (maker *JWTMaker) PublicKeys() []JWK {
+	return nil
+}
+
+func (maker *JWTMaker) keyID() string {
+	return maker.kid
 }
@@ -0,0 +1,108 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// JWTPublicMaker is a JSON Web Token maker signing the tokens with a private
+// key, EdDSA for an Ed25519 key and RS256 for an RSA key, so that the services
+// verifying them with the public key can't mint them.
+type JWTPublicMaker struct {
+	method     jwt.SigningMethod
+	privateKey crypto.Signer
+	jwk        JWK
+}
+
+// NewJWTPublicMaker creates a new JWTPublicMaker
+func NewJWTPublicMaker(privateKey crypto.Signer) (Maker, error) {
+	var method jwt.SigningMethod
+	switch key := privateKey.(type) {
+	case ed25519.PrivateKey:
+		method = jwt.SigningMethodEdDSA
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("invalid key size: RSA keys must have at least %d bits", minRSAKeyBits)
+		}
+		method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+
+	jwk, err := newJWK(privateKey.Public(), method.Alg())
+	if err != nil {
+		return nil, err
+	}
+
+	return &JWTPublicMaker{method: method, privateKey: privateKey, jwk: jwk}, nil
+}
+
+// CreateToken creates a new token for a specific username, role and duration
+func (maker *JWTPublicMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	token, err := maker.sign(payload)
+	return token, payload, err
+}
+
+// CreateClientToken creates a new token for a third-party client acting for the user
+func (maker *JWTPublicMaker) CreateClientToken(username string, role string, clientID string, scopes []string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewClientPayload(username, role, clientID, scopes, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	token, err := maker.sign(payload)
+	return token, payload, err
+}
+
+func (maker *JWTPublicMaker) sign(payload *Payload) (string, error) {
+	jwtToken := jwt.NewWithClaims(maker.method, newJWTClaims(payload))
+	jwtToken.Header["kid"] = maker.jwk.KeyID
+	return jwtToken.SignedString(maker.privateKey)
+}
+
+// VerifyToken checks if the token is valid or not
+func (maker *JWTPublicMaker) VerifyToken(token string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		// Checking the algorithm, so that a token can't pick a weaker one.
+		if token.Method.Alg() != maker.method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return maker.privateKey.Public(), nil
+	}
+
+	jwtToken, err := jwt.ParseWithClaims(token, &jwtClaims{}, keyFunc)
+	if err != nil {
+		verr, ok := err.(*jwt.ValidationError)
+		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := jwtToken.Claims.(*jwtClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return claims.payload()
+}
+
+// PublicKeys returns the public key verifying the tokens
+func (maker *JWTPublicMaker) PublicKeys() []JWK {
+	return []JWK{maker.jwk}
+}
+
+func (maker *JWTPublicMaker) keyID() string {
+	return maker.jwk.KeyID
+}
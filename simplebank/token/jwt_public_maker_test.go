@@ -0,0 +1,189 @@
+package token
+
+import (
+	"crypto"
+	"testing"
+	"time"
+
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTPublicMaker(t *testing.T) {
+	testCases := []struct {
+		name       string
+		privateKey crypto.Signer
+		algorithm  string
+	}{
+		{name: "EdDSA", privateKey: randomEd25519Key(t), algorithm: "EdDSA"},
+		{name: "RS256", privateKey: randomRSAKey(t, 2048), algorithm: "RS256"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			maker, err := NewJWTPublicMaker(tc.privateKey)
+			require.NoError(t, err)
+
+			username := utils.RandomOwner()
+			role := utils.DepositorRole
+			duration := time.Minute
+
+			issuedAt := time.Now()
+			expiredAt := issuedAt.Add(duration)
+
+			token, _, err := maker.CreateToken(username, role, duration)
+			require.NoError(t, err)
+
+			payload, err := maker.VerifyToken(token)
+			require.NoError(t, err)
+			require.Equal(t, username, payload.Username)
+			require.Equal(t, role, payload.Role)
+			require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+			require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+
+			jwk := maker.PublicKeys()[0]
+			require.Equal(t, tc.algorithm, jwk.Algorithm)
+
+			// the header names the algorithm and the key verifying the token
+			claims := jwt.MapClaims{}
+			jwtToken, _, err := new(jwt.Parser).ParseUnverified(token, claims)
+			require.NoError(t, err)
+			require.Equal(t, tc.algorithm, jwtToken.Header["alg"])
+			require.Equal(t, jwk.KeyID, jwtToken.Header["kid"])
+
+			// the times are the registered claims
+			require.Equal(t, JWTIssuer, claims["iss"])
+			require.Equal(t, JWTAudience, claims["aud"])
+			require.Equal(t, payload.ID.String(), claims["jti"])
+			require.InDelta(t, float64(issuedAt.Unix()), claims["iat"], 1)
+			require.Equal(t, claims["iat"], claims["nbf"])
+			require.InDelta(t, float64(expiredAt.Unix()), claims["exp"], 1)
+			require.NotContains(t, claims, "issued_at")
+			require.NotContains(t, claims, "expired_at")
+
+			token, _, err = maker.CreateToken(username, role, -time.Minute)
+			require.NoError(t, err)
+			payload, err = maker.VerifyToken(token)
+			require.EqualError(t, err, ErrExpiredToken.Error())
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestJWTPublicMakerSmallRSAKey(t *testing.T) {
+	maker, err := NewJWTPublicMaker(randomRSAKey(t, 1024))
+	require.Error(t, err)
+	require.Nil(t, maker)
+}
+
+func TestInvalidJWTPublicTokenAlgorithm(t *testing.T) {
+	maker, err := NewJWTPublicMaker(randomEd25519Key(t))
+	require.NoError(t, err)
+
+	payload, err := NewPayload(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, payload)
+	token, err := jwtToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	verified, err := maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, verified)
+
+	// a token signed with HS256 and the public key as the secret is refused
+	jwk := maker.PublicKeys()[0]
+	jwtToken = jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	token, err = jwtToken.SignedString([]byte(jwk.X))
+	require.NoError(t, err)
+
+	verified, err = maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, verified)
+}
+
+func TestJWTPublicClientToken(t *testing.T) {
+	maker, err := NewJWTPublicMaker(randomEd25519Key(t))
+	require.NoError(t, err)
+
+	clientID := utils.RandomString(16)
+	scopes := []string{"accounts:read"}
+
+	token, _, err := maker.CreateClientToken(utils.RandomOwner(), utils.DepositorRole, clientID, scopes, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, clientID, payload.ClientID)
+	require.Equal(t, scopes, payload.Scopes)
+}
+
+func TestJWTPublicMakerRegisteredClaims(t *testing.T) {
+	maker, err := NewJWTPublicMaker(randomEd25519Key(t))
+	require.NoError(t, err)
+	publicMaker := maker.(*JWTPublicMaker)
+
+	payload, err := NewPayload(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		change func(claims *jwtClaims)
+		err    error
+	}{
+		{name: "OK", change: func(claims *jwtClaims) {}},
+		{name: "OtherIssuer", change: func(claims *jwtClaims) { claims.Issuer = "other" }, err: ErrInvalidToken},
+		{name: "OtherAudience", change: func(claims *jwtClaims) { claims.Audience = "other" }, err: ErrInvalidToken},
+		{name: "NoExpiry", change: func(claims *jwtClaims) { claims.ExpiresAt = 0 }, err: ErrInvalidToken},
+		{name: "NoIssuedAt", change: func(claims *jwtClaims) { claims.IssuedAt = 0 }, err: ErrInvalidToken},
+		{
+			name:   "NotYetValid",
+			change: func(claims *jwtClaims) { claims.NotBefore = numericDate(time.Now().Add(time.Minute)) },
+			err:    ErrInvalidToken,
+		},
+		{
+			// the clock of the bank may be a little ahead of the verifier's
+			name: "ClockSkew",
+			change: func(claims *jwtClaims) {
+				claims.IssuedAt = numericDate(time.Now().Add(jwtLeeway - time.Second))
+				claims.NotBefore = claims.IssuedAt
+			},
+		},
+		{
+			name:   "IssuedInTheFuture",
+			change: func(claims *jwtClaims) { claims.IssuedAt = numericDate(time.Now().Add(time.Minute)) },
+			err:    ErrInvalidToken,
+		},
+		{
+			name:   "Expired",
+			change: func(claims *jwtClaims) { claims.ExpiresAt = numericDate(time.Now().Add(-time.Second)) },
+			err:    ErrExpiredToken,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			claims := newJWTClaims(payload)
+			tc.change(claims)
+
+			token, err := jwt.NewWithClaims(publicMaker.method, claims).SignedString(publicMaker.privateKey)
+			require.NoError(t, err)
+
+			got, err := maker.VerifyToken(token)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				require.Nil(t, got)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, payload.ID, got.ID)
+			require.WithinDuration(t, numericDateTime(claims.IssuedAt), got.IssuedAt, time.Microsecond)
+			require.WithinDuration(t, payload.ExpiredAt, got.ExpiredAt, time.Microsecond)
+		})
+	}
+}
@@ -0,0 +1,515 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	keyFileExt = ".json"
+	// symmetricKeySize is the size of the generated symmetric keys, as the
+	// PASETO v4.local keys must be.
+	symmetricKeySize = 32
+	// rsaKeyBits is the size of the generated RSA keys.
+	rsaKeyBits = 3072
+	// keyIDSize is the size of the random IDs of the generated symmetric keys.
+	keyIDSize = 16
+)
+
+// ErrKeyringNotStored is returned when rotating the keys of a keyring without
+// a directory: a new key would be lost on restart, along with its tokens.
+var ErrKeyringNotStored = errors.New("keyring has no directory to store the keys in")
+
+// keyedMaker is a maker naming its key in its tokens.
+type keyedMaker interface {
+	Maker
+	keyID() string
+}
+
+// pasetoFooter is the footer of the PASETO tokens, naming the key to verify
+// them with.
+type pasetoFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// jwtHeader is the header of the JSON web tokens, as far as the key is concerned.
+type jwtHeader struct {
+	KeyID string `json:"kid"`
+}
+
+// symmetricKeyID derives the ID of the configured symmetric key, the same on
+// every instance. It is a MAC under the key, so that unlike a plain hash it
+// tells nothing about the key to who doesn't have it. The generated keys get
+// random IDs instead, stored along with them.
+func symmetricKeyID(symmetricKey string) string {
+	mac := hmac.New(sha256.New, []byte(symmetricKey))
+	mac.Write([]byte("simplebank token key id"))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:keyIDSize])
+}
+
+// newKeyID generates a random ID for a symmetric key.
+func newKeyID() (string, error) {
+	id := make([]byte, keyIDSize)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(id), nil
+}
+
+// tokenKeyID returns the ID of the key named in the PASETO footer or the JWT
+// header of the token, if any.
+func tokenKeyID(token string) string {
+	parts := strings.Split(token, ".")
+
+	var encoded string
+	switch {
+	case strings.HasPrefix(token, "v4."):
+		if len(parts) != 4 {
+			return ""
+		}
+		encoded = parts[3]
+	case len(parts) == 3:
+		encoded = parts[0]
+	default:
+		return ""
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ""
+	}
+	// the PASETO footer names the key as the JWT header does
+	var header jwtHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return ""
+	}
+	return header.KeyID
+}
+
+// KeyringConfig configures a Keyring.
+type KeyringConfig struct {
+	// Kind is the kind of maker of the keys created on rotation
+	Kind string
+	// Dir stores the keys created on rotation, shared by the instances of the
+	// server. Without it, the keys can't be rotated.
+	Dir string
+	// MaxTokenLifetime is the longest duration of the tokens. A key replaced
+	// by a newer one still verifies the tokens for that long.
+	MaxTokenLifetime time.Duration
+	// RefreshInterval is how often the keys are reloaded from the directory.
+	// A new key only signs tokens once every instance could load it.
+	RefreshInterval time.Duration
+}
+
+// keyFile is the content of a key file of the directory.
+type keyFile struct {
+	Kind string `json:"kind"`
+	// KeyID is the random ID of a symmetric key, the asymmetric keys are
+	// named after their public key
+	KeyID string `json:"kid,omitempty"`
+	// Key is the symmetric key, or the PEM encoded private key
+	Key         []byte    `json:"key"`
+	ActivatesAt time.Time `json:"activates_at"`
+}
+
+type keyringKey struct {
+	maker       keyedMaker
+	kind        string
+	activatesAt time.Time
+	// expiresAt is zero until a newer key replaces it
+	expiresAt time.Time
+}
+
+// KeyInfo describes a key of a Keyring.
+type KeyInfo struct {
+	KeyID       string
+	Kind        string
+	ActivatesAt time.Time
+	// ExpiresAt is zero until a newer key replaces it
+	ExpiresAt time.Time
+	Primary   bool
+}
+
+// Keyring is a token maker signing the tokens with its primary key, which is
+// the last activated one, and verifying them with the key named in them. The
+// keys replaced by a newer one keep verifying the tokens until the tokens they
+// signed have all expired, so that rotating the keys logs nobody out.
+type Keyring struct {
+	config    KeyringConfig
+	configKey keyringKey
+	now       func() time.Time
+
+	mu       sync.RWMutex
+	keys     []keyringKey
+	loadedAt time.Time
+}
+
+// NewKeyring creates a new Keyring, starting with the key of the maker made
+// from the configuration and the keys of the directory.
+func NewKeyring(maker Maker, config KeyringConfig) (*Keyring, error) {
+	keyed, ok := maker.(keyedMaker)
+	if !ok {
+		return nil, fmt.Errorf("unsupported token maker %T", maker)
+	}
+
+	if config.Kind == "" {
+		config.Kind = MakerPaseto
+	}
+
+	keyring := &Keyring{
+		config:    config,
+		configKey: keyringKey{maker: keyed, kind: config.Kind},
+		now:       time.Now,
+	}
+	if err := keyring.Reload(); err != nil {
+		return nil, err
+	}
+	return keyring, nil
+}
+
+// Reload loads the keys of the directory again, and deletes the files of the
+// keys which have expired.
+func (keyring *Keyring) Reload() error {
+	var keys []keyringKey
+	files := map[string]string{}
+
+	if keyring.config.Dir != "" {
+		entries, err := os.ReadDir(keyring.config.Dir)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("cannot read keyring directory: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != keyFileExt {
+				continue
+			}
+			path := filepath.Join(keyring.config.Dir, entry.Name())
+			key, err := readKeyFile(path)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+			files[key.maker.keyID()] = path
+		}
+	}
+
+	// the configured key may also have been stored in the directory
+	if !containsKey(keys, keyring.configKey.maker.keyID()) {
+		keys = append(keys, keyring.configKey)
+	}
+
+	now := keyring.now()
+	keys = keyring.activeKeys(keys, now)
+	for keyID, path := range files {
+		if !containsKey(keys, keyID) {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("cannot delete expired token key %s: %v", keyID, err)
+			}
+		}
+	}
+
+	keyring.mu.Lock()
+	defer keyring.mu.Unlock()
+	keyring.keys = keys
+	keyring.loadedAt = now
+	return nil
+}
+
+// activeKeys sorts the keys by activation and sets when they expire. The keys
+// which have expired are left out.
+func (keyring *Keyring) activeKeys(keys []keyringKey, now time.Time) []keyringKey {
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].activatesAt.Before(keys[j].activatesAt)
+	})
+
+	// a newer key signs its first tokens once every instance could load it,
+	// the instances which haven't may sign with the older key until then
+	grace := keyring.config.MaxTokenLifetime + keyring.config.RefreshInterval
+
+	active := make([]keyringKey, 0, len(keys))
+	for i, key := range keys {
+		if i+1 < len(keys) {
+			key.expiresAt = keys[i+1].activatesAt.Add(grace)
+			if !now.Before(key.expiresAt) {
+				continue
+			}
+		}
+		active = append(active, key)
+	}
+	return active
+}
+
+func containsKey(keys []keyringKey, keyID string) bool {
+	for _, key := range keys {
+		if key.maker.keyID() == keyID {
+			return true
+		}
+	}
+	return false
+}
+
+func readKeyFile(path string) (keyringKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return keyringKey{}, fmt.Errorf("cannot read key file: %w", err)
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return keyringKey{}, fmt.Errorf("cannot parse key file %s: %w", path, err)
+	}
+
+	maker, err := newKeyMaker(file.Kind, file.Key, file.KeyID)
+	if err != nil {
+		return keyringKey{}, fmt.Errorf("invalid key file %s: %w", path, err)
+	}
+	return keyringKey{maker: maker, kind: file.Kind, activatesAt: file.ActivatesAt}, nil
+}
+
+// newKeyMaker creates the maker of the kind for the key of a key file. The
+// symmetric keys are named by the key ID, when they have one.
+func newKeyMaker(kind string, key []byte, keyID string) (keyedMaker, error) {
+	var maker Maker
+	var err error
+	switch kind {
+	case MakerPaseto, MakerJWT:
+		maker, err = NewMaker(kind, string(key), nil)
+	default:
+		maker, err = NewMaker(kind, "", key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if keyID != "" {
+		switch maker := maker.(type) {
+		case *PasetoMaker:
+			maker.kid = keyID
+		case *JWTMaker:
+			maker.kid = keyID
+		}
+	}
+	return maker.(keyedMaker), nil
+}
+
+// newKey generates a key for a maker of the kind.
+func newKey(kind string) ([]byte, error) {
+	var privateKey interface{}
+	switch kind {
+	case MakerPaseto, MakerJWT:
+		key := make([]byte, symmetricKeySize)
+		_, err := rand.Read(key)
+		return key, err
+	case MakerPasetoPublic, MakerJWTEdDSA:
+		_, edKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		privateKey = edKey
+	case MakerJWTRS256:
+		rsaKey, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		privateKey = rsaKey
+	default:
+		return nil, fmt.Errorf("unsupported token maker %q", kind)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// Rotate creates a new key of the configured kind in the directory. It becomes
+// the primary key once every instance could load it, and the current primary
+// key is phased out once the tokens it signed until then have expired.
+func (keyring *Keyring) Rotate() (KeyInfo, error) {
+	if keyring.config.Dir == "" {
+		return KeyInfo{}, ErrKeyringNotStored
+	}
+
+	kind := keyring.config.Kind
+	key, err := newKey(kind)
+	if err != nil {
+		return KeyInfo{}, fmt.Errorf("cannot generate key: %w", err)
+	}
+	var keyID string
+	if kind == MakerPaseto || kind == MakerJWT {
+		if keyID, err = newKeyID(); err != nil {
+			return KeyInfo{}, fmt.Errorf("cannot generate key ID: %w", err)
+		}
+	}
+	maker, err := newKeyMaker(kind, key, keyID)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+
+	file := keyFile{
+		Kind:        kind,
+		KeyID:       keyID,
+		Key:         key,
+		ActivatesAt: keyring.now().Add(keyring.config.RefreshInterval),
+	}
+	if err := writeKeyFile(keyring.config.Dir, maker.keyID(), file); err != nil {
+		return KeyInfo{}, err
+	}
+
+	if err := keyring.Reload(); err != nil {
+		return KeyInfo{}, err
+	}
+	for _, info := range keyring.Keys() {
+		if info.KeyID == maker.keyID() {
+			return info, nil
+		}
+	}
+	return KeyInfo{}, fmt.Errorf("key %s was not loaded", maker.keyID())
+}
+
+// writeKeyFile writes the key file atomically, so that the other instances
+// never load it half written.
+func writeKeyFile(dir string, keyID string, file keyFile) error {
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("cannot create keyring directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, keyID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("cannot create key file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot write key file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot write key file: %w", err)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, keyID+keyFileExt))
+}
+
+// refresh reloads the keys when they were loaded more than the refresh
+// interval ago. On failure, the keys loaded before are kept.
+func (keyring *Keyring) refresh() {
+	if keyring.config.Dir == "" {
+		return
+	}
+
+	keyring.mu.RLock()
+	stale := keyring.now().Sub(keyring.loadedAt) >= keyring.config.RefreshInterval
+	keyring.mu.RUnlock()
+	if !stale {
+		return
+	}
+
+	if err := keyring.Reload(); err != nil {
+		log.Printf("cannot reload token keys: %v", err)
+	}
+}
+
+// primary returns the maker of the last activated key.
+func (keyring *Keyring) primary() Maker {
+	keyring.refresh()
+
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+
+	now := keyring.now()
+	primary := keyring.keys[0]
+	for _, key := range keyring.keys[1:] {
+		if key.activatesAt.After(now) {
+			break
+		}
+		primary = key
+	}
+	return primary.maker
+}
+
+// Keys describes the keys of the keyring, ordered by activation.
+func (keyring *Keyring) Keys() []KeyInfo {
+	primary := keyring.primary().(keyedMaker).keyID()
+
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+
+	infos := make([]KeyInfo, len(keyring.keys))
+	for i, key := range keyring.keys {
+		infos[i] = KeyInfo{
+			KeyID:       key.maker.keyID(),
+			Kind:        key.kind,
+			ActivatesAt: key.activatesAt,
+			ExpiresAt:   key.expiresAt,
+			Primary:     key.maker.keyID() == primary,
+		}
+	}
+	return infos
+}
+
+// CreateToken creates a new token for a specific username, role and duration
+func (keyring *Keyring) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	return keyring.primary().CreateToken(username, role, duration)
+}
+
+// CreateClientToken creates a new token for a third-party client acting for the user
+func (keyring *Keyring) CreateClientToken(username string, role string, clientID string, scopes []string, duration time.Duration) (string, *Payload, error) {
+	return keyring.primary().CreateClientToken(username, role, clientID, scopes, duration)
+}
+
+// VerifyToken checks the token with the key named in it. The tokens naming no
+// key predate the keyring, they can only have been signed by the configured key.
+func (keyring *Keyring) VerifyToken(token string) (*Payload, error) {
+	keyring.refresh()
+
+	keyID := tokenKeyID(token)
+
+	keyring.mu.RLock()
+	var maker Maker
+	for _, key := range keyring.keys {
+		if key.maker.keyID() == keyID || (keyID == "" && key.maker.keyID() == keyring.configKey.maker.keyID()) {
+			maker = key.maker
+			break
+		}
+	}
+	keyring.mu.RUnlock()
+
+	if maker == nil {
+		return nil, ErrInvalidToken
+	}
+	return maker.VerifyToken(token)
+}
+
+// PublicKeys returns the public keys of the keyring which verify tokens
+func (keyring *Keyring) PublicKeys() []JWK {
+	keyring.refresh()
+
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+
+	var keys []JWK
+	for _, key := range keyring.keys {
+		keys = append(keys, key.maker.PublicKeys()...)
+	}
+	return keys
+}
@@ -0,0 +1,215 @@
+package token
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"github.com/vk-rv/pvx"
+)
+
+// newTestKeyring creates a keyring of the kind whose clock is set by the returned function.
+func newTestKeyring(t *testing.T, maker Maker, kind string, dir string) (*Keyring, func(time.Time)) {
+	keyring, err := NewKeyring(maker, KeyringConfig{
+		Kind:             kind,
+		Dir:              dir,
+		MaxTokenLifetime: time.Hour,
+		RefreshInterval:  time.Minute,
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	keyring.now = func() time.Time { return now }
+	return keyring, func(t time.Time) { now = t }
+}
+
+func TestTokenKeyID(t *testing.T) {
+	pasetoMaker, err := NewPasetoMaker(utils.RandomString(32))
+	require.NoError(t, err)
+	jwtMaker, err := NewJWTMaker(utils.RandomString(32))
+	require.NoError(t, err)
+	publicMaker, err := NewPasetoPublicMaker(randomEd25519Key(t))
+	require.NoError(t, err)
+
+	for _, maker := range []Maker{pasetoMaker, jwtMaker, publicMaker} {
+		token, _, err := maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+		require.NoError(t, err)
+		require.Equal(t, maker.(keyedMaker).keyID(), tokenKeyID(token))
+	}
+
+	require.Empty(t, tokenKeyID("v4.local.payload"))
+	require.Empty(t, tokenKeyID("not a token"))
+}
+
+func TestSymmetricKeyID(t *testing.T) {
+	key := utils.RandomString(32)
+	maker, err := NewPasetoMaker(key)
+	require.NoError(t, err)
+
+	// the ID of the configured key is the same on every instance, but isn't a
+	// plain hash of the key
+	again, err := NewJWTMaker(key)
+	require.NoError(t, err)
+	require.Equal(t, maker.(keyedMaker).keyID(), again.(keyedMaker).keyID())
+	sum := sha256.Sum256([]byte(key))
+	require.NotEqual(t, base64.RawURLEncoding.EncodeToString(sum[:16]), maker.(keyedMaker).keyID())
+
+	// the generated keys are named by a random ID, stored along with them
+	dir := t.TempDir()
+	keyring, _ := newTestKeyring(t, maker, MakerPaseto, dir)
+	info, err := keyring.Rotate()
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, info.KeyID+keyFileExt))
+	require.NoError(t, err)
+	var file keyFile
+	require.NoError(t, json.Unmarshal(data, &file))
+	require.Equal(t, info.KeyID, file.KeyID)
+	require.NotEqual(t, symmetricKeyID(string(file.Key)), file.KeyID)
+
+	loaded, err := readKeyFile(filepath.Join(dir, info.KeyID+keyFileExt))
+	require.NoError(t, err)
+	require.Equal(t, info.KeyID, loaded.maker.keyID())
+}
+
+func TestKeyringRotation(t *testing.T) {
+	dir := t.TempDir()
+	configMaker, err := NewPasetoMaker(utils.RandomString(32))
+	require.NoError(t, err)
+
+	keyring, setNow := newTestKeyring(t, configMaker, MakerPaseto, dir)
+	start := time.Now()
+
+	oldToken, _, err := keyring.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	key, err := keyring.Rotate()
+	require.NoError(t, err)
+	require.False(t, key.Primary)
+	require.WithinDuration(t, start.Add(time.Minute), key.ActivatesAt, time.Second)
+	require.FileExists(t, filepath.Join(dir, key.KeyID+keyFileExt))
+
+	// the configured key signs until every instance could load the new key
+	keys := keyring.Keys()
+	require.Len(t, keys, 2)
+	require.True(t, keys[0].Primary)
+	require.WithinDuration(t, key.ActivatesAt.Add(time.Hour+time.Minute), keys[0].ExpiresAt, time.Second)
+
+	token, _, err := keyring.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, configMaker.(keyedMaker).keyID(), tokenKeyID(token))
+
+	setNow(start.Add(2 * time.Minute))
+	newToken, _, err := keyring.CreateToken(utils.RandomOwner(), utils.DepositorRole, 2*time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, key.KeyID, tokenKeyID(newToken))
+
+	// the old key still verifies the tokens it signed
+	_, err = keyring.VerifyToken(newToken)
+	require.NoError(t, err)
+	_, err = keyring.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	// another instance sharing the directory verifies the tokens of the new key
+	otherKeyring, _ := newTestKeyring(t, configMaker, MakerPaseto, dir)
+	_, err = otherKeyring.VerifyToken(newToken)
+	require.NoError(t, err)
+
+	// the old key is phased out once its tokens have all expired
+	setNow(start.Add(2 * time.Hour))
+	keys = keyring.Keys()
+	require.Len(t, keys, 1)
+	require.Equal(t, key.KeyID, keys[0].KeyID)
+	require.True(t, keys[0].Primary)
+
+	_, err = keyring.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	_, err = keyring.VerifyToken(newToken)
+	require.NoError(t, err)
+}
+
+func TestKeyringExpiredKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	configMaker, err := NewPasetoMaker(utils.RandomString(32))
+	require.NoError(t, err)
+
+	keyring, setNow := newTestKeyring(t, configMaker, MakerPaseto, dir)
+	start := time.Now()
+
+	first, err := keyring.Rotate()
+	require.NoError(t, err)
+	setNow(start.Add(2 * time.Minute))
+	second, err := keyring.Rotate()
+	require.NoError(t, err)
+
+	setNow(start.Add(3 * time.Hour))
+	err = keyring.Reload()
+	require.NoError(t, err)
+
+	require.NoFileExists(t, filepath.Join(dir, first.KeyID+keyFileExt))
+	require.FileExists(t, filepath.Join(dir, second.KeyID+keyFileExt))
+}
+
+func TestKeyringLegacyToken(t *testing.T) {
+	symmetricKey := utils.RandomString(32)
+	configMaker, err := NewPasetoMaker(symmetricKey)
+	require.NoError(t, err)
+
+	keyring, _ := newTestKeyring(t, configMaker, MakerPaseto, "")
+
+	// a token made before the keys were named in the tokens
+	payload, err := NewPayload(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	token, err := pvx.NewPV4Local().Encrypt(pvx.NewSymmetricKey([]byte(symmetricKey), pvx.Version4), payload)
+	require.NoError(t, err)
+
+	verified, err := keyring.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, payload.ID, verified.ID)
+}
+
+func TestKeyringNotStored(t *testing.T) {
+	configMaker, err := NewPasetoMaker(utils.RandomString(32))
+	require.NoError(t, err)
+
+	keyring, _ := newTestKeyring(t, configMaker, MakerPaseto, "")
+
+	_, err = keyring.Rotate()
+	require.ErrorIs(t, err, ErrKeyringNotStored)
+}
+
+func TestKeyringPublicKeys(t *testing.T) {
+	dir := t.TempDir()
+	configMaker, err := NewJWTPublicMaker(randomEd25519Key(t))
+	require.NoError(t, err)
+
+	keyring, _ := newTestKeyring(t, configMaker, MakerJWTEdDSA, dir)
+	require.Len(t, keyring.PublicKeys(), 1)
+
+	key, err := keyring.Rotate()
+	require.NoError(t, err)
+
+	// the new key is published before it signs any token
+	publicKeys := keyring.PublicKeys()
+	require.Len(t, publicKeys, 2)
+	require.Equal(t, key.KeyID, publicKeys[1].KeyID)
+	require.Equal(t, "EdDSA", publicKeys[1].Algorithm)
+}
+
+func TestKeyringInvalidKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "invalid"+keyFileExt), []byte("{"), 0600)
+	require.NoError(t, err)
+
+	configMaker, err := NewPasetoMaker(utils.RandomString(32))
+	require.NoError(t, err)
+
+	_, err = NewKeyring(configMaker, KeyringConfig{Dir: dir})
+	require.Error(t, err)
+}
@@ -0,0 +1,118 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const minRSAKeyBits = 2048
+
+// JWK is a public key in the JSON Web Key format of RFC 7517, for the services
+// verifying the tokens on their own.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg,omitempty"`
+	// Curve and X are the Ed25519 public key
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	// N and E are the RSA public key
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ParsePrivateKey parses a PEM encoded Ed25519 or RSA private key, in the
+// PKCS #8 format or, for RSA, in the PKCS #1 format.
+func ParsePrivateKey(pemKey []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse private key: %w", err)
+	}
+
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		return key, nil
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("invalid key size: RSA keys must have at least %d bits", minRSAKeyBits)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+}
+
+// newJWK returns the JWK of an Ed25519 or RSA public key. Its key ID is the
+// thumbprint of the key, as of RFC 7638.
+func newJWK(publicKey crypto.PublicKey, algorithm string) (JWK, error) {
+	var jwk JWK
+	var thumbprintInput interface{}
+
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		jwk = JWK{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       base64.RawURLEncoding.EncodeToString(key),
+		}
+		thumbprintInput = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	case *rsa.PublicKey:
+		jwk = JWK{
+			KeyType: "RSA",
+			N:       base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+		thumbprintInput = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	default:
+		return JWK{}, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+
+	// the members are in lexicographic order, as the thumbprint requires
+	data, err := json.Marshal(thumbprintInput)
+	if err != nil {
+		return JWK{}, err
+	}
+	sum := sha256.Sum256(data)
+
+	jwk.Use = "sig"
+	jwk.KeyID = base64.RawURLEncoding.EncodeToString(sum[:])
+	jwk.Algorithm = algorithm
+	return jwk, nil
+}
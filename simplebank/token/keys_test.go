@@ -0,0 +1,84 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func randomEd25519Key(t *testing.T) ed25519.PrivateKey {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return privateKey
+}
+
+func randomRSAKey(t *testing.T, bits int) *rsa.PrivateKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	require.NoError(t, err)
+	return privateKey
+}
+
+func encodePKCS8(t *testing.T, privateKey interface{}) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	edKey := randomEd25519Key(t)
+	rsaKey := randomRSAKey(t, 2048)
+
+	key, err := ParsePrivateKey(encodePKCS8(t, edKey))
+	require.NoError(t, err)
+	require.Equal(t, edKey, key)
+
+	key, err = ParsePrivateKey(encodePKCS8(t, rsaKey))
+	require.NoError(t, err)
+	require.True(t, rsaKey.Equal(key))
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	key, err = ParsePrivateKey(pkcs1)
+	require.NoError(t, err)
+	require.True(t, rsaKey.Equal(key))
+
+	_, err = ParsePrivateKey(encodePKCS8(t, randomRSAKey(t, 1024)))
+	require.Error(t, err)
+
+	_, err = ParsePrivateKey([]byte("not a key"))
+	require.Error(t, err)
+
+	_, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("key")}))
+	require.Error(t, err)
+}
+
+func TestNewJWK(t *testing.T) {
+	edKey := randomEd25519Key(t)
+
+	jwk, err := newJWK(edKey.Public(), "EdDSA")
+	require.NoError(t, err)
+	require.Equal(t, "OKP", jwk.KeyType)
+	require.Equal(t, "Ed25519", jwk.Curve)
+	require.Equal(t, "sig", jwk.Use)
+	require.Equal(t, "EdDSA", jwk.Algorithm)
+
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	require.NoError(t, err)
+	require.Equal(t, []byte(edKey.Public().(ed25519.PublicKey)), x)
+
+	// the key ID is stable for a key, and differs between keys
+	again, err := newJWK(edKey.Public(), "EdDSA")
+	require.NoError(t, err)
+	require.Equal(t, jwk.KeyID, again.KeyID)
+
+	rsaJWK, err := newJWK(randomRSAKey(t, 2048).Public(), "RS256")
+	require.NoError(t, err)
+	require.Equal(t, "RSA", rsaJWK.KeyType)
+	require.Equal(t, "AQAB", rsaJWK.E)
+	require.NotEqual(t, jwk.KeyID, rsaJWK.KeyID)
+}
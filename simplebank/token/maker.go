@@ -1,14 +1,77 @@
 package token
 
 import (
+	"crypto/ed25519"
+	"fmt"
 	"time"
 )
 
+// Kinds of token makers, selected by the configuration.
+const (
+	// PASETO v4.local, encrypted with the symmetric key
+	MakerPaseto = "paseto"
+	// PASETO v4.public, signed with an Ed25519 private key
+	MakerPasetoPublic = "paseto_public"
+	// JWT signed with the symmetric key, HS256
+	MakerJWT = "jwt"
+	// JWT signed with an Ed25519 private key, EdDSA
+	MakerJWTEdDSA = "jwt_eddsa"
+	// JWT signed with an RSA private key, RS256
+	MakerJWTRS256 = "jwt_rs256"
+)
+
 // Maker is an interface for managing tokens
 type Maker interface {
-	// CreateToken creates a new token for a specific username and duration
-	CreateToken(username string, duration time.Duration) (string, *Payload, error)
+	// CreateToken creates a new token for a specific username, role and duration
+	CreateToken(username string, role string, duration time.Duration) (string, *Payload, error)
+
+	// CreateClientToken creates a new token for a third-party client acting for
+	// the user, limited to the routes of the scopes
+	CreateClientToken(username string, role string, clientID string, scopes []string, duration time.Duration) (string, *Payload, error)
 
 	// VerifyToken checks if a token is valid or not
 	VerifyToken(token string) (*Payload, error)
+
+	// PublicKeys returns the public keys verifying the tokens, none for the
+	// symmetric makers
+	PublicKeys() []JWK
+}
+
+// NewMaker creates the maker of the kind. The symmetric makers use the
+// symmetric key, the others the PEM encoded private key.
+func NewMaker(kind string, symmetricKey string, privateKey []byte) (Maker, error) {
+	switch kind {
+	case MakerPaseto, "":
+		return NewPasetoMaker(symmetricKey)
+	case MakerJWT:
+		return NewJWTMaker(symmetricKey)
+	case MakerPasetoPublic, MakerJWTEdDSA, MakerJWTRS256:
+	default:
+		return nil, fmt.Errorf("unsupported token maker %q", kind)
+	}
+
+	if len(privateKey) == 0 {
+		return nil, fmt.Errorf("%s token maker requires a private key", kind)
+	}
+	key, err := ParsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case MakerPasetoPublic, MakerJWTEdDSA:
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s token maker requires an Ed25519 private key", kind)
+		}
+		if kind == MakerPasetoPublic {
+			return NewPasetoPublicMaker(edKey)
+		}
+		return NewJWTPublicMaker(edKey)
+	default:
+		if _, ok := key.(ed25519.PrivateKey); ok {
+			return nil, fmt.Errorf("%s token maker requires an RSA private key", kind)
+		}
+		return NewJWTPublicMaker(key)
+	}
 }
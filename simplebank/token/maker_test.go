@@ -0,0 +1,95 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMaker(t *testing.T) {
+	symmetricKey := "12345678901234567890123456789012"
+	edKey := encodePKCS8(t, randomEd25519Key(t))
+	rsaKey := encodePKCS8(t, randomRSAKey(t, 2048))
+
+	testCases := []struct {
+		name       string
+		kind       string
+		privateKey []byte
+		publicKeys int
+		ok         bool
+	}{
+		{name: "Default", kind: "", ok: true},
+		{name: "Paseto", kind: MakerPaseto, ok: true},
+		{name: "JWT", kind: MakerJWT, ok: true},
+		{name: "PasetoPublic", kind: MakerPasetoPublic, privateKey: edKey, publicKeys: 1, ok: true},
+		{name: "JWTEdDSA", kind: MakerJWTEdDSA, privateKey: edKey, publicKeys: 1, ok: true},
+		{name: "JWTRS256", kind: MakerJWTRS256, privateKey: rsaKey, publicKeys: 1, ok: true},
+		{name: "NoPrivateKey", kind: MakerPasetoPublic, ok: false},
+		{name: "RSAKeyForPaseto", kind: MakerPasetoPublic, privateKey: rsaKey, ok: false},
+		{name: "Ed25519KeyForRS256", kind: MakerJWTRS256, privateKey: edKey, ok: false},
+		{name: "Unsupported", kind: "macaroon", ok: false},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			maker, err := NewMaker(tc.kind, symmetricKey, tc.privateKey)
+			if !tc.ok {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, maker.PublicKeys(), tc.publicKeys)
+		})
+	}
+}
+
+func TestClientTokenWithoutScopes(t *testing.T) {
+	symmetricKey := "12345678901234567890123456789012"
+	edKey := encodePKCS8(t, randomEd25519Key(t))
+	rsaKey := encodePKCS8(t, randomRSAKey(t, 2048))
+
+	testCases := []struct {
+		name       string
+		kind       string
+		privateKey []byte
+	}{
+		{name: "Paseto", kind: MakerPaseto},
+		{name: "JWT", kind: MakerJWT},
+		{name: "PasetoPublic", kind: MakerPasetoPublic, privateKey: edKey},
+		{name: "JWTEdDSA", kind: MakerJWTEdDSA, privateKey: edKey},
+		{name: "JWTRS256", kind: MakerJWTRS256, privateKey: rsaKey},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			maker, err := NewMaker(tc.kind, symmetricKey, tc.privateKey)
+			require.NoError(t, err)
+
+			token, _, err := maker.CreateClientToken(utils.RandomOwner(), utils.DepositorRole, utils.RandomString(16), nil, time.Minute)
+			require.NoError(t, err)
+
+			// the empty scopes reach no route once the token is verified
+			payload, err := maker.VerifyToken(token)
+			require.NoError(t, err)
+			require.NotNil(t, payload.Scopes)
+			require.Empty(t, payload.Scopes)
+			require.False(t, payload.AllowsScope("accounts:read"))
+
+			// even if the scopes are missing from the token
+			payload.Scopes = nil
+			require.False(t, payload.AllowsScope("accounts:read"))
+
+			token, _, err = maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+			require.NoError(t, err)
+			payload, err = maker.VerifyToken(token)
+			require.NoError(t, err)
+			require.True(t, payload.AllowsScope("accounts:read"))
+		})
+	}
+}
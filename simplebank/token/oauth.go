@@ -0,0 +1,46 @@
+package token
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+const (
+	// clientSecretSize is the number of random bytes of an OAuth client secret.
+	clientSecretSize = 32
+	// authorizationCodeSize is the number of random bytes of an OAuth authorization code.
+	authorizationCodeSize = 32
+
+	// CodeChallengeMethodS256 is the only PKCE method supported, the plain
+	// method would leak the verifier along with the authorization request.
+	CodeChallengeMethodS256 = "S256"
+)
+
+// NewClientSecret creates a new secret for an OAuth client.
+func NewClientSecret() (string, error) {
+	return newOpaqueToken(clientSecretSize)
+}
+
+// HashClientSecret returns the hash the client secret is stored and checked with.
+func HashClientSecret(secret string) string {
+	return hashOpaqueToken(secret)
+}
+
+// NewAuthorizationCode creates a new OAuth authorization code.
+func NewAuthorizationCode() (string, error) {
+	return newOpaqueToken(authorizationCodeSize)
+}
+
+// HashAuthorizationCode returns the hash the authorization code is stored and looked up with.
+func HashAuthorizationCode(code string) string {
+	return hashOpaqueToken(code)
+}
+
+// VerifyCodeChallenge checks the PKCE code verifier sent when exchanging an
+// authorization code against the S256 challenge sent when requesting it.
+func VerifyCodeChallenge(verifier string, challenge string) bool {
+	hash := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(hash[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
@@ -0,0 +1,29 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSecret(t *testing.T) {
+	secret1, err := NewClientSecret()
+	require.NoError(t, err)
+
+	secret2, err := NewClientSecret()
+	require.NoError(t, err)
+	require.NotEqual(t, secret1, secret2)
+
+	require.Len(t, HashClientSecret(secret1), 64)
+	require.NotEqual(t, HashClientSecret(secret1), HashClientSecret(secret2))
+}
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	// example of RFC 7636, appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	require.True(t, VerifyCodeChallenge(verifier, challenge))
+	require.False(t, VerifyCodeChallenge(verifier+"x", challenge))
+	require.False(t, VerifyCodeChallenge(verifier, verifier))
+}
@@ -12,6 +12,7 @@ import (
 type PasetoMaker struct {
 	paseto       *pvx.ProtoV4Local
 	symmetricKey *pvx.SymKey
+	kid          string
 }
 
 // NewPasetoMaker creates a new PasetoMaker
@@ -22,21 +23,37 @@ func NewPasetoMaker(symmetricKey string) (Maker, error) {
 	maker := &PasetoMaker{
 		paseto:       pvx.NewPV4Local(),
 		symmetricKey: pvx.NewSymmetricKey([]byte(symmetricKey), pvx.Version4),
+		kid:          symmetricKeyID(symmetricKey),
 	}
 	return maker, nil
 }
 
-// CreateToken creates a new token for a specific username and duration
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, *Payload, error) {
-	payload, err := NewPayload(username, duration)
+// CreateToken creates a new token for a specific username, role and duration
+func (maker *PasetoMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
 	if err != nil {
 		return "", payload, err
 	}
 
-	token, err := maker.paseto.Encrypt(maker.symmetricKey, payload)
+	token, err := maker.encrypt(payload)
 	return token, payload, err
 }
 
+// CreateClientToken creates a new token for a third-party client acting for the user
+func (maker *PasetoMaker) CreateClientToken(username string, role string, clientID string, scopes []string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewClientPayload(username, role, clientID, scopes, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	token, err := maker.encrypt(payload)
+	return token, payload, err
+}
+
+func (maker *PasetoMaker) encrypt(payload *Payload) (string, error) {
+	return maker.paseto.Encrypt(maker.symmetricKey, payload, pvx.WithFooter(pasetoFooter{KeyID: maker.kid}))
+}
+
 // VerifyToken checks if a token is valid or not
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 	payload := &Payload{}
@@ -51,3 +68,12 @@ func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 
 	return payload, nil
 }
+
+// PublicKeys returns no keys, the tokens are verified with the symmetric key
+func (maker *PasetoMaker) PublicKeys() []JWK {
+	return nil
+}
+
+func (maker *PasetoMaker) keyID() string {
+	return maker.kid
+}
@@ -0,0 +1,85 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"time"
+
+	"github.com/vk-rv/pvx"
+)
+
+// PasetoPublicMaker is a PASETO v4.public token maker. The tokens are signed
+// with an Ed25519 private key, so that the services verifying them with the
+// public key can't mint them.
+type PasetoPublicMaker struct {
+	paseto     *pvx.ProtoV4Public
+	privateKey *pvx.AsymSecretKey
+	publicKey  *pvx.AsymPublicKey
+	jwk        JWK
+}
+
+// NewPasetoPublicMaker creates a new PasetoPublicMaker
+func NewPasetoPublicMaker(privateKey ed25519.PrivateKey) (Maker, error) {
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	jwk, err := newJWK(publicKey, "")
+	if err != nil {
+		return nil, err
+	}
+
+	maker := &PasetoPublicMaker{
+		paseto:     pvx.NewPV4Public(),
+		privateKey: pvx.NewAsymmetricSecretKey(privateKey, pvx.Version4),
+		publicKey:  pvx.NewAsymmetricPublicKey(publicKey, pvx.Version4),
+		jwk:        jwk,
+	}
+	return maker, nil
+}
+
+// CreateToken creates a new token for a specific username, role and duration
+func (maker *PasetoPublicMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	token, err := maker.sign(payload)
+	return token, payload, err
+}
+
+// CreateClientToken creates a new token for a third-party client acting for the user
+func (maker *PasetoPublicMaker) CreateClientToken(username string, role string, clientID string, scopes []string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewClientPayload(username, role, clientID, scopes, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	token, err := maker.sign(payload)
+	return token, payload, err
+}
+
+func (maker *PasetoPublicMaker) sign(payload *Payload) (string, error) {
+	return maker.paseto.Sign(maker.privateKey, payload, pvx.WithFooter(pasetoFooter{KeyID: maker.jwk.KeyID}))
+}
+
+// VerifyToken checks if a token is valid or not
+func (maker *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	payload := &Payload{}
+
+	err := maker.paseto.Verify(token, maker.publicKey).ScanClaims(payload)
+	if err != nil {
+		if err == ErrExpiredToken {
+			return nil, err
+		}
+		return nil, ErrInvalidToken
+	}
+
+	return payload, nil
+}
+
+// PublicKeys returns the public key verifying the tokens
+func (maker *PasetoPublicMaker) PublicKeys() []JWK {
+	return []JWK{maker.jwk}
+}
+
+func (maker *PasetoPublicMaker) keyID() string {
+	return maker.jwk.KeyID
+}
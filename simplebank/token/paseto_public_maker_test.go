@@ -0,0 +1,97 @@
+package token
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ebaudet/simplebank/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasetoPublicMaker(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(randomEd25519Key(t))
+	require.NoError(t, err)
+
+	username := utils.RandomOwner()
+	role := utils.DepositorRole
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+	require.True(t, strings.HasPrefix(token, "v4.public."))
+
+	payload, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+
+	// the footer names the key verifying the token
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 4)
+	data, err := base64.RawURLEncoding.DecodeString(parts[3])
+	require.NoError(t, err)
+	var footer pasetoFooter
+	require.NoError(t, json.Unmarshal(data, &footer))
+	require.Equal(t, maker.PublicKeys()[0].KeyID, footer.KeyID)
+}
+
+func TestExpiredPasetoPublicToken(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(randomEd25519Key(t))
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(utils.RandomOwner(), utils.DepositorRole, -time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestPasetoPublicTokenOfOtherKey(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(randomEd25519Key(t))
+	require.NoError(t, err)
+	otherMaker, err := NewPasetoPublicMaker(randomEd25519Key(t))
+	require.NoError(t, err)
+	localMaker, err := NewPasetoMaker(utils.RandomString(32))
+	require.NoError(t, err)
+
+	token, _, err := otherMaker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	payload, err := maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+
+	token, _, err = localMaker.CreateToken(utils.RandomOwner(), utils.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	payload, err = maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestPasetoPublicClientToken(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(randomEd25519Key(t))
+	require.NoError(t, err)
+
+	clientID := utils.RandomString(16)
+	scopes := []string{"accounts:read"}
+
+	token, _, err := maker.CreateClientToken(utils.RandomOwner(), utils.DepositorRole, clientID, scopes, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, clientID, payload.ClientID)
+	require.Equal(t, scopes, payload.Scopes)
+}
@@ -13,16 +13,24 @@ var (
 	ErrExpiredToken = errors.New("token has expired")
 )
 
-// Payload contains the payload data of the token
+// Payload contains the payload data of the token. The JWTs carry it as
+// their registered claims, see jwtClaims.
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
+	// ClientID is the third-party client the token was issued to, if any.
+	ClientID string `json:"client_id,omitempty"`
+	// Scopes limits the routes reachable by an API key or a client token,
+	// the access tokens of the users have none and reach every route.
+	// An empty list is kept as such in the token, so that it reaches no route.
+	Scopes []string `json:"scopes"`
 }
 
 // NewPayload creates a new Payload
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+func NewPayload(username string, role string, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -31,15 +39,44 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 	payload := &Payload{
 		ID:        tokenID,
 		Username:  username,
+		Role:      role,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
 	return payload, nil
 }
 
+// NewClientPayload creates a new Payload for a third-party client acting for
+// the user, limited to the scopes.
+func NewClientPayload(username string, role string, clientID string, scopes []string, duration time.Duration) (*Payload, error) {
+	payload, err := NewPayload(username, role, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.ClientID = clientID
+	// never nil, which would reach every route
+	payload.Scopes = append([]string{}, scopes...)
+	return payload, nil
+}
+
 func (payload *Payload) Valid() error {
 	if time.Now().After(payload.ExpiredAt) {
 		return ErrExpiredToken
 	}
 	return nil
 }
+
+// AllowsScope returns true if the payload reaches the routes of the scope.
+// A client token is always limited to its scopes.
+func (payload *Payload) AllowsScope(scope string) bool {
+	if payload.Scopes == nil && payload.ClientID == "" {
+		return true
+	}
+	for _, s := range payload.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
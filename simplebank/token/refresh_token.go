@@ -0,0 +1,38 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// refreshTokenSize is the number of random bytes of a refresh token.
+const refreshTokenSize = 32
+
+// NewRefreshToken creates a new opaque refresh token. Unlike the access
+// tokens it carries no payload: it is only worth the session it is stored with.
+func NewRefreshToken() (string, error) {
+	return newOpaqueToken(refreshTokenSize)
+}
+
+// HashRefreshToken returns the hash the refresh token is stored and looked up with.
+func HashRefreshToken(refreshToken string) string {
+	return hashOpaqueToken(refreshToken)
+}
+
+// newOpaqueToken returns size random bytes, base64url encoded.
+func newOpaqueToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashOpaqueToken returns the sha256 of the token, hex encoded. The opaque
+// tokens are random enough for a fast hash to be safe.
+func hashOpaqueToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,22 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshToken(t *testing.T) {
+	refreshToken1, err := NewRefreshToken()
+	require.NoError(t, err)
+	require.Len(t, refreshToken1, 43)
+
+	refreshToken2, err := NewRefreshToken()
+	require.NoError(t, err)
+	require.NotEqual(t, refreshToken1, refreshToken2)
+
+	hash := HashRefreshToken(refreshToken1)
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, HashRefreshToken(refreshToken1))
+	require.NotEqual(t, hash, HashRefreshToken(refreshToken2))
+}
@@ -0,0 +1,15 @@
+package token
+
+// resetPasswordTokenSize is the number of random bytes of a password reset token.
+const resetPasswordTokenSize = 32
+
+// NewResetPasswordToken creates a new token for a password reset link. Only
+// its hash is stored, so that the link can't be forged from the database.
+func NewResetPasswordToken() (string, error) {
+	return newOpaqueToken(resetPasswordTokenSize)
+}
+
+// HashResetPasswordToken returns the hash the reset token is stored and looked up with.
+func HashResetPasswordToken(resetToken string) string {
+	return hashOpaqueToken(resetToken)
+}
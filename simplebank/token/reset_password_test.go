@@ -0,0 +1,22 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetPasswordToken(t *testing.T) {
+	resetToken1, err := NewResetPasswordToken()
+	require.NoError(t, err)
+	require.Len(t, resetToken1, 43)
+
+	resetToken2, err := NewResetPasswordToken()
+	require.NoError(t, err)
+	require.NotEqual(t, resetToken1, resetToken2)
+
+	hash := HashResetPasswordToken(resetToken1)
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, HashResetPasswordToken(resetToken1))
+	require.NotEqual(t, hash, HashResetPasswordToken(resetToken2))
+}
@@ -0,0 +1,148 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters of RFC 6238, the defaults every authenticator app supports.
+const (
+	totpSecretSize = 20
+	totpDigits     = 6
+	totpPeriod     = 30 * time.Second
+	// totpSkew is the number of steps a code may be off by, for the clock
+	// drift of the phones
+	totpSkew = 1
+)
+
+const (
+	// recoveryCodeSize is the number of random bytes of a recovery code.
+	recoveryCodeSize = 10
+	// mfaTokenSize is the number of random bytes of an MFA challenge token.
+	mfaTokenSize = 32
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret creates a new TOTP secret, base32 encoded as the
+// authenticator apps expect it.
+func NewTOTPSecret() (string, error) {
+	b := make([]byte, totpSecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPURI returns the otpauth URI of the secret, which the authenticator apps
+// scan as a QR code.
+func TOTPURI(issuer string, account string, secret string) string {
+	params := url.Values{
+		"secret":    {secret},
+		"issuer":    {issuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(totpDigits)},
+		"period":    {fmt.Sprint(int(totpPeriod / time.Second))},
+	}
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: params.Encode(),
+	}
+	return uri.String()
+}
+
+// TOTPStep returns the time step of the time.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(totpPeriod/time.Second)
+}
+
+// TOTPCode returns the code of the secret at the time step.
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation of RFC 4226
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulo), nil
+}
+
+// VerifyTOTP checks the code against the secret around the time, and returns
+// the time step it matched. The steps up to lastUsedStep are refused, so that
+// a code can't be used twice.
+func VerifyTOTP(secret string, code string, now time.Time, lastUsedStep int64) (int64, bool) {
+	current := TOTPStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// NewRecoveryCodes creates n recovery codes, each usable once instead of a
+// TOTP code. They are grouped by 4 characters to be easier to copy.
+func NewRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(b))
+		groups := make([]string, 0, len(code)/4)
+		for len(code) > 4 {
+			groups = append(groups, code[:4])
+			code = code[4:]
+		}
+		codes[i] = strings.Join(append(groups, code), "-")
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns the hash the recovery code is stored and looked up
+// with. The case and the separators are ignored.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return hashOpaqueToken(normalized)
+}
+
+// NewMFAToken creates a new opaque token for the second step of the login.
+func NewMFAToken() (string, error) {
+	return newOpaqueToken(mfaTokenSize)
+}
+
+// HashMFAToken returns the hash the MFA token is stored and looked up with.
+func HashMFAToken(mfaToken string) string {
+	return hashOpaqueToken(mfaToken)
+}
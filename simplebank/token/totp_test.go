@@ -0,0 +1,92 @@
+package token
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// rfc6238Secret is the SHA1 secret of the test vectors of RFC 6238.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// the test vectors of RFC 6238, truncated to 6 digits
+	testCases := []struct {
+		time int64
+		code string
+	}{
+		{time: 59, code: "287082"},
+		{time: 1111111109, code: "081804"},
+		{time: 1234567890, code: "005924"},
+		{time: 2000000000, code: "279037"},
+	}
+
+	for _, tc := range testCases {
+		code, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(tc.time, 0)))
+		require.NoError(t, err)
+		require.Equal(t, tc.code, code)
+	}
+
+	_, err := TOTPCode("not base32!", 1)
+	require.Error(t, err)
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	step := TOTPStep(now)
+	code, err := TOTPCode(secret, step)
+	require.NoError(t, err)
+
+	matched, ok := VerifyTOTP(secret, code, now, 0)
+	require.True(t, ok)
+	require.Equal(t, step, matched)
+
+	// the clock of the phone may be a step behind
+	previous, err := TOTPCode(secret, step-1)
+	require.NoError(t, err)
+	matched, ok = VerifyTOTP(secret, previous, now, 0)
+	require.True(t, ok)
+	require.Equal(t, step-1, matched)
+
+	// a code can't be used twice
+	_, ok = VerifyTOTP(secret, code, now, step)
+	require.False(t, ok)
+
+	tooOld, err := TOTPCode(secret, step-2)
+	require.NoError(t, err)
+	_, ok = VerifyTOTP(secret, tooOld, now, 0)
+	require.False(t, ok)
+
+	_, ok = VerifyTOTP(secret, "000000", time.Unix(59, 0), 0)
+	require.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(TOTPURI("SimpleBank", "alice", rfc6238Secret))
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", uri.Scheme)
+	require.Equal(t, "totp", uri.Host)
+	require.Equal(t, "/SimpleBank:alice", uri.Path)
+	require.Equal(t, rfc6238Secret, uri.Query().Get("secret"))
+	require.Equal(t, "SimpleBank", uri.Query().Get("issuer"))
+	require.Equal(t, "6", uri.Query().Get("digits"))
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(10)
+	require.NoError(t, err)
+	require.Len(t, codes, 10)
+	require.NotEqual(t, codes[0], codes[1])
+
+	code := codes[0]
+	require.Len(t, strings.Split(code, "-"), 4)
+	require.Equal(t, HashRecoveryCode(code), HashRecoveryCode(strings.ToUpper(code)))
+	require.Equal(t, HashRecoveryCode(code), HashRecoveryCode(strings.ReplaceAll(code, "-", "")))
+	require.NotEqual(t, HashRecoveryCode(code), HashRecoveryCode(codes[1]))
+}
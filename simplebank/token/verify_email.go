@@ -0,0 +1,17 @@
+package token
+
+// verifyEmailCodeSize is the number of random bytes of the secret code of an
+// email verification link.
+const verifyEmailCodeSize = 32
+
+// NewVerifyEmailCode creates a new secret code for an email verification
+// link. Only its hash is stored, so that the link can't be forged from the
+// database.
+func NewVerifyEmailCode() (string, error) {
+	return newOpaqueToken(verifyEmailCodeSize)
+}
+
+// HashVerifyEmailCode returns the hash the secret code is stored and checked with.
+func HashVerifyEmailCode(code string) string {
+	return hashOpaqueToken(code)
+}
@@ -0,0 +1,22 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyEmailCode(t *testing.T) {
+	code1, err := NewVerifyEmailCode()
+	require.NoError(t, err)
+	require.Len(t, code1, 43)
+
+	code2, err := NewVerifyEmailCode()
+	require.NoError(t, err)
+	require.NotEqual(t, code1, code2)
+
+	hash := HashVerifyEmailCode(code1)
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, HashVerifyEmailCode(code1))
+	require.NotEqual(t, hash, HashVerifyEmailCode(code2))
+}
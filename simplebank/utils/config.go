@@ -9,11 +9,75 @@ import (
 // Config stores al configuration of the application.
 // The values are read by viper form a config file or environment variables.
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	DBSource                 string        `mapstructure:"DB_SOURCE"`
+	DBMaxConns               int32         `mapstructure:"DB_MAX_CONNS"`
+	DBMinConns               int32         `mapstructure:"DB_MIN_CONNS"`
+	DBMaxConnLifetime        time.Duration `mapstructure:"DB_MAX_CONN_LIFETIME"`
+	DBMaxConnIdleTime        time.Duration `mapstructure:"DB_MAX_CONN_IDLE_TIME"`
+	DBHealthCheckPeriod      time.Duration `mapstructure:"DB_HEALTH_CHECK_PERIOD"`
+	DBReplicaSource          string        `mapstructure:"DB_REPLICA_SOURCE"`
+	ReadYourWritesWindow     time.Duration `mapstructure:"READ_YOUR_WRITES_WINDOW"`
+	ServerAddress            string        `mapstructure:"SERVER_ADDRESS"`
+	TrustedProxies           string        `mapstructure:"TRUSTED_PROXIES"`
+	TokenMaker               string        `mapstructure:"TOKEN_MAKER"`
+	TokenSymmetricKey        string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenPrivateKeyFile      string        `mapstructure:"TOKEN_PRIVATE_KEY_FILE"`
+	TokenKeyringDir          string        `mapstructure:"TOKEN_KEYRING_DIR"`
+	TokenKeyRefreshInterval  time.Duration `mapstructure:"TOKEN_KEY_REFRESH_INTERVAL"`
+	AccessTokenDuration      time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration     time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	RevokedTokenCleanup      time.Duration `mapstructure:"REVOKED_TOKEN_CLEANUP_INTERVAL"`
+	OAuthAccessTokenDuration time.Duration `mapstructure:"OAUTH_ACCESS_TOKEN_DURATION"`
+	OAuthCodeDuration        time.Duration `mapstructure:"OAUTH_CODE_DURATION"`
+	BalanceSnapshotInterval  time.Duration `mapstructure:"BALANCE_SNAPSHOT_INTERVAL"`
+	GoalContributionInterval time.Duration `mapstructure:"GOAL_CONTRIBUTION_INTERVAL"`
+	OutboxDispatchInterval   time.Duration `mapstructure:"OUTBOX_DISPATCH_INTERVAL"`
+	WebhookDeliveryInterval  time.Duration `mapstructure:"WEBHOOK_DELIVERY_INTERVAL"`
+	WebhookTimeout           time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	WebhookMaxAttempts       int32         `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	RateLimitPublic          string        `mapstructure:"RATE_LIMIT_PUBLIC"`
+	RateLimitUser            string        `mapstructure:"RATE_LIMIT_USER"`
+	RateLimitAdmin           string        `mapstructure:"RATE_LIMIT_ADMIN"`
+	LoginMaxUserFailures     int32         `mapstructure:"LOGIN_MAX_USER_FAILURES"`
+	LoginMaxIPFailures       int32         `mapstructure:"LOGIN_MAX_IP_FAILURES"`
+	LoginLockoutBase         time.Duration `mapstructure:"LOGIN_LOCKOUT_BASE"`
+	LoginLockoutMax          time.Duration `mapstructure:"LOGIN_LOCKOUT_MAX"`
+	LoginFailureWindow       time.Duration `mapstructure:"LOGIN_FAILURE_WINDOW"`
+	MFAChallengeDuration     time.Duration `mapstructure:"MFA_CHALLENGE_DURATION"`
+	TOTPIssuer               string        `mapstructure:"TOTP_ISSUER"`
+	MailSender               string        `mapstructure:"MAIL_SENDER"`
+	MailFrom                 string        `mapstructure:"MAIL_FROM"`
+	MailDir                  string        `mapstructure:"MAIL_DIR"`
+	SMTPHost                 string        `mapstructure:"SMTP_HOST"`
+	SMTPPort                 int           `mapstructure:"SMTP_PORT"`
+	SMTPUsername             string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword             string        `mapstructure:"SMTP_PASSWORD"`
+	EmailVerifyURL           string        `mapstructure:"EMAIL_VERIFY_URL"`
+	EmailVerifyDuration      time.Duration `mapstructure:"EMAIL_VERIFY_DURATION"`
+	ResetPasswordURL         string        `mapstructure:"RESET_PASSWORD_URL"`
+	ResetPasswordDuration    time.Duration `mapstructure:"RESET_PASSWORD_DURATION"`
+	PasswordHashAlgorithm    string        `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	BcryptCost               int           `mapstructure:"BCRYPT_COST"`
+	Argon2Memory             uint32        `mapstructure:"ARGON2_MEMORY"`
+	Argon2Iterations         uint32        `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism        uint8         `mapstructure:"ARGON2_PARALLELISM"`
+	PasswordMinLength        int           `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMaxLength        int           `mapstructure:"PASSWORD_MAX_LENGTH"`
+	PasswordRequireUpper     bool          `mapstructure:"PASSWORD_REQUIRE_UPPER"`
+	PasswordRequireLower     bool          `mapstructure:"PASSWORD_REQUIRE_LOWER"`
+	PasswordRequireDigit     bool          `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol    bool          `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+	PasswordRejectUserInfo   bool          `mapstructure:"PASSWORD_REJECT_USER_INFO"`
+	PasswordBreachedList     string        `mapstructure:"PASSWORD_BREACHED_LIST"`
+	TxMaxAttempts            int           `mapstructure:"TX_MAX_ATTEMPTS"`
+	TxRetryBaseDelay         time.Duration `mapstructure:"TX_RETRY_BASE_DELAY"`
+	TxRetryMaxDelay          time.Duration `mapstructure:"TX_RETRY_MAX_DELAY"`
+	TxIsolation              string        `mapstructure:"TX_ISOLATION"`
+	TxIsolationOverrides     string        `mapstructure:"TX_ISOLATION_OVERRIDES"`
+	PartitionInterval        time.Duration `mapstructure:"PARTITION_MAINTENANCE_INTERVAL"`
+	PartitionPremakeMonths   int           `mapstructure:"PARTITION_PREMAKE_MONTHS"`
+	PartitionRetentionMonths int           `mapstructure:"PARTITION_RETENTION_MONTHS"`
+	PartitionArchiveDir      string        `mapstructure:"PARTITION_ARCHIVE_DIR"`
 }
 
 // LoadConfig reads configuration from file or environment variables.
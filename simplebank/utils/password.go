@@ -1,12 +1,74 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword returns the bcrypt hash of the password.
+// Supported password hashing algorithms.
+const (
+	BcryptAlgorithm   = "bcrypt"
+	Argon2idAlgorithm = "argon2id"
+)
+
+// Default argon2id parameters, the second recommended option of RFC 9106.
+const (
+	defaultArgon2Memory      = 64 * 1024
+	defaultArgon2Iterations  = 3
+	defaultArgon2Parallelism = 4
+	argon2SaltLength         = 16
+	argon2KeyLength          = 32
+)
+
+// ErrMismatchedPassword is returned by CheckPassword when the password
+// doesn't match the hash, whatever its algorithm.
+var ErrMismatchedPassword = bcrypt.ErrMismatchedHashAndPassword
+
+var (
+	errUnknownPasswordHash = errors.New("unknown password hash algorithm")
+	errInvalidArgon2idHash = errors.New("invalid argon2id password hash")
+)
+
+// PasswordHasher hashes the passwords with an algorithm and its parameters.
+type PasswordHasher interface {
+	// HashPassword returns the encoded hash of the password.
+	HashPassword(password string) (string, error)
+	// NeedsRehash reports whether the hash was made with another algorithm or
+	// other parameters, and should be replaced by a new one.
+	NeedsRehash(hashedPassword string) bool
+}
+
+// PasswordHasherConfig contains the settings of a password hasher. The zero
+// parameters are replaced by the defaults.
+type PasswordHasherConfig struct {
+	// Algorithm is "bcrypt" or "argon2id", bcrypt when empty
+	Algorithm         string
+	BcryptCost        int
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+// NewPasswordHasher returns the password hasher of the algorithm of the config.
+func NewPasswordHasher(config PasswordHasherConfig) (PasswordHasher, error) {
+	switch config.Algorithm {
+	case "", BcryptAlgorithm:
+		return NewBcryptHasher(config.BcryptCost)
+	case Argon2idAlgorithm:
+		return NewArgon2idHasher(config.Argon2Memory, config.Argon2Iterations, config.Argon2Parallelism), nil
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm: %q", config.Algorithm)
+	}
+}
+
+// HashPassword returns the bcrypt hash of the password, with the default cost.
 func HashPassword(password string) (string, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -15,7 +77,149 @@ func HashPassword(password string) (string, error) {
 	return string(hash), nil
 }
 
-// CheckPassword checks if the password is correct or not
+// CheckPassword checks if the password is correct or not. The algorithm is
+// told by the prefix of the hash, so that the hashes made with an algorithm
+// no longer used can still be checked.
 func CheckPassword(password, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	switch {
+	case strings.HasPrefix(hashedPassword, "$2"):
+		return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	case strings.HasPrefix(hashedPassword, "$argon2id$"):
+		return checkArgon2idPassword(password, hashedPassword)
+	default:
+		return errUnknownPasswordHash
+	}
+}
+
+// BcryptHasher hashes the passwords with bcrypt.
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher creates a bcrypt hasher of the cost, the default one when zero.
+func NewBcryptHasher(cost int) (*BcryptHasher, error) {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("invalid bcrypt cost: must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return &BcryptHasher{cost: cost}, nil
+}
+
+// HashPassword returns the bcrypt hash of the password.
+func (hasher *BcryptHasher) HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), hasher.cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// NeedsRehash reports whether the hash isn't a bcrypt one of the cost of the hasher.
+func (hasher *BcryptHasher) NeedsRehash(hashedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != hasher.cost
+}
+
+// Argon2idHasher hashes the passwords with argon2id, encoded in the PHC string
+// format along with its parameters and salt, e.g.
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+type Argon2idHasher struct {
+	params argon2idParams
+}
+
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// NewArgon2idHasher creates an argon2id hasher using the memory in KiB, the
+// iterations and the parallelism, the defaults for the ones zero.
+func NewArgon2idHasher(memory uint32, iterations uint32, parallelism uint8) *Argon2idHasher {
+	if memory == 0 {
+		memory = defaultArgon2Memory
+	}
+	if iterations == 0 {
+		iterations = defaultArgon2Iterations
+	}
+	if parallelism == 0 {
+		parallelism = defaultArgon2Parallelism
+	}
+	return &Argon2idHasher{params: argon2idParams{
+		memory:      memory,
+		iterations:  iterations,
+		parallelism: parallelism,
+	}}
+}
+
+// HashPassword returns the argon2id hash of the password, with a random salt.
+func (hasher *Argon2idHasher) HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	p := hasher.params
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// NeedsRehash reports whether the hash isn't an argon2id one of the parameters of the hasher.
+func (hasher *Argon2idHasher) NeedsRehash(hashedPassword string) bool {
+	params, _, key, err := decodeArgon2idHash(hashedPassword)
+	return err != nil || params != hasher.params || len(key) != argon2KeyLength
+}
+
+func checkArgon2idPassword(password, hashedPassword string) error {
+	p, salt, key, err := decodeArgon2idHash(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	otherKey := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+		return ErrMismatchedPassword
+	}
+	return nil
+}
+
+// decodeArgon2idHash returns the parameters, the salt and the key of a hash
+// in the PHC string format.
+func decodeArgon2idHash(hashedPassword string) (argon2idParams, []byte, []byte, error) {
+	var params argon2idParams
+
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != Argon2idAlgorithm {
+		return params, nil, nil, errInvalidArgon2idHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, errInvalidArgon2idHash
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version: %d", version)
+	}
+
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism)
+	if err != nil || params.memory == 0 || params.iterations == 0 || params.parallelism == 0 {
+		return params, nil, nil, errInvalidArgon2idHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errInvalidArgon2idHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errInvalidArgon2idHash
+	}
+
+	return params, salt, key, nil
 }
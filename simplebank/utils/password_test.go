@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -25,3 +26,88 @@ func TestHashPassword(t *testing.T) {
 	err = CheckPassword(wrongPassword, hash1)
 	require.EqualError(t, err, bcrypt.ErrMismatchedHashAndPassword.Error())
 }
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher := NewArgon2idHasher(1024, 2, 1)
+	password := RandomPassword(6, 25)
+
+	hash1, err := hasher.HashPassword(password)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hash1, "$argon2id$v=19$m=1024,t=2,p=1$"))
+
+	hash2, err := hasher.HashPassword(password)
+	require.NoError(t, err)
+	require.NotEqual(t, hash1, hash2)
+
+	err = CheckPassword(password, hash1)
+	require.NoError(t, err)
+
+	wrongPassword := RandomPassword(6, 25)
+	err = CheckPassword(wrongPassword, hash1)
+	require.ErrorIs(t, err, ErrMismatchedPassword)
+
+	require.False(t, hasher.NeedsRehash(hash1))
+	require.True(t, NewArgon2idHasher(2048, 2, 1).NeedsRehash(hash1))
+}
+
+func TestCheckPasswordInvalidHash(t *testing.T) {
+	password := RandomPassword(6, 25)
+
+	err := CheckPassword(password, "$5$rounds=5000$salt$hash")
+	require.ErrorIs(t, err, errUnknownPasswordHash)
+
+	invalidHashes := []string{
+		"$argon2id$v=19$m=1024,t=2,p=1$c2FsdA",
+		"$argon2id$v=19$m=0,t=2,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=2,p=1$not base64$a2V5",
+		"$argon2id$v=19$m=1024,t=2,p=1$c2FsdA$",
+	}
+	for _, hash := range invalidHashes {
+		err = CheckPassword(password, hash)
+		require.ErrorIs(t, err, errInvalidArgon2idHash, hash)
+	}
+
+	err = CheckPassword(password, "$argon2id$v=16$m=1024,t=2,p=1$c2FsdA$a2V5")
+	require.EqualError(t, err, "unsupported argon2id version: 16")
+}
+
+func TestBcryptHasher(t *testing.T) {
+	hasher, err := NewBcryptHasher(bcrypt.MinCost)
+	require.NoError(t, err)
+	password := RandomPassword(6, 25)
+
+	hash, err := hasher.HashPassword(password)
+	require.NoError(t, err)
+	require.NoError(t, CheckPassword(password, hash))
+	require.False(t, hasher.NeedsRehash(hash))
+
+	// a bcrypt hash of another cost, or an argon2id hash
+	defaultHash, err := HashPassword(password)
+	require.NoError(t, err)
+	require.True(t, hasher.NeedsRehash(defaultHash))
+
+	argon2idHash, err := NewArgon2idHasher(1024, 1, 1).HashPassword(password)
+	require.NoError(t, err)
+	require.True(t, hasher.NeedsRehash(argon2idHash))
+
+	_, err = NewBcryptHasher(bcrypt.MaxCost + 1)
+	require.Error(t, err)
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	hasher, err := NewPasswordHasher(PasswordHasherConfig{})
+	require.NoError(t, err)
+	require.IsType(t, &BcryptHasher{}, hasher)
+
+	hasher, err = NewPasswordHasher(PasswordHasherConfig{Algorithm: Argon2idAlgorithm})
+	require.NoError(t, err)
+	require.IsType(t, &Argon2idHasher{}, hasher)
+	require.Equal(t, argon2idParams{
+		memory:      defaultArgon2Memory,
+		iterations:  defaultArgon2Iterations,
+		parallelism: defaultArgon2Parallelism,
+	}, hasher.(*Argon2idHasher).params)
+
+	_, err = NewPasswordHasher(PasswordHasherConfig{Algorithm: "md5"})
+	require.Error(t, err)
+}
@@ -0,0 +1,167 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned when the URL of a subscription is not https
+// or its host resolves to an address of the bank's own network.
+var ErrForbiddenAddress = errors.New("forbidden webhook address")
+
+// Headers sent along with every delivery.
+const (
+	SignatureHeader = "X-Simplebank-Signature"
+	TimestampHeader = "X-Simplebank-Timestamp"
+	EventHeader     = "X-Simplebank-Event"
+	DeliveryHeader  = "X-Simplebank-Delivery"
+)
+
+// Event is the body posted to the subscribers.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Client posts signed events to the subscribers.
+// The URLs are given by the users, so the client only posts over https to
+// public addresses, checked once the host is resolved so that a DNS name can't
+// point it to the internal services, and never follows the redirects.
+type Client struct {
+	httpClient *http.Client
+	insecure   bool
+}
+
+// ClientOption configures a Client created by NewClient.
+type ClientOption func(*Client)
+
+// WithInsecureTargets lets the client post over http and to any address,
+// e.g. to the test servers on the loopback interface.
+func WithInsecureTargets() ClientOption {
+	return func(client *Client) {
+		client.insecure = true
+	}
+}
+
+// NewClient creates a new Client
+func NewClient(timeout time.Duration, opts ...ClientOption) *Client {
+	client := &Client{}
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if !client.insecure {
+		dialer.Control = checkAddress
+	}
+	client.httpClient = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// no proxy, the addresses are checked when dialing the subscriber
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return client
+}
+
+// checkAddress refuses to connect to the addresses which are not public.
+// It is called with the resolved address, right before the connection.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	return nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// Deliver posts the event to the URL and returns the response status code.
+// Any status outside of 2xx is reported as an error.
+func (client *Client) Deliver(ctx context.Context, rawURL string, secret string, deliveryID int64, event Event) (int, error) {
+	if !client.insecure {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return 0, err
+		}
+		if u.Scheme != "https" {
+			return 0, fmt.Errorf("%w: %s is not https", ErrForbiddenAddress, rawURL)
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("cannot marshal event: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+	request.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(EventHeader, event.Type)
+	request.Header.Set(DeliveryHeader, strconv.FormatInt(deliveryID, 10))
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	// drain the body so the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(response.Body, 1<<16))
+
+	// the redirects are not followed, and fail like any other status
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
+
+// Backoff returns the delay before retrying a delivery which failed attempts times.
+// The delay doubles at each attempt up to max, with up to 10% of jitter so the
+// retries of a failing endpoint don't all happen at once.
+func Backoff(attempts int, base time.Duration, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/10 + 1))
+	return delay + jitter
+}
@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliver(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	event := Event{
+		ID:        42,
+		Type:      "transfer.created",
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Data:      json.RawMessage(`{"amount":10}`),
+	}
+
+	testCases := []struct {
+		name       string
+		status     int
+		checkError func(t *testing.T, status int, err error)
+	}{
+		{
+			name:   "OK",
+			status: http.StatusNoContent,
+			checkError: func(t *testing.T, status int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, http.StatusNoContent, status)
+			},
+		},
+		{
+			name:   "ServerError",
+			status: http.StatusBadGateway,
+			checkError: func(t *testing.T, status int, err error) {
+				require.Error(t, err)
+				require.Equal(t, http.StatusBadGateway, status)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+				require.NoError(t, err)
+				require.True(t, Verify(secret, timestamp, body, r.Header.Get(SignatureHeader)))
+				require.Equal(t, event.Type, r.Header.Get(EventHeader))
+				require.Equal(t, "7", r.Header.Get(DeliveryHeader))
+
+				var got Event
+				require.NoError(t, json.Unmarshal(body, &got))
+				require.Equal(t, event.ID, got.ID)
+
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			client := NewClient(time.Second, WithInsecureTargets())
+			status, err := client.Deliver(context.Background(), server.URL, secret, 7, event)
+			tc.checkError(t, status, err)
+		})
+	}
+}
+
+func TestDeliverForbiddenAddress(t *testing.T) {
+	delivered := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered = true
+	}))
+	defer server.Close()
+
+	client := NewClient(time.Second)
+	for _, url := range []string{
+		server.URL,
+		"http://example.com/hooks",
+		"https://127.0.0.1/hooks",
+		"https://169.254.169.254/latest/meta-data",
+		"https://10.0.0.1/hooks",
+		"https://[::1]/hooks",
+	} {
+		status, err := client.Deliver(context.Background(), url, "secret", 7, Event{})
+		require.ErrorIs(t, err, ErrForbiddenAddress, url)
+		require.Zero(t, status)
+	}
+	require.False(t, delivered)
+}
+
+func TestDeliverNoRedirect(t *testing.T) {
+	redirected := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	client := NewClient(time.Second, WithInsecureTargets())
+	status, err := client.Deliver(context.Background(), server.URL, "secret", 7, Event{})
+	require.Error(t, err)
+	require.Equal(t, http.StatusTemporaryRedirect, status)
+	require.False(t, redirected)
+}
+
+func TestBackoff(t *testing.T) {
+	base := time.Second
+	max := time.Minute
+
+	require.InDelta(t, float64(base), float64(Backoff(1, base, max)), float64(base)/10)
+	require.InDelta(t, float64(4*base), float64(Backoff(3, base, max)), float64(4*base)/10)
+	require.InDelta(t, float64(max), float64(Backoff(20, base, max)), float64(max)/10)
+
+	for attempts := 1; attempts < 30; attempts++ {
+		delay := Backoff(attempts, base, max)
+		require.GreaterOrEqual(t, delay, base)
+		require.LessOrEqual(t, delay, max+max/10)
+	}
+}
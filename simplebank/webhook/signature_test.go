@@ -0,0 +1,27 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignature(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 2*secretSize)
+
+	body := []byte(`{"id":1,"type":"transfer.created"}`)
+	timestamp := time.Now().Unix()
+
+	signature := Sign(secret, timestamp, body)
+	require.True(t, Verify(secret, timestamp, body, signature))
+
+	require.False(t, Verify(secret, timestamp+1, body, signature))
+	require.False(t, Verify(secret, timestamp, []byte(`{}`), signature))
+
+	otherSecret, err := GenerateSecret()
+	require.NoError(t, err)
+	require.False(t, Verify(otherSecret, timestamp, body, signature))
+}
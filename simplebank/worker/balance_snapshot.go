@@ -0,0 +1,37 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+)
+
+// BalanceSnapshotJob refreshes the account_daily_balances table from the entries.
+type BalanceSnapshotJob struct {
+	store db.Store
+}
+
+// NewBalanceSnapshotJob creates a new BalanceSnapshotJob
+func NewBalanceSnapshotJob(store db.Store) *BalanceSnapshotJob {
+	return &BalanceSnapshotJob{store: store}
+}
+
+func (job *BalanceSnapshotJob) Name() string {
+	return "balance_snapshot"
+}
+
+// Run recomputes the daily snapshots starting from the latest snapshotted day.
+// That day is refreshed again since it may have been captured while still in progress.
+func (job *BalanceSnapshotJob) Run(ctx context.Context) error {
+	latestDay, err := job.store.GetLatestDailyBalanceDay(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot get latest snapshot day: %w", err)
+	}
+
+	_, err = job.store.RefreshDailyBalances(ctx, latestDay)
+	if err != nil {
+		return fmt.Errorf("cannot refresh daily balances: %w", err)
+	}
+	return nil
+}
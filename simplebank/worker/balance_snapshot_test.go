@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceSnapshotJob(t *testing.T) {
+	latestDay := time.Date(2022, 7, 20, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLatestDailyBalanceDay(gomock.Any()).
+					Times(1).
+					Return(latestDay, nil)
+				store.EXPECT().
+					RefreshDailyBalances(gomock.Any(), gomock.Eq(latestDay)).
+					Times(1).
+					Return(int64(3), nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "LatestDayError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLatestDailyBalanceDay(gomock.Any()).
+					Times(1).
+					Return(time.Time{}, sql.ErrConnDone)
+				store.EXPECT().
+					RefreshDailyBalances(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+		{
+			name: "RefreshError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLatestDailyBalanceDay(gomock.Any()).
+					Times(1).
+					Return(latestDay, nil)
+				store.EXPECT().
+					RefreshDailyBalances(gomock.Any(), gomock.Eq(latestDay)).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			job := NewBalanceSnapshotJob(store)
+			tc.checkError(t, job.Run(context.Background()))
+		})
+	}
+}
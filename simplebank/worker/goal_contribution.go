@@ -0,0 +1,63 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+)
+
+// goalContributionBatchSize is the maximum number of contributions made per run.
+const goalContributionBatchSize = 100
+
+// GoalContributionJob makes the scheduled contributions of the savings goals.
+type GoalContributionJob struct {
+	store db.Store
+}
+
+// NewGoalContributionJob creates a new GoalContributionJob
+func NewGoalContributionJob(store db.Store) *GoalContributionJob {
+	return &GoalContributionJob{store: store}
+}
+
+func (job *GoalContributionJob) Name() string {
+	return "goal_contribution"
+}
+
+// Run makes every contribution which is due. A failing goal doesn't prevent
+// the others from being processed, it will be retried on the next run.
+func (job *GoalContributionJob) Run(ctx context.Context) error {
+	arg := db.ListDueGoalContributionsParams{
+		Now:        time.Now(),
+		LimitCount: goalContributionBatchSize,
+	}
+	goals, err := job.store.ListDueGoalContributions(ctx, arg)
+	if err != nil {
+		return fmt.Errorf("cannot list due contributions: %w", err)
+	}
+
+	var failed int
+	for _, goal := range goals {
+		result, err := job.store.ContributeGoalTx(ctx, goal.ID)
+		if errors.Is(err, db.ErrGoalNotDue) {
+			// made by a concurrent run meanwhile
+			continue
+		}
+		if err != nil {
+			log.Printf("cannot contribute to goal %d: %v", goal.ID, err)
+			failed++
+			continue
+		}
+		if result.Skipped {
+			log.Printf("contribution to goal %d skipped: insufficient funds", goal.ID)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d contributions failed", failed, len(goals))
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoalContributionJob(t *testing.T) {
+	goals := []db.Goal{
+		{ID: 1, AccountID: 10, ContributionAmount: 50, ContributionInterval: "week"},
+		{ID: 2, AccountID: 11, ContributionAmount: 20, ContributionInterval: "month"},
+	}
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListDueGoalContributions(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(goals, nil)
+				store.EXPECT().
+					ContributeGoalTx(gomock.Any(), gomock.Eq(goals[0].ID)).
+					Times(1).
+					Return(db.GoalTransferTxResult{Goal: goals[0]}, nil)
+				store.EXPECT().
+					ContributeGoalTx(gomock.Any(), gomock.Eq(goals[1].ID)).
+					Times(1).
+					Return(db.GoalTransferTxResult{Goal: goals[1], Skipped: true}, nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "ContinueAfterFailure",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListDueGoalContributions(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(goals, nil)
+				store.EXPECT().
+					ContributeGoalTx(gomock.Any(), gomock.Eq(goals[0].ID)).
+					Times(1).
+					Return(db.GoalTransferTxResult{}, sql.ErrConnDone)
+				store.EXPECT().
+					ContributeGoalTx(gomock.Any(), gomock.Eq(goals[1].ID)).
+					Times(1).
+					Return(db.GoalTransferTxResult{Goal: goals[1]}, nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "NotDueAnymore",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListDueGoalContributions(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(goals, nil)
+				store.EXPECT().
+					ContributeGoalTx(gomock.Any(), gomock.Eq(goals[0].ID)).
+					Times(1).
+					Return(db.GoalTransferTxResult{}, db.ErrGoalNotDue)
+				store.EXPECT().
+					ContributeGoalTx(gomock.Any(), gomock.Eq(goals[1].ID)).
+					Times(1).
+					Return(db.GoalTransferTxResult{Goal: goals[1]}, nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "ListError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListDueGoalContributions(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Goal{}, sql.ErrConnDone)
+				store.EXPECT().
+					ContributeGoalTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			job := NewGoalContributionJob(store)
+			tc.checkError(t, job.Run(context.Background()))
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+)
+
+// outboxDispatchBatchSize is the maximum number of events dispatched per transaction.
+const outboxDispatchBatchSize = 100
+
+// OutboxDispatchJob turns the outbox events into webhook deliveries.
+type OutboxDispatchJob struct {
+	store db.Store
+}
+
+// NewOutboxDispatchJob creates a new OutboxDispatchJob
+func NewOutboxDispatchJob(store db.Store) *OutboxDispatchJob {
+	return &OutboxDispatchJob{store: store}
+}
+
+func (job *OutboxDispatchJob) Name() string {
+	return "outbox_dispatch"
+}
+
+// Run dispatches batches of events until the outbox is drained.
+func (job *OutboxDispatchJob) Run(ctx context.Context) error {
+	for {
+		dispatched, err := job.store.DispatchOutboxTx(ctx, outboxDispatchBatchSize)
+		if err != nil {
+			return fmt.Errorf("cannot dispatch outbox events: %w", err)
+		}
+		if dispatched < outboxDispatchBatchSize {
+			return nil
+		}
+	}
+}
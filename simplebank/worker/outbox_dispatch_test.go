@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxDispatchJob(t *testing.T) {
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		{
+			name: "DrainOutbox",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						DispatchOutboxTx(gomock.Any(), gomock.Eq(int32(outboxDispatchBatchSize))).
+						Times(2).
+						Return(outboxDispatchBatchSize, nil),
+					store.EXPECT().
+						DispatchOutboxTx(gomock.Any(), gomock.Eq(int32(outboxDispatchBatchSize))).
+						Times(1).
+						Return(3, nil),
+				)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "DispatchError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DispatchOutboxTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(0, sql.ErrConnDone)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			job := NewOutboxDispatchJob(store)
+			tc.checkError(t, job.Run(context.Background()))
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package worker
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+)
+
+// PartitionMaintenanceJob creates the monthly partitions of the entries and
+// transfers ahead of time, and archives the old ones to gzipped CSV files
+// before dropping them.
+type PartitionMaintenanceJob struct {
+	store           db.Store
+	archiveDir      string
+	premakeMonths   int
+	retentionMonths int
+	now             func() time.Time
+}
+
+// NewPartitionMaintenanceJob creates a new PartitionMaintenanceJob keeping
+// premakeMonths partitions ahead of the current month, and retentionMonths
+// before it. A retentionMonths of 0 keeps every partition, as dropping them
+// destroys ledger data and must be opted into.
+func NewPartitionMaintenanceJob(store db.Store, archiveDir string, premakeMonths, retentionMonths int) *PartitionMaintenanceJob {
+	return &PartitionMaintenanceJob{
+		store:           store,
+		archiveDir:      archiveDir,
+		premakeMonths:   premakeMonths,
+		retentionMonths: retentionMonths,
+		now:             time.Now,
+	}
+}
+
+func (job *PartitionMaintenanceJob) Name() string {
+	return "partition_maintenance"
+}
+
+// Run creates the partitions of the current month and of the months ahead,
+// then archives and drops the partitions older than the retention.
+func (job *PartitionMaintenanceJob) Run(ctx context.Context) error {
+	now := job.now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	for _, table := range db.PartitionedTables {
+		for i := 0; i <= job.premakeMonths; i++ {
+			_, err := job.store.CreateMonthlyPartition(ctx, table, month.AddDate(0, i, 0))
+			if err != nil {
+				return fmt.Errorf("cannot create partition of %s: %w", table, err)
+			}
+		}
+
+		if job.retentionMonths <= 0 {
+			continue
+		}
+
+		partitions, err := job.store.ListPartitions(ctx, table)
+		if err != nil {
+			return fmt.Errorf("cannot list partitions of %s: %w", table, err)
+		}
+
+		cutoff := month.AddDate(0, -job.retentionMonths, 0)
+		for _, partition := range partitions {
+			if !partition.Month.Before(cutoff) {
+				continue
+			}
+			if err := job.archive(ctx, partition); err != nil {
+				return fmt.Errorf("cannot archive partition %s: %w", partition.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// archive writes the partition to <archiveDir>/<partition>.csv.gz, then drops it.
+// The file is only put in place once complete, and the partition is only
+// dropped once the file is.
+func (job *PartitionMaintenanceJob) archive(ctx context.Context, partition db.Partition) error {
+	if err := os.MkdirAll(job.archiveDir, 0o750); err != nil {
+		return err
+	}
+
+	path := filepath.Join(job.archiveDir, partition.Name+".csv.gz")
+	tmpPath := path + ".tmp"
+	rows, err := job.writeArchive(ctx, partition, tmpPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := job.store.DropPartition(ctx, partition); err != nil {
+		return err
+	}
+	log.Printf("archived %d rows of partition %s to %s", rows, partition.Name, path)
+	return nil
+}
+
+func (job *PartitionMaintenanceJob) writeArchive(ctx context.Context, partition db.Partition, path string) (int64, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o640)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	rows, err := job.store.ArchivePartition(ctx, partition, gz)
+	if err != nil {
+		return 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, err
+	}
+	if err := file.Sync(); err != nil {
+		return 0, err
+	}
+	return rows, file.Close()
+}
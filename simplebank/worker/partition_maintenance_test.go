@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionMaintenanceJob(t *testing.T) {
+	now := time.Date(2022, 10, 15, 12, 0, 0, 0, time.UTC)
+	month := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	oldPartition := db.Partition{Table: "entries", Name: "entries_p202009", Month: time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)}
+	keptPartition := db.Partition{Table: "entries", Name: "entries_p202010", Month: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)}
+
+	expectCreate := func(store *mockdb.MockStore, tables ...string) {
+		for _, table := range tables {
+			for i := 0; i <= 2; i++ {
+				store.EXPECT().
+					CreateMonthlyPartition(gomock.Any(), table, month.AddDate(0, i, 0)).
+					Times(1).
+					Return(table, nil)
+			}
+		}
+	}
+
+	testCases := []struct {
+		name            string
+		retentionMonths int
+		buildStubs      func(store *mockdb.MockStore)
+		checkResult     func(t *testing.T, archiveDir string, err error)
+	}{
+		{
+			name:            "OK",
+			retentionMonths: 24,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectCreate(store, db.PartitionedTables...)
+				store.EXPECT().
+					ListPartitions(gomock.Any(), "entries").
+					Times(1).
+					Return([]db.Partition{oldPartition, keptPartition}, nil)
+				store.EXPECT().
+					ListPartitions(gomock.Any(), "transfers").
+					Times(1).
+					Return([]db.Partition{}, nil)
+				store.EXPECT().
+					ArchivePartition(gomock.Any(), oldPartition, gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, _ db.Partition, w io.Writer) (int64, error) {
+						_, err := io.WriteString(w, "id,amount\n1,10\n")
+						return 1, err
+					})
+				store.EXPECT().
+					DropPartition(gomock.Any(), oldPartition).
+					Times(1).
+					Return(nil)
+			},
+			checkResult: func(t *testing.T, archiveDir string, err error) {
+				require.NoError(t, err)
+
+				file, err := os.Open(filepath.Join(archiveDir, "entries_p202009.csv.gz"))
+				require.NoError(t, err)
+				defer file.Close()
+				gz, err := gzip.NewReader(file)
+				require.NoError(t, err)
+				content, err := io.ReadAll(gz)
+				require.NoError(t, err)
+				require.Equal(t, "id,amount\n1,10\n", string(content))
+			},
+		},
+		{
+			name:            "RetentionDisabled",
+			retentionMonths: 0,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectCreate(store, db.PartitionedTables...)
+				store.EXPECT().ListPartitions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResult: func(t *testing.T, archiveDir string, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:            "CreateError",
+			retentionMonths: 24,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateMonthlyPartition(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return("", sql.ErrConnDone)
+				store.EXPECT().ListPartitions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResult: func(t *testing.T, archiveDir string, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+		{
+			name:            "ArchiveError",
+			retentionMonths: 24,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectCreate(store, "entries")
+				store.EXPECT().
+					ListPartitions(gomock.Any(), "entries").
+					Times(1).
+					Return([]db.Partition{oldPartition}, nil)
+				store.EXPECT().
+					ArchivePartition(gomock.Any(), oldPartition, gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+				store.EXPECT().DropPartition(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResult: func(t *testing.T, archiveDir string, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+
+				// the partition is kept and no partial archive is left behind
+				files, err := os.ReadDir(archiveDir)
+				require.NoError(t, err)
+				require.Empty(t, files)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			archiveDir := t.TempDir()
+			job := NewPartitionMaintenanceJob(store, archiveDir, 2, tc.retentionMonths)
+			job.now = func() time.Time { return now }
+			tc.checkResult(t, archiveDir, job.Run(context.Background()))
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+)
+
+// RevokedTokenCleanupJob purges the revoked tokens which have expired since,
+// as the expired tokens are rejected anyway. It also purges the expired OAuth
+// authorization codes, once the tokens issued for them have expired too:
+// until then, using a code again must still revoke its token. The expired MFA
+// challenges of the logins, the email verification links and the password
+// reset links are purged along.
+type RevokedTokenCleanupJob struct {
+	store              db.Store
+	oauthTokenDuration time.Duration
+	now                func() time.Time
+}
+
+// NewRevokedTokenCleanupJob creates a new RevokedTokenCleanupJob
+func NewRevokedTokenCleanupJob(store db.Store, oauthTokenDuration time.Duration) *RevokedTokenCleanupJob {
+	return &RevokedTokenCleanupJob{store: store, oauthTokenDuration: oauthTokenDuration, now: time.Now}
+}
+
+func (job *RevokedTokenCleanupJob) Name() string {
+	return "revoked_token_cleanup"
+}
+
+// Run deletes the revoked tokens, the authorization codes, the MFA challenges,
+// the email verification links and the password reset links expired by now.
+func (job *RevokedTokenCleanupJob) Run(ctx context.Context) error {
+	now := job.now()
+
+	_, err := job.store.DeleteExpiredRevokedTokens(ctx, now)
+	if err != nil {
+		return fmt.Errorf("cannot delete expired revoked tokens: %w", err)
+	}
+
+	_, err = job.store.DeleteExpiredOAuthAuthorizationCodes(ctx, now.Add(-job.oauthTokenDuration))
+	if err != nil {
+		return fmt.Errorf("cannot delete expired authorization codes: %w", err)
+	}
+
+	_, err = job.store.DeleteExpiredMFAChallenges(ctx, now)
+	if err != nil {
+		return fmt.Errorf("cannot delete expired MFA challenges: %w", err)
+	}
+
+	_, err = job.store.DeleteExpiredVerifyEmails(ctx, now)
+	if err != nil {
+		return fmt.Errorf("cannot delete expired email verifications: %w", err)
+	}
+
+	_, err = job.store.DeleteExpiredResetPasswords(ctx, now)
+	if err != nil {
+		return fmt.Errorf("cannot delete expired password resets: %w", err)
+	}
+	return nil
+}
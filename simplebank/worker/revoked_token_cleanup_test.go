@@ -0,0 +1,124 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokedTokenCleanupJob(t *testing.T) {
+	now := time.Date(2022, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteExpiredRevokedTokens(gomock.Any(), gomock.Eq(now)).
+					Times(1).
+					Return(int64(3), nil)
+				store.EXPECT().
+					DeleteExpiredOAuthAuthorizationCodes(gomock.Any(), gomock.Eq(now.Add(-time.Hour))).
+					Times(1).
+					Return(int64(2), nil)
+				store.EXPECT().
+					DeleteExpiredMFAChallenges(gomock.Any(), gomock.Eq(now)).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					DeleteExpiredVerifyEmails(gomock.Any(), gomock.Eq(now)).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					DeleteExpiredResetPasswords(gomock.Any(), gomock.Eq(now)).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "DeleteError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteExpiredRevokedTokens(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+				store.EXPECT().
+					DeleteExpiredOAuthAuthorizationCodes(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+		{
+			name: "DeleteCodesError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteExpiredRevokedTokens(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					DeleteExpiredOAuthAuthorizationCodes(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+				store.EXPECT().
+					DeleteExpiredMFAChallenges(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+		{
+			name: "DeleteMFAChallengesError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteExpiredRevokedTokens(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					DeleteExpiredOAuthAuthorizationCodes(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					DeleteExpiredMFAChallenges(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+				store.EXPECT().
+					DeleteExpiredVerifyEmails(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			job := NewRevokedTokenCleanupJob(store, time.Hour)
+			job.now = func() time.Time { return now }
+			tc.checkError(t, job.Run(context.Background()))
+		})
+	}
+}
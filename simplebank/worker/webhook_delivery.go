@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/webhook"
+)
+
+const (
+	webhookDeliveryBatchSize = 50
+	// webhookDeliveryLease is how long a claimed delivery is hidden from the
+	// other workers while it is being sent.
+	webhookDeliveryLease = time.Minute
+	webhookBackoffBase   = 10 * time.Second
+	webhookBackoffMax    = 6 * time.Hour
+)
+
+// Delivery statuses of the webhook_deliveries table.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// WebhookDeliveryJob sends the due webhook deliveries, retrying the failed
+// ones with an exponential backoff until maxAttempts, when they are dead-lettered.
+type WebhookDeliveryJob struct {
+	store       db.Store
+	client      *webhook.Client
+	maxAttempts int32
+}
+
+// NewWebhookDeliveryJob creates a new WebhookDeliveryJob
+func NewWebhookDeliveryJob(store db.Store, client *webhook.Client, maxAttempts int32) *WebhookDeliveryJob {
+	return &WebhookDeliveryJob{
+		store:       store,
+		client:      client,
+		maxAttempts: maxAttempts,
+	}
+}
+
+func (job *WebhookDeliveryJob) Name() string {
+	return "webhook_delivery"
+}
+
+// Run sends one batch of due deliveries and records the outcome of each one.
+func (job *WebhookDeliveryJob) Run(ctx context.Context) error {
+	arg := db.ClaimDueWebhookDeliveriesParams{
+		LeaseSeconds: int32(webhookDeliveryLease / time.Second),
+		LimitCount:   webhookDeliveryBatchSize,
+	}
+	deliveries, err := job.store.ClaimDueWebhookDeliveries(ctx, arg)
+	if err != nil {
+		return fmt.Errorf("cannot claim deliveries: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		event := webhook.Event{
+			ID:        delivery.EventID,
+			Type:      delivery.EventType,
+			CreatedAt: delivery.EventCreatedAt,
+			Data:      delivery.Payload,
+		}
+		status, err := job.client.Deliver(ctx, delivery.Url, delivery.Secret, delivery.ID, event)
+
+		update := job.nextState(delivery, status, err, time.Now())
+		if _, err := job.store.UpdateWebhookDelivery(ctx, update); err != nil {
+			return fmt.Errorf("cannot update delivery %d: %w", delivery.ID, err)
+		}
+	}
+	return nil
+}
+
+// nextState computes the state of a delivery after an attempt.
+func (job *WebhookDeliveryJob) nextState(
+	delivery db.ClaimDueWebhookDeliveriesRow,
+	status int,
+	deliverErr error,
+	now time.Time,
+) db.UpdateWebhookDeliveryParams {
+	arg := db.UpdateWebhookDeliveryParams{
+		ID:             delivery.ID,
+		Status:         DeliverySucceeded,
+		NextAttemptAt:  now,
+		ResponseStatus: int32(status),
+	}
+	if deliverErr == nil {
+		return arg
+	}
+
+	attempts := delivery.Attempts + 1
+	arg.LastError = deliverErr.Error()
+	if attempts >= job.maxAttempts {
+		arg.Status = DeliveryDead
+		return arg
+	}
+
+	arg.Status = DeliveryPending
+	arg.NextAttemptAt = now.Add(webhook.Backoff(int(attempts), webhookBackoffBase, webhookBackoffMax))
+	return arg
+}
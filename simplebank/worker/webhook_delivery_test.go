@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/ebaudet/simplebank/db/mock"
+	db "github.com/ebaudet/simplebank/db/sqlc"
+	"github.com/ebaudet/simplebank/webhook"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDeliveryJob(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get(webhook.EventHeader))
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	deliveries := []db.ClaimDueWebhookDeliveriesRow{
+		{ID: 1, Url: server.URL + "/ok", Secret: "secret", EventID: 10, EventType: db.EventAccountCreated, Payload: []byte(`{}`)},
+		{ID: 2, Url: server.URL + "/fail", Secret: "secret", EventID: 11, EventType: db.EventTransferCreated, Payload: []byte(`{}`)},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ClaimDueWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(deliveries, nil)
+	store.EXPECT().
+		UpdateWebhookDelivery(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ context.Context, arg db.UpdateWebhookDeliveryParams) (db.WebhookDelivery, error) {
+			switch arg.ID {
+			case 1:
+				require.Equal(t, DeliverySucceeded, arg.Status)
+				require.Equal(t, int32(http.StatusNoContent), arg.ResponseStatus)
+			case 2:
+				require.Equal(t, DeliveryPending, arg.Status)
+				require.Equal(t, int32(http.StatusInternalServerError), arg.ResponseStatus)
+				require.NotEmpty(t, arg.LastError)
+			}
+			return db.WebhookDelivery{ID: arg.ID, Status: arg.Status}, nil
+		})
+
+	job := NewWebhookDeliveryJob(store, webhook.NewClient(time.Second, webhook.WithInsecureTargets()), 3)
+	require.NoError(t, job.Run(context.Background()))
+	require.Equal(t, []string{db.EventAccountCreated, db.EventTransferCreated}, received)
+}
+
+func TestWebhookDeliveryJobClaimError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ClaimDueWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil, sql.ErrConnDone)
+	store.EXPECT().
+		UpdateWebhookDelivery(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	job := NewWebhookDeliveryJob(store, webhook.NewClient(time.Second, webhook.WithInsecureTargets()), 3)
+	require.ErrorIs(t, job.Run(context.Background()), sql.ErrConnDone)
+}
+
+func TestWebhookDeliveryNextState(t *testing.T) {
+	job := NewWebhookDeliveryJob(nil, nil, 3)
+	now := time.Now()
+	deliverErr := errors.New("unexpected status 502")
+
+	// success
+	arg := job.nextState(db.ClaimDueWebhookDeliveriesRow{ID: 1}, http.StatusOK, nil, now)
+	require.Equal(t, DeliverySucceeded, arg.Status)
+	require.Empty(t, arg.LastError)
+
+	// failure with attempts left is retried later
+	arg = job.nextState(db.ClaimDueWebhookDeliveriesRow{ID: 1, Attempts: 1}, http.StatusBadGateway, deliverErr, now)
+	require.Equal(t, DeliveryPending, arg.Status)
+	require.Equal(t, deliverErr.Error(), arg.LastError)
+	require.True(t, arg.NextAttemptAt.After(now))
+
+	// last attempt is dead-lettered
+	arg = job.nextState(db.ClaimDueWebhookDeliveriesRow{ID: 1, Attempts: 2}, http.StatusBadGateway, deliverErr, now)
+	require.Equal(t, DeliveryDead, arg.Status)
+}
@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Job is a unit of background work executed periodically.
+type Job interface {
+	// Name identifies the job in the logs
+	Name() string
+
+	// Run executes the job once
+	Run(ctx context.Context) error
+}
+
+// RunPeriodic runs the job immediately, then every interval until the context is cancelled.
+// Errors are logged and don't stop the following runs.
+func RunPeriodic(ctx context.Context, job Job, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job.Run(ctx); err != nil {
+			log.Printf("job %s failed: %v", job.Name(), err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}